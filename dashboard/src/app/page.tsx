@@ -7,12 +7,15 @@ import { LoginForm } from '@/components/LoginForm'
 export default function Home() {
   const [token, setToken] = useState<string | null>(null)
   const [loading, setLoading] = useState(true)
+  // Alert links from notifications open /?alert=<fingerprint>
+  const [focusAlert, setFocusAlert] = useState<string | null>(null)
 
   useEffect(() => {
     const storedToken = localStorage.getItem('token')
     if (storedToken) {
       setToken(storedToken)
     }
+    setFocusAlert(new URLSearchParams(window.location.search).get('alert'))
     setLoading(false)
   }, [])
 
@@ -38,5 +41,5 @@ export default function Home() {
     return <LoginForm onLogin={handleLogin} />
   }
 
-  return <Dashboard token={token} onLogout={handleLogout} />
+  return <Dashboard token={token} onLogout={handleLogout} focusAlert={focusAlert} />
 }
@@ -1,6 +1,6 @@
 'use client'
 
-import { useState } from 'react'
+import { useState, useEffect } from 'react'
 import { AlertTriangle, AlertCircle, Info, CheckCircle, Clock, RefreshCw } from 'lucide-react'
 
 interface Alert {
@@ -19,13 +19,21 @@ interface Alert {
 
 interface AlertListProps {
   alerts: Alert[]
+  // ID of an alert to highlight and scroll to
+  highlightId?: string
   onAcknowledge: (alertId: string) => void
   onRefresh: () => void
 }
 
-export function AlertList({ alerts, onAcknowledge, onRefresh }: AlertListProps) {
+export function AlertList({ alerts, highlightId, onAcknowledge, onRefresh }: AlertListProps) {
   const [filter, setFilter] = useState<'all' | 'critical' | 'warning' | 'info'>('all')
-  const [showAcknowledged, setShowAcknowledged] = useState(false)
+  const [showAcknowledged, setShowAcknowledged] = useState(!!highlightId)
+
+  useEffect(() => {
+    if (highlightId) {
+      document.getElementById(`alert-${highlightId}`)?.scrollIntoView({ block: 'center' })
+    }
+  }, [highlightId, alerts])
 
   const severityConfig = {
     critical: {
@@ -125,7 +133,10 @@ export function AlertList({ alerts, onAcknowledge, onRefresh }: AlertListProps)
             return (
               <div
                 key={alert.id}
-                className={`p-4 ${config.bg} ${alert.acknowledged ? 'opacity-60' : ''}`}
+                id={`alert-${alert.id}`}
+                className={`p-4 ${config.bg} ${alert.acknowledged ? 'opacity-60' : ''} ${
+                  alert.id === highlightId ? 'ring-2 ring-inset ring-primary-600' : ''
+                }`}
               >
                 <div className="flex items-start gap-3">
                   <div className={`p-2 rounded-lg ${config.badge}`}>
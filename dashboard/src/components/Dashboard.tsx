@@ -25,6 +25,8 @@ import { useWebSocket } from '@/hooks/useWebSocket'
 interface DashboardProps {
   token: string
   onLogout: () => void
+  // Fingerprint of an alert to open on the alerts tab
+  focusAlert?: string | null
 }
 
 interface DashboardStats {
@@ -50,6 +52,7 @@ interface ServiceStats {
 
 interface Alert {
   id: string
+  fingerprint: string
   service_name: string
   severity: string
   title: string
@@ -58,9 +61,9 @@ interface Alert {
   acknowledged: boolean
 }
 
-export function Dashboard({ token, onLogout }: DashboardProps) {
+export function Dashboard({ token, onLogout, focusAlert }: DashboardProps) {
   const [stats, setStats] = useState<DashboardStats | null>(null)
-  const [activeTab, setActiveTab] = useState<'overview' | 'alerts' | 'rules'>('overview')
+  const [activeTab, setActiveTab] = useState<'overview' | 'alerts' | 'rules'>(focusAlert ? 'alerts' : 'overview')
   const [sidebarOpen, setSidebarOpen] = useState(false)
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState('')
@@ -248,7 +251,7 @@ export function Dashboard({ token, onLogout }: DashboardProps) {
                 <div className="bg-white rounded-xl shadow-sm border border-gray-200">
                   {stats.recent_alerts.map((alert) => (
                     <div
-                      key={alert.id}
+                      key={alert.fingerprint}
                       className={`p-4 border-b border-gray-100 last:border-b-0 flex items-center gap-4 ${
                         alert.severity === 'critical' ? 'bg-red-50' :
                         alert.severity === 'warning' ? 'bg-yellow-50' : ''
@@ -276,7 +279,7 @@ export function Dashboard({ token, onLogout }: DashboardProps) {
         {activeTab === 'alerts' && (
           <AlertList 
             alerts={stats?.recent_alerts?.map(a => ({
-              id: a.id,
+              id: a.fingerprint,
               service: a.service_name,
               type: 'threshold',
               severity: a.severity as 'critical' | 'warning' | 'info',
@@ -286,6 +289,7 @@ export function Dashboard({ token, onLogout }: DashboardProps) {
               timestamp: a.timestamp,
               acknowledged: a.acknowledged,
             })) || []}
+            highlightId={focusAlert || undefined}
             onAcknowledge={async (alertId) => {
               try {
                 await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/alerts/${alertId}/acknowledge`, {
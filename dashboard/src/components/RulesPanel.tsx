@@ -16,6 +16,8 @@ interface ThresholdRule {
   cooldown: number
   created_at: string
   updated_at: string
+  source?: 'api' | 'file'
+  source_file?: string
 }
 
 interface RulesPanelProps {
@@ -60,8 +62,10 @@ const metricOptions = [
   { value: 'cpu', label: 'CPU Usage' },
   { value: 'memory', label: 'Memory Usage' },
   { value: 'latency', label: 'Latency' },
+  { value: 'latency_p95', label: 'Latency (p95)' },
+  { value: 'latency_p99', label: 'Latency (p99)' },
   { value: 'error_rate', label: 'Error Rate' },
-  { value: 'request_count', label: 'Request Count' },
+  { value: 'request_rate', label: 'Request Rate' },
 ]
 
 export function RulesPanel({
@@ -319,6 +323,14 @@ export function RulesPanel({
                               Disabled
                             </span>
                           )}
+                          {rule.source === 'file' && (
+                            <span
+                              className="text-xs px-2 py-0.5 rounded bg-gray-100 text-gray-500"
+                              title="Managed by a rule file; edit the file to change it"
+                            >
+                              {rule.source_file}
+                            </span>
+                          )}
                         </div>
                         <p className="text-sm text-gray-500 mt-1">{rule.description}</p>
                         <div className="flex items-center gap-4 mt-2 text-sm text-gray-600">
@@ -336,6 +348,7 @@ export function RulesPanel({
                       </div>
                     </div>
 
+                    {rule.source !== 'file' && (
                     <div className="flex items-center gap-2">
                       <button
                         onClick={() => onToggleRule(rule.id, !rule.enabled)}
@@ -365,6 +378,7 @@ export function RulesPanel({
                         <Trash2 className="w-4 h-4" />
                       </button>
                     </div>
+                    )}
                   </div>
                 </div>
               )
@@ -11,6 +11,7 @@ interface ServiceCardProps {
     latency_p95: number
     error_rate: number
     last_update: string
+    last_heartbeat?: string
   }
 }
 
@@ -19,6 +20,7 @@ export function ServiceCard({ service }: ServiceCardProps) {
     healthy: 'bg-green-500',
     warning: 'bg-yellow-500',
     unhealthy: 'bg-red-500',
+    stale: 'bg-orange-500',
     unknown: 'bg-gray-400',
   }
 
@@ -26,6 +28,7 @@ export function ServiceCard({ service }: ServiceCardProps) {
     healthy: 'bg-green-50 border-green-200',
     warning: 'bg-yellow-50 border-yellow-200',
     unhealthy: 'bg-red-50 border-red-200',
+    stale: 'bg-orange-50 border-orange-200',
     unknown: 'bg-gray-50 border-gray-200',
   }
 
@@ -118,6 +121,11 @@ export function ServiceCard({ service }: ServiceCardProps) {
           Last update: {new Date(service.last_update).toLocaleTimeString()}
         </p>
       )}
+      {service.status === 'stale' && service.last_heartbeat && (
+        <p className="text-xs text-orange-600 mt-1">
+          No data since {new Date(service.last_heartbeat).toLocaleTimeString()}
+        </p>
+      )}
     </div>
   )
 }
@@ -147,6 +147,7 @@ class ApiClient {
     return this.request<
       Array<{
         id: string
+        fingerprint: string
         service_name: string
         type: string
         severity: 'critical' | 'warning' | 'info'
@@ -161,12 +162,12 @@ class ApiClient {
     >('GET', `/api/alerts${query ? `?${query}` : ''}`)
   }
 
-  async getAlert(id: string) {
-    return this.request<object>('GET', `/api/alerts/${id}`)
+  async getAlert(fingerprint: string) {
+    return this.request<object>('GET', `/api/alerts/${fingerprint}`)
   }
 
-  async acknowledgeAlert(id: string) {
-    return this.request<void>('POST', `/api/alerts/${id}/acknowledge`)
+  async acknowledgeAlert(fingerprint: string) {
+    return this.request<void>('POST', `/api/alerts/${fingerprint}/acknowledge`)
   }
 
   // Rules
@@ -224,6 +225,73 @@ class ApiClient {
     return this.request<void>('DELETE', `/api/rules/${id}`)
   }
 
+  async backtestRule(request: {
+    rule_id?: string
+    rule?: {
+      service_name: string
+      metric_type: string
+      threshold: number
+      operator: string
+      severity: string
+      enabled: boolean
+      cooldown_seconds?: number
+    }
+    from?: string
+    to?: string
+    window_seconds?: number
+    cooldown_seconds?: number
+  }) {
+    return this.request<{
+      from: string
+      to: string
+      samples_evaluated: number
+      alert_count: number
+      breach_count: number
+      total_breach_seconds: number
+      alerts: Array<{ timestamp: string; value: number }>
+      breaches: Array<{
+        start: string
+        end: string
+        duration_seconds: number
+        peak_value: number
+        alerts: number
+        open: boolean
+      }>
+    }>('POST', '/api/rules/backtest', request)
+  }
+
+  async getRuleVersions(id: string) {
+    return this.request<
+      Array<{
+        rule_id: string
+        version: number
+        action: 'create' | 'update' | 'delete' | 'rollback'
+        author: string
+        timestamp: string
+        rule: object
+        changes: Array<{ field: string; old: unknown; new: unknown }>
+        rollback_to?: number
+      }>
+    >('GET', `/api/rules/${id}/versions`)
+  }
+
+  async diffRuleVersions(id: string, from?: number, to?: number) {
+    const params = new URLSearchParams()
+    if (from !== undefined) params.append('from', String(from))
+    if (to !== undefined) params.append('to', String(to))
+    const query = params.toString()
+    return this.request<{
+      rule_id: string
+      from: number
+      to: number
+      changes: Array<{ field: string; old: unknown; new: unknown }>
+    }>('GET', `/api/rules/${id}/diff${query ? `?${query}` : ''}`)
+  }
+
+  async rollbackRule(id: string, version: number) {
+    return this.request<object>('POST', `/api/rules/${id}/rollback`, { version })
+  }
+
   // Dashboard Stats
   async getDashboardStats() {
     return this.request<{
@@ -243,6 +311,7 @@ class ApiClient {
           latency_p95: number
           error_rate: number
           last_update: string
+          last_heartbeat?: string
         }
       >
       recent_alerts: object[]
@@ -56,7 +56,7 @@ echo "----------------------------------------"
 echo ""
 
 # Required topics
-REQUIRED_TOPICS=("service-metrics" "service-logs" "alerts" "alerts-dlq")
+REQUIRED_TOPICS=("service-metrics" "service-logs" "alerts" "alerts-dlq" "service-metrics-dlq" "service-logs-dlq")
 
 echo "Verifying required topics..."
 MISSING_TOPICS=()
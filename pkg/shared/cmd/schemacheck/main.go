@@ -0,0 +1,94 @@
+// Command schemacheck fails when a change to the platform event models breaks
+// their consumers. Each event schema is checked against its model and against
+// the snapshot of its last released version in models/schemas. Compatible
+// changes are accepted with -write, which updates the snapshots.
+//
+// Run it from pkg/shared:
+//
+//	go run ./cmd/schemacheck
+//	go run ./cmd/schemacheck -write
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+func main() {
+	dir := flag.String("dir", filepath.Join("models", "schemas"), "directory of the schema snapshots")
+	write := flag.Bool("write", false, "update the snapshots of compatible schema changes")
+	flag.Parse()
+
+	failed := false
+	for _, schema := range models.Schemas() {
+		if err := check(schema, *dir, *write); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", schema.Name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: ok\n", schema.Name)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func check(schema *models.Schema, dir string, write bool) error {
+	if err := schema.CheckModel(); err != nil {
+		return err
+	}
+
+	current, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	current = append(current, '\n')
+
+	path := filepath.Join(dir, snapshotName(schema.Name))
+	snapshot, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if !write {
+			return fmt.Errorf("no snapshot at %s; run with -write to create it", path)
+		}
+		return os.WriteFile(path, current, 0o644)
+	case err != nil:
+		return err
+	}
+
+	var old models.Schema
+	if err := json.Unmarshal(snapshot, &old); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if problems := schema.CheckCompatibility(&old); len(problems) > 0 {
+		return fmt.Errorf("incompatible with %s:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+
+	if bytes.Equal(snapshot, current) {
+		return nil
+	}
+	if !write {
+		return fmt.Errorf("compatible change not in %s; run with -write to update it", path)
+	}
+	return os.WriteFile(path, current, 0o644)
+}
+
+// snapshotName returns the snapshot file of a schema, e.g. service_metric.json.
+func snapshotName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(b.String()) + ".json"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// writeSnapshot writes the snapshot of a schema as released.
+func writeSnapshot(t *testing.T, dir string, schema *models.Schema) {
+	t.Helper()
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshotName(schema.Name)), append(data, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// released returns a copy of the alert schema changed by change.
+func released(change func(*models.Schema)) *models.Schema {
+	schema := &models.Schema{
+		Name:     models.AlertSchema.Name,
+		Fields:   append([]models.SchemaField(nil), models.AlertSchema.Fields...),
+		Reserved: append([]models.SchemaField(nil), models.AlertSchema.Reserved...),
+	}
+	change(schema)
+	return schema
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot *models.Schema // nil for no snapshot
+		write    bool
+		wantErr  string
+	}{
+		{name: "no snapshot", wantErr: "no snapshot"},
+		{name: "no snapshot written", write: true},
+		{name: "unchanged", snapshot: released(func(*models.Schema) {})},
+		{
+			name: "field added",
+			snapshot: released(func(s *models.Schema) {
+				s.Fields = s.Fields[:len(s.Fields)-1]
+			}),
+			wantErr: "compatible change",
+		},
+		{
+			name: "field added and written",
+			snapshot: released(func(s *models.Schema) {
+				s.Fields = s.Fields[:len(s.Fields)-1]
+			}),
+			write: true,
+		},
+		{
+			name: "field removed",
+			snapshot: released(func(s *models.Schema) {
+				s.Fields = append(s.Fields, models.SchemaField{Name: "owner", Number: 99, Type: models.FieldString})
+			}),
+			write:   true,
+			wantErr: `field "owner" was removed`,
+		},
+		{
+			name: "field type changed",
+			snapshot: released(func(s *models.Schema) {
+				s.Fields[0].Type = models.FieldLong
+			}),
+			write:   true,
+			wantErr: `field "id" changed type`,
+		},
+		{
+			name: "field renumbered",
+			snapshot: released(func(s *models.Schema) {
+				s.Fields[0].Number = 99
+			}),
+			write:   true,
+			wantErr: `field "id" changed number`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.snapshot != nil {
+				writeSnapshot(t, dir, tt.snapshot)
+			}
+			path := filepath.Join(dir, "alert.json")
+			before, _ := os.ReadFile(path)
+
+			err := check(models.AlertSchema, dir, tt.write)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				// Rejected changes leave the snapshot as released
+				if after, _ := os.ReadFile(path); string(after) != string(before) {
+					t.Error("snapshot changed by a rejected check")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Accepted schemas pass without -write afterwards
+			if err := check(models.AlertSchema, dir, false); err != nil {
+				t.Errorf("check after accepting: %v", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Alert":         "alert.json",
+		"ServiceMetric": "service_metric.json",
+		"ServiceLog":    "service_log.json",
+	} {
+		if got := snapshotName(name); got != want {
+			t.Errorf("snapshotName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestSnapshotsUpToDate runs the check against the released snapshots.
+func TestSnapshotsUpToDate(t *testing.T) {
+	for _, schema := range models.Schemas() {
+		if err := check(schema, filepath.Join("..", "..", "models", "schemas"), false); err != nil {
+			t.Errorf("%s: %v", schema.Name, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Command topics verifies and creates the platform's Kafka topics against
+// their declared partitions, replication, retention and cleanup policy.
+//
+//	go run ./cmd/topics describe   # report each topic and its drift
+//	go run ./cmd/topics ensure     # create missing topics, then report
+//
+// The brokers, replication factor and security settings are read from the
+// same environment variables as the services and can be overridden by flags.
+// describe exits with 1 when any topic drifted; ensure only when a topic is
+// still missing or has too few partitions.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/kafka"
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/utils"
+)
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "ensure" && os.Args[1] != "describe") {
+		fmt.Fprintln(os.Stderr, "usage: topics ensure|describe [flags]")
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	topicsConfig := utils.LoadKafkaTopicsConfig()
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	brokers := flags.String("brokers", utils.GetEnv("KAFKA_BROKERS", "localhost:9092"), "comma-separated Kafka brokers")
+	replication := flags.Int("replication", topicsConfig.ReplicationFactor, "declared replication factor")
+	topics := flags.String("topics", "", "comma-separated topics to check; all platform topics when empty")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the admin requests")
+	flags.Parse(os.Args[2:])
+
+	logger, err := logging.NewLogger(logging.DefaultConfig("topics"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	security, err := kafka.NewSecurity(utils.LoadKafkaSecurityConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid Kafka security configuration: %v\n", err)
+		os.Exit(1)
+	}
+	admin, err := kafka.NewAdmin(strings.Split(*brokers, ","), security, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	specs := kafka.PlatformTopics(*replication)
+	if *topics != "" {
+		specs = kafka.TopicSpecsFor(*replication, strings.Split(*topics, ",")...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	reports, err := admin.Ensure(ctx, specs, command == "ensure")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	failed := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITIONS\tREPLICATION\tRETENTION\tCLEANUP\tSTATUS")
+	for _, report := range reports {
+		status := "ok"
+		switch {
+		case report.Created:
+			status = "created"
+		case len(report.Drift) > 0:
+			status = "drift: " + strings.Join(report.Drift, "; ")
+		}
+		if command == "ensure" && !report.Usable() || command == "describe" && len(report.Drift) > 0 {
+			failed = true
+		}
+
+		state := report.State
+		if !state.Exists {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", state.Name, status)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n",
+			state.Name, state.Partitions, state.ReplicationFactor, retention(state.Retention), state.CleanupPolicy, status)
+	}
+	w.Flush()
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func retention(d time.Duration) string {
+	if d < 0 {
+		return "unlimited"
+	}
+	return d.String()
+}
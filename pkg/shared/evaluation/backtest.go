@@ -0,0 +1,168 @@
+package evaluation
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// BacktestOptions configures a backtest run. The defaults match the analyzer's.
+type BacktestOptions struct {
+	WindowSize       time.Duration `json:"window_size"`
+	MaxWindowSamples int           `json:"max_window_samples"`
+	Cooldown         time.Duration `json:"cooldown"` // for rules without their own cooldown
+}
+
+// DefaultBacktestOptions returns the analyzer's default evaluation settings.
+func DefaultBacktestOptions() BacktestOptions {
+	return BacktestOptions{
+		WindowSize:       5 * time.Minute,
+		MaxWindowSamples: 2048,
+		Cooldown:         5 * time.Minute,
+	}
+}
+
+// BacktestAlert is an alert the rule would have fired.
+type BacktestAlert struct {
+	Timestamp time.Time            `json:"timestamp"`
+	Value     float64              `json:"value"`
+	Severity  models.AlertSeverity `json:"severity"`
+	Labels    models.Labels        `json:"labels,omitempty"` // group of a grouped rule
+}
+
+// BacktestBreach is a contiguous period during which the rule was breached.
+type BacktestBreach struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	PeakValue       float64   `json:"peak_value"`
+	Alerts          int       `json:"alerts"`
+	// Open is true when the rule was still breached at the end of the history.
+	Open   bool          `json:"open"`
+	Labels models.Labels `json:"labels,omitempty"` // group of a grouped rule
+}
+
+// BacktestResult summarises how a rule would have behaved over a history.
+type BacktestResult struct {
+	Rule               *models.ThresholdRule `json:"rule"`
+	From               time.Time             `json:"from"`
+	To                 time.Time             `json:"to"`
+	SamplesEvaluated   int                   `json:"samples_evaluated"`
+	AlertCount         int                   `json:"alert_count"`
+	BreachCount        int                   `json:"breach_count"`
+	TotalBreachSeconds float64               `json:"total_breach_seconds"`
+	Alerts             []BacktestAlert       `json:"alerts"`
+	Breaches           []BacktestBreach      `json:"breaches"`
+}
+
+// Backtest replays history through the live evaluator and reports when the
+// rule would have fired. Samples of other services or series are ignored.
+// Grouped rules are replayed per group, as the analyzer evaluates them.
+func Backtest(rule *models.ThresholdRule, history []*models.ServiceMetric, opts BacktestOptions) (*BacktestResult, error) {
+	if err := ValidateRule(rule); err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+	if rule.Composite() {
+		return nil, fmt.Errorf("composite rules cannot be backtested")
+	}
+	series, _ := SeriesFor(rule)
+
+	samples := make([]*models.ServiceMetric, 0, len(history))
+	for _, m := range history {
+		if m.ServiceName == rule.ServiceName && models.CanonicalMetricType(m.MetricType) == series {
+			samples = append(samples, m)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp.Before(samples[j].Timestamp)
+	})
+
+	result := &BacktestResult{
+		Rule:     rule,
+		Alerts:   []BacktestAlert{},
+		Breaches: []BacktestBreach{},
+	}
+	if len(samples) == 0 {
+		return result, nil
+	}
+	result.From = samples[0].Timestamp
+	result.To = samples[len(samples)-1].Timestamp
+
+	for _, group := range GroupSamples(rule, samples) {
+		result.replay(rule, group, opts)
+	}
+
+	sort.SliceStable(result.Alerts, func(i, j int) bool {
+		return result.Alerts[i].Timestamp.Before(result.Alerts[j].Timestamp)
+	})
+	sort.SliceStable(result.Breaches, func(i, j int) bool {
+		return result.Breaches[i].Start.Before(result.Breaches[j].Start)
+	})
+	result.AlertCount = len(result.Alerts)
+
+	return result, nil
+}
+
+// replay evaluates the rule over the samples of one group.
+func (r *BacktestResult) replay(rule *models.ThresholdRule, group Group, opts BacktestOptions) {
+	w := NewWindow(opts.MaxWindowSamples, opts.WindowSize)
+	cooldown := opts.Cooldown
+	if rule.CooldownPeriod() > 0 {
+		cooldown = rule.CooldownPeriod()
+	}
+	var current *BacktestBreach
+	var lastAlert time.Time
+	var lastSeverity models.AlertSeverity
+
+	for _, sample := range group.Samples {
+		w.Add(sample)
+		res, ok := Evaluate(rule, w)
+		if !ok {
+			continue
+		}
+		r.SamplesEvaluated++
+
+		if !res.Breached {
+			if current != nil {
+				r.closeBreach(current, sample.Timestamp)
+				current = nil
+			}
+			continue
+		}
+
+		if current == nil {
+			current = &BacktestBreach{Start: sample.Timestamp, PeakValue: res.Value, Labels: group.Labels}
+		}
+		if res.Value > current.PeakValue {
+			current.PeakValue = res.Value
+		}
+
+		// Alerts repeat while breached once the cooldown has passed, and
+		// escalations fire right away
+		if lastAlert.IsZero() || sample.Timestamp.Sub(lastAlert) >= cooldown || res.Severity.Rank() > lastSeverity.Rank() {
+			lastAlert, lastSeverity = sample.Timestamp, res.Severity
+			current.Alerts++
+			r.Alerts = append(r.Alerts, BacktestAlert{
+				Timestamp: sample.Timestamp,
+				Value:     res.Value,
+				Severity:  res.Severity,
+				Labels:    group.Labels,
+			})
+		}
+	}
+
+	if current != nil {
+		current.Open = true
+		r.closeBreach(current, r.To)
+	}
+}
+
+func (r *BacktestResult) closeBreach(b *BacktestBreach, end time.Time) {
+	b.End = end
+	b.DurationSeconds = end.Sub(b.Start).Seconds()
+	r.Breaches = append(r.Breaches, *b)
+	r.BreachCount++
+	r.TotalBreachSeconds += b.DurationSeconds
+}
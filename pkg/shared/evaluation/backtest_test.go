@@ -0,0 +1,212 @@
+package evaluation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+var backtestOrigin = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// latencies returns payments latency samples spaced 10s apart.
+func latencies(labels models.Labels, values ...float64) []*models.ServiceMetric {
+	samples := make([]*models.ServiceMetric, len(values))
+	for i, v := range values {
+		samples[i] = &models.ServiceMetric{
+			ServiceName: models.ServicePayments,
+			MetricType:  models.MetricTypeLatency,
+			Value:       v,
+			Timestamp:   backtestOrigin.Add(time.Duration(i) * 10 * time.Second),
+			Labels:      labels,
+		}
+	}
+	return samples
+}
+
+func latencyRule() *models.ThresholdRule {
+	return &models.ThresholdRule{
+		ID:          "latency",
+		ServiceName: models.ServicePayments,
+		MetricType:  models.MetricTypeLatency,
+		Operator:    ">",
+		Threshold:   100,
+		Severity:    models.AlertSeverityWarning,
+		CooldownSec: 30,
+	}
+}
+
+// at is the offset of t from the start of the history in seconds.
+func at(t time.Time) float64 {
+	return t.Sub(backtestOrigin).Seconds()
+}
+
+func TestBacktest(t *testing.T) {
+	escalating := latencyRule()
+	escalating.Escalations = []models.SeverityThreshold{{Severity: models.AlertSeverityCritical, Threshold: 200}}
+	defaultCooldown := latencyRule()
+	defaultCooldown.CooldownSec = 0
+
+	tests := []struct {
+		name     string
+		rule     *models.ThresholdRule
+		history  []*models.ServiceMetric
+		alerts   []string // offset:severity
+		breaches []string // start-end:peak:alerts, with "open" for open breaches
+	}{
+		{
+			name:     "breaches closed by recovery",
+			rule:     latencyRule(),
+			history:  latencies(nil, 50, 150, 200, 120, 80, 90, 300),
+			alerts:   []string{"10:warning", "60:warning"},
+			breaches: []string{"10-40:200:1", "60-60:300:1 open"},
+		},
+		{
+			name:     "alerts repeat after the cooldown",
+			rule:     latencyRule(),
+			history:  latencies(nil, 150, 150, 150, 150, 150, 150, 150),
+			alerts:   []string{"0:warning", "30:warning", "60:warning"},
+			breaches: []string{"0-60:150:3 open"},
+		},
+		{
+			name:     "default cooldown for rules without one",
+			rule:     defaultCooldown,
+			history:  latencies(nil, 150, 150, 150, 150, 150, 150, 150),
+			alerts:   []string{"0:warning"},
+			breaches: []string{"0-60:150:1 open"},
+		},
+		{
+			name:     "escalations fire within the cooldown",
+			rule:     escalating,
+			history:  latencies(nil, 150, 250, 250, 50),
+			alerts:   []string{"0:warning", "10:critical"},
+			breaches: []string{"0-30:250:2"},
+		},
+		{
+			name:    "never breached",
+			rule:    latencyRule(),
+			history: latencies(nil, 50, 60, 70),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Backtest(tt.rule, tt.history, DefaultBacktestOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.SamplesEvaluated != len(tt.history) {
+				t.Errorf("evaluated %d samples, want %d", result.SamplesEvaluated, len(tt.history))
+			}
+			if at(result.From) != 0 || !result.To.Equal(tt.history[len(tt.history)-1].Timestamp) {
+				t.Errorf("history from %v to %v, want the sample range", result.From, result.To)
+			}
+
+			alerts := []string{}
+			for _, a := range result.Alerts {
+				alerts = append(alerts, fmt.Sprintf("%v:%s", at(a.Timestamp), a.Severity))
+			}
+			if fmt.Sprint(alerts) != fmt.Sprint(tt.alerts) || result.AlertCount != len(tt.alerts) {
+				t.Errorf("%d alerts %v, want %v", result.AlertCount, alerts, tt.alerts)
+			}
+
+			breaches := []string{}
+			var total float64
+			for _, b := range result.Breaches {
+				s := fmt.Sprintf("%v-%v:%v:%d", at(b.Start), at(b.End), b.PeakValue, b.Alerts)
+				if b.Open {
+					s += " open"
+				}
+				breaches = append(breaches, s)
+				total += b.DurationSeconds
+			}
+			if fmt.Sprint(breaches) != fmt.Sprint(tt.breaches) || result.BreachCount != len(tt.breaches) {
+				t.Errorf("%d breaches %v, want %v", result.BreachCount, breaches, tt.breaches)
+			}
+			if result.TotalBreachSeconds != total {
+				t.Errorf("total breach %vs, want the sum of the breaches %vs", result.TotalBreachSeconds, total)
+			}
+		})
+	}
+}
+
+// TestBacktestHistory checks that samples of other series are ignored and
+// that the history is replayed in time order.
+func TestBacktestHistory(t *testing.T) {
+	history := latencies(nil, 50, 150, 50)
+	history[0], history[2] = history[2], history[0]
+	history = append(history,
+		&models.ServiceMetric{ServiceName: models.ServiceOrders, MetricType: models.MetricTypeLatency, Value: 500, Timestamp: backtestOrigin},
+		&models.ServiceMetric{ServiceName: models.ServicePayments, MetricType: models.MetricTypeErrorRate, Value: 500, Timestamp: backtestOrigin},
+	)
+
+	result, err := Backtest(latencyRule(), history, DefaultBacktestOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SamplesEvaluated != 3 {
+		t.Errorf("evaluated %d samples, want the 3 payments latencies", result.SamplesEvaluated)
+	}
+	if len(result.Breaches) != 1 || at(result.Breaches[0].Start) != 10 || at(result.Breaches[0].End) != 20 || result.Breaches[0].Open {
+		t.Errorf("breaches %+v, want one closed from 10s to 20s", result.Breaches)
+	}
+
+	// Without samples of the series nothing is evaluated
+	result, err = Backtest(latencyRule(), history[3:], DefaultBacktestOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SamplesEvaluated != 0 || result.Alerts == nil || result.Breaches == nil || !result.From.IsZero() {
+		t.Errorf("result %+v, want an empty result", result)
+	}
+}
+
+func TestBacktestGroups(t *testing.T) {
+	rule := latencyRule()
+	rule.GroupBy = []string{"instance"}
+	var history []*models.ServiceMetric
+	history = append(history, latencies(models.Labels{"instance": "b"}, 150, 50)...)
+	history = append(history, latencies(models.Labels{"instance": "a"}, 50, 150)...)
+
+	result, err := Backtest(rule, history, DefaultBacktestOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each group breaches on its own, with its labels
+	var alerts, breaches []string
+	for _, a := range result.Alerts {
+		alerts = append(alerts, fmt.Sprintf("%v:%s", at(a.Timestamp), a.Labels["instance"]))
+	}
+	for _, b := range result.Breaches {
+		breaches = append(breaches, fmt.Sprintf("%v-%v:%s:%v", at(b.Start), at(b.End), b.Labels["instance"], b.Open))
+	}
+	if want := []string{"0:b", "10:a"}; fmt.Sprint(alerts) != fmt.Sprint(want) {
+		t.Errorf("alerts %v, want %v", alerts, want)
+	}
+	if want := []string{"0-10:b:false", "10-10:a:true"}; fmt.Sprint(breaches) != fmt.Sprint(want) {
+		t.Errorf("breaches %v, want %v", breaches, want)
+	}
+}
+
+func TestBacktestRejectsRules(t *testing.T) {
+	unknownMetric := latencyRule()
+	unknownMetric.MetricType = "queue_depth"
+	composite := &models.ThresholdRule{
+		ServiceName: models.ServicePayments,
+		Severity:    models.AlertSeverityWarning,
+		Expression:  "payments.latency AND NOT payments.errors",
+	}
+
+	for name, rule := range map[string]*models.ThresholdRule{
+		"invalid rule":   unknownMetric,
+		"composite rule": composite,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Backtest(rule, latencies(nil, 150), DefaultBacktestOptions()); err == nil {
+				t.Error("backtested, want an error")
+			}
+		})
+	}
+}
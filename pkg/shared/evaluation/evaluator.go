@@ -0,0 +1,229 @@
+package evaluation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// validOperators lists the accepted rule operators in both spellings.
+var validOperators = map[string]bool{
+	">": true, ">=": true, "<": true, "<=": true, "==": true, "!=": true,
+	"gt": true, "gte": true, "lt": true, "lte": true, "eq": true, "ne": true,
+}
+
+// orderingOperators lists the operators a forecast can cross.
+var orderingOperators = map[string]bool{
+	">": true, ">=": true, "<": true, "<=": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+}
+
+// validSeverities lists the accepted rule severities.
+var validSeverities = map[models.AlertSeverity]bool{
+	models.AlertSeverityInfo:     true,
+	models.AlertSeverityWarning:  true,
+	models.AlertSeverityCritical: true,
+}
+
+// ValidateRule checks that a rule can be evaluated on its own. References to
+// other rules are checked by NewRuleGraph.
+func ValidateRule(rule *models.ThresholdRule) error {
+	if rule.ServiceName == "" {
+		return fmt.Errorf("service_name is required")
+	}
+	if rule.Composite() {
+		if _, err := ParseExpression(rule.Expression); err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+		if rule.Aggregation != "" {
+			return fmt.Errorf("composite rules have no aggregation")
+		}
+		if len(rule.GroupBy) > 0 {
+			return fmt.Errorf("composite rules cannot be grouped")
+		}
+	} else {
+		def, ok := models.LookupMetric(rule.MetricType)
+		if !ok {
+			return fmt.Errorf("unknown metric type %q", rule.MetricType)
+		}
+		if !validOperators[rule.Operator] {
+			return fmt.Errorf("unknown operator %q", rule.Operator)
+		}
+		if err := validateAggregation(rule, def); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool, len(rule.GroupBy))
+	for _, label := range rule.GroupBy {
+		if label == "" || seen[label] {
+			return fmt.Errorf("group_by must list distinct, non-empty labels")
+		}
+		seen[label] = true
+	}
+	for _, ref := range rule.DependsOn {
+		if ref == "" {
+			return fmt.Errorf("depends_on must not contain empty references")
+		}
+	}
+	if !validSeverities[rule.Severity] {
+		return fmt.Errorf("unknown severity %q", rule.Severity)
+	}
+	if rule.CooldownSec < 0 || rule.CooldownSeconds < 0 {
+		return fmt.Errorf("cooldown must not be negative")
+	}
+	return validateEscalations(rule)
+}
+
+// validateEscalations checks that each escalation raises the severity at a
+// threshold further in the direction of the rule's operator.
+func validateEscalations(rule *models.ThresholdRule) error {
+	if len(rule.Escalations) == 0 {
+		return nil
+	}
+	if rule.Composite() || rule.Forecast() {
+		return fmt.Errorf("only threshold rules can escalate")
+	}
+	if !orderingOperators[rule.Operator] {
+		return fmt.Errorf("escalations need an ordering operator, got %q", rule.Operator)
+	}
+
+	severity, threshold := rule.Severity, rule.Threshold
+	for _, escalation := range rule.Escalations {
+		if !validSeverities[escalation.Severity] {
+			return fmt.Errorf("unknown escalation severity %q", escalation.Severity)
+		}
+		if escalation.Severity.Rank() <= severity.Rank() {
+			return fmt.Errorf("escalation to %s must raise the severity above %s", escalation.Severity, severity)
+		}
+		if !beyond(rule.Operator, escalation.Threshold, threshold) {
+			return fmt.Errorf("escalation to %s must have a threshold beyond %v", escalation.Severity, threshold)
+		}
+		severity, threshold = escalation.Severity, escalation.Threshold
+	}
+	return nil
+}
+
+// beyond reports whether threshold a is stricter than b for an ordering operator.
+func beyond(operator string, a, b float64) bool {
+	switch operator {
+	case ">", ">=", "gt", "gte":
+		return a > b
+	default:
+		return a < b
+	}
+}
+
+func validateAggregation(rule *models.ThresholdRule, def *models.MetricDefinition) error {
+	switch rule.Aggregation {
+	case "", models.AggregationLast:
+		return nil
+	case models.AggregationForecastLinear, models.AggregationForecastHolt:
+	default:
+		return fmt.Errorf("unknown aggregation %q", rule.Aggregation)
+	}
+
+	if def.Derived() {
+		return fmt.Errorf("cannot forecast derived metric %q", def.Type)
+	}
+	if !orderingOperators[rule.Operator] {
+		return fmt.Errorf("forecasts need an ordering operator, got %q", rule.Operator)
+	}
+	if rule.ForecastHorizon <= 0 {
+		return fmt.Errorf("forecast_horizon must be positive")
+	}
+	if rule.MinConfidence < 0 || rule.MinConfidence > 1 {
+		return fmt.Errorf("min_confidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// SeriesFor returns the metric series whose samples a rule reads.
+// Derived metrics read their source series; composite rules read none.
+func SeriesFor(rule *models.ThresholdRule) (models.MetricType, bool) {
+	if rule.Composite() {
+		return "", false
+	}
+	def, ok := models.LookupMetric(rule.MetricType)
+	if !ok {
+		return "", false
+	}
+	if def.Derived() {
+		return def.Source, true
+	}
+	return def.Type, true
+}
+
+// Result is the outcome of evaluating a rule.
+type Result struct {
+	MetricType models.MetricType
+	Value      float64 // the predicted value at the horizon for forecast rules
+	Breached   bool
+	Severity   models.AlertSeverity // when breached, escalated by the value
+
+	// Forecast rules only: the fitted forecast and, when breached, the
+	// predicted threshold crossing
+	Forecast    *Forecast
+	PredictedAt time.Time
+}
+
+// Evaluate evaluates a rule against the window of the series it reads
+// (see SeriesFor). ok is false when the window holds nothing to evaluate.
+func Evaluate(rule *models.ThresholdRule, w *Window) (Result, bool) {
+	def, ok := models.LookupMetric(rule.MetricType)
+	if !ok || w.Len() == 0 {
+		return Result{}, false
+	}
+	if rule.Forecast() {
+		return evaluateForecast(rule, def, w)
+	}
+
+	// Only derived metrics need the whole window
+	var samples []*models.ServiceMetric
+	series := def.Type
+	if def.Derived() {
+		samples = w.Samples()
+		series = def.Source
+	} else {
+		samples = []*models.ServiceMetric{w.Latest()}
+	}
+
+	value, ok := def.Extract(map[models.MetricType][]*models.ServiceMetric{series: samples})
+	if !ok {
+		return Result{}, false
+	}
+
+	result := Result{
+		MetricType: def.Type,
+		Value:      value,
+		Breached:   rule.Breached(value),
+	}
+	if result.Breached {
+		result.Severity = rule.SeverityFor(value)
+	}
+	return result, true
+}
+
+// evaluateForecast fits a forecast to the whole window. The rule is breached
+// when the threshold is predicted to be crossed within its horizon with at
+// least its minimum confidence.
+func evaluateForecast(rule *models.ThresholdRule, def *models.MetricDefinition, w *Window) (Result, bool) {
+	forecast, ok := FitForecast(rule.Aggregation, w.Samples())
+	if !ok {
+		return Result{}, false
+	}
+
+	horizon := time.Duration(rule.ForecastHorizon) * time.Second
+	result := Result{
+		MetricType: def.Type,
+		Value:      forecast.Predict(forecast.At.Add(horizon)),
+		Forecast:   forecast,
+	}
+	if crossing, ok := forecast.Crossing(rule, horizon); ok && forecast.Confidence >= rule.MinConfidence {
+		result.Breached = true
+		result.Severity = rule.Severity
+		result.PredictedAt = crossing
+	}
+
+	return result, true
+}
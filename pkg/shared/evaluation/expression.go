@@ -0,0 +1,280 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed composite rule expression. It combines rule
+// references with AND, OR and NOT (or &&, || and !) and parentheses. A
+// reference is a rule ID or service.name; names containing spaces are quoted:
+//
+//	payments.latency_high AND payments.error_rate_high
+//	NOT auth.status_healthy
+//	"payments.Payments p95 latency" || (orders.errors && !orders.deploying)
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ParseExpression parses a composite rule expression.
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression source.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Refs returns the rule references of the expression in order of appearance.
+func (e *Expression) Refs() []string {
+	return e.root.refs(nil)
+}
+
+// Eval evaluates the expression with three-valued logic. lookup reports the
+// state of a referenced rule and whether it is known; known is false when
+// the result depends on a rule whose state is unknown.
+func (e *Expression) Eval(lookup func(ref string) (breached, known bool)) (value, known bool) {
+	return e.root.eval(lookup)
+}
+
+type exprNode interface {
+	eval(lookup func(ref string) (bool, bool)) (bool, bool)
+	refs(dst []string) []string
+}
+
+type refNode struct{ ref string }
+
+func (n refNode) eval(lookup func(string) (bool, bool)) (bool, bool) {
+	return lookup(n.ref)
+}
+
+func (n refNode) refs(dst []string) []string {
+	return append(dst, n.ref)
+}
+
+type notNode struct{ x exprNode }
+
+func (n notNode) eval(lookup func(string) (bool, bool)) (bool, bool) {
+	v, known := n.x.eval(lookup)
+	return !v && known, known
+}
+
+func (n notNode) refs(dst []string) []string {
+	return n.x.refs(dst)
+}
+
+type andNode struct{ l, r exprNode }
+
+func (n andNode) eval(lookup func(string) (bool, bool)) (bool, bool) {
+	lv, lk := n.l.eval(lookup)
+	rv, rk := n.r.eval(lookup)
+	// A known false side decides the result on its own
+	if (lk && !lv) || (rk && !rv) {
+		return false, true
+	}
+	return lk && rk, lk && rk
+}
+
+func (n andNode) refs(dst []string) []string {
+	return n.r.refs(n.l.refs(dst))
+}
+
+type orNode struct{ l, r exprNode }
+
+func (n orNode) eval(lookup func(string) (bool, bool)) (bool, bool) {
+	lv, lk := n.l.eval(lookup)
+	rv, rk := n.r.eval(lookup)
+	// A known true side decides the result on its own
+	if (lk && lv) || (rk && rv) {
+		return true, true
+	}
+	return false, lk && rk
+}
+
+func (n orNode) refs(dst []string) []string {
+	return n.r.refs(n.l.refs(dst))
+}
+
+type tokenKind int
+
+const (
+	tokenRef tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i})
+			i++
+		case c == '!':
+			tokens = append(tokens, token{kind: tokenNot, text: "!", pos: i})
+			i++
+		case c == '&' || c == '|':
+			if i+1 >= len(runes) || runes[i+1] != c {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			kind := tokenAnd
+			if c == '|' {
+				kind = tokenOr
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[i : i+2]), pos: i})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated quote at position %d", i)
+			}
+			ref := strings.TrimSpace(string(runes[i+1 : end]))
+			if ref == "" {
+				return nil, fmt.Errorf("empty reference at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenRef, text: ref, pos: i})
+			i = end + 1
+		case isRefRune(c):
+			start := i
+			for i < len(runes) && isRefRune(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			kind := tokenRef
+			switch strings.ToUpper(word) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind: kind, text: word, pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+		}
+	}
+
+	return tokens, nil
+}
+
+func isRefRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("_.-:/", c)
+}
+
+// exprParser is a recursive descent parser; NOT binds tighter than AND, AND tighter than OR.
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) next(kind tokenKind) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.next(tokenOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.next(tokenAnd) {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.next(tokenNot) {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x: x}, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+
+	switch tok.kind {
+	case tokenOpen:
+		p.pos++
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(tokenClose) {
+			return nil, fmt.Errorf("missing ) for ( at position %d", tok.pos)
+		}
+		return x, nil
+	case tokenRef:
+		p.pos++
+		return refNode{ref: tok.text}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+}
@@ -0,0 +1,111 @@
+package evaluation
+
+import (
+	"strings"
+	"testing"
+)
+
+// lookupStates builds an Eval lookup from "t"/"f" states; other rules are unknown.
+func lookupStates(states map[string]string) func(string) (bool, bool) {
+	return func(ref string) (bool, bool) {
+		switch states[ref] {
+		case "t":
+			return true, true
+		case "f":
+			return false, true
+		}
+		return false, false
+	}
+}
+
+func TestExpressionEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		states map[string]string
+		value  bool
+		known  bool
+	}{
+		{"AND binds tighter than OR", "a OR b AND c", map[string]string{"a": "t", "b": "f", "c": "f"}, true, true},
+		{"NOT binds tighter than AND", "NOT a AND b", map[string]string{"a": "f", "b": "f"}, false, true},
+		{"parentheses override precedence", "(a OR b) AND c", map[string]string{"a": "t", "b": "f", "c": "f"}, false, true},
+		{"double negation", "NOT NOT a", map[string]string{"a": "t"}, true, true},
+		{"keywords are case insensitive", "a and not b", map[string]string{"a": "t", "b": "f"}, true, true},
+		{"&& alias", "a && b", map[string]string{"a": "t", "b": "t"}, true, true},
+		{"|| alias", "a || b", map[string]string{"a": "f", "b": "t"}, true, true},
+		{"! alias", "!a", map[string]string{"a": "f"}, true, true},
+		{"aliases mix with keywords", "!a && (b OR c)", map[string]string{"a": "f", "b": "f", "c": "t"}, true, true},
+		{"quoted reference", `"payments.latency high" AND b`, map[string]string{"payments.latency high": "t", "b": "t"}, true, true},
+
+		{"unknown reference", "a", nil, false, false},
+		{"NOT unknown", "NOT a", nil, false, false},
+		{"unknown AND true", "a AND b", map[string]string{"b": "t"}, false, false},
+		{"unknown AND false", "a AND b", map[string]string{"b": "f"}, false, true},
+		{"false AND unknown", "a AND b", map[string]string{"a": "f"}, false, true},
+		{"unknown OR false", "a OR b", map[string]string{"b": "f"}, false, false},
+		{"unknown OR true", "a OR b", map[string]string{"b": "t"}, true, true},
+		{"true OR unknown", "a OR b", map[string]string{"a": "t"}, true, true},
+		{"NOT of a decided AND", "NOT (a AND b)", map[string]string{"b": "f"}, true, true},
+		{"NOT of an undecided OR", "NOT (a OR b)", map[string]string{"b": "f"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tt.expr, err)
+			}
+			value, known := expr.Eval(lookupStates(tt.states))
+			if value != tt.value || known != tt.known {
+				t.Errorf("%q = %v known %v, want %v known %v", tt.expr, value, known, tt.value, tt.known)
+			}
+		})
+	}
+}
+
+func TestExpressionRefs(t *testing.T) {
+	expr, err := ParseExpression(`orders.errors AND NOT ("auth.down" || payments.latency)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(expr.Refs(), ",")
+	if want := "orders.errors,auth.down,payments.latency"; got != want {
+		t.Errorf("refs %s, want %s", got, want)
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"", "empty expression"},
+		{"   ", "empty expression"},
+		{"a & b", `unexpected '&' at position 2`},
+		{"a |", `unexpected '|' at position 2`},
+		{"a AND $b", `unexpected '$' at position 6`},
+		{`a AND "b`, "unterminated quote at position 6"},
+		{`a OR ""`, "empty reference at position 5"},
+		{"a AND", "unexpected end of expression"},
+		{"NOT", "unexpected end of expression"},
+		{"(a OR b", "missing ) for ( at position 0"},
+		{"a AND (b OR (c)", "missing ) for ( at position 6"},
+		{"a b", `unexpected "b" at position 2`},
+		{"a )", `unexpected ")" at position 2`},
+		{"AND a", `unexpected "AND" at position 0`},
+		{"a OR || b", `unexpected "||" at position 5`},
+		{"é AND ?", `unexpected '?' at position 6`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseExpression(tt.expr)
+			if err == nil {
+				t.Fatalf("%q parsed, want error %q", tt.expr, tt.err)
+			}
+			if err.Error() != tt.err {
+				t.Errorf("%q: error %q, want %q", tt.expr, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package evaluation
+
+import (
+	"math"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// MinForecastSamples is the number of samples needed to fit a forecast.
+const MinForecastSamples = 5
+
+// Holt smoothing factors for the level and the trend.
+const (
+	holtAlpha = 0.5
+	holtBeta  = 0.3
+)
+
+// Forecast is a fitted trend of a metric series. Forecasts are linear: the
+// series is predicted to move from Level at At by Slope per second.
+type Forecast struct {
+	Method models.RuleAggregation `json:"method"`
+	At     time.Time              `json:"at"`    // time of the latest sample
+	Level  float64                `json:"level"` // fitted value at At
+	Slope  float64                `json:"slope"` // change per second
+	// Confidence is the quality of the fit from 0 to 1: the coefficient of
+	// determination for a line, one minus the relative one-step error for Holt.
+	Confidence float64 `json:"confidence"`
+}
+
+// Predict returns the predicted value at t.
+func (f *Forecast) Predict(t time.Time) float64 {
+	return f.Level + f.Slope*t.Sub(f.At).Seconds()
+}
+
+// Crossing returns when the forecast first breaches the rule within horizon
+// of At. A series already breached crosses at At.
+func (f *Forecast) Crossing(rule *models.ThresholdRule, horizon time.Duration) (time.Time, bool) {
+	if rule.Breached(f.Level) {
+		return f.At, true
+	}
+	// The forecast is a line, so it breaches within the horizon if and only if it does at its end
+	if f.Slope == 0 || !rule.Breached(f.Predict(f.At.Add(horizon))) {
+		return time.Time{}, false
+	}
+
+	seconds := (rule.Threshold - f.Level) / f.Slope
+	return f.At.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// FitForecast fits a forecast to samples ordered by time. ok is false when
+// there are too few samples or they do not span any time.
+func FitForecast(method models.RuleAggregation, samples []*models.ServiceMetric) (*Forecast, bool) {
+	if len(samples) < MinForecastSamples {
+		return nil, false
+	}
+	first, last := samples[0].Timestamp, samples[len(samples)-1].Timestamp
+	if !last.After(first) {
+		return nil, false
+	}
+
+	switch method {
+	case models.AggregationForecastLinear:
+		return fitLinear(samples), true
+	case models.AggregationForecastHolt:
+		return fitHolt(samples), true
+	default:
+		return nil, false
+	}
+}
+
+// fitLinear fits a least-squares line through the samples.
+func fitLinear(samples []*models.ServiceMetric) *Forecast {
+	origin := samples[0].Timestamp
+	n := float64(len(samples))
+
+	var sumX, sumY, sumXX, sumXY float64
+	for _, s := range samples {
+		x := s.Timestamp.Sub(origin).Seconds()
+		sumX += x
+		sumY += s.Value
+		sumXX += x * x
+		sumXY += x * s.Value
+	}
+
+	meanX, meanY := sumX/n, sumY/n
+	slope := 0.0
+	if d := sumXX - n*meanX*meanX; d > 0 {
+		slope = (sumXY - n*meanX*meanY) / d
+	}
+	intercept := meanY - slope*meanX
+
+	var ssRes, ssTot float64
+	for _, s := range samples {
+		x := s.Timestamp.Sub(origin).Seconds()
+		residual := s.Value - (intercept + slope*x)
+		ssRes += residual * residual
+		ssTot += (s.Value - meanY) * (s.Value - meanY)
+	}
+
+	last := samples[len(samples)-1].Timestamp
+	return &Forecast{
+		Method:     models.AggregationForecastLinear,
+		At:         last,
+		Level:      intercept + slope*last.Sub(origin).Seconds(),
+		Slope:      slope,
+		Confidence: fitConfidence(ssRes, ssTot),
+	}
+}
+
+// fitHolt applies Holt's double exponential smoothing. Samples are treated as
+// evenly spaced at their mean interval.
+func fitHolt(samples []*models.ServiceMetric) *Forecast {
+	step := samples[len(samples)-1].Timestamp.Sub(samples[0].Timestamp).Seconds() / float64(len(samples)-1)
+
+	level := samples[0].Value
+	trend := samples[1].Value - samples[0].Value
+
+	var sum, ssRes, ssTot float64
+	for _, s := range samples {
+		sum += s.Value
+	}
+	mean := sum / float64(len(samples))
+
+	for _, s := range samples[1:] {
+		predicted := level + trend
+		ssRes += (s.Value - predicted) * (s.Value - predicted)
+
+		previous := level
+		level = holtAlpha*s.Value + (1-holtAlpha)*(level+trend)
+		trend = holtBeta*(level-previous) + (1-holtBeta)*trend
+	}
+	for _, s := range samples {
+		ssTot += (s.Value - mean) * (s.Value - mean)
+	}
+
+	return &Forecast{
+		Method:     models.AggregationForecastHolt,
+		At:         samples[len(samples)-1].Timestamp,
+		Level:      level,
+		Slope:      trend / step,
+		Confidence: fitConfidence(ssRes*float64(len(samples))/float64(len(samples)-1), ssTot),
+	}
+}
+
+// fitConfidence maps residual and total sums of squares to a confidence in [0, 1].
+func fitConfidence(ssRes, ssTot float64) float64 {
+	if ssTot == 0 {
+		// A constant series is fitted exactly
+		if ssRes == 0 {
+			return 1
+		}
+		return 0
+	}
+	return math.Max(0, math.Min(1, 1-ssRes/ssTot))
+}
@@ -0,0 +1,159 @@
+package evaluation
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+var forecastOrigin = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// series returns samples of values spaced step apart.
+func series(step time.Duration, values ...float64) []*models.ServiceMetric {
+	samples := make([]*models.ServiceMetric, len(values))
+	for i, v := range values {
+		samples[i] = &models.ServiceMetric{
+			Value:     v,
+			Timestamp: forecastOrigin.Add(time.Duration(i) * step),
+		}
+	}
+	return samples
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestFitLinear(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    []*models.ServiceMetric
+		level      float64
+		slope      float64
+		confidence float64
+	}{
+		{"rising line", series(10*time.Second, 0, 10, 20, 30, 40), 40, 1, 1},
+		{"falling line", series(10*time.Second, 40, 30, 20, 10, 0), 0, -1, 1},
+		{"constant series", series(10*time.Second, 5, 5, 5, 5, 5), 5, 0, 1},
+		{"zero slope through noise", series(10*time.Second, 1, 3, 1, 3, 1), 1.8, 0, 0},
+		{"timestamps spanning no time", series(0, 1, 2, 3, 4, 5), 3, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fitLinear(tt.samples)
+			if !approx(f.Level, tt.level) || !approx(f.Slope, tt.slope) || !approx(f.Confidence, tt.confidence) {
+				t.Errorf("level %v slope %v confidence %v, want %v %v %v",
+					f.Level, f.Slope, f.Confidence, tt.level, tt.slope, tt.confidence)
+			}
+			if want := tt.samples[len(tt.samples)-1].Timestamp; !f.At.Equal(want) {
+				t.Errorf("at %v, want the latest sample %v", f.At, want)
+			}
+		})
+	}
+}
+
+func TestFitHolt(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    []*models.ServiceMetric
+		level      float64
+		slope      float64
+		confidence float64
+	}{
+		{"rising line", series(10*time.Second, 0, 10, 20, 30, 40), 40, 1, 1},
+		{"constant series", series(10*time.Second, 5, 5, 5, 5, 5), 5, 0, 1},
+		{"step change", series(time.Second, 0, 0, 0, 0, 10), 5, 1.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fitHolt(tt.samples)
+			if !approx(f.Level, tt.level) || !approx(f.Slope, tt.slope) || !approx(f.Confidence, tt.confidence) {
+				t.Errorf("level %v slope %v confidence %v, want %v %v %v",
+					f.Level, f.Slope, f.Confidence, tt.level, tt.slope, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestFitForecastRejectsUnfittableSeries(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  models.RuleAggregation
+		samples []*models.ServiceMetric
+	}{
+		{"too few samples", models.AggregationForecastLinear, series(time.Second, 1, 2, 3, 4)},
+		{"linear over no time", models.AggregationForecastLinear, series(0, 1, 2, 3, 4, 5)},
+		{"holt over no time", models.AggregationForecastHolt, series(0, 1, 2, 3, 4, 5)},
+		{"not a forecast", models.AggregationLast, series(time.Second, 1, 2, 3, 4, 5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if f, ok := FitForecast(tt.method, tt.samples); ok {
+				t.Errorf("fitted %+v, want no forecast", f)
+			}
+		})
+	}
+}
+
+func TestForecastCrossing(t *testing.T) {
+	above := &models.ThresholdRule{Operator: ">", Threshold: 50}
+	below := &models.ThresholdRule{Operator: "<", Threshold: 30}
+
+	tests := []struct {
+		name     string
+		rule     *models.ThresholdRule
+		level    float64
+		slope    float64
+		horizon  time.Duration
+		crosses  bool
+		crossing time.Duration // after At
+	}{
+		{"rising through the threshold", above, 40, 1, time.Minute, true, 10 * time.Second},
+		{"rising past the horizon", above, 40, 1, 5 * time.Second, false, 0},
+		{"falling away", above, 40, -1, time.Minute, false, 0},
+		{"zero slope below", above, 40, 0, time.Hour, false, 0},
+		{"already breached", above, 60, -1, time.Minute, true, 0},
+		{"already breached with zero slope", above, 60, 0, time.Minute, true, 0},
+		{"falling through a lower bound", below, 40, -0.5, time.Minute, true, 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Forecast{At: forecastOrigin, Level: tt.level, Slope: tt.slope}
+			at, ok := f.Crossing(tt.rule, tt.horizon)
+			if ok != tt.crosses {
+				t.Fatalf("crosses %v, want %v", ok, tt.crosses)
+			}
+			if ok && !at.Equal(forecastOrigin.Add(tt.crossing)) {
+				t.Errorf("crosses at %v, want %v", at.Sub(forecastOrigin), tt.crossing)
+			}
+		})
+	}
+}
+
+func TestFitConfidence(t *testing.T) {
+	tests := []struct {
+		name         string
+		ssRes, ssTot float64
+		want         float64
+	}{
+		{"constant series fitted exactly", 0, 0, 1},
+		{"constant series missed", 1, 0, 0},
+		{"exact fit", 0, 10, 1},
+		{"half explained", 5, 10, 0.5},
+		{"worse than the mean", 20, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fitConfidence(tt.ssRes, tt.ssTot); !approx(got, tt.want) {
+				t.Errorf("confidence %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// RuleState is the latest evaluation of a rule. States are shared between
+// analyzer replicas so composite rules can read rules evaluated elsewhere.
+type RuleState struct {
+	Breached    bool      `json:"breached"`
+	Value       float64   `json:"value"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// RuleGraph resolves the references between rules made by composite
+// expressions and dependencies, and evaluates composite rules from the
+// states of the rules they reference.
+type RuleGraph struct {
+	rules      map[string]*models.ThresholdRule
+	refs       map[string]string // reference -> rule ID; empty for ambiguous names
+	exprs      map[string]*Expression
+	excluded   map[string]bool
+	composites []*models.ThresholdRule // dependencies first
+}
+
+// NewRuleGraph builds the graph of rules. It fails if an expression does not
+// parse, a reference does not resolve to exactly one rule, or the references
+// form a cycle.
+func NewRuleGraph(rules []*models.ThresholdRule) (*RuleGraph, error) {
+	g, errs := BuildRuleGraph(rules)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return g, nil
+}
+
+// BuildRuleGraph builds the graph of the valid rules. Rules whose expression
+// does not parse, whose references do not resolve to exactly one rule, or
+// which are on a cycle are excluded, with an error for each: they are not
+// evaluated as composites and their dependencies do not inhibit them. Other
+// rules may still reference them.
+func BuildRuleGraph(rules []*models.ThresholdRule) (*RuleGraph, []error) {
+	g := &RuleGraph{
+		rules:    make(map[string]*models.ThresholdRule, len(rules)),
+		refs:     make(map[string]string, 2*len(rules)),
+		exprs:    make(map[string]*Expression),
+		excluded: make(map[string]bool),
+	}
+	var errs []error
+
+	for _, rule := range rules {
+		g.rules[rule.ID] = rule
+		g.refs[rule.ID] = rule.ID
+
+		name := RuleReference(rule)
+		if _, dup := g.refs[name]; dup {
+			g.refs[name] = ""
+		} else {
+			g.refs[name] = rule.ID
+		}
+	}
+
+	for _, rule := range rules {
+		if !rule.Composite() {
+			continue
+		}
+		expr, err := ParseExpression(rule.Expression)
+		if err != nil {
+			g.exclude(rule.ID)
+			errs = append(errs, fmt.Errorf("rule %q: invalid expression: %w", rule.Name, err))
+			continue
+		}
+		g.exprs[rule.ID] = expr
+	}
+
+	// Resolve every reference before walking the graph
+	for _, rule := range rules {
+		for _, ref := range g.edges(rule) {
+			if _, err := g.resolve(ref); err != nil {
+				g.exclude(rule.ID)
+				errs = append(errs, fmt.Errorf("rule %q: %w", rule.Name, err))
+				break
+			}
+		}
+	}
+
+	// Break cycles until the rest sorts
+	for {
+		cycle, err := g.sort(rules)
+		if err == nil {
+			break
+		}
+		for _, id := range cycle {
+			g.exclude(id)
+		}
+		errs = append(errs, err)
+	}
+
+	return g, errs
+}
+
+// exclude drops a rule's expression and references from the graph.
+func (g *RuleGraph) exclude(id string) {
+	g.excluded[id] = true
+	delete(g.exprs, id)
+}
+
+// RuleReference returns the service.name reference of a rule.
+func RuleReference(rule *models.ThresholdRule) string {
+	return string(rule.ServiceName) + "." + rule.Name
+}
+
+// Resolve returns the rule a reference points to.
+func (g *RuleGraph) Resolve(ref string) (*models.ThresholdRule, bool) {
+	id, err := g.resolve(ref)
+	if err != nil {
+		return nil, false
+	}
+	return g.rules[id], true
+}
+
+func (g *RuleGraph) resolve(ref string) (string, error) {
+	id, ok := g.refs[ref]
+	if !ok {
+		return "", fmt.Errorf("unknown rule %q", ref)
+	}
+	if id == "" {
+		return "", fmt.Errorf("ambiguous rule %q: reference it by ID", ref)
+	}
+	return id, nil
+}
+
+// edges returns the references a rule makes through its expression and
+// dependencies. Excluded rules make none.
+func (g *RuleGraph) edges(rule *models.ThresholdRule) []string {
+	if g.excluded[rule.ID] {
+		return nil
+	}
+	var refs []string
+	if expr, ok := g.exprs[rule.ID]; ok {
+		refs = expr.Refs()
+	}
+	return append(refs, rule.DependsOn...)
+}
+
+// sort orders the composite rules so every rule comes after the rules it
+// references, and fails on the first cycle found, returning its rule IDs.
+func (g *RuleGraph) sort(rules []*models.ThresholdRule) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(rules))
+	var path, cycle []string
+	g.composites = nil
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case done:
+			return nil
+		case visiting:
+			// Report the cycle from its first rule back to itself
+			start := 0
+			for i, p := range path {
+				if p == id {
+					start = i
+				}
+			}
+			cycle = append(cycle, path[start:]...)
+			names := make([]string, 0, len(cycle)+1)
+			for _, p := range cycle {
+				names = append(names, RuleReference(g.rules[p]))
+			}
+			names = append(names, RuleReference(g.rules[id]))
+			return fmt.Errorf("rule cycle: %s", strings.Join(names, " -> "))
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		rule := g.rules[id]
+		for _, ref := range g.edges(rule) {
+			target, _ := g.resolve(ref)
+			if err := visit(target); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+
+		if _, ok := g.exprs[id]; ok {
+			g.composites = append(g.composites, rule)
+		}
+		return nil
+	}
+
+	for _, rule := range rules {
+		if err := visit(rule.ID); err != nil {
+			return cycle, err
+		}
+	}
+	return nil, nil
+}
+
+// Composites returns the composite rules, each after the rules it references.
+func (g *RuleGraph) Composites() []*models.ThresholdRule {
+	return g.composites
+}
+
+// EvaluateComposite evaluates a composite rule against the states of the
+// rules it references, keyed by rule ID. Rules without a state are unknown;
+// ok is false when the result depends on them.
+func (g *RuleGraph) EvaluateComposite(rule *models.ThresholdRule, states map[string]RuleState) (breached, ok bool) {
+	expr, found := g.exprs[rule.ID]
+	if !found {
+		return false, false
+	}
+
+	return expr.Eval(func(ref string) (bool, bool) {
+		id, err := g.resolve(ref)
+		if err != nil {
+			return false, false
+		}
+		state, known := states[id]
+		return state.Breached, known
+	})
+}
+
+// Inhibitor returns the first dependency of a rule that is currently
+// breached. Alerts of the rule are suppressed while it is.
+func (g *RuleGraph) Inhibitor(rule *models.ThresholdRule, states map[string]RuleState) (*models.ThresholdRule, bool) {
+	if g.excluded[rule.ID] {
+		return nil, false
+	}
+	for _, ref := range rule.DependsOn {
+		id, err := g.resolve(ref)
+		if err != nil {
+			continue
+		}
+		if states[id].Breached {
+			return g.rules[id], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,186 @@
+package evaluation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+func testRule(id, service, name, expression string, dependsOn ...string) *models.ThresholdRule {
+	return &models.ThresholdRule{
+		ID:          id,
+		Name:        name,
+		ServiceName: models.ServiceName(service),
+		Expression:  expression,
+		DependsOn:   dependsOn,
+	}
+}
+
+func compositeIDs(g *RuleGraph) string {
+	ids := make([]string, 0, len(g.Composites()))
+	for _, rule := range g.Composites() {
+		ids = append(ids, rule.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestNewRuleGraph(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      []*models.ThresholdRule
+		composites string
+		err        string
+	}{
+		{
+			name: "composites follow the rules they reference",
+			rules: []*models.ThresholdRule{
+				testRule("outage", "orders", "outage", "degraded AND auth.down"),
+				testRule("degraded", "orders", "degraded", "orders.errors OR payments.latency"),
+				testRule("errors", "orders", "errors", ""),
+				testRule("latency", "payments", "latency", ""),
+				testRule("down", "auth", "down", ""),
+			},
+			composites: "degraded,outage",
+		},
+		{
+			name: "dependencies are edges too",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "b", "auth.down"),
+				testRule("b", "orders", "b", "", "a"),
+				testRule("down", "auth", "down", ""),
+			},
+			err: "rule cycle: orders.a -> orders.b -> orders.a",
+		},
+		{
+			name: "self cycle",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "a OR orders.b"),
+				testRule("b", "orders", "b", ""),
+			},
+			err: "rule cycle: orders.a -> orders.a",
+		},
+		{
+			name: "indirect cycle",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "b"),
+				testRule("b", "orders", "b", "NOT c"),
+				testRule("c", "orders", "c", "x AND a"),
+				testRule("x", "orders", "x", ""),
+			},
+			err: "rule cycle: orders.a -> orders.b -> orders.c -> orders.a",
+		},
+		{
+			name: "unknown reference",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "orders.missing"),
+			},
+			err: `rule "a": unknown rule "orders.missing"`,
+		},
+		{
+			name: "unknown dependency",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "", "auth.missing"),
+			},
+			err: `rule "a": unknown rule "auth.missing"`,
+		},
+		{
+			name: "ambiguous reference",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "orders.dup"),
+				testRule("dup1", "orders", "dup", ""),
+				testRule("dup2", "orders", "dup", ""),
+			},
+			err: `rule "a": ambiguous rule "orders.dup": reference it by ID`,
+		},
+		{
+			name: "invalid expression",
+			rules: []*models.ThresholdRule{
+				testRule("a", "orders", "a", "b AND"),
+				testRule("b", "orders", "b", ""),
+			},
+			err: `rule "a": invalid expression: unexpected end of expression`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewRuleGraph(tt.rules)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := compositeIDs(g); got != tt.composites {
+				t.Errorf("composites %s, want %s", got, tt.composites)
+			}
+		})
+	}
+}
+
+func TestBuildRuleGraphExcludesOnlyInvalidRules(t *testing.T) {
+	rules := []*models.ThresholdRule{
+		testRule("ok", "orders", "ok", "errors AND NOT payments.latency"),
+		testRule("unknown", "orders", "unknown", "orders.missing"),
+		testRule("loop1", "orders", "loop1", "loop2"),
+		testRule("loop2", "orders", "loop2", "loop1"),
+		testRule("uses-loop", "orders", "uses-loop", "loop1 OR errors"),
+		testRule("errors", "orders", "errors", "", "auth.missing"),
+		testRule("latency", "payments", "latency", ""),
+	}
+
+	g, errs := BuildRuleGraph(rules)
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`rule "unknown": unknown rule "orders.missing"`,
+		`rule "errors": unknown rule "auth.missing"`,
+		"rule cycle: orders.loop1 -> orders.loop2 -> orders.loop1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Rules referencing excluded rules still evaluate them as rule states
+	if ids := compositeIDs(g); ids != "ok,uses-loop" {
+		t.Errorf("composites %s, want ok,uses-loop", ids)
+	}
+	states := map[string]RuleState{"errors": {Breached: true}, "latency": {}, "loop1": {Breached: true}}
+	for _, id := range []string{"ok", "uses-loop"} {
+		if breached, ok := g.EvaluateComposite(g.rules[id], states); !breached || !ok {
+			t.Errorf("%s = %v ok %v, want breached", id, breached, ok)
+		}
+	}
+	if _, ok := g.EvaluateComposite(g.rules["loop1"], states); ok {
+		t.Error("excluded composite evaluated")
+	}
+	if _, inhibited := g.Inhibitor(g.rules["errors"], states); inhibited {
+		t.Error("excluded rule inhibited")
+	}
+}
+
+func TestRuleGraphInhibitor(t *testing.T) {
+	g, err := NewRuleGraph([]*models.ThresholdRule{
+		testRule("errors", "orders", "errors", "", "auth.down", "payments"),
+		testRule("down", "auth", "down", ""),
+		testRule("payments", "payments", "latency", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule, _ := g.Resolve("orders.errors")
+
+	if _, inhibited := g.Inhibitor(rule, map[string]RuleState{"down": {}}); inhibited {
+		t.Error("inhibited without a breached dependency")
+	}
+	inhibitor, inhibited := g.Inhibitor(rule, map[string]RuleState{"payments": {Breached: true}})
+	if !inhibited || inhibitor.ID != "payments" {
+		t.Errorf("inhibitor %v, want the payments rule referenced by ID", inhibitor)
+	}
+}
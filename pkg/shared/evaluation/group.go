@@ -0,0 +1,41 @@
+package evaluation
+
+import (
+	"sort"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// Group is the samples of one label combination of a grouped rule.
+type Group struct {
+	Labels  models.Labels
+	Samples []*models.ServiceMetric
+}
+
+// GroupSamples partitions samples by the rule's group_by labels, preserving
+// their order, and returns the groups ordered by label set. A rule without
+// group_by yields a single group with no labels.
+func GroupSamples(rule *models.ThresholdRule, samples []*models.ServiceMetric) []Group {
+	if len(rule.GroupBy) == 0 {
+		return []Group{{Samples: samples}}
+	}
+
+	index := make(map[string]int)
+	var groups []Group
+	for _, sample := range samples {
+		labels := sample.Labels.Project(rule.GroupBy)
+		key := labels.Fingerprint()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Labels: labels})
+		}
+		groups[i].Samples = append(groups[i].Samples, sample)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Labels.Fingerprint() < groups[j].Labels.Fingerprint()
+	})
+	return groups
+}
@@ -0,0 +1,105 @@
+// Package evaluation provides the threshold rule evaluation shared by the
+// analyzer's live evaluator and the rule backtest engine.
+package evaluation
+
+import (
+	"math"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// Window is a time-bounded ring buffer holding the samples of one metric
+// series. Running sums keep mean and standard deviation O(1) per sample.
+type Window struct {
+	samples []*models.ServiceMetric
+	head    int
+	size    int
+	span    time.Duration
+
+	sum        float64
+	sumSquares float64
+}
+
+// NewWindow creates a window holding at most capacity samples spanning span.
+func NewWindow(capacity int, span time.Duration) *Window {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Window{
+		samples: make([]*models.ServiceMetric, capacity),
+		span:    span,
+	}
+}
+
+// Add appends a sample, evicting the oldest sample when full and every sample
+// older than the window span relative to the new sample.
+func (w *Window) Add(metric *models.ServiceMetric) {
+	if w.size == len(w.samples) {
+		w.removeOldest()
+	}
+
+	w.samples[(w.head+w.size)%len(w.samples)] = metric
+	w.size++
+	w.sum += metric.Value
+	w.sumSquares += metric.Value * metric.Value
+
+	w.EvictBefore(metric.Timestamp.Add(-w.span))
+}
+
+// EvictBefore drops samples with a timestamp before cutoff.
+func (w *Window) EvictBefore(cutoff time.Time) {
+	for w.size > 0 && w.samples[w.head].Timestamp.Before(cutoff) {
+		w.removeOldest()
+	}
+}
+
+func (w *Window) removeOldest() {
+	oldest := w.samples[w.head]
+	w.samples[w.head] = nil
+	w.head = (w.head + 1) % len(w.samples)
+	w.size--
+	w.sum -= oldest.Value
+	w.sumSquares -= oldest.Value * oldest.Value
+
+	// Reset the sums when empty so floating point drift does not accumulate
+	if w.size == 0 {
+		w.sum, w.sumSquares = 0, 0
+	}
+}
+
+// Len returns the number of samples in the window.
+func (w *Window) Len() int {
+	return w.size
+}
+
+// Latest returns the most recent sample, or nil if the window is empty.
+func (w *Window) Latest() *models.ServiceMetric {
+	if w.size == 0 {
+		return nil
+	}
+	return w.samples[(w.head+w.size-1)%len(w.samples)]
+}
+
+// Samples returns the samples ordered from oldest to newest.
+func (w *Window) Samples() []*models.ServiceMetric {
+	out := make([]*models.ServiceMetric, w.size)
+	for i := 0; i < w.size; i++ {
+		out[i] = w.samples[(w.head+i)%len(w.samples)]
+	}
+	return out
+}
+
+// Stats returns the mean and population standard deviation of the window.
+func (w *Window) Stats() (mean, stdDev float64) {
+	if w.size == 0 {
+		return 0, 0
+	}
+	n := float64(w.size)
+	mean = w.sum / n
+	variance := (w.sumSquares / n) - (mean * mean)
+	if variance < 0 {
+		variance = 0
+	}
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,375 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+)
+
+// ErrTopicsUnusable is returned when topics are missing or have fewer
+// partitions than declared.
+var ErrTopicsUnusable = errors.New("kafka topics are not usable")
+
+// Topic configuration names.
+const (
+	configRetentionMs   = "retention.ms"
+	configCleanupPolicy = "cleanup.policy"
+)
+
+// Cleanup policies of topics.
+const (
+	CleanupDelete  = "delete"
+	CleanupCompact = "compact"
+)
+
+// adminTimeout bounds each admin request.
+const adminTimeout = 15 * time.Second
+
+// TopicSpec declares a topic.
+type TopicSpec struct {
+	Name              string        `json:"name"`
+	Partitions        int           `json:"partitions"`
+	ReplicationFactor int           `json:"replication_factor"`
+	Retention         time.Duration `json:"retention"` // negative for unlimited
+	CleanupPolicy     string        `json:"cleanup_policy"`
+}
+
+// EventTopicSpec returns the spec of a topic of platform events.
+func EventTopicSpec(name string, replicationFactor int) TopicSpec {
+	return TopicSpec{
+		Name:              name,
+		Partitions:        3,
+		ReplicationFactor: replicationFactor,
+		Retention:         7 * 24 * time.Hour,
+		CleanupPolicy:     CleanupDelete,
+	}
+}
+
+// DLQTopicSpec returns the spec of a dead-letter topic.
+func DLQTopicSpec(name string, replicationFactor int) TopicSpec {
+	return TopicSpec{
+		Name:              name,
+		Partitions:        1,
+		ReplicationFactor: replicationFactor,
+		Retention:         30 * 24 * time.Hour,
+		CleanupPolicy:     CleanupDelete,
+	}
+}
+
+// TopicSpecsFor returns the specs of the named topics: DLQTopicSpec for
+// dead-letter topics and EventTopicSpec for the others.
+func TopicSpecsFor(replicationFactor int, names ...string) []TopicSpec {
+	specs := make([]TopicSpec, 0, len(names))
+	for _, name := range names {
+		if strings.HasSuffix(name, DLQTopic("")) {
+			specs = append(specs, DLQTopicSpec(name, replicationFactor))
+		} else {
+			specs = append(specs, EventTopicSpec(name, replicationFactor))
+		}
+	}
+	return specs
+}
+
+// PlatformTopics returns the specs of all topics of the platform.
+func PlatformTopics(replicationFactor int) []TopicSpec {
+	return TopicSpecsFor(replicationFactor,
+		TopicServiceMetrics,
+		TopicServiceLogs,
+		TopicAlerts,
+		DLQTopic(TopicAlerts),
+		DLQTopic(TopicServiceMetrics),
+		DLQTopic(TopicServiceLogs),
+	)
+}
+
+// TopicState is a topic as it exists on the cluster.
+type TopicState struct {
+	TopicSpec
+	Exists bool `json:"exists"`
+}
+
+// Drift lists how a topic differs from its spec.
+func (s TopicSpec) Drift(state TopicState) []string {
+	if !state.Exists {
+		return []string{"topic does not exist"}
+	}
+
+	var drift []string
+	if state.Partitions != s.Partitions {
+		drift = append(drift, fmt.Sprintf("partitions %d, declared %d", state.Partitions, s.Partitions))
+	}
+	if state.ReplicationFactor != s.ReplicationFactor {
+		drift = append(drift, fmt.Sprintf("replication factor %d, declared %d", state.ReplicationFactor, s.ReplicationFactor))
+	}
+	if state.Retention != s.Retention {
+		drift = append(drift, fmt.Sprintf("retention %s, declared %s", formatRetention(state.Retention), formatRetention(s.Retention)))
+	}
+	if state.CleanupPolicy != s.CleanupPolicy {
+		drift = append(drift, fmt.Sprintf("cleanup policy %q, declared %q", state.CleanupPolicy, s.CleanupPolicy))
+	}
+	return drift
+}
+
+// TopicReport is the outcome of ensuring a topic.
+type TopicReport struct {
+	Spec    TopicSpec  `json:"spec"`
+	State   TopicState `json:"state"`
+	Created bool       `json:"created"`
+	Drift   []string   `json:"drift,omitempty"`
+}
+
+// Usable reports whether services can use the topic: it exists and has at
+// least the declared partitions, which keyed consumers rely on. Other drift
+// only degrades retention or durability.
+func (r TopicReport) Usable() bool {
+	return r.State.Exists && r.State.Partitions >= r.Spec.Partitions
+}
+
+// Admin verifies and creates topics.
+type Admin struct {
+	client *kafka.Client
+	logger *logging.Logger
+}
+
+// NewAdmin creates an Admin for the cluster of brokers.
+func NewAdmin(brokers []string, security Security, logger *logging.Logger) (*Admin, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("at least one broker is required")
+	}
+
+	client := &kafka.Client{
+		Addr:    kafka.TCP(brokers...),
+		Timeout: adminTimeout,
+	}
+	transport, err := security.transport()
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		client.Transport = transport
+	}
+
+	return &Admin{
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// Describe returns the state of the named topics.
+func (a *Admin) Describe(ctx context.Context, names []string) ([]TopicState, error) {
+	metadata, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: names})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topic metadata: %w", err)
+	}
+
+	states := make(map[string]*TopicState, len(names))
+	var existing []kafka.DescribeConfigRequestResource
+	for _, topic := range metadata.Topics {
+		if errors.Is(topic.Error, kafka.UnknownTopicOrPartition) {
+			continue
+		}
+		if topic.Error != nil {
+			return nil, fmt.Errorf("failed to get metadata of topic %s: %w", topic.Name, topic.Error)
+		}
+
+		state := &TopicState{Exists: true}
+		state.Name = topic.Name
+		state.Partitions = len(topic.Partitions)
+		if len(topic.Partitions) > 0 {
+			state.ReplicationFactor = len(topic.Partitions[0].Replicas)
+		}
+		states[topic.Name] = state
+		existing = append(existing, kafka.DescribeConfigRequestResource{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic.Name,
+			ConfigNames:  []string{configRetentionMs, configCleanupPolicy},
+		})
+	}
+
+	if len(existing) > 0 {
+		configs, err := a.client.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{Resources: existing})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe topic configs: %w", err)
+		}
+		for _, resource := range configs.Resources {
+			if resource.Error != nil {
+				return nil, fmt.Errorf("failed to describe configs of topic %s: %w", resource.ResourceName, resource.Error)
+			}
+			state, ok := states[resource.ResourceName]
+			if !ok {
+				continue
+			}
+			for _, entry := range resource.ConfigEntries {
+				switch entry.ConfigName {
+				case configRetentionMs:
+					ms, err := strconv.ParseInt(entry.ConfigValue, 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("invalid %s of topic %s: %w", configRetentionMs, resource.ResourceName, err)
+					}
+					state.Retention = retentionFromMs(ms)
+				case configCleanupPolicy:
+					state.CleanupPolicy = entry.ConfigValue
+				}
+			}
+		}
+	}
+
+	result := make([]TopicState, 0, len(names))
+	for _, name := range names {
+		if state, ok := states[name]; ok {
+			result = append(result, *state)
+			continue
+		}
+		missing := TopicState{}
+		missing.Name = name
+		result = append(result, missing)
+	}
+	return result, nil
+}
+
+// Ensure verifies topics against their specs, creating the missing ones
+// when create is set. Existing topics are never altered; their drift is
+// reported instead.
+func (a *Admin) Ensure(ctx context.Context, specs []TopicSpec, create bool) ([]TopicReport, error) {
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+
+	states, err := a.Describe(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []kafka.TopicConfig
+	for i, spec := range specs {
+		if !states[i].Exists && create {
+			missing = append(missing, spec.topicConfig())
+		}
+	}
+	created := make(map[string]bool, len(missing))
+	if len(missing) > 0 {
+		resp, err := a.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: missing})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create topics: %w", err)
+		}
+		for _, topic := range missing {
+			err := resp.Errors[topic.Topic]
+			if err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+				return nil, fmt.Errorf("failed to create topic %s: %w", topic.Topic, err)
+			}
+			created[topic.Topic] = err == nil
+			if err == nil {
+				a.logger.Info("created kafka topic",
+					zap.String("topic", topic.Topic),
+					zap.Int("partitions", topic.NumPartitions),
+					zap.Int("replication_factor", topic.ReplicationFactor),
+				)
+			}
+		}
+
+		// Describe again for the state of created topics, or of topics
+		// another service created concurrently
+		if states, err = a.Describe(ctx, names); err != nil {
+			return nil, err
+		}
+	}
+
+	reports := make([]TopicReport, len(specs))
+	for i, spec := range specs {
+		reports[i] = TopicReport{
+			Spec:    spec,
+			State:   states[i],
+			Created: created[spec.Name],
+			Drift:   spec.Drift(states[i]),
+		}
+	}
+	return reports, nil
+}
+
+// EnsureTopics verifies the topics a service uses on startup, creating the
+// missing ones when create is set, logs their drift and returns their state,
+// e.g. for their actual partition counts. It fails with ErrTopicsUnusable
+// when a topic is missing or has too few partitions. An unreachable cluster
+// is only logged, as services start without Kafka, and returns no state.
+func EnsureTopics(ctx context.Context, brokers []string, security Security, specs []TopicSpec, create bool, logger *logging.Logger) ([]TopicState, error) {
+	admin, err := NewAdmin(brokers, security, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, adminTimeout)
+	defer cancel()
+
+	reports, err := admin.Ensure(ctx, specs, create)
+	if err != nil {
+		logger.Warn("failed to verify kafka topics",
+			zap.Strings("brokers", brokers),
+			zap.Error(err),
+		)
+		return nil, nil
+	}
+
+	states := make([]TopicState, len(reports))
+	var unusable []string
+	for i, report := range reports {
+		states[i] = report.State
+		if !report.Usable() {
+			unusable = append(unusable, fmt.Sprintf("%s: %s", report.Spec.Name, strings.Join(report.Drift, ", ")))
+			continue
+		}
+		if len(report.Drift) > 0 {
+			logger.Warn("kafka topic differs from its declaration",
+				zap.String("topic", report.Spec.Name),
+				zap.Strings("drift", report.Drift),
+			)
+		}
+	}
+	if len(unusable) > 0 {
+		sort.Strings(unusable)
+		return nil, fmt.Errorf("%w: %s", ErrTopicsUnusable, strings.Join(unusable, "; "))
+	}
+	return states, nil
+}
+
+func (s TopicSpec) topicConfig() kafka.TopicConfig {
+	return kafka.TopicConfig{
+		Topic:             s.Name,
+		NumPartitions:     s.Partitions,
+		ReplicationFactor: s.ReplicationFactor,
+		ConfigEntries: []kafka.ConfigEntry{
+			{ConfigName: configRetentionMs, ConfigValue: strconv.FormatInt(retentionToMs(s.Retention), 10)},
+			{ConfigName: configCleanupPolicy, ConfigValue: s.CleanupPolicy},
+		},
+	}
+}
+
+func retentionToMs(retention time.Duration) int64 {
+	if retention < 0 {
+		return -1
+	}
+	return retention.Milliseconds()
+}
+
+func retentionFromMs(ms int64) time.Duration {
+	if ms < 0 {
+		return -1
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func formatRetention(retention time.Duration) string {
+	if retention < 0 {
+		return "unlimited"
+	}
+	return retention.String()
+}
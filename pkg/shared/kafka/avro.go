@@ -0,0 +1,368 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// AvroCodec encodes events as Avro records. Every field is a union of null
+// and its type, so fields can be added and removed compatibly. Values are
+// decoded with the schema they were written with, from the registry.
+type AvroCodec struct {
+	*schemaCodec
+
+	writersMu sync.RWMutex
+	writers   map[int]*models.Schema // by schema ID
+}
+
+// NewAvroCodec creates an AvroCodec registering its schemas in registry.
+func NewAvroCodec(registry SchemaRegistry) *AvroCodec {
+	return &AvroCodec{
+		schemaCodec: newSchemaCodec(registry, SchemaTypeAvro, AvroSchema),
+		writers:     make(map[int]*models.Schema),
+	}
+}
+
+// ContentType returns the Avro content type.
+func (c *AvroCodec) ContentType() string { return ContentTypeAvro }
+
+// Encode encodes an event with a schema as an Avro record.
+func (c *AvroCodec) Encode(ctx context.Context, topic string, v interface{}) ([]byte, error) {
+	schema, id, err := c.schemaOf(ctx, topic, v)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := schemaFields(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b := appendWireHeader(nil, id)
+	for _, f := range schema.Fields {
+		value, ok := fields[f.Name]
+		if !ok || value == nil {
+			b = binary.AppendVarint(b, 0) // null branch
+			continue
+		}
+		b = binary.AppendVarint(b, 1)
+		if b, err = appendAvroValue(b, f.Type, value); err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", f.Name, err)
+		}
+	}
+	return b, nil
+}
+
+// Decode decodes an Avro record into an event.
+func (c *AvroCodec) Decode(ctx context.Context, data []byte, v interface{}) error {
+	id, payload, err := readWireHeader(data)
+	if err != nil {
+		return err
+	}
+	writer, err := c.writerSchema(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	r := &avroReader{data: payload}
+	fields := make(map[string]interface{}, len(writer.Fields))
+	for _, f := range writer.Fields {
+		branch, err := r.long()
+		if err != nil {
+			return Malformed(fmt.Errorf("failed to decode field %s: %w", f.Name, err))
+		}
+		if branch == 0 {
+			continue
+		}
+		value, err := r.value(f.Type)
+		if err != nil {
+			return Malformed(fmt.Errorf("failed to decode field %s: %w", f.Name, err))
+		}
+		fields[f.Name] = value
+	}
+	return setSchemaFields(fields, v)
+}
+
+// writerSchema returns the schema a value was written with.
+func (c *AvroCodec) writerSchema(ctx context.Context, id int) (*models.Schema, error) {
+	c.writersMu.RLock()
+	schema, ok := c.writers[id]
+	c.writersMu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	registered, err := c.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if registered.Type != SchemaTypeAvro {
+		return nil, Malformed(fmt.Errorf("schema %d is %s, not Avro", id, registered.Type))
+	}
+	if schema, err = parseAvroSchema(registered.Schema); err != nil {
+		return nil, Malformed(fmt.Errorf("failed to parse schema %d: %w", id, err))
+	}
+
+	c.writersMu.Lock()
+	c.writers[id] = schema
+	c.writersMu.Unlock()
+	return schema, nil
+}
+
+// avroField is a field of an Avro record schema.
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    json.RawMessage `json:"type"`
+	Default interface{}     `json:"default"`
+}
+
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Fields    []avroField `json:"fields"`
+}
+
+// avroType is a named or complex Avro type. Format marks strings holding
+// JSON, so they are decoded as nested values.
+type avroType struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType,omitempty"`
+	Values      string `json:"values,omitempty"`
+	Format      string `json:"format,omitempty"`
+}
+
+var avroTypes = map[models.FieldType]interface{}{
+	models.FieldString:    "string",
+	models.FieldLong:      "long",
+	models.FieldDouble:    "double",
+	models.FieldBool:      "boolean",
+	models.FieldTimestamp: avroType{Type: "long", LogicalType: "timestamp-micros"},
+	models.FieldLabels:    avroType{Type: "map", Values: "string"},
+	models.FieldJSON:      avroType{Type: "string", Format: "json"},
+}
+
+// AvroSchema renders an event schema as an Avro record schema.
+func AvroSchema(schema *models.Schema) string {
+	record := avroRecord{Type: "record", Name: schema.Name, Namespace: models.SchemaNamespace}
+	for _, f := range schema.Fields {
+		fieldType, _ := json.Marshal([]interface{}{"null", avroTypes[f.Type]})
+		record.Fields = append(record.Fields, avroField{Name: f.Name, Type: fieldType})
+	}
+	data, _ := json.Marshal(record)
+	return string(data)
+}
+
+// parseAvroSchema parses a record schema rendered by AvroSchema.
+func parseAvroSchema(text string) (*models.Schema, error) {
+	var record avroRecord
+	if err := json.Unmarshal([]byte(text), &record); err != nil {
+		return nil, err
+	}
+
+	rendered := make(map[string]models.FieldType, len(avroTypes))
+	for fieldType, t := range avroTypes {
+		data, _ := json.Marshal(t)
+		rendered[string(canonicalJSON(data))] = fieldType
+	}
+
+	schema := &models.Schema{Name: record.Name}
+	for _, f := range record.Fields {
+		var union []json.RawMessage
+		if err := json.Unmarshal(f.Type, &union); err != nil || len(union) != 2 {
+			return nil, fmt.Errorf("field %s is not a nullable union", f.Name)
+		}
+		fieldType, ok := rendered[string(canonicalJSON(union[1]))]
+		if !ok {
+			return nil, fmt.Errorf("field %s has unsupported type %s", f.Name, union[1])
+		}
+		schema.Fields = append(schema.Fields, models.SchemaField{Name: f.Name, Type: fieldType})
+	}
+	return schema, nil
+}
+
+// canonicalJSON re-encodes a JSON value with sorted keys and no spacing.
+func canonicalJSON(raw json.RawMessage) []byte {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func appendAvroValue(b []byte, fieldType models.FieldType, value interface{}) ([]byte, error) {
+	switch fieldType {
+	case models.FieldString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", value)
+		}
+		return appendAvroString(b, s), nil
+	case models.FieldLong:
+		i, err := toLong(value)
+		if err != nil {
+			return nil, err
+		}
+		return binary.AppendVarint(b, i), nil
+	case models.FieldDouble:
+		f, err := toDouble(value)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case models.FieldBool:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %T", value)
+		}
+		if v {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case models.FieldTimestamp:
+		micros, err := toTimestampMicros(value)
+		if err != nil {
+			return nil, err
+		}
+		return binary.AppendVarint(b, micros), nil
+	case models.FieldLabels:
+		labels, err := toLabels(value)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			b = binary.AppendVarint(b, int64(len(keys)))
+			for _, k := range keys {
+				b = appendAvroString(b, k)
+				b = appendAvroString(b, labels[k])
+			}
+		}
+		return binary.AppendVarint(b, 0), nil
+	case models.FieldJSON:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return appendAvroString(b, string(data)), nil
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", fieldType)
+	}
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	b = binary.AppendVarint(b, int64(len(s)))
+	return append(b, s...)
+}
+
+var errAvroTruncated = errors.New("truncated avro value")
+
+// avroReader reads Avro binary values.
+type avroReader struct {
+	data []byte
+}
+
+func (r *avroReader) long() (int64, error) {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		return 0, errAvroTruncated
+	}
+	r.data = r.data[n:]
+	return v, nil
+}
+
+func (r *avroReader) bytes() ([]byte, error) {
+	n, err := r.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || int64(len(r.data)) < n {
+		return nil, errAvroTruncated
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
+
+// value reads a value of a field type as its JSON value.
+func (r *avroReader) value(fieldType models.FieldType) (interface{}, error) {
+	switch fieldType {
+	case models.FieldString:
+		b, err := r.bytes()
+		return string(b), err
+	case models.FieldLong:
+		return r.long()
+	case models.FieldDouble:
+		if len(r.data) < 8 {
+			return nil, errAvroTruncated
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(r.data))
+		r.data = r.data[8:]
+		return f, nil
+	case models.FieldBool:
+		if len(r.data) < 1 {
+			return nil, errAvroTruncated
+		}
+		v := r.data[0] != 0
+		r.data = r.data[1:]
+		return v, nil
+	case models.FieldTimestamp:
+		micros, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		return fromTimestampMicros(micros), nil
+	case models.FieldLabels:
+		labels := make(map[string]string)
+		for {
+			count, err := r.long()
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return labels, nil
+			}
+			if count < 0 {
+				// A negative count is followed by the block's size in bytes
+				count = -count
+				if _, err := r.long(); err != nil {
+					return nil, err
+				}
+			}
+			for i := int64(0); i < count; i++ {
+				k, err := r.bytes()
+				if err != nil {
+					return nil, err
+				}
+				v, err := r.bytes()
+				if err != nil {
+					return nil, err
+				}
+				labels[string(k)] = string(v)
+			}
+		}
+	case models.FieldJSON:
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid(b) {
+			return nil, fmt.Errorf("invalid JSON value")
+		}
+		return json.RawMessage(b), nil
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", fieldType)
+	}
+}
@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+)
+
+// MemoryBrokers is the KAFKA_BROKERS value that selects a MemoryBroker.
+const MemoryBrokers = "memory"
+
+// MessageProducer publishes messages to a topic. It is implemented by
+// Producer, for a Kafka cluster or a MemoryBroker.
+type MessageProducer interface {
+	// Topic returns the topic the producer publishes to.
+	Topic() string
+	Publish(ctx context.Context, key, value []byte) error
+	PublishValue(ctx context.Context, key []byte, v interface{}) error
+	PublishBatch(ctx context.Context, messages []kafka.Message) error
+	Close() error
+}
+
+// MessageConsumer consumes a topic as a member of a consumer group. It is
+// implemented by Consumer and GroupConsumer.
+type MessageConsumer interface {
+	// Consume handles messages until ctx is cancelled or the consumer is closed.
+	Consume(ctx context.Context, handler MessageHandler, opts *ConsumeOptions) error
+	Close() error
+}
+
+// Broker creates the producers and consumers of services. Producers and
+// consumers connect with the broker's settings, so their configs leave
+// Brokers and Security unset.
+type Broker interface {
+	NewProducer(cfg *ProducerConfig, logger *logging.Logger) (MessageProducer, error)
+	// NewConsumer creates a Consumer.
+	NewConsumer(cfg *ConsumerConfig, logger *logging.Logger) (MessageConsumer, error)
+	// NewGroupConsumer creates a GroupConsumer. listener may be nil.
+	NewGroupConsumer(cfg *ConsumerConfig, listener RebalanceListener, logger *logging.Logger) (MessageConsumer, error)
+	// EnsureTopics verifies the topics a service uses and returns their
+	// state, as the package-level EnsureTopics does.
+	EnsureTopics(ctx context.Context, specs []TopicSpec, create bool, logger *logging.Logger) ([]TopicState, error)
+}
+
+// NewBroker returns the Broker of the configured brokers: a MemoryBroker
+// when they are MemoryBrokers, the Kafka cluster otherwise.
+func NewBroker(brokers []string, security Security) Broker {
+	if len(brokers) == 1 && brokers[0] == MemoryBrokers {
+		return NewMemoryBroker()
+	}
+	return NewKafkaBroker(brokers, security)
+}
+
+// KafkaBroker creates producers and consumers of a Kafka cluster.
+type KafkaBroker struct {
+	brokers  []string
+	security Security
+}
+
+// NewKafkaBroker creates a KafkaBroker connecting to brokers with security.
+func NewKafkaBroker(brokers []string, security Security) *KafkaBroker {
+	return &KafkaBroker{brokers: brokers, security: security}
+}
+
+// NewProducer creates a Producer.
+func (b *KafkaBroker) NewProducer(cfg *ProducerConfig, logger *logging.Logger) (MessageProducer, error) {
+	cfg.Brokers = b.brokers
+	cfg.Security = b.security
+	return NewProducer(cfg, logger)
+}
+
+// NewConsumer creates a Consumer.
+func (b *KafkaBroker) NewConsumer(cfg *ConsumerConfig, logger *logging.Logger) (MessageConsumer, error) {
+	cfg.Brokers = b.brokers
+	cfg.Security = b.security
+	return NewConsumer(cfg, logger)
+}
+
+// NewGroupConsumer creates a GroupConsumer.
+func (b *KafkaBroker) NewGroupConsumer(cfg *ConsumerConfig, listener RebalanceListener, logger *logging.Logger) (MessageConsumer, error) {
+	cfg.Brokers = b.brokers
+	cfg.Security = b.security
+	return NewGroupConsumer(cfg, listener, logger)
+}
+
+// EnsureTopics verifies the topics a service uses on the cluster.
+func (b *KafkaBroker) EnsureTopics(ctx context.Context, specs []TopicSpec, create bool, logger *logging.Logger) ([]TopicState, error) {
+	return EnsureTopics(ctx, b.brokers, b.security, specs, create, logger)
+}
+
+// validateConsumerConfig checks the settings every consumer needs.
+func validateConsumerConfig(cfg *ConsumerConfig) error {
+	if cfg.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if cfg.GroupID == "" {
+		return fmt.Errorf("group ID is required")
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// Content types of the supported encodings, sent in the content-type header.
+const (
+	ContentTypeAvro     = "application/vnd.apache.avro"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Encoding names a message encoding.
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingAvro     Encoding = "avro"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// Codec encodes and decodes message values.
+type Codec interface {
+	// ContentType is sent in the content-type header of encoded messages.
+	ContentType() string
+	// Encode encodes a value for a topic.
+	Encode(ctx context.Context, topic string, v interface{}) ([]byte, error)
+	// Decode decodes a value. Payloads that can never be decoded return an
+	// error wrapped with Malformed.
+	Decode(ctx context.Context, data []byte, v interface{}) error
+}
+
+// NewCodec creates the codec of an encoding. Avro and Protobuf values are
+// framed in the Confluent wire format and refer to their schema in registry
+// by ID; they require an event with a schema in models.
+func NewCodec(encoding Encoding, registry SchemaRegistry) (Codec, error) {
+	switch encoding {
+	case EncodingJSON, "":
+		return JSONCodec{}, nil
+	case EncodingAvro, EncodingProtobuf:
+		if registry == nil {
+			return nil, fmt.Errorf("%s encoding requires a schema registry", encoding)
+		}
+		if encoding == EncodingAvro {
+			return NewAvroCodec(registry), nil
+		}
+		return NewProtobufCodec(registry), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// JSONCodec encodes values as plain JSON.
+type JSONCodec struct{}
+
+// ContentType returns the JSON content type.
+func (JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Encode encodes a value as JSON.
+func (JSONCodec) Encode(ctx context.Context, topic string, v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode decodes a JSON value.
+func (JSONCodec) Decode(ctx context.Context, data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return Malformed(err)
+	}
+	return nil
+}
+
+// Decoder decodes consumed messages with the codec named by their
+// content-type header, so producers can change their encoding without
+// coordinating with consumers. Messages without the header are JSON.
+type Decoder struct {
+	codecs map[string]Codec
+}
+
+// NewDecoder creates a Decoder. Without a registry only JSON is decoded.
+func NewDecoder(registry SchemaRegistry) *Decoder {
+	d := &Decoder{codecs: map[string]Codec{ContentTypeJSON: JSONCodec{}}}
+	if registry != nil {
+		d.codecs[ContentTypeAvro] = NewAvroCodec(registry)
+		d.codecs[ContentTypeProtobuf] = NewProtobufCodec(registry)
+	}
+	return d
+}
+
+// Decode decodes the value of a message into v.
+func (d *Decoder) Decode(ctx context.Context, msg kafka.Message, v interface{}) error {
+	contentType := Header(msg, HeaderContentType)
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	codec, ok := d.codecs[contentType]
+	if !ok {
+		return Malformed(fmt.Errorf("unsupported content type %q", contentType))
+	}
+	return codec.Decode(ctx, msg.Value, v)
+}
+
+// Confluent wire format: a zero magic byte and the big-endian schema ID
+// precede the encoded value.
+const (
+	wireMagic      byte = 0
+	wireHeaderSize      = 5
+)
+
+func appendWireHeader(b []byte, schemaID int) []byte {
+	b = append(b, wireMagic)
+	return binary.BigEndian.AppendUint32(b, uint32(schemaID))
+}
+
+func readWireHeader(data []byte) (int, []byte, error) {
+	if len(data) < wireHeaderSize || data[0] != wireMagic {
+		return 0, nil, Malformed(fmt.Errorf("missing schema registry wire header"))
+	}
+	return int(binary.BigEndian.Uint32(data[1:wireHeaderSize])), data[wireHeaderSize:], nil
+}
+
+// schemaCodec registers the schemas of the events it encodes and looks up
+// the schemas of the events it decodes.
+type schemaCodec struct {
+	registry   SchemaRegistry
+	schemaType SchemaType
+	render     func(*models.Schema) string
+
+	mu  sync.RWMutex
+	ids map[string]int // by subject and schema name
+}
+
+func newSchemaCodec(registry SchemaRegistry, schemaType SchemaType, render func(*models.Schema) string) *schemaCodec {
+	return &schemaCodec{
+		registry:   registry,
+		schemaType: schemaType,
+		render:     render,
+		ids:        make(map[string]int),
+	}
+}
+
+// schemaOf returns the schema of an event to encode and its registry ID.
+func (c *schemaCodec) schemaOf(ctx context.Context, topic string, v interface{}) (*models.Schema, int, error) {
+	schema, ok := models.SchemaFor(v)
+	if !ok {
+		return nil, 0, fmt.Errorf("no schema for %T", v)
+	}
+
+	subject := SubjectForTopic(topic)
+	key := subject + "\x00" + schema.Name
+	c.mu.RLock()
+	id, ok := c.ids[key]
+	c.mu.RUnlock()
+	if ok {
+		return schema, id, nil
+	}
+
+	id, err := c.registry.Register(ctx, subject, c.schemaType, c.render(schema))
+	if err != nil {
+		return nil, 0, err
+	}
+	c.mu.Lock()
+	c.ids[key] = id
+	c.mu.Unlock()
+	return schema, id, nil
+}
+
+// schemaFields returns the fields of an event as their JSON values, keyed
+// by JSON name.
+func schemaFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// setSchemaFields sets the fields of an event from their JSON values.
+func setSchemaFields(fields map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return Malformed(err)
+	}
+	return nil
+}
+
+// Conversions between JSON field values and their encoded forms.
+
+func toLong(value interface{}) (int64, error) {
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := n.Float64()
+		return int64(f), err
+	}
+	return 0, fmt.Errorf("expected a number, got %T", value)
+}
+
+func toDouble(value interface{}) (float64, error) {
+	if n, ok := value.(json.Number); ok {
+		return n.Float64()
+	}
+	return 0, fmt.Errorf("expected a number, got %T", value)
+}
+
+func toTimestampMicros(value interface{}) (int64, error) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected a timestamp, got %T", value)
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMicro(), nil
+}
+
+func fromTimestampMicros(micros int64) string {
+	return time.UnixMicro(micros).UTC().Format(time.RFC3339Nano)
+}
+
+func toLabels(value interface{}) (map[string]string, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected labels, got %T", value)
+	}
+	labels := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("label %q is not a string", k)
+		}
+		labels[k] = s
+	}
+	return labels, nil
+}
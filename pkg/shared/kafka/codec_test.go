@@ -0,0 +1,231 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+func testAlert() *models.Alert {
+	fired := time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	resolved := fired.Add(5 * time.Minute)
+	return &models.Alert{
+		ID:           "4a1f2c3e-0000-4000-8000-000000000001",
+		Fingerprint:  "fp",
+		Type:         models.AlertTypeThresholdViolation,
+		Severity:     models.AlertSeverityCritical,
+		ServiceName:  models.ServiceOrders,
+		MetricType:   models.MetricTypeErrorRate,
+		Title:        "High error rate",
+		Description:  "error rate above threshold",
+		CurrentValue: 0.25,
+		Threshold:    0.05,
+		Timestamp:    fired,
+		ResolvedAt:   &resolved,
+		Acknowledged: true,
+		Labels:       models.Labels{"service": "orders", "state": "resolved"},
+		RuleID:       "error-rate",
+		Confidence:   0.9,
+		Enrichment: &models.AlertEnrichment{
+			OwnerTeam:     "checkout",
+			RecentLogs:    []string{"payment declined"},
+			RecentSamples: []models.MetricSample{{Timestamp: fired, Value: 0.25}},
+		},
+	}
+}
+
+func testMetric() *models.ServiceMetric {
+	return &models.ServiceMetric{
+		ID:          "m-1",
+		ServiceName: models.ServicePayments,
+		MetricType:  models.MetricTypeLatency,
+		Value:       123.5,
+		Unit:        "ms",
+		Timestamp:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Labels:      models.Labels{"instance": "payments-1"},
+		LatencyP99:  250,
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, encoding := range []Encoding{EncodingJSON, EncodingAvro, EncodingProtobuf} {
+		t.Run(string(encoding), func(t *testing.T) {
+			registry := NewMemoryRegistry()
+			codec, err := NewCodec(encoding, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, event := range []interface{}{testAlert(), testMetric()} {
+				data, err := codec.Encode(context.Background(), "events", event)
+				if err != nil {
+					t.Fatalf("failed to encode %T: %v", event, err)
+				}
+
+				// Consumers pick the codec by the content-type header
+				msg := kafka.Message{
+					Value:   data,
+					Headers: []kafka.Header{{Key: HeaderContentType, Value: []byte(codec.ContentType())}},
+				}
+				decoded := reflect.New(reflect.TypeOf(event).Elem()).Interface()
+				if err := NewDecoder(registry).Decode(context.Background(), msg, decoded); err != nil {
+					t.Fatalf("failed to decode %T: %v", event, err)
+				}
+				if !reflect.DeepEqual(decoded, event) {
+					t.Errorf("decoded %+v, want %+v", decoded, event)
+				}
+			}
+		})
+	}
+}
+
+func TestCodecWireFormat(t *testing.T) {
+	tests := []struct {
+		encoding   Encoding
+		schemaType SchemaType
+	}{
+		{EncodingAvro, SchemaTypeAvro},
+		{EncodingProtobuf, SchemaTypeProtobuf},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.encoding), func(t *testing.T) {
+			registry := NewMemoryRegistry()
+			// Take ID 1 so the codec's schema gets another ID
+			registry.Register(context.Background(), "other-value", SchemaTypeAvro, "{}")
+			codec, _ := NewCodec(tt.encoding, registry)
+
+			data, err := codec.Encode(context.Background(), "alerts", testAlert())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data[0] != wireMagic {
+				t.Errorf("magic byte %d, want %d", data[0], wireMagic)
+			}
+			id := int(binary.BigEndian.Uint32(data[1:wireHeaderSize]))
+			schema, err := registry.SchemaByID(context.Background(), id)
+			if err != nil {
+				t.Fatalf("schema ID %d not registered: %v", id, err)
+			}
+			if id != 2 || schema.Type != tt.schemaType {
+				t.Errorf("schema %d of type %s, want 2 of type %s", id, schema.Type, tt.schemaType)
+			}
+			if ids := registry.subjects[SubjectForTopic("alerts")]; len(ids) != 1 || ids[0] != id {
+				t.Errorf("alerts-value versions %v, want [%d]", ids, id)
+			}
+		})
+	}
+}
+
+func TestCodecRejectsUndecodableValues(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	avro := NewAvroCodec(registry)
+	proto := NewProtobufCodec(registry)
+
+	avroData, err := avro.Encode(ctx, "alerts", testAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoData, err := proto.Encode(ctx, "alerts", testAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		codec Codec
+		data  []byte
+	}{
+		{"avro without wire header", avro, []byte(`{"id":"1"}`)},
+		{"protobuf without wire header", proto, []byte{1, 2}},
+		{"avro truncated", avro, avroData[:len(avroData)/2]},
+		{"protobuf truncated", proto, protoData[:len(protoData)-3]},
+		// The writer schema of a Protobuf value is not an Avro schema
+		{"avro with a protobuf schema", avro, protoData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alert models.Alert
+			if err := tt.codec.Decode(ctx, tt.data, &alert); !errors.Is(err, ErrMalformed) {
+				t.Errorf("got %v, want ErrMalformed", err)
+			}
+		})
+	}
+
+	unknown := appendWireHeader(nil, 99)
+	var alert models.Alert
+	if err := avro.Decode(ctx, unknown, &alert); err == nil || errors.Is(err, ErrMalformed) {
+		t.Errorf("unknown schema: got %v, want a registry error", err)
+	}
+}
+
+// TestAvroDecodesWithWriterSchema writes an alert with an older schema
+// lacking most fields and checks that it decodes with those fields unset.
+func TestAvroDecodesWithWriterSchema(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	old := &models.Schema{Name: "Alert", Fields: models.AlertSchema.Fields[:5]}
+	id, err := registry.Register(ctx, "alerts-value", SchemaTypeAvro, AvroSchema(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := schemaFields(testAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := appendWireHeader(nil, id)
+	for _, f := range old.Fields {
+		data = binary.AppendVarint(data, 1)
+		if data, err = appendAvroValue(data, f.Type, fields[f.Name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var alert models.Alert
+	if err := NewAvroCodec(registry).Decode(ctx, data, &alert); err != nil {
+		t.Fatal(err)
+	}
+	want := testAlert()
+	if alert.ID != want.ID || alert.Severity != want.Severity || alert.ServiceName != want.ServiceName {
+		t.Errorf("decoded %+v, want the fields of the old schema of %+v", alert, want)
+	}
+	if alert.Title != "" || alert.Labels != nil || alert.Enrichment != nil {
+		t.Errorf("decoded %+v, want the fields missing from the old schema unset", alert)
+	}
+}
+
+// TestProtobufSkipsFieldsOfOtherSchemas checks that fields of newer schemas
+// and fields whose type changed are skipped rather than misread.
+func TestProtobufSkipsFieldsOfOtherSchemas(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	codec := NewProtobufCodec(registry)
+
+	data, err := codec.Encode(ctx, "alerts", testAlert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A field added by a newer schema
+	data = protowire.AppendTag(data, 100, protowire.BytesType)
+	data = protowire.AppendString(data, "new field")
+	// The title written as a number by an incompatible schema
+	data = protowire.AppendTag(data, 7, protowire.VarintType)
+	data = protowire.AppendVarint(data, 42)
+
+	var alert models.Alert
+	if err := codec.Decode(ctx, data, &alert); err != nil {
+		t.Fatal(err)
+	}
+	if want := testAlert(); !reflect.DeepEqual(&alert, want) {
+		t.Errorf("decoded %+v, want %+v", &alert, want)
+	}
+}
@@ -0,0 +1,252 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
+)
+
+// Headers added to dead-lettered messages, next to their original headers.
+const (
+	HeaderDLQTopic     = "dlq-original-topic"
+	HeaderDLQPartition = "dlq-original-partition"
+	HeaderDLQOffset    = "dlq-original-offset"
+	HeaderDLQGroup     = "dlq-consumer-group"
+	HeaderDLQError     = "dlq-error"
+	HeaderDLQAttempts  = "dlq-attempts"
+)
+
+// Reasons a message is dead-lettered.
+const (
+	DeadLetterMalformed        = "malformed"
+	DeadLetterRetriesExhausted = "retries_exhausted"
+)
+
+// ErrMalformed marks a message that can never be handled, e.g. because it
+// fails to deserialize. Such messages are dead-lettered without retries.
+var ErrMalformed = errors.New("malformed message")
+
+// Malformed wraps a deserialization error so the message is dead-lettered at once.
+func Malformed(err error) error {
+	return fmt.Errorf("%w: %w", ErrMalformed, err)
+}
+
+// DLQTopic returns the dead-letter topic of a topic, e.g. "alerts-dlq".
+func DLQTopic(topic string) string {
+	return topic + "-dlq"
+}
+
+// ConsumeOptions configures how consumers handle messages: on how many
+// workers, and what happens to messages whose handler fails.
+type ConsumeOptions struct {
+	// MaxRetries is how often a failing message is retried before it is dead-lettered.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles with every
+	// further retry up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// DLQ receives the messages that fail for good, with the DLQ headers.
+	// Without one they are logged and skipped.
+	DLQ MessageProducer
+	// Metrics counts consumed, retried and dead-lettered messages. May be nil.
+	Metrics *metrics.Metrics
+	// Deduplicator skips messages whose message-id header was already
+	// handled. May be nil.
+	Deduplicator *Deduplicator
+	// Workers handle messages concurrently when more than one. Messages with
+	// the same key are always handled by the same worker, in order.
+	Workers int
+	// QueueSize is how many messages may wait for each worker before
+	// fetching blocks.
+	QueueSize int
+	// CommitInterval is how often concurrent consumers commit the offsets
+	// of completed messages. Only contiguous ranges are committed, so a slow
+	// message holds back the commits of its partition, not its handling.
+	CommitInterval time.Duration
+}
+
+// DefaultConsumeOptions returns default consume options without a DLQ.
+func DefaultConsumeOptions() *ConsumeOptions {
+	return &ConsumeOptions{
+		MaxRetries:      3,
+		RetryBackoff:    200 * time.Millisecond,
+		MaxRetryBackoff: 5 * time.Second,
+		Workers:         1,
+		QueueSize:       100,
+		CommitInterval:  time.Second,
+	}
+}
+
+// concurrent reports whether messages are handled on a worker pool.
+func (o *ConsumeOptions) concurrent() bool {
+	return o.Workers > 1
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (o *ConsumeOptions) backoff(retry int) time.Duration {
+	backoff := float64(o.RetryBackoff) * math.Pow(2, float64(retry-1))
+	if limit := float64(o.MaxRetryBackoff); limit > 0 && backoff > limit {
+		backoff = limit
+	}
+	return time.Duration(backoff)
+}
+
+// handleMessage runs handler on a message until it succeeds, retrying with
+// backoff, and dead-letters the message once it fails for good. The message
+// is settled and may be committed unless an error is returned, which only
+// happens when ctx is done first. The handler's context continues the trace
+// of the message and carries its metadata. Duplicates of handled messages
+// are settled without running handler.
+func handleMessage(
+	ctx context.Context,
+	msg kafka.Message,
+	groupID string,
+	handler MessageHandler,
+	opts *ConsumeOptions,
+	logger *logging.Logger,
+) (err error) {
+	ctx, span := startProcessSpan(ctx, msg, groupID)
+	defer func() { endSpan(span, err) }()
+
+	messageID := Header(msg, HeaderMessageID)
+	if opts.Deduplicator != nil && messageID != "" {
+		if opts.Deduplicator.Seen(messageID) {
+			logger.Debug("skipping duplicate kafka message",
+				zap.String("topic", msg.Topic),
+				zap.Int64("offset", msg.Offset),
+				zap.String("message_id", messageID),
+			)
+			if opts.Metrics != nil {
+				opts.Metrics.RecordKafkaDuplicate(msg.Topic)
+			}
+			return nil
+		}
+		defer func() {
+			if err == nil {
+				opts.Deduplicator.Mark(messageID)
+			}
+		}()
+	}
+
+	attempts := 0
+	for {
+		attempts++
+		if err = handler(ctx, msg); err == nil {
+			if opts.Metrics != nil {
+				opts.Metrics.RecordKafkaConsume(msg.Topic)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrMalformed) || attempts > opts.MaxRetries {
+			break
+		}
+
+		backoff := opts.backoff(attempts)
+		logger.Warn("failed to handle kafka message, retrying",
+			zap.Error(err),
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Int("attempt", attempts),
+			zap.Duration("backoff", backoff),
+		)
+		if opts.Metrics != nil {
+			opts.Metrics.RecordKafkaRetry(msg.Topic)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+
+	reason := DeadLetterRetriesExhausted
+	if errors.Is(err, ErrMalformed) {
+		reason = DeadLetterMalformed
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, reason)
+	return deadLetter(ctx, msg, groupID, reason, err, attempts, opts, logger)
+}
+
+// deadLetter publishes a message that failed for good to the DLQ, retrying
+// until it succeeds or ctx is done, or drops it when there is no DLQ.
+func deadLetter(
+	ctx context.Context,
+	msg kafka.Message,
+	groupID, reason string,
+	handlerErr error,
+	attempts int,
+	opts *ConsumeOptions,
+	logger *logging.Logger,
+) error {
+	fields := []zap.Field{
+		zap.Error(handlerErr),
+		zap.String("topic", msg.Topic),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.Int("attempts", attempts),
+		zap.String("reason", reason),
+	}
+
+	if opts.DLQ == nil {
+		logger.Error("dropping kafka message", fields...)
+	} else {
+		dlqMsg := kafka.Message{
+			Key:   msg.Key,
+			Value: msg.Value,
+			Headers: append(append([]kafka.Header{}, msg.Headers...),
+				kafka.Header{Key: HeaderDLQTopic, Value: []byte(msg.Topic)},
+				kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+				kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+				kafka.Header{Key: HeaderDLQGroup, Value: []byte(groupID)},
+				kafka.Header{Key: HeaderDLQError, Value: []byte(handlerErr.Error())},
+				kafka.Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(attempts))},
+			),
+			Time: time.Now(),
+		}
+
+		// The message is only committed once it is safely dead-lettered
+		for retry := 1; ; retry++ {
+			err := opts.DLQ.PublishBatch(ctx, []kafka.Message{dlqMsg})
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Error("failed to dead-letter kafka message",
+				zap.Error(err),
+				zap.String("dlq_topic", opts.DLQ.Topic()),
+				zap.String("topic", msg.Topic),
+				zap.Int64("offset", msg.Offset),
+			)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.backoff(retry)):
+			}
+		}
+		logger.Warn("kafka message dead-lettered",
+			append(fields, zap.String("dlq_topic", opts.DLQ.Topic()))...)
+	}
+
+	if opts.Metrics != nil {
+		opts.Metrics.RecordKafkaDeadLetter(msg.Topic, reason)
+	}
+	return nil
+}
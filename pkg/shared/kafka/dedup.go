@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultDedupCapacity is how many message IDs a Deduplicator remembers by default.
+const DefaultDedupCapacity = 100000
+
+// messageIDNamespace namespaces the UUIDs derived from consumed messages.
+var messageIDNamespace = uuid.MustParse("5b0c7f8e-3a6d-4c1e-9f2a-8d4b6e1c7a90")
+
+type messageIDKey struct{}
+
+// WithMessageID returns a context in which Publish and PublishValue send id
+// in the message-id header. Use it for one message only.
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+func messageIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(messageIDKey{}).(string)
+	return id
+}
+
+// DerivedMessageID returns the ID of a message produced while handling the
+// consumed message in ctx. The same consumed message and discriminator, which
+// tells apart the messages produced for it, always give the same ID, so
+// outputs republished for a redelivered message are recognised as duplicates.
+// It returns false outside a message handler.
+func DerivedMessageID(ctx context.Context, discriminator string) (string, bool) {
+	md, ok := MetadataFromContext(ctx)
+	if !ok || md.Topic == "" {
+		return "", false
+	}
+	source := md.Topic + "/" + strconv.Itoa(md.Partition) + "/" + strconv.FormatInt(md.Offset, 10) + "/" + discriminator
+	return uuid.NewSHA1(messageIDNamespace, []byte(source)).String(), true
+}
+
+// Deduplicator remembers the IDs of recently handled messages so that
+// consumers skip duplicates: messages re-sent by producer retries or
+// republished when their input was redelivered. IDs are forgotten after the
+// window or once capacity newer IDs were seen. Duplicates share their key
+// and so their partition, so one consumer sees all copies of a message.
+type Deduplicator struct {
+	window   time.Duration
+	capacity int
+
+	mu    sync.Mutex
+	seen  map[string]*list.Element
+	order *list.List // of *dedupEntry, oldest first
+}
+
+type dedupEntry struct {
+	id     string
+	seenAt time.Time
+}
+
+// NewDeduplicator creates a Deduplicator remembering up to capacity IDs for window.
+func NewDeduplicator(window time.Duration, capacity int) *Deduplicator {
+	if capacity <= 0 {
+		capacity = DefaultDedupCapacity
+	}
+	return &Deduplicator{
+		window:   window,
+		capacity: capacity,
+		seen:     make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Seen reports whether a message with the ID was handled within the window.
+func (d *Deduplicator) Seen(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(time.Now())
+	_, ok := d.seen[id]
+	return ok
+}
+
+// Mark records that a message with the ID was handled.
+func (d *Deduplicator) Mark(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if el, ok := d.seen[id]; ok {
+		d.order.Remove(el)
+	}
+	d.seen[id] = d.order.PushBack(&dedupEntry{id: id, seenAt: now})
+	for d.order.Len() > d.capacity {
+		d.remove(d.order.Front())
+	}
+	d.expire(now)
+}
+
+// expire forgets the IDs seen before the window.
+func (d *Deduplicator) expire(now time.Time) {
+	for el := d.order.Front(); el != nil; el = d.order.Front() {
+		if now.Sub(el.Value.(*dedupEntry).seenAt) < d.window {
+			return
+		}
+		d.remove(el)
+	}
+}
+
+func (d *Deduplicator) remove(el *list.Element) {
+	d.order.Remove(el)
+	delete(d.seen, el.Value.(*dedupEntry).id)
+}
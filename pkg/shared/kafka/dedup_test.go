@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator(time.Hour, 2)
+	if d.Seen("a") {
+		t.Error("unmarked ID seen")
+	}
+	d.Mark("a")
+	if !d.Seen("a") {
+		t.Error("marked ID not seen")
+	}
+
+	// Beyond capacity the oldest IDs are forgotten
+	d.Mark("b")
+	d.Mark("c")
+	if d.Seen("a") || !d.Seen("b") || !d.Seen("c") {
+		t.Error("want only the 2 newest IDs remembered")
+	}
+	// Marking again makes an ID the newest
+	d.Mark("b")
+	d.Mark("d")
+	if d.Seen("c") || !d.Seen("b") {
+		t.Error("want the re-marked ID kept over the older one")
+	}
+
+	// After the window IDs are forgotten
+	d = NewDeduplicator(20*time.Millisecond, 0)
+	d.Mark("a")
+	time.Sleep(30 * time.Millisecond)
+	if d.Seen("a") {
+		t.Error("ID seen after the window")
+	}
+}
+
+func TestDerivedMessageID(t *testing.T) {
+	consumed := func(topic string, partition int, offset int64) context.Context {
+		return context.WithValue(context.Background(), metadataKey{}, MessageMetadata{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    offset,
+		})
+	}
+	id := func(ctx context.Context, discriminator string) string {
+		t.Helper()
+		id, ok := DerivedMessageID(ctx, discriminator)
+		if !ok {
+			t.Fatal("no derived ID in a handler context")
+		}
+		return id
+	}
+
+	if _, ok := DerivedMessageID(context.Background(), "fp:critical"); ok {
+		t.Error("derived an ID outside a handler")
+	}
+
+	// A redelivered message gives its outputs the same IDs
+	first := id(consumed("metrics", 1, 42), "fp:critical")
+	if again := id(consumed("metrics", 1, 42), "fp:critical"); again != first {
+		t.Errorf("redelivery derived %s, want %s", again, first)
+	}
+
+	for name, other := range map[string]string{
+		"other severity":  id(consumed("metrics", 1, 42), "fp:warning"),
+		"other problem":   id(consumed("metrics", 1, 42), "other:critical"),
+		"other offset":    id(consumed("metrics", 1, 43), "fp:critical"),
+		"other partition": id(consumed("metrics", 2, 42), "fp:critical"),
+		"other topic":     id(consumed("logs", 1, 42), "fp:critical"),
+	} {
+		if other == first {
+			t.Errorf("%s derived the same ID", name)
+		}
+	}
+}
+
+// TestConsumeSkipsDuplicates publishes a message twice with the same ID, as
+// a producer retry or a republished output does, and messages without an
+// ID, and checks which ones the handler gets.
+func TestConsumeSkipsDuplicates(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := producer.Publish(WithMessageID(ctx, "id-1"), []byte("key"), []byte(fmt.Sprint("copy ", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Without the header every message is handled
+	publish(t, producer, "key", "key")
+	if err := producer.Publish(WithMessageID(ctx, "id-2"), []byte("key"), []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err := broker.NewConsumer(testConsumerConfig("events", "group"), testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	opts := DefaultConsumeOptions()
+	opts.Deduplicator = NewDeduplicator(time.Hour, 0)
+	handled := &collector{}
+	stop := consume(consumer, handled.handle, opts)
+	defer stop()
+
+	waitFor(t, "the offsets committed", func() bool {
+		var committed int64
+		for _, offset := range broker.CommittedOffsets("group", "events") {
+			committed += offset
+		}
+		return committed == 5
+	})
+
+	handled.mu.Lock()
+	defer handled.mu.Unlock()
+	var values []string
+	for _, msg := range handled.msgs {
+		values = append(values, string(msg.Value))
+	}
+	if want := []string{"copy 0", "0", "1", "other"}; fmt.Sprint(values) != fmt.Sprint(want) {
+		t.Errorf("handled %q, want %q", values, want)
+	}
+}
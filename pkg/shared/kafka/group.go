@@ -0,0 +1,351 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+)
+
+// RebalanceListener is notified when the consumer group assigns partitions to
+// this member or takes them away. It lets consumers keep partition-affine state.
+type RebalanceListener interface {
+	// PartitionsAssigned is called before any message of the new assignment is handled.
+	PartitionsAssigned(ctx context.Context, topic string, partitions []int)
+	// PartitionsRevoked is called after the last message of the assignment has been
+	// handled and before the partitions are handed to another member.
+	PartitionsRevoked(ctx context.Context, topic string, partitions []int)
+}
+
+// GroupConsumer consumes a topic as a member of a consumer group and exposes
+// partition assignments through a RebalanceListener. Each assigned partition is
+// read by its own goroutine, and handled on its own workers when opts has more
+// than one, so messages with the same key are handled in order.
+type GroupConsumer struct {
+	group    consumerGroup
+	config   *ConsumerConfig
+	listener RebalanceListener
+	logger   *logging.Logger
+	stats    *consumerStats
+
+	readersMu sync.Mutex
+	readers   map[int]partitionReader // by assigned partition
+}
+
+// consumerGroup is the membership of a GroupConsumer in its group: a
+// kafka-go consumer group, or a MemoryBroker's group.
+type consumerGroup interface {
+	// Next joins the next generation of the group.
+	Next(ctx context.Context) (groupGeneration, error)
+	// NewReader returns a reader of one partition of the topic.
+	NewReader(partition int) partitionReader
+	Close() error
+}
+
+// groupGeneration is one assignment of the topic's partitions to the members.
+type groupGeneration interface {
+	ID() int32
+	// Assignments returns this member's partitions and their committed offsets.
+	Assignments() []kafka.PartitionAssignment
+	// Start runs fn with a context that is done when the generation ends.
+	// The next generation waits for fn to return.
+	Start(fn func(ctx context.Context))
+	CommitOffsets(offsets map[int]int64) error
+}
+
+// partitionReader reads one partition.
+type partitionReader interface {
+	SetOffset(offset int64) error
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
+// NewGroupConsumer creates a new GroupConsumer. listener may be nil.
+func NewGroupConsumer(cfg *ConsumerConfig, listener RebalanceListener, logger *logging.Logger) (*GroupConsumer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one broker is required")
+	}
+	if err := validateConsumerConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	dialer, err := cfg.dialer()
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:          cfg.GroupID,
+		Brokers:     cfg.Brokers,
+		Dialer:      dialer,
+		Topics:      []string{cfg.Topic},
+		StartOffset: cfg.StartOffset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	return newGroupConsumer(cfg, &kafkaGroup{group: group, config: cfg, dialer: dialer}, listener, logger), nil
+}
+
+// newGroupConsumer creates a GroupConsumer that is a member of group.
+func newGroupConsumer(cfg *ConsumerConfig, group consumerGroup, listener RebalanceListener, logger *logging.Logger) *GroupConsumer {
+	consumer := &GroupConsumer{
+		group:    group,
+		config:   cfg,
+		listener: listener,
+		logger:   logger,
+		stats:    newConsumerStats(cfg.Topic, cfg.GroupID),
+		readers:  make(map[int]partitionReader),
+	}
+	if cfg.Metrics != nil {
+		cfg.Metrics.RegisterKafkaConsumer(consumer)
+	}
+	return consumer
+}
+
+// Consume joins the group and handles messages until ctx is cancelled or the
+// consumer is closed. Failing messages are retried and then dead-lettered per
+// opts, and the offset of each message is committed once it is settled, in
+// batches when opts has more than one worker; nil opts uses the defaults.
+func (c *GroupConsumer) Consume(ctx context.Context, handler MessageHandler, opts *ConsumeOptions) error {
+	if opts == nil {
+		opts = DefaultConsumeOptions()
+	}
+
+	c.logger.Info("starting kafka group consumer",
+		zap.String("topic", c.config.Topic),
+		zap.String("group_id", c.config.GroupID),
+	)
+
+	for {
+		gen, err := c.group.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, kafka.ErrGroupClosed) {
+				return nil
+			}
+			c.logger.Error("failed to join consumer group",
+				zap.Error(err),
+				zap.String("group_id", c.config.GroupID),
+			)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		c.stats.rebalanced()
+		c.runGeneration(ctx, gen, handler, opts)
+	}
+}
+
+func (c *GroupConsumer) runGeneration(ctx context.Context, gen groupGeneration, handler MessageHandler, opts *ConsumeOptions) {
+	assignments := gen.Assignments()
+	partitions := make([]int, 0, len(assignments))
+	for _, a := range assignments {
+		partitions = append(partitions, a.ID)
+	}
+	sort.Ints(partitions)
+
+	c.logger.Info("consumer group partitions assigned",
+		zap.String("topic", c.config.Topic),
+		zap.Int32("generation", gen.ID()),
+		zap.Ints("partitions", partitions),
+	)
+
+	if c.listener != nil {
+		c.listener.PartitionsAssigned(ctx, c.config.Topic, partitions)
+	}
+
+	gen.Start(func(genCtx context.Context) {
+		// Stop reading when either the generation ends or the caller cancels
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-genCtx.Done():
+				cancel()
+			case <-runCtx.Done():
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for _, a := range assignments {
+			wg.Add(1)
+			go func(a kafka.PartitionAssignment) {
+				defer wg.Done()
+				c.readPartition(runCtx, gen, a, handler, opts)
+			}(a)
+		}
+		wg.Wait()
+
+		c.logger.Info("consumer group partitions revoked",
+			zap.String("topic", c.config.Topic),
+			zap.Int32("generation", gen.ID()),
+			zap.Ints("partitions", partitions),
+		)
+
+		if c.listener != nil {
+			// Both contexts may be done; give the handoff its own deadline
+			revokeCtx, revokeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			c.listener.PartitionsRevoked(revokeCtx, c.config.Topic, partitions)
+			revokeCancel()
+		}
+	})
+}
+
+func (c *GroupConsumer) readPartition(
+	ctx context.Context,
+	gen groupGeneration,
+	assignment kafka.PartitionAssignment,
+	handler MessageHandler,
+	opts *ConsumeOptions,
+) {
+	reader := c.group.NewReader(assignment.ID)
+	c.readersMu.Lock()
+	c.readers[assignment.ID] = reader
+	c.readersMu.Unlock()
+	defer func() {
+		c.readersMu.Lock()
+		delete(c.readers, assignment.ID)
+		c.stats.add(reader.Stats())
+		c.readersMu.Unlock()
+		c.stats.revoked(assignment.ID)
+		reader.Close()
+	}()
+
+	if err := reader.SetOffset(assignment.Offset); err != nil {
+		c.logger.Error("failed to set partition offset",
+			zap.Error(err),
+			zap.Int("partition", assignment.ID),
+		)
+		return
+	}
+
+	fetch := func(ctx context.Context) (kafka.Message, bool) {
+		for {
+			msg, err := reader.FetchMessage(ctx)
+			if err == nil {
+				c.stats.fetched(msg)
+				return msg, true
+			}
+			if ctx.Err() != nil {
+				return kafka.Message{}, false
+			}
+			c.logger.Error("failed to fetch kafka message",
+				zap.Error(err),
+				zap.String("topic", c.config.Topic),
+				zap.Int("partition", assignment.ID),
+			)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	handle := func(ctx context.Context, msg kafka.Message) error {
+		return handleMessage(ctx, msg, c.config.GroupID, handler, opts, c.logger)
+	}
+	commit := func(ctx context.Context, offsets map[int]int64) error {
+		if err := gen.CommitOffsets(offsets); err != nil {
+			return err
+		}
+		c.stats.committed()
+		return nil
+	}
+
+	if opts.concurrent() {
+		// Returns once every queued message is settled or abandoned, so the
+		// revocation still follows the last handled message
+		consumeConcurrently(ctx, fetch, handle, commit, opts, c.logger)
+		return
+	}
+
+	for {
+		msg, ok := fetch(ctx)
+		if !ok {
+			return
+		}
+
+		if err := handle(ctx, msg); err != nil {
+			// Revoked before the message was settled; the next owner redelivers it
+			return
+		}
+
+		if err := commit(ctx, map[int]int64{msg.Partition: msg.Offset + 1}); err != nil {
+			c.logger.Error("failed to commit kafka message",
+				zap.Error(err),
+				zap.String("topic", c.config.Topic),
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+			)
+		}
+	}
+}
+
+// Close leaves the consumer group.
+func (c *GroupConsumer) Close() error {
+	if c.config.Metrics != nil {
+		c.config.Metrics.UnregisterKafkaConsumer(c)
+	}
+	return c.group.Close()
+}
+
+// kafkaGroup is a kafka-go consumer group consuming one topic.
+type kafkaGroup struct {
+	group  *kafka.ConsumerGroup
+	config *ConsumerConfig
+	dialer *kafka.Dialer
+}
+
+func (g *kafkaGroup) Next(ctx context.Context) (groupGeneration, error) {
+	gen, err := g.group.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaGeneration{gen: gen, topic: g.config.Topic}, nil
+}
+
+func (g *kafkaGroup) NewReader(partition int) partitionReader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   g.config.Brokers,
+		Topic:     g.config.Topic,
+		Partition: partition,
+		MinBytes:  g.config.MinBytes,
+		MaxBytes:  g.config.MaxBytes,
+		MaxWait:   g.config.MaxWait,
+		Dialer:    g.dialer,
+	})
+}
+
+func (g *kafkaGroup) Close() error {
+	return g.group.Close()
+}
+
+// kafkaGeneration is a generation of a kafka-go consumer group.
+type kafkaGeneration struct {
+	gen   *kafka.Generation
+	topic string
+}
+
+func (g *kafkaGeneration) ID() int32 {
+	return g.gen.ID
+}
+
+func (g *kafkaGeneration) Assignments() []kafka.PartitionAssignment {
+	return g.gen.Assignments[g.topic]
+}
+
+func (g *kafkaGeneration) Start(fn func(ctx context.Context)) {
+	g.gen.Start(fn)
+}
+
+func (g *kafkaGeneration) CommitOffsets(offsets map[int]int64) error {
+	return g.gen.CommitOffsets(map[string]map[int]int64{g.topic: offsets})
+}
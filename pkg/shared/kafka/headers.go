@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers added to every produced message. Trace context and baggage are
+// carried in the W3C "traceparent", "tracestate" and "baggage" headers.
+const (
+	HeaderContentType   = "content-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderProducer      = "producer"
+	HeaderTraceParent   = "traceparent"
+	// HeaderMessageID identifies a message across producer retries and
+	// republishing, for consumers to skip duplicates.
+	HeaderMessageID = "message-id"
+)
+
+// Defaults of the content headers.
+const (
+	ContentTypeJSON      = "application/json"
+	DefaultSchemaVersion = "1"
+)
+
+// tracerName names the spans of producing and consuming messages.
+const tracerName = "github.com/microservices-platform/pkg/shared/kafka"
+
+// MessageMetadata is what the headers of a consumed message say about it.
+type MessageMetadata struct {
+	ContentType   string
+	SchemaVersion string
+	Producer      string // service that produced the message
+	MessageID     string
+
+	// Where the message was consumed from
+	Topic     string
+	Partition int
+	Offset    int64
+}
+
+type metadataKey struct{}
+
+// MetadataFromContext returns the metadata of the message being handled.
+func MetadataFromContext(ctx context.Context) (MessageMetadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(MessageMetadata)
+	return md, ok
+}
+
+// Header returns the value of a message header, or "" if it is missing.
+func Header(msg kafka.Message, key string) string {
+	return headerCarrier{headers: &msg.Headers}.Get(key)
+}
+
+// headerCarrier adapts message headers to the OTel propagators.
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+// setDefault sets a header unless the message already has it.
+func (c headerCarrier) setDefault(key, value string) {
+	if value != "" && c.Get(key) == "" {
+		c.Set(key, value)
+	}
+}
+
+// addHeaders adds the producer's headers and the trace context of ctx to a
+// message. Headers the message already has are kept, so re-published
+// messages, e.g. dead-lettered ones, keep their original trace. Idempotent
+// producers give messages without one a message ID, which is added before
+// the first attempt so every retry carries the same ID.
+func (p *Producer) addHeaders(ctx context.Context, msg *kafka.Message) {
+	carrier := headerCarrier{headers: &msg.Headers}
+	carrier.setDefault(HeaderContentType, p.config.ContentType)
+	carrier.setDefault(HeaderSchemaVersion, p.config.SchemaVersion)
+	carrier.setDefault(HeaderProducer, p.config.ServiceName)
+	if p.config.Idempotent {
+		carrier.setDefault(HeaderMessageID, uuid.NewString())
+	}
+	if carrier.Get(HeaderTraceParent) == "" {
+		otel.GetTextMapPropagator().Inject(ctx, carrier)
+	}
+}
+
+// startPublishSpan starts the producer span of publishing count messages,
+// continuing the trace of ctx.
+func (p *Producer) startPublishSpan(ctx context.Context, count int) (context.Context, trace.Span) {
+	attrs := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(p.config.Topic),
+		),
+	}
+	if count > 1 {
+		attrs = append(attrs, trace.WithAttributes(semconv.MessagingBatchMessageCount(count)))
+	}
+	return otel.Tracer(tracerName).Start(ctx, p.config.Topic+" publish", attrs...)
+}
+
+// startProcessSpan extracts the trace context and metadata of a consumed
+// message into ctx and starts the consumer span of handling it, so the
+// handler continues the producer's trace.
+func startProcessSpan(ctx context.Context, msg kafka.Message, groupID string) (context.Context, trace.Span) {
+	carrier := headerCarrier{headers: &msg.Headers}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	ctx = context.WithValue(ctx, metadataKey{}, MessageMetadata{
+		ContentType:   carrier.Get(HeaderContentType),
+		SchemaVersion: carrier.Get(HeaderSchemaVersion),
+		Producer:      carrier.Get(HeaderProducer),
+		MessageID:     carrier.Get(HeaderMessageID),
+		Topic:         msg.Topic,
+		Partition:     msg.Partition,
+		Offset:        msg.Offset,
+	})
+
+	return otel.Tracer(tracerName).Start(ctx, msg.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingKafkaConsumerGroup(groupID),
+			semconv.MessagingKafkaDestinationPartition(msg.Partition),
+			semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+		),
+	)
+}
+
+// endSpan ends a span, recording err as its failure if non-nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/microservices-platform/pkg/shared/tracing"
+)
+
+// TestTraceContextRoundTrip publishes a message within a span and checks
+// that the handler consuming it continues the same trace, the way services
+// are set up at startup.
+func TestTraceContextRoundTrip(t *testing.T) {
+	tracing.InitPropagation()
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+	otel.SetTracerProvider(provider)
+
+	broker := NewMemoryBroker()
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "request")
+	if err := producer.Publish(ctx, []byte("key"), []byte("value")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	span.End()
+	sent := span.SpanContext()
+
+	msg := broker.Messages("events")[0]
+	if (headerCarrier{headers: &msg.Headers}).Get(HeaderTraceParent) == "" {
+		t.Fatal("published message has no traceparent header")
+	}
+
+	consumer, err := broker.NewConsumer(testConsumerConfig("events", "group"), testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	received := make(chan trace.SpanContext, 1)
+	stop := consume(consumer, func(ctx context.Context, msg kafka.Message) error {
+		received <- trace.SpanContextFromContext(ctx)
+		return nil
+	}, DefaultConsumeOptions())
+	defer stop()
+
+	waitFor(t, "the message", func() bool { return len(received) == 1 })
+	got := <-received
+	if got.TraceID() != sent.TraceID() {
+		t.Errorf("handler trace %s, want the producer's trace %s", got.TraceID(), sent.TraceID())
+	}
+	if got.SpanID() == sent.SpanID() {
+		t.Error("handler runs in the producer's span, want its own consumer span")
+	}
+}
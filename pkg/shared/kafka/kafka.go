@@ -4,7 +4,6 @@ package kafka
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"math"
 	"sync"
@@ -14,6 +13,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
 )
 
 // Topic names for the microservices platform.
@@ -25,39 +25,79 @@ const (
 
 // ProducerConfig holds Kafka producer configuration.
 type ProducerConfig struct {
-	Brokers       []string      `json:"brokers"`
-	Topic         string        `json:"topic"`
-	BatchSize     int           `json:"batch_size"`
-	BatchTimeout  time.Duration `json:"batch_timeout"`
-	MaxRetries    int           `json:"max_retries"`
-	RetryBackoff  time.Duration `json:"retry_backoff"`
-	RequiredAcks  int           `json:"required_acks"`
-	Async         bool          `json:"async"`
-	TLS           *tls.Config   `json:"-"`
-	SASLMechanism string        `json:"sasl_mechanism"`
-	SASLUsername  string        `json:"sasl_username"`
-	SASLPassword  string        `json:"sasl_password"`
+	Brokers      []string      `json:"brokers"`
+	Topic        string        `json:"topic"`
+	BatchSize    int           `json:"batch_size"`
+	BatchTimeout time.Duration `json:"batch_timeout"`
+	MaxRetries   int           `json:"max_retries"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+	RequiredAcks int           `json:"required_acks"`
+	Async        bool          `json:"async"`
+	// Security configures TLS and SASL authentication to the brokers.
+	Security
+	// ContentType and SchemaVersion are sent as headers of every message.
+	ContentType   string `json:"content_type"`
+	SchemaVersion string `json:"schema_version"`
+	// ServiceName is sent as the producer header; it defaults to the
+	// logger's service name.
+	ServiceName string `json:"service_name"`
+	// Codec encodes the values given to PublishValue; it defaults to JSON.
+	Codec Codec `json:"-"`
+	// Idempotent producers wait for all in-sync replicas and give every
+	// message a message-id header that stays the same across retries, so
+	// consumers with a Deduplicator handle each message once.
+	Idempotent bool `json:"idempotent"`
+	// Outbox, when set, buffers messages on disk while the brokers are
+	// unreachable instead of failing Publish and PublishValue.
+	Outbox *OutboxConfig `json:"-"`
+	// Metrics, when set, exports the producer's statistics.
+	Metrics *metrics.Metrics `json:"-"`
 }
 
 // DefaultProducerConfig returns default producer configuration.
 func DefaultProducerConfig(brokers []string, topic string) *ProducerConfig {
 	return &ProducerConfig{
-		Brokers:      brokers,
-		Topic:        topic,
-		BatchSize:    100,
-		BatchTimeout: 1 * time.Second,
-		MaxRetries:   5,
-		RetryBackoff: 100 * time.Millisecond,
-		RequiredAcks: 1, // Leader ack
-		Async:        false,
+		Brokers:       brokers,
+		Topic:         topic,
+		BatchSize:     100,
+		BatchTimeout:  1 * time.Second,
+		MaxRetries:    5,
+		RetryBackoff:  100 * time.Millisecond,
+		RequiredAcks:  1, // Leader ack
+		Async:         false,
+		ContentType:   ContentTypeJSON,
+		SchemaVersion: DefaultSchemaVersion,
 	}
 }
 
+// KeyPartition returns the partition producers write messages with key to,
+// of a topic with the given number of partitions.
+func KeyPartition(key []byte, partitions int) int {
+	if partitions <= 0 {
+		return 0
+	}
+	ids := make([]int, partitions)
+	for i := range ids {
+		ids[i] = i
+	}
+	return (&kafka.Hash{}).Balance(kafka.Message{Key: key}, ids...)
+}
+
+// messageWriter writes messages to a topic: a kafka.Writer, or a
+// MemoryBroker's writer.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Stats() kafka.WriterStats
+	Close() error
+}
+
 // Producer wraps kafka.Writer with retry logic and observability.
 type Producer struct {
-	writer *kafka.Writer
+	writer messageWriter
 	config *ProducerConfig
 	logger *logging.Logger
+	outbox *Outbox
+	stats  producerStats
 	mu     sync.RWMutex
 	closed bool
 }
@@ -74,29 +114,73 @@ func NewProducer(cfg *ProducerConfig, logger *logging.Logger) (*Producer, error)
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(cfg.Brokers...),
 		Topic:        cfg.Topic,
-		Balancer:     &kafka.LeastBytes{},
+		Balancer:     &kafka.Hash{}, // same key, same partition; keyless messages are spread round-robin
 		BatchSize:    cfg.BatchSize,
 		BatchTimeout: cfg.BatchTimeout,
 		RequiredAcks: kafka.RequiredAcks(cfg.RequiredAcks),
 		Async:        cfg.Async,
 		Compression:  kafka.Snappy,
 	}
+	if cfg.Idempotent {
+		writer.RequiredAcks = kafka.RequireAll
+	}
 
-	if cfg.TLS != nil {
-		writer.Transport = &kafka.Transport{
-			TLS: cfg.TLS,
-		}
+	transport, err := cfg.transport()
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		writer.Transport = transport
+	}
+
+	return newProducer(cfg, writer, logger)
+}
+
+// newProducer creates a producer writing with writer.
+func newProducer(cfg *ProducerConfig, writer messageWriter, logger *logging.Logger) (*Producer, error) {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = logger.ServiceName()
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = JSONCodec{}
 	}
 
-	return &Producer{
+	producer := &Producer{
 		writer: writer,
 		config: cfg,
 		logger: logger,
-	}, nil
+	}
+
+	if cfg.Outbox != nil {
+		outbox, err := openOutbox(cfg.Outbox, cfg.Topic, func(ctx context.Context, msg kafka.Message) error {
+			return writer.WriteMessages(ctx, msg)
+		}, logger)
+		if err != nil {
+			return nil, err
+		}
+		outbox.Start()
+		producer.outbox = outbox
+	}
+
+	if cfg.Metrics != nil {
+		cfg.Metrics.RegisterKafkaProducer(producer)
+	}
+
+	return producer, nil
+}
+
+// Topic returns the topic the producer publishes to.
+func (p *Producer) Topic() string {
+	return p.config.Topic
 }
 
 // Publish publishes a message to Kafka with retry logic and exponential backoff.
+// The message carries the producer's headers and the trace context of ctx.
 func (p *Producer) Publish(ctx context.Context, key, value []byte) error {
+	return p.publish(ctx, kafka.Message{Key: key, Value: value})
+}
+
+func (p *Producer) publish(ctx context.Context, msg kafka.Message) (err error) {
 	p.mu.RLock()
 	if p.closed {
 		p.mu.RUnlock()
@@ -104,17 +188,49 @@ func (p *Producer) Publish(ctx context.Context, key, value []byte) error {
 	}
 	p.mu.RUnlock()
 
-	msg := kafka.Message{
-		Key:   key,
-		Value: value,
-		Time:  time.Now(),
+	ctx, span := p.startPublishSpan(ctx, 1)
+	defer func() { endSpan(span, err) }()
+
+	msg.Time = time.Now()
+	if id := messageIDFromContext(ctx); id != "" {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: HeaderMessageID, Value: []byte(id)})
+	}
+	p.addHeaders(ctx, &msg)
+
+	if p.outbox == nil {
+		return p.publishWithRetry(ctx, msg)
+	}
+
+	// Messages queue behind buffered ones to keep them in order
+	if p.outbox.Depth() == 0 {
+		err = p.publishWithRetry(ctx, msg)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	if err := p.outbox.Append(msg); err != nil {
+		return fmt.Errorf("failed to buffer message in outbox: %w", err)
 	}
+	return nil
+}
 
-	return p.publishWithRetry(ctx, msg)
+// PublishValue encodes a value with the producer's codec and publishes it,
+// with the codec's content type.
+func (p *Producer) PublishValue(ctx context.Context, key []byte, v interface{}) error {
+	value, err := p.config.Codec.Encode(ctx, p.config.Topic, v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return p.publish(ctx, kafka.Message{
+		Key:     key,
+		Value:   value,
+		Headers: []kafka.Header{{Key: HeaderContentType, Value: []byte(p.config.Codec.ContentType())}},
+	})
 }
 
 // PublishBatch publishes multiple messages to Kafka with retry logic.
-func (p *Producer) PublishBatch(ctx context.Context, messages []kafka.Message) error {
+// Messages without them get the producer's headers and the trace context of ctx.
+func (p *Producer) PublishBatch(ctx context.Context, messages []kafka.Message) (err error) {
 	p.mu.RLock()
 	if p.closed {
 		p.mu.RUnlock()
@@ -122,7 +238,18 @@ func (p *Producer) PublishBatch(ctx context.Context, messages []kafka.Message) e
 	}
 	p.mu.RUnlock()
 
-	return p.publishBatchWithRetry(ctx, messages)
+	ctx, span := p.startPublishSpan(ctx, len(messages))
+	defer func() { endSpan(span, err) }()
+
+	// Leave the caller's messages and headers untouched
+	batch := make([]kafka.Message, len(messages))
+	for i, msg := range messages {
+		msg.Headers = append([]kafka.Header(nil), msg.Headers...)
+		p.addHeaders(ctx, &msg)
+		batch[i] = msg
+	}
+
+	return p.publishBatchWithRetry(ctx, batch)
 }
 
 // publishWithRetry implements retry logic with exponential backoff.
@@ -218,6 +345,14 @@ func (p *Producer) Close() error {
 	}
 
 	p.closed = true
+	if p.config.Metrics != nil {
+		p.config.Metrics.UnregisterKafkaProducer(p)
+	}
+	if p.outbox != nil {
+		if err := p.outbox.Stop(); err != nil {
+			p.logger.Warn("failed to close outbox", zap.Error(err))
+		}
+	}
 	return p.writer.Close()
 }
 
@@ -236,7 +371,10 @@ type ConsumerConfig struct {
 	MaxWait        time.Duration `json:"max_wait"`
 	StartOffset    int64         `json:"start_offset"`
 	CommitInterval time.Duration `json:"commit_interval"`
-	TLS            *tls.Config   `json:"-"`
+	// Security configures TLS and SASL authentication to the brokers.
+	Security
+	// Metrics, when set, exports the consumer's statistics.
+	Metrics *metrics.Metrics `json:"-"`
 }
 
 // DefaultConsumerConfig returns default consumer configuration.
@@ -254,7 +392,11 @@ func DefaultConsumerConfig(brokers []string, topic, groupID string) *ConsumerCon
 }
 
 // ToReaderConfig converts ConsumerConfig to kafka.ReaderConfig.
-func (c *ConsumerConfig) ToReaderConfig() kafka.ReaderConfig {
+func (c *ConsumerConfig) ToReaderConfig() (kafka.ReaderConfig, error) {
+	dialer, err := c.dialer()
+	if err != nil {
+		return kafka.ReaderConfig{}, err
+	}
 	return kafka.ReaderConfig{
 		Brokers:        c.Brokers,
 		Topic:          c.Topic,
@@ -264,14 +406,26 @@ func (c *ConsumerConfig) ToReaderConfig() kafka.ReaderConfig {
 		MaxWait:        c.MaxWait,
 		StartOffset:    c.StartOffset,
 		CommitInterval: c.CommitInterval,
-	}
+		Dialer:         dialer,
+	}, nil
+}
+
+// messageReader reads the messages of a consumer group member: a
+// kafka.Reader, or a MemoryBroker's reader.
+type messageReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Stats() kafka.ReaderStats
+	Close() error
 }
 
 // Consumer wraps kafka.Reader with observability.
 type Consumer struct {
-	reader *kafka.Reader
+	reader messageReader
 	config *ConsumerConfig
 	logger *logging.Logger
+	stats  *consumerStats
 	mu     sync.RWMutex
 	closed bool
 }
@@ -281,31 +435,30 @@ func NewConsumer(cfg *ConsumerConfig, logger *logging.Logger) (*Consumer, error)
 	if len(cfg.Brokers) == 0 {
 		return nil, fmt.Errorf("at least one broker is required")
 	}
-	if cfg.Topic == "" {
-		return nil, fmt.Errorf("topic is required")
-	}
-	if cfg.GroupID == "" {
-		return nil, fmt.Errorf("group ID is required")
+	if err := validateConsumerConfig(cfg); err != nil {
+		return nil, err
 	}
 
-	readerConfig := kafka.ReaderConfig{
-		Brokers:        cfg.Brokers,
-		Topic:          cfg.Topic,
-		GroupID:        cfg.GroupID,
-		MinBytes:       cfg.MinBytes,
-		MaxBytes:       cfg.MaxBytes,
-		MaxWait:        cfg.MaxWait,
-		StartOffset:    cfg.StartOffset,
-		CommitInterval: cfg.CommitInterval,
+	readerConfig, err := cfg.ToReaderConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	reader := kafka.NewReader(readerConfig)
+	return newConsumer(cfg, kafka.NewReader(readerConfig), logger), nil
+}
 
-	return &Consumer{
+// newConsumer creates a consumer reading with reader.
+func newConsumer(cfg *ConsumerConfig, reader messageReader, logger *logging.Logger) *Consumer {
+	consumer := &Consumer{
 		reader: reader,
 		config: cfg,
 		logger: logger,
-	}, nil
+		stats:  newConsumerStats(cfg.Topic, cfg.GroupID),
+	}
+	if cfg.Metrics != nil {
+		cfg.Metrics.RegisterKafkaConsumer(consumer)
+	}
+	return consumer
 }
 
 // ReadMessage reads a single message from Kafka.
@@ -317,7 +470,11 @@ func (c *Consumer) ReadMessage(ctx context.Context) (kafka.Message, error) {
 	}
 	c.mu.RUnlock()
 
-	return c.reader.ReadMessage(ctx)
+	msg, err := c.reader.ReadMessage(ctx)
+	if err == nil {
+		c.stats.fetched(msg)
+	}
+	return msg, err
 }
 
 // FetchMessage fetches a message without committing.
@@ -329,12 +486,20 @@ func (c *Consumer) FetchMessage(ctx context.Context) (kafka.Message, error) {
 	}
 	c.mu.RUnlock()
 
-	return c.reader.FetchMessage(ctx)
+	msg, err := c.reader.FetchMessage(ctx)
+	if err == nil {
+		c.stats.fetched(msg)
+	}
+	return msg, err
 }
 
 // CommitMessages commits the given messages.
 func (c *Consumer) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
-	return c.reader.CommitMessages(ctx, msgs...)
+	if err := c.reader.CommitMessages(ctx, msgs...); err != nil {
+		return err
+	}
+	c.stats.committed()
+	return nil
 }
 
 // Close closes the consumer gracefully.
@@ -347,59 +512,100 @@ func (c *Consumer) Close() error {
 	}
 
 	c.closed = true
+	if c.config.Metrics != nil {
+		c.config.Metrics.UnregisterKafkaConsumer(c)
+	}
 	return c.reader.Close()
 }
 
+func (c *Consumer) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closed
+}
+
 // Stats returns the current consumer statistics.
 func (c *Consumer) Stats() kafka.ReaderStats {
 	return c.reader.Stats()
 }
 
-// MessageHandler is a function type for processing Kafka messages.
+// MessageHandler is a function type for processing Kafka messages. Handlers
+// return an error wrapped with Malformed for messages that can never succeed.
 type MessageHandler func(ctx context.Context, msg kafka.Message) error
 
-// ConsumeLoop starts a continuous consumption loop.
-func (c *Consumer) ConsumeLoop(ctx context.Context, handler MessageHandler) error {
+// Consume handles messages until ctx is cancelled or the consumer is
+// closed. Failing messages are retried and then dead-lettered per opts, so
+// every message is committed once settled; nil opts uses the defaults. With
+// more than one worker, messages are handled concurrently, ordered per key,
+// and committed in batches.
+func (c *Consumer) Consume(ctx context.Context, handler MessageHandler, opts *ConsumeOptions) error {
+	if opts == nil {
+		opts = DefaultConsumeOptions()
+	}
+
 	c.logger.Info("starting kafka consumer loop",
 		zap.String("topic", c.config.Topic),
 		zap.String("group_id", c.config.GroupID),
+		zap.Int("workers", opts.Workers),
 	)
 
+	handle := func(ctx context.Context, msg kafka.Message) error {
+		return handleMessage(ctx, msg, c.config.GroupID, handler, opts, c.logger)
+	}
+
+	if opts.concurrent() {
+		consumeConcurrently(ctx, c.fetch, handle, c.commitOffsets, opts, c.logger)
+		c.logger.Info("kafka consumer loop stopped", zap.String("topic", c.config.Topic))
+		return ctx.Err()
+	}
+
 	for {
-		select {
-		case <-ctx.Done():
+		msg, ok := c.fetch(ctx)
+		if !ok {
 			c.logger.Info("kafka consumer loop stopped", zap.String("topic", c.config.Topic))
 			return ctx.Err()
-		default:
-			msg, err := c.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return ctx.Err()
-				}
-				c.logger.Error("failed to fetch kafka message",
-					zap.Error(err),
-					zap.String("topic", c.config.Topic),
-				)
-				continue
-			}
+		}
 
-			if err := handler(ctx, msg); err != nil {
-				c.logger.Error("failed to handle kafka message",
-					zap.Error(err),
-					zap.String("topic", c.config.Topic),
-					zap.Int64("offset", msg.Offset),
-				)
-				// Don't commit on error - message will be reprocessed
-				continue
-			}
+		if err := handle(ctx, msg); err != nil {
+			// Stopped before the message was settled; it is redelivered
+			return err
+		}
 
-			if err := c.CommitMessages(ctx, msg); err != nil {
-				c.logger.Error("failed to commit kafka message",
-					zap.Error(err),
-					zap.String("topic", c.config.Topic),
-					zap.Int64("offset", msg.Offset),
-				)
-			}
+		if err := c.CommitMessages(ctx, msg); err != nil {
+			c.logger.Error("failed to commit kafka message",
+				zap.Error(err),
+				zap.String("topic", c.config.Topic),
+				zap.Int64("offset", msg.Offset),
+			)
 		}
 	}
 }
+
+// fetch returns the next message, retrying failed fetches. It returns false
+// once ctx is cancelled or the consumer is closed.
+func (c *Consumer) fetch(ctx context.Context) (kafka.Message, bool) {
+	for {
+		msg, err := c.FetchMessage(ctx)
+		if err == nil {
+			return msg, true
+		}
+		if ctx.Err() != nil || c.isClosed() {
+			return kafka.Message{}, false
+		}
+		c.logger.Error("failed to fetch kafka message",
+			zap.Error(err),
+			zap.String("topic", c.config.Topic),
+		)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// commitOffsets commits the given next offsets per partition.
+func (c *Consumer) commitOffsets(ctx context.Context, offsets map[int]int64) error {
+	msgs := make([]kafka.Message, 0, len(offsets))
+	for partition, offset := range offsets {
+		// The reader commits the offset after the given message
+		msgs = append(msgs, kafka.Message{Topic: c.config.Topic, Partition: partition, Offset: offset - 1})
+	}
+	return c.CommitMessages(ctx, msgs...)
+}
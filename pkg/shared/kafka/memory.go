@@ -0,0 +1,659 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+)
+
+// DefaultMemoryPartitions is the number of partitions of the topics a
+// MemoryBroker creates on first use.
+const DefaultMemoryPartitions = 3
+
+// MemoryBroker is an in-process Kafka for tests and for running services
+// without a cluster. It keeps topics, partitions, consumer groups and their
+// committed offsets in memory with Kafka's semantics: messages are
+// partitioned by key, each partition is consumed by one member of a group,
+// and members resume from the committed offsets after a rebalance, so
+// uncommitted messages are redelivered.
+type MemoryBroker struct {
+	mu      sync.Mutex
+	topics  map[string][][]kafka.Message // partitions by topic
+	groups  map[string]*memoryGroup      // by group ID and topic
+	changed chan struct{}                // closed when messages or members change
+}
+
+// NewMemoryBroker creates an empty MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:  make(map[string][][]kafka.Message),
+		groups:  make(map[string]*memoryGroup),
+		changed: make(chan struct{}),
+	}
+}
+
+// CreateTopic creates a topic with the given number of partitions unless it
+// already exists.
+func (b *MemoryBroker) CreateTopic(name string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.createTopicLocked(name, partitions)
+}
+
+// Messages returns the messages of a topic, by partition and offset.
+func (b *MemoryBroker) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var msgs []kafka.Message
+	for _, partition := range b.topics[topic] {
+		msgs = append(msgs, partition...)
+	}
+	return msgs
+}
+
+// CommittedOffsets returns the offsets a group committed on a topic, by
+// partition. They are the offsets of the next messages to consume.
+func (b *MemoryBroker) CommittedOffsets(groupID, topic string) map[int]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offsets := make(map[int]int64)
+	if g, ok := b.groups[groupID+"\x00"+topic]; ok {
+		for partition, offset := range g.committed {
+			offsets[partition] = offset
+		}
+	}
+	return offsets
+}
+
+// NewProducer creates a Producer writing to the broker.
+func (b *MemoryBroker) NewProducer(cfg *ProducerConfig, logger *logging.Logger) (MessageProducer, error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+	b.CreateTopic(cfg.Topic, DefaultMemoryPartitions)
+	return newProducer(cfg, &memoryWriter{broker: b, topic: cfg.Topic}, logger)
+}
+
+// NewConsumer creates a Consumer reading from the broker.
+func (b *MemoryBroker) NewConsumer(cfg *ConsumerConfig, logger *logging.Logger) (MessageConsumer, error) {
+	if err := validateConsumerConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	reader := &memoryReader{broker: b, positions: make(map[int]int64)}
+	reader.member = b.groupLocked(cfg).joinLocked()
+	return newConsumer(cfg, reader, logger), nil
+}
+
+// NewGroupConsumer creates a GroupConsumer reading from the broker.
+func (b *MemoryBroker) NewGroupConsumer(cfg *ConsumerConfig, listener RebalanceListener, logger *logging.Logger) (MessageConsumer, error) {
+	if err := validateConsumerConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	group := &memoryConsumerGroup{broker: b, group: b.groupLocked(cfg)}
+	b.mu.Unlock()
+	return newGroupConsumer(cfg, group, listener, logger), nil
+}
+
+// EnsureTopics creates the missing topics when create is set and returns
+// their state. It fails with ErrTopicsUnusable when a topic is missing or
+// has too few partitions.
+func (b *MemoryBroker) EnsureTopics(ctx context.Context, specs []TopicSpec, create bool, logger *logging.Logger) ([]TopicState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make([]TopicState, len(specs))
+	var unusable []string
+	for i, spec := range specs {
+		partitions, ok := b.topics[spec.Name]
+		if !ok && create {
+			partitions, ok = b.createTopicLocked(spec.Name, spec.Partitions), true
+		}
+		states[i].TopicSpec = spec
+		states[i].Exists = ok
+		states[i].Partitions = len(partitions)
+
+		switch {
+		case !ok:
+			unusable = append(unusable, spec.Name+": topic does not exist")
+		case len(partitions) < spec.Partitions:
+			unusable = append(unusable, fmt.Sprintf("%s: partitions %d, declared %d", spec.Name, len(partitions), spec.Partitions))
+		}
+	}
+	if len(unusable) > 0 {
+		sort.Strings(unusable)
+		return nil, fmt.Errorf("%w: %s", ErrTopicsUnusable, strings.Join(unusable, "; "))
+	}
+	return states, nil
+}
+
+func (b *MemoryBroker) createTopicLocked(name string, partitions int) [][]kafka.Message {
+	if topic, ok := b.topics[name]; ok {
+		return topic
+	}
+	if partitions <= 0 {
+		partitions = DefaultMemoryPartitions
+	}
+	topic := make([][]kafka.Message, partitions)
+	b.topics[name] = topic
+	return topic
+}
+
+func (b *MemoryBroker) groupLocked(cfg *ConsumerConfig) *memoryGroup {
+	key := cfg.GroupID + "\x00" + cfg.Topic
+	if g, ok := b.groups[key]; ok {
+		return g
+	}
+
+	g := &memoryGroup{
+		broker:    b,
+		topic:     cfg.Topic,
+		start:     make(map[int]int64),
+		committed: make(map[int]int64),
+		active:    make(map[*memoryGeneration]bool),
+	}
+	for p, partition := range b.createTopicLocked(cfg.Topic, DefaultMemoryPartitions) {
+		g.start[p] = resolveOffset(cfg.StartOffset, len(partition))
+	}
+	b.groups[key] = g
+	return g
+}
+
+// notifyLocked wakes everyone waiting for a change.
+func (b *MemoryBroker) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// wait waits for the next change after ch was taken under b.mu.
+func wait(ctx context.Context, ch <-chan struct{}) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ch:
+		return nil
+	}
+}
+
+// memoryGroup is a consumer group consuming one topic. Every membership
+// change starts a new generation, which reassigns the partitions.
+type memoryGroup struct {
+	broker     *MemoryBroker
+	topic      string
+	start      map[int]int64 // offsets by partition when the group was created
+	committed  map[int]int64
+	members    []*memoryMember
+	generation int32
+	active     map[*memoryGeneration]bool // generations not ended yet
+}
+
+type memoryMember struct {
+	group *memoryGroup
+}
+
+func (g *memoryGroup) joinLocked() *memoryMember {
+	m := &memoryMember{group: g}
+	g.members = append(g.members, m)
+	g.rebalanceLocked()
+	return m
+}
+
+func (g *memoryGroup) leaveLocked(m *memoryMember) {
+	for i, member := range g.members {
+		if member == m {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			g.rebalanceLocked()
+			return
+		}
+	}
+}
+
+// rebalanceLocked starts a new generation, ending the running ones.
+func (g *memoryGroup) rebalanceLocked() {
+	g.generation++
+	for gen := range g.active {
+		gen.cancel()
+	}
+	g.broker.notifyLocked()
+}
+
+// assignmentLocked returns the partitions of a member, round-robin over
+// the members in the order they joined.
+func (g *memoryGroup) assignmentLocked(m *memoryMember) []int {
+	index := -1
+	for i, member := range g.members {
+		if member == m {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+
+	var partitions []int
+	for p := range g.broker.topics[g.topic] {
+		if p%len(g.members) == index {
+			partitions = append(partitions, p)
+		}
+	}
+	return partitions
+}
+
+// offsetLocked returns the offset a partition is consumed from: the
+// committed offset, or without one the consumer's StartOffset as of when
+// the group was created, so no message published since is skipped.
+func (g *memoryGroup) offsetLocked(partition int) int64 {
+	if offset, ok := g.committed[partition]; ok {
+		return offset
+	}
+	return g.start[partition]
+}
+
+// resolveOffset resolves FirstOffset and LastOffset for a partition of size messages.
+func resolveOffset(offset int64, size int) int64 {
+	switch offset {
+	case kafka.FirstOffset:
+		return 0
+	case kafka.LastOffset:
+		return int64(size)
+	default:
+		return offset
+	}
+}
+
+// memoryMessage returns the message at offset of a partition, as fetched.
+func memoryMessage(partition []kafka.Message, offset int64) kafka.Message {
+	msg := partition[offset]
+	msg.HighWaterMark = int64(len(partition))
+	return msg
+}
+
+// memoryWriter appends messages to the partitions of a topic.
+type memoryWriter struct {
+	broker   *MemoryBroker
+	topic    string
+	balancer kafka.Hash
+
+	mu     sync.Mutex
+	stats  kafka.WriterStats
+	closed bool
+}
+
+func (w *memoryWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+
+	w.broker.mu.Lock()
+	topic := w.broker.topics[w.topic]
+	partitions := make([]int, len(topic))
+	for i := range partitions {
+		partitions[i] = i
+	}
+	var bytes int64
+	for _, msg := range msgs {
+		p := w.balancer.Balance(msg, partitions...)
+		msg.Topic = w.topic
+		msg.Partition = p
+		msg.Offset = int64(len(topic[p]))
+		msg.Headers = append([]kafka.Header(nil), msg.Headers...)
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+		topic[p] = append(topic[p], msg)
+		bytes += int64(len(msg.Key) + len(msg.Value))
+	}
+	w.broker.notifyLocked()
+	w.broker.mu.Unlock()
+
+	w.stats.Writes++
+	w.stats.Messages += int64(len(msgs))
+	w.stats.Bytes += bytes
+	w.stats.BatchSize.Count++
+	w.stats.BatchSize.Sum += int64(len(msgs))
+	return nil
+}
+
+// Stats returns the statistics since the last call, like kafka.Writer.
+func (w *memoryWriter) Stats() kafka.WriterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Topic = w.topic
+	w.stats = kafka.WriterStats{}
+	return stats
+}
+
+func (w *memoryWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+// memoryReaderStats are the counters of a reader since they were last read.
+type memoryReaderStats struct {
+	fetches, messages, bytes, rebalances int64
+}
+
+func (s *memoryReaderStats) fetched(msg kafka.Message) {
+	s.fetches++
+	s.messages++
+	s.bytes += int64(len(msg.Key) + len(msg.Value))
+}
+
+func (s *memoryReaderStats) read(topic string, partition int) kafka.ReaderStats {
+	stats := kafka.ReaderStats{
+		Fetches:    s.fetches,
+		Messages:   s.messages,
+		Bytes:      s.bytes,
+		Rebalances: s.rebalances,
+		Topic:      topic,
+		Partition:  fmt.Sprint(partition),
+	}
+	*s = memoryReaderStats{}
+	return stats
+}
+
+// memoryReader is a group member reading its assigned partitions, like a
+// kafka.Reader with a GroupID.
+type memoryReader struct {
+	broker     *MemoryBroker
+	member     *memoryMember
+	generation int32         // generation positions were reset for
+	positions  map[int]int64 // next offset by assigned partition
+	next       int           // partition to look at first, for fairness
+	stats      memoryReaderStats
+	closed     bool
+}
+
+func (r *memoryReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	g := r.member.group
+	for {
+		r.broker.mu.Lock()
+		if r.closed {
+			r.broker.mu.Unlock()
+			return kafka.Message{}, io.EOF
+		}
+
+		// Resume from the committed offsets after a rebalance
+		if r.generation != g.generation {
+			r.generation = g.generation
+			r.positions = make(map[int]int64)
+			for _, p := range g.assignmentLocked(r.member) {
+				r.positions[p] = g.offsetLocked(p)
+			}
+			r.stats.rebalances++
+		}
+
+		topic := r.broker.topics[g.topic]
+		for i := range topic {
+			p := (r.next + i) % len(topic)
+			offset, ok := r.positions[p]
+			if !ok || offset >= int64(len(topic[p])) {
+				continue
+			}
+			msg := memoryMessage(topic[p], offset)
+			r.positions[p] = offset + 1
+			r.next = p + 1
+			r.stats.fetched(msg)
+			r.broker.mu.Unlock()
+			return msg, nil
+		}
+
+		ch := r.broker.changed
+		r.broker.mu.Unlock()
+		if err := wait(ctx, ch); err != nil {
+			return kafka.Message{}, err
+		}
+	}
+}
+
+func (r *memoryReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	msg, err := r.FetchMessage(ctx)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return msg, r.CommitMessages(ctx, msg)
+}
+
+func (r *memoryReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	for _, msg := range msgs {
+		r.member.group.committed[msg.Partition] = msg.Offset + 1
+	}
+	return nil
+}
+
+func (r *memoryReader) Stats() kafka.ReaderStats {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	return r.stats.read(r.member.group.topic, 0)
+}
+
+func (r *memoryReader) Close() error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	if !r.closed {
+		r.closed = true
+		r.member.group.leaveLocked(r.member)
+	}
+	return nil
+}
+
+// memoryConsumerGroup is a GroupConsumer's membership of a memoryGroup,
+// with generations like a kafka.ConsumerGroup: a new generation only starts
+// once every member's previous one ended, so partitions are never read by
+// two members at once.
+type memoryConsumerGroup struct {
+	broker  *MemoryBroker
+	group   *memoryGroup
+	member  *memoryMember
+	current *memoryGeneration
+	closed  bool
+}
+
+func (c *memoryConsumerGroup) Next(ctx context.Context) (groupGeneration, error) {
+	b := c.broker
+	g := c.group
+	for {
+		b.mu.Lock()
+		if c.closed {
+			b.mu.Unlock()
+			return nil, kafka.ErrGroupClosed
+		}
+		if c.member == nil {
+			c.member = g.joinLocked()
+		}
+
+		ready := c.current == nil || c.current.ended
+		for gen := range g.active {
+			if gen.id != g.generation {
+				ready = false
+			}
+		}
+		if ready {
+			gen := c.startGenerationLocked()
+			b.mu.Unlock()
+			return gen, nil
+		}
+
+		ch := b.changed
+		b.mu.Unlock()
+		if err := wait(ctx, ch); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *memoryConsumerGroup) startGenerationLocked() *memoryGeneration {
+	g := c.group
+	ctx, cancel := context.WithCancel(context.Background())
+	gen := &memoryGeneration{
+		group:  c,
+		id:     g.generation,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, p := range g.assignmentLocked(c.member) {
+		gen.assignments = append(gen.assignments, kafka.PartitionAssignment{ID: p, Offset: g.offsetLocked(p)})
+	}
+	g.active[gen] = true
+	c.current = gen
+
+	// The generation ends once it is cancelled and its functions returned
+	go func() {
+		<-ctx.Done()
+		c.broker.mu.Lock()
+		gen.ending = true
+		c.broker.mu.Unlock()
+
+		gen.wg.Wait()
+
+		c.broker.mu.Lock()
+		gen.ended = true
+		delete(g.active, gen)
+		c.broker.notifyLocked()
+		c.broker.mu.Unlock()
+	}()
+	return gen
+}
+
+func (c *memoryConsumerGroup) NewReader(partition int) partitionReader {
+	return &memoryPartitionReader{broker: c.broker, topic: c.group.topic, partition: partition}
+}
+
+func (c *memoryConsumerGroup) Close() error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.member != nil {
+		c.group.leaveLocked(c.member)
+	} else {
+		c.broker.notifyLocked()
+	}
+	return nil
+}
+
+// memoryGeneration is a generation of a memoryConsumerGroup.
+type memoryGeneration struct {
+	group       *memoryConsumerGroup
+	id          int32
+	assignments []kafka.PartitionAssignment
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	ending      bool // cancelled; no functions start any more
+	ended       bool
+}
+
+func (g *memoryGeneration) ID() int32 {
+	return g.id
+}
+
+func (g *memoryGeneration) Assignments() []kafka.PartitionAssignment {
+	return g.assignments
+}
+
+func (g *memoryGeneration) Start(fn func(ctx context.Context)) {
+	g.group.broker.mu.Lock()
+	defer g.group.broker.mu.Unlock()
+	if g.ending {
+		return
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+func (g *memoryGeneration) CommitOffsets(offsets map[int]int64) error {
+	g.group.broker.mu.Lock()
+	defer g.group.broker.mu.Unlock()
+	if g.ended {
+		return fmt.Errorf("generation %d has ended", g.id)
+	}
+	for partition, offset := range offsets {
+		g.group.group.committed[partition] = offset
+	}
+	return nil
+}
+
+// memoryPartitionReader reads one partition from an offset.
+type memoryPartitionReader struct {
+	broker    *MemoryBroker
+	topic     string
+	partition int
+	offset    int64
+	stats     memoryReaderStats
+	closed    bool
+}
+
+func (r *memoryPartitionReader) SetOffset(offset int64) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	r.offset = resolveOffset(offset, len(r.broker.topics[r.topic][r.partition]))
+	return nil
+}
+
+func (r *memoryPartitionReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		if r.closed {
+			r.broker.mu.Unlock()
+			return kafka.Message{}, io.EOF
+		}
+		partition := r.broker.topics[r.topic][r.partition]
+		if r.offset < int64(len(partition)) {
+			msg := memoryMessage(partition, r.offset)
+			r.offset++
+			r.stats.fetched(msg)
+			r.broker.mu.Unlock()
+			return msg, nil
+		}
+
+		ch := r.broker.changed
+		r.broker.mu.Unlock()
+		if err := wait(ctx, ch); err != nil {
+			return kafka.Message{}, err
+		}
+	}
+}
+
+func (r *memoryPartitionReader) Stats() kafka.ReaderStats {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	return r.stats.read(r.topic, r.partition)
+}
+
+func (r *memoryPartitionReader) Close() error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	r.closed = true
+	return nil
+}
@@ -0,0 +1,366 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+)
+
+func testLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	logger, err := logging.NewLogger(logging.DefaultConfig("test"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	return logger
+}
+
+func testConsumerConfig(topic, groupID string) *ConsumerConfig {
+	cfg := DefaultConsumerConfig(nil, topic, groupID)
+	cfg.StartOffset = kafka.FirstOffset
+	return cfg
+}
+
+func publish(t *testing.T, producer MessageProducer, keys ...string) {
+	t.Helper()
+	for i, key := range keys {
+		if err := producer.Publish(context.Background(), []byte(key), []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+}
+
+// collector records the messages a handler received.
+type collector struct {
+	mu   sync.Mutex
+	msgs []kafka.Message
+}
+
+func (c *collector) handle(ctx context.Context, msg kafka.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.msgs)
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// consume runs Consume in the background until the returned stop is called.
+func consume(consumer MessageConsumer, handler MessageHandler, opts *ConsumeOptions) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Consume(ctx, handler, opts)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestMemoryBrokerPartitionsByKey(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	publish(t, producer, "a", "b", "c", "a", "b", "c", "a")
+
+	partitions := make(map[string]int)
+	next := make(map[int]int64)
+	for _, msg := range broker.Messages("events") {
+		key := string(msg.Key)
+		if p, ok := partitions[key]; ok && p != msg.Partition {
+			t.Errorf("key %q on partitions %d and %d", key, p, msg.Partition)
+		}
+		partitions[key] = msg.Partition
+		if msg.Offset != next[msg.Partition] {
+			t.Errorf("partition %d: offset %d, want %d", msg.Partition, msg.Offset, next[msg.Partition])
+		}
+		next[msg.Partition]++
+	}
+	if got := len(broker.Messages("events")); got != 7 {
+		t.Errorf("got %d messages, want 7", got)
+	}
+}
+
+func TestMemoryConsumerResumesFromCommittedOffsets(t *testing.T) {
+	broker := NewMemoryBroker()
+	logger := testLogger(t)
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	publish(t, producer, "a", "b", "c")
+
+	first, err := broker.NewConsumer(testConsumerConfig("events", "group"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := &collector{}
+	stop := consume(first, received.handle, nil)
+	waitFor(t, "the first messages", func() bool { return received.count() == 3 })
+	stop()
+	first.Close()
+
+	var committed int64
+	for _, offset := range broker.CommittedOffsets("group", "events") {
+		committed += offset
+	}
+	if committed != 3 {
+		t.Errorf("committed %d messages, want 3", committed)
+	}
+
+	// A new member of the group only receives what was published since
+	publish(t, producer, "d", "e")
+	second, err := broker.NewConsumer(testConsumerConfig("events", "group"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	received = &collector{}
+	stop = consume(second, received.handle, nil)
+	defer stop()
+	waitFor(t, "the new messages", func() bool { return received.count() == 2 })
+	time.Sleep(50 * time.Millisecond)
+	if got := received.count(); got != 2 {
+		t.Errorf("got %d messages, want 2", got)
+	}
+}
+
+func TestMemoryConsumerRedeliversUncommittedMessages(t *testing.T) {
+	broker := NewMemoryBroker()
+	broker.CreateTopic("events", 1)
+	logger := testLogger(t)
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	publish(t, producer, "a", "b")
+
+	consumer, err := broker.NewConsumer(testConsumerConfig("events", "group"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := consumer.(*Consumer)
+	msg, err := reader.FetchMessage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.CommitMessages(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.FetchMessage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	consumer.Close()
+
+	// The second message was fetched but not committed
+	consumer, err = broker.NewConsumer(testConsumerConfig("events", "group"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+	msg, err = consumer.(*Consumer).FetchMessage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Offset != 1 || string(msg.Key) != "b" {
+		t.Errorf("redelivered offset %d key %q, want offset 1 key \"b\"", msg.Offset, msg.Key)
+	}
+}
+
+// assignments records the partitions a RebalanceListener holds.
+type assignments struct {
+	mu         sync.Mutex
+	partitions map[int]bool
+}
+
+func (a *assignments) PartitionsAssigned(ctx context.Context, topic string, partitions []int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.partitions = make(map[int]bool)
+	for _, p := range partitions {
+		a.partitions[p] = true
+	}
+}
+
+func (a *assignments) PartitionsRevoked(ctx context.Context, topic string, partitions []int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range partitions {
+		delete(a.partitions, p)
+	}
+}
+
+func (a *assignments) held() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var partitions []int
+	for p := range a.partitions {
+		partitions = append(partitions, p)
+	}
+	sort.Ints(partitions)
+	return partitions
+}
+
+func TestMemoryGroupConsumerRebalances(t *testing.T) {
+	broker := NewMemoryBroker()
+	broker.CreateTopic("events", 4)
+	logger := testLogger(t)
+
+	first, second := &assignments{}, &assignments{}
+	firstConsumer, err := broker.NewGroupConsumer(testConsumerConfig("events", "group"), first, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondConsumer, err := broker.NewGroupConsumer(testConsumerConfig("events", "group"), second, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer firstConsumer.Close()
+
+	received := &collector{}
+	stopFirst := consume(firstConsumer, received.handle, nil)
+	defer stopFirst()
+	stopSecond := consume(secondConsumer, received.handle, nil)
+
+	waitFor(t, "both members to share the partitions", func() bool {
+		return len(first.held()) == 2 && len(second.held()) == 2
+	})
+	for _, p := range first.held() {
+		for _, q := range second.held() {
+			if p == q {
+				t.Fatalf("partition %d assigned to both members", p)
+			}
+		}
+	}
+
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	publish(t, producer, "a", "b", "c", "d", "e", "f", "g", "h")
+	waitFor(t, "the messages", func() bool { return received.count() == 8 })
+
+	// The remaining member takes over the partitions of a member that left
+	stopSecond()
+	secondConsumer.Close()
+	waitFor(t, "the first member to hold every partition", func() bool {
+		return len(first.held()) == 4 && len(second.held()) == 0
+	})
+
+	publish(t, producer, "i", "j", "k", "l")
+	waitFor(t, "the messages after the rebalance", func() bool { return received.count() == 12 })
+	time.Sleep(50 * time.Millisecond)
+	if got := received.count(); got != 12 {
+		t.Errorf("got %d messages, want each of the 12 once", got)
+	}
+}
+
+func TestMemoryConsumerDeadLetters(t *testing.T) {
+	broker := NewMemoryBroker()
+	logger := testLogger(t)
+	producer, err := broker.NewProducer(DefaultProducerConfig(nil, "events"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	dlq, err := broker.NewProducer(DefaultProducerConfig(nil, DLQTopic("events")), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dlq.Close()
+
+	consumer, err := broker.NewConsumer(testConsumerConfig("events", "group"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	opts := DefaultConsumeOptions()
+	opts.DLQ = dlq
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		if string(msg.Key) == "bad" {
+			return Malformed(errors.New("cannot decode"))
+		}
+		return nil
+	}
+	stop := consume(consumer, handler, opts)
+	defer stop()
+
+	publish(t, producer, "good", "bad")
+	waitFor(t, "the dead letter", func() bool { return len(broker.Messages(DLQTopic("events"))) == 1 })
+
+	msg := broker.Messages(DLQTopic("events"))[0]
+	headers := make(map[string]string)
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if string(msg.Key) != "bad" || headers[HeaderDLQTopic] != "events" || headers[HeaderDLQGroup] != "group" {
+		t.Errorf("dead letter key %q headers %v", msg.Key, headers)
+	}
+}
+
+func TestMemoryBrokerEnsureTopics(t *testing.T) {
+	broker := NewMemoryBroker()
+	logger := testLogger(t)
+	specs := []TopicSpec{EventTopicSpec("events", 1), DLQTopicSpec("events-dlq", 1)}
+
+	if _, err := broker.EnsureTopics(context.Background(), specs, false, logger); !errors.Is(err, ErrTopicsUnusable) {
+		t.Errorf("missing topics: got %v, want ErrTopicsUnusable", err)
+	}
+	if _, err := broker.EnsureTopics(context.Background(), specs, true, logger); err != nil {
+		t.Errorf("creating topics: %v", err)
+	}
+	if _, err := broker.EnsureTopics(context.Background(), specs, false, logger); err != nil {
+		t.Errorf("existing topics: %v", err)
+	}
+
+	broker.CreateTopic("small", 1)
+	if _, err := broker.EnsureTopics(context.Background(), []TopicSpec{EventTopicSpec("small", 1)}, true, logger); !errors.Is(err, ErrTopicsUnusable) {
+		t.Errorf("too few partitions: got %v, want ErrTopicsUnusable", err)
+	}
+
+	// Topics with more partitions than declared are usable, and report
+	// their actual partitions
+	broker.CreateTopic("large", 6)
+	states, err := broker.EnsureTopics(context.Background(), []TopicSpec{EventTopicSpec("large", 1)}, false, logger)
+	if err != nil {
+		t.Fatalf("more partitions: %v", err)
+	}
+	if len(states) != 1 || !states[0].Exists || states[0].Partitions != 6 {
+		t.Errorf("states %+v, want large with 6 partitions", states)
+	}
+}
@@ -0,0 +1,641 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
+)
+
+// ErrOutboxFull is returned when a message does not fit in the outbox.
+var ErrOutboxFull = errors.New("outbox is full")
+
+// errOutboxCorrupt marks a torn or damaged frame in a segment file.
+var errOutboxCorrupt = errors.New("corrupt outbox frame")
+
+const (
+	outboxSegmentExt = ".seg"
+	outboxCursorFile = "cursor"
+	// Frames are the payload length, the CRC-32 of the rest of the frame and
+	// the time the message was buffered, followed by the payload.
+	outboxFrameHeader = 16
+)
+
+// OutboxConfig configures the outbox of a producer.
+type OutboxConfig struct {
+	// Dir holds a directory of segment files per topic.
+	Dir string
+	// MaxBytes bounds the messages buffered per topic; messages beyond it
+	// are rejected with ErrOutboxFull.
+	MaxBytes int64
+	// SegmentBytes is the size at which a new segment file is started.
+	SegmentBytes int64
+	// DrainInterval is how often buffered messages are retried.
+	DrainInterval time.Duration
+	// Metrics reports the outbox depth and age. May be nil.
+	Metrics *metrics.Metrics
+}
+
+// DefaultOutboxConfig returns default outbox configuration.
+func DefaultOutboxConfig(dir string) *OutboxConfig {
+	return &OutboxConfig{
+		Dir:           dir,
+		MaxBytes:      256 << 20, // 256MB
+		SegmentBytes:  16 << 20,  // 16MB
+		DrainInterval: 5 * time.Second,
+	}
+}
+
+// outboxRecord is a buffered message as stored in a frame.
+type outboxRecord struct {
+	Key     []byte         `json:"key,omitempty"`
+	Value   []byte         `json:"value"`
+	Headers []kafka.Header `json:"headers,omitempty"`
+}
+
+// outboxPosition is the position of a frame in the segment files.
+type outboxPosition struct {
+	Segment int64 `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// Outbox buffers the messages of a topic on disk while the brokers are
+// unreachable and publishes them in order once they are back. Messages are
+// appended to segment files and a cursor file records how far they were
+// published, so buffered messages survive restarts; a crash while draining
+// may publish a message twice.
+type Outbox struct {
+	config  *OutboxConfig
+	topic   string
+	dir     string
+	publish func(context.Context, kafka.Message) error
+	logger  *logging.Logger
+
+	mu       sync.Mutex
+	segments []int64 // oldest first; messages are appended to the last
+	sizes    map[int64]int64
+	counts   map[int64]int // unpublished messages by segment
+	active   *os.File
+	reader   *os.File // segment being drained
+	readerID int64
+	cursor   outboxPosition // next message to publish
+	depth    int
+	bytes    int64
+	headAt   time.Time // when the message at the cursor was buffered, if known
+
+	running bool
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// openOutbox opens the outbox of a topic, recovering the messages buffered
+// before a restart. publish makes a single attempt to publish a message.
+func openOutbox(cfg *OutboxConfig, topic string, publish func(context.Context, kafka.Message) error, logger *logging.Logger) (*Outbox, error) {
+	dir := filepath.Join(cfg.Dir, topic)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	o := &Outbox{
+		config:  cfg,
+		topic:   topic,
+		dir:     dir,
+		publish: publish,
+		logger:  logger,
+		sizes:   make(map[int64]int64),
+		counts:  make(map[int64]int),
+	}
+	if err := o.recover(); err != nil {
+		o.closeFiles()
+		return nil, err
+	}
+	o.reportMetrics()
+	return o, nil
+}
+
+// recover loads the segments and cursor and counts the buffered messages.
+// A torn frame at the end of the last segment, left by a crash while
+// appending, is truncated.
+func (o *Outbox) recover() error {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, outboxSegmentExt) {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, outboxSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		o.segments = append(o.segments, id)
+	}
+	sort.Slice(o.segments, func(i, j int) bool { return o.segments[i] < o.segments[j] })
+
+	if data, err := os.ReadFile(filepath.Join(o.dir, outboxCursorFile)); err == nil {
+		if err := json.Unmarshal(data, &o.cursor); err != nil {
+			return fmt.Errorf("failed to parse outbox cursor: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read outbox cursor: %w", err)
+	}
+
+	// Segments before the cursor were published
+	for len(o.segments) > 0 && o.segments[0] < o.cursor.Segment {
+		if err := os.Remove(o.segmentPath(o.segments[0])); err != nil {
+			return fmt.Errorf("failed to remove outbox segment: %w", err)
+		}
+		o.segments = o.segments[1:]
+	}
+	if len(o.segments) == 0 {
+		o.segments = []int64{o.cursor.Segment + 1}
+	}
+	if o.segments[0] != o.cursor.Segment {
+		o.cursor = outboxPosition{Segment: o.segments[0]}
+	}
+
+	for i, id := range o.segments {
+		f, err := os.Open(o.segmentPath(id))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to open outbox segment: %w", err)
+		}
+
+		var offset int64
+		if id == o.cursor.Segment {
+			offset = o.cursor.Offset
+		}
+		for f != nil {
+			_, _, size, err := readOutboxFrame(f, offset, o.config.MaxBytes)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				last := i == len(o.segments)-1
+				o.logger.Warn("discarding damaged end of outbox segment",
+					zap.String("topic", o.topic),
+					zap.Int64("segment", id),
+					zap.Int64("offset", offset),
+					zap.Bool("last_segment", last),
+					zap.Error(err),
+				)
+				if last {
+					if err := os.Truncate(o.segmentPath(id), offset); err != nil {
+						f.Close()
+						return fmt.Errorf("failed to truncate outbox segment: %w", err)
+					}
+				}
+				break
+			}
+			offset += size
+			o.counts[id]++
+			o.depth++
+			o.bytes += size
+		}
+		if f != nil {
+			f.Close()
+		}
+		o.sizes[id] = offset
+	}
+
+	active, err := os.OpenFile(o.segmentPath(o.activeID()), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox segment: %w", err)
+	}
+	o.active = active
+	return nil
+}
+
+// Start starts draining the outbox.
+func (o *Outbox) Start() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.running {
+		return
+	}
+	o.running = true
+	o.stopCh = make(chan struct{})
+
+	o.wg.Add(1)
+	go o.run()
+}
+
+// Stop stops draining the outbox and closes its files. Buffered messages
+// stay on disk for the next start.
+func (o *Outbox) Stop() error {
+	o.mu.Lock()
+	if !o.running {
+		o.mu.Unlock()
+		return nil
+	}
+	o.running = false
+	close(o.stopCh)
+	o.mu.Unlock()
+
+	o.wg.Wait()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closeFiles()
+}
+
+// Depth returns the number of buffered messages.
+func (o *Outbox) Depth() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.depth
+}
+
+// Append buffers a message.
+func (o *Outbox) Append(msg kafka.Message) error {
+	payload, err := json.Marshal(outboxRecord{Key: msg.Key, Value: msg.Value, Headers: msg.Headers})
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox message: %w", err)
+	}
+	frame := make([]byte, outboxFrameHeader+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint64(frame[8:16], uint64(time.Now().UnixNano()))
+	copy(frame[outboxFrameHeader:], payload)
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(frame[8:]))
+	size := int64(len(frame))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.bytes+size > o.config.MaxBytes {
+		return ErrOutboxFull
+	}
+	if o.sizes[o.activeID()] > 0 && o.sizes[o.activeID()]+size > o.config.SegmentBytes {
+		if err := o.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := o.active.Write(frame); err != nil {
+		return o.undoAppend(fmt.Errorf("failed to write outbox segment: %w", err))
+	}
+	if err := o.active.Sync(); err != nil {
+		return o.undoAppend(fmt.Errorf("failed to sync outbox segment: %w", err))
+	}
+	o.sizes[o.activeID()] += size
+	o.counts[o.activeID()]++
+	o.depth++
+	o.bytes += size
+	if o.depth == 1 {
+		o.headAt = time.Now()
+	}
+	o.reportMetricsLocked()
+	return nil
+}
+
+// undoAppend truncates the active segment back to its last complete frame
+// after a failed append, so that later frames do not follow a torn one.
+// o.mu must be held.
+func (o *Outbox) undoAppend(err error) error {
+	if terr := o.active.Truncate(o.sizes[o.activeID()]); terr != nil {
+		o.logger.Error("failed to truncate outbox segment after failed append",
+			zap.String("topic", o.topic),
+			zap.Int64("segment", o.activeID()),
+			zap.Error(terr),
+		)
+	}
+	return err
+}
+
+func (o *Outbox) run() {
+	defer o.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-o.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(o.config.DrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stopCh:
+			return
+		case <-ticker.C:
+			o.drain(ctx)
+			o.reportMetrics()
+		}
+	}
+}
+
+// drain publishes buffered messages in order until the outbox is empty or
+// publishing fails.
+func (o *Outbox) drain(ctx context.Context) {
+	drained := 0
+	for {
+		msg, next, size, ok, err := o.peek()
+		if errors.Is(err, errOutboxCorrupt) {
+			// Set the damaged segment aside rather than retrying it forever
+			if err := o.quarantine(err); err != nil {
+				o.logger.Error("failed to quarantine outbox segment",
+					zap.String("topic", o.topic),
+					zap.Error(err),
+				)
+				return
+			}
+			continue
+		}
+		if err != nil {
+			o.logger.Error("failed to read outbox",
+				zap.String("topic", o.topic),
+				zap.Error(err),
+			)
+			return
+		}
+		if !ok {
+			break
+		}
+
+		if err := o.publish(ctx, msg); err != nil {
+			if ctx.Err() == nil {
+				o.logger.Warn("kafka still unavailable, keeping messages in outbox",
+					zap.String("topic", o.topic),
+					zap.Int("depth", o.Depth()),
+					zap.Error(err),
+				)
+			}
+			break
+		}
+
+		if err := o.commit(next, size); err != nil {
+			o.logger.Error("failed to save outbox cursor",
+				zap.String("topic", o.topic),
+				zap.Error(err),
+			)
+			return
+		}
+		drained++
+	}
+
+	if drained > 0 {
+		o.logger.Info("drained outbox",
+			zap.String("topic", o.topic),
+			zap.Int("messages", drained),
+			zap.Int("depth", o.Depth()),
+		)
+	}
+}
+
+// peek reads the next buffered message and the position after it.
+func (o *Outbox) peek() (kafka.Message, outboxPosition, int64, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.advanceSegment() {
+		return kafka.Message{}, outboxPosition{}, 0, false, nil
+	}
+	if o.reader == nil || o.readerID != o.cursor.Segment {
+		if o.reader != nil {
+			o.reader.Close()
+		}
+		f, err := os.Open(o.segmentPath(o.cursor.Segment))
+		if err != nil {
+			o.reader = nil
+			return kafka.Message{}, outboxPosition{}, 0, false, err
+		}
+		o.reader, o.readerID = f, o.cursor.Segment
+	}
+
+	payload, _, size, err := readOutboxFrame(o.reader, o.cursor.Offset, o.config.MaxBytes)
+	if err != nil {
+		return kafka.Message{}, outboxPosition{}, 0, false, err
+	}
+	var rec outboxRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return kafka.Message{}, outboxPosition{}, 0, false, fmt.Errorf("%w: %v", errOutboxCorrupt, err)
+	}
+
+	msg := kafka.Message{Key: rec.Key, Value: rec.Value, Headers: rec.Headers, Time: time.Now()}
+	next := outboxPosition{Segment: o.cursor.Segment, Offset: o.cursor.Offset + size}
+	return msg, next, size, true, nil
+}
+
+// commit records that the message before next was published.
+func (o *Outbox) commit(next outboxPosition, size int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.counts[next.Segment]--
+	o.cursor = next
+	o.depth--
+	o.bytes -= size
+	o.headAt = time.Time{}
+	if o.depth == 0 && o.sizes[o.activeID()] > 0 {
+		// Start afresh so published segments can be removed
+		if err := o.rotate(); err != nil {
+			return err
+		}
+		o.advanceSegment()
+	}
+	o.reportMetricsLocked()
+	return o.saveCursor()
+}
+
+// advanceSegment moves the cursor past published segments, removing them,
+// and reports whether messages are buffered. o.mu must be held.
+func (o *Outbox) advanceSegment() bool {
+	for o.cursor.Segment != o.activeID() && o.cursor.Offset >= o.sizes[o.cursor.Segment] {
+		done := o.segments[0]
+		if o.reader != nil && o.readerID == done {
+			o.reader.Close()
+			o.reader = nil
+		}
+		if err := os.Remove(o.segmentPath(done)); err != nil && !os.IsNotExist(err) {
+			o.logger.Warn("failed to remove outbox segment", zap.Int64("segment", done), zap.Error(err))
+		}
+		delete(o.sizes, done)
+		delete(o.counts, done)
+		o.segments = o.segments[1:]
+		o.cursor = outboxPosition{Segment: o.segments[0]}
+	}
+	return o.depth > 0
+}
+
+// quarantine sets the segment at the cursor aside after a damaged frame was
+// read from it, renaming it with a .corrupt suffix. Its remaining messages
+// are dropped so that the segments after it still drain.
+func (o *Outbox) quarantine(cause error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	id := o.cursor.Segment
+	if id == o.activeID() {
+		if err := o.rotate(); err != nil {
+			return err
+		}
+	}
+	if o.reader != nil && o.readerID == id {
+		o.reader.Close()
+		o.reader = nil
+	}
+	quarantined := o.segmentPath(id) + ".corrupt"
+	if err := os.Rename(o.segmentPath(id), quarantined); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rename outbox segment: %w", err)
+	}
+
+	lost := o.counts[id]
+	o.logger.Error("quarantined damaged outbox segment",
+		zap.String("topic", o.topic),
+		zap.Int64("segment", id),
+		zap.Int64("offset", o.cursor.Offset),
+		zap.Int("dropped_messages", lost),
+		zap.String("path", quarantined),
+		zap.Error(cause),
+	)
+
+	o.depth -= lost
+	o.bytes -= o.sizes[id] - o.cursor.Offset
+	delete(o.sizes, id)
+	delete(o.counts, id)
+	o.segments = o.segments[1:]
+	o.cursor = outboxPosition{Segment: o.segments[0]}
+	o.headAt = time.Time{}
+	o.reportMetricsLocked()
+	return o.saveCursor()
+}
+
+// rotate starts a new segment file. o.mu must be held.
+func (o *Outbox) rotate() error {
+	id := o.activeID() + 1
+	f, err := os.OpenFile(o.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox segment: %w", err)
+	}
+	if err := o.active.Close(); err != nil {
+		o.logger.Warn("failed to close outbox segment", zap.Error(err))
+	}
+	o.active = f
+	o.segments = append(o.segments, id)
+	o.sizes[id] = 0
+	return nil
+}
+
+// saveCursor persists the cursor by atomically replacing the cursor file.
+// o.mu must be held.
+func (o *Outbox) saveCursor() error {
+	data, err := json.Marshal(o.cursor)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(o.dir, outboxCursorFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(o.dir, outboxCursorFile))
+}
+
+// oldestAge returns how long the next message to publish has been buffered.
+// o.mu must be held.
+func (o *Outbox) oldestAge() time.Duration {
+	if !o.advanceSegment() {
+		return 0
+	}
+	if o.headAt.IsZero() {
+		f, err := os.Open(o.segmentPath(o.cursor.Segment))
+		if err != nil {
+			return 0
+		}
+		_, bufferedAt, _, err := readOutboxFrame(f, o.cursor.Offset, o.config.MaxBytes)
+		f.Close()
+		if err != nil {
+			return 0
+		}
+		o.headAt = bufferedAt
+	}
+	return time.Since(o.headAt)
+}
+
+func (o *Outbox) reportMetrics() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reportMetricsLocked()
+}
+
+func (o *Outbox) reportMetricsLocked() {
+	if o.config.Metrics != nil {
+		o.config.Metrics.SetOutboxState(o.topic, o.depth, o.oldestAge())
+	}
+}
+
+func (o *Outbox) activeID() int64 {
+	return o.segments[len(o.segments)-1]
+}
+
+func (o *Outbox) segmentPath(id int64) string {
+	return filepath.Join(o.dir, fmt.Sprintf("%020d%s", id, outboxSegmentExt))
+}
+
+func (o *Outbox) closeFiles() error {
+	var lastErr error
+	if o.reader != nil {
+		if err := o.reader.Close(); err != nil {
+			lastErr = err
+		}
+		o.reader = nil
+	}
+	if o.active != nil {
+		if err := o.active.Close(); err != nil {
+			lastErr = err
+		}
+		o.active = nil
+	}
+	return lastErr
+}
+
+// readOutboxFrame reads the frame at offset, returning its payload, when it
+// was buffered and its size. It returns io.EOF at the end of the segment.
+// Payloads longer than maxLength, which Append never writes, are corrupt.
+func readOutboxFrame(f *os.File, offset, maxLength int64) ([]byte, time.Time, int64, error) {
+	header := make([]byte, outboxFrameHeader)
+	n, err := f.ReadAt(header, offset)
+	if n == 0 && err == io.EOF {
+		return nil, time.Time{}, 0, io.EOF
+	}
+	if n < outboxFrameHeader {
+		return nil, time.Time{}, 0, errOutboxCorrupt
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if int64(length) > maxLength {
+		return nil, time.Time{}, 0, errOutboxCorrupt
+	}
+	frame := make([]byte, 8+int(length))
+	copy(frame, header[8:])
+	if n, _ := f.ReadAt(frame[8:], offset+outboxFrameHeader); n < int(length) {
+		return nil, time.Time{}, 0, errOutboxCorrupt
+	}
+	if crc32.ChecksumIEEE(frame) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, time.Time{}, 0, errOutboxCorrupt
+	}
+
+	bufferedAt := time.Unix(0, int64(binary.BigEndian.Uint64(header[8:16])))
+	return frame[8:], bufferedAt, outboxFrameHeader + int64(length), nil
+}
@@ -0,0 +1,268 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// recordingPublisher publishes to a slice, failing while fail is set.
+type recordingPublisher struct {
+	mu   sync.Mutex
+	fail bool
+	msgs []kafka.Message
+}
+
+func (p *recordingPublisher) publish(ctx context.Context, msg kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return errors.New("brokers unreachable")
+	}
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *recordingPublisher) setFail(fail bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fail = fail
+}
+
+func (p *recordingPublisher) values() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	values := make([]string, len(p.msgs))
+	for i, msg := range p.msgs {
+		values[i] = string(msg.Value)
+	}
+	return values
+}
+
+func openTestOutbox(t *testing.T, cfg *OutboxConfig, publisher *recordingPublisher) *Outbox {
+	t.Helper()
+	o, err := openOutbox(cfg, "events", publisher.publish, testLogger(t))
+	if err != nil {
+		t.Fatalf("failed to open outbox: %v", err)
+	}
+	t.Cleanup(func() { o.closeFiles() })
+	return o
+}
+
+func appendValues(t *testing.T, o *Outbox, values ...string) {
+	t.Helper()
+	for _, v := range values {
+		if err := o.Append(kafka.Message{Key: []byte("key"), Value: []byte(v)}); err != nil {
+			t.Fatalf("failed to append %q: %v", v, err)
+		}
+	}
+}
+
+func segmentFiles(t *testing.T, dir, ext string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "events", "*"+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func assertValues(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+func TestOutboxDrainsInOrder(t *testing.T) {
+	publisher := &recordingPublisher{}
+	o := openTestOutbox(t, DefaultOutboxConfig(t.TempDir()), publisher)
+
+	appendValues(t, o, "1", "2", "3", "4")
+	if got := o.Depth(); got != 4 {
+		t.Fatalf("depth %d, want 4", got)
+	}
+	o.drain(context.Background())
+
+	assertValues(t, publisher.values(), "1", "2", "3", "4")
+	if got := o.Depth(); got != 0 {
+		t.Errorf("depth %d after drain, want 0", got)
+	}
+}
+
+func TestOutboxResumesFromCursorAfterRestart(t *testing.T) {
+	cfg := DefaultOutboxConfig(t.TempDir())
+	publisher := &recordingPublisher{}
+	o := openTestOutbox(t, cfg, publisher)
+	appendValues(t, o, "1", "2")
+	o.drain(context.Background())
+	appendValues(t, o, "3", "4")
+	o.closeFiles()
+
+	reopened := openTestOutbox(t, cfg, publisher)
+	if got := reopened.Depth(); got != 2 {
+		t.Fatalf("depth %d after restart, want 2", got)
+	}
+	reopened.drain(context.Background())
+	assertValues(t, publisher.values(), "1", "2", "3", "4")
+}
+
+func TestOutboxTruncatesTornLastFrame(t *testing.T) {
+	cfg := DefaultOutboxConfig(t.TempDir())
+	publisher := &recordingPublisher{}
+	o := openTestOutbox(t, cfg, publisher)
+	appendValues(t, o, "1", "2")
+	segment := o.segmentPath(o.activeID())
+	o.closeFiles()
+
+	// A crash while appending leaves half a frame behind
+	f, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 40, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	reopened := openTestOutbox(t, cfg, publisher)
+	if got := reopened.Depth(); got != 2 {
+		t.Fatalf("depth %d after recovery, want 2", got)
+	}
+	appendValues(t, reopened, "3")
+	reopened.drain(context.Background())
+	assertValues(t, publisher.values(), "1", "2", "3")
+}
+
+func TestOutboxRotatesAndRemovesPublishedSegments(t *testing.T) {
+	cfg := DefaultOutboxConfig(t.TempDir())
+	cfg.SegmentBytes = 1 // one message per segment
+	publisher := &recordingPublisher{}
+	o := openTestOutbox(t, cfg, publisher)
+
+	appendValues(t, o, "1", "2", "3")
+	if got := len(segmentFiles(t, cfg.Dir, outboxSegmentExt)); got != 3 {
+		t.Fatalf("%d segment files, want 3", got)
+	}
+
+	o.drain(context.Background())
+	assertValues(t, publisher.values(), "1", "2", "3")
+	if got := len(segmentFiles(t, cfg.Dir, outboxSegmentExt)); got != 1 {
+		t.Errorf("%d segment files after drain, want only the active one", got)
+	}
+}
+
+func TestOutboxRejectsMessagesWhenFull(t *testing.T) {
+	cfg := DefaultOutboxConfig(t.TempDir())
+	cfg.MaxBytes = 100
+	o := openTestOutbox(t, cfg, &recordingPublisher{})
+
+	appendValues(t, o, "1")
+	err := o.Append(kafka.Message{Value: []byte(strings.Repeat("x", 100))})
+	if !errors.Is(err, ErrOutboxFull) {
+		t.Errorf("got %v, want ErrOutboxFull", err)
+	}
+	if got := o.Depth(); got != 1 {
+		t.Errorf("depth %d, want 1", got)
+	}
+}
+
+func TestOutboxKeepsMessagesWhilePublishingFails(t *testing.T) {
+	publisher := &recordingPublisher{fail: true}
+	o := openTestOutbox(t, DefaultOutboxConfig(t.TempDir()), publisher)
+
+	appendValues(t, o, "1", "2")
+	o.drain(context.Background())
+	if got := o.Depth(); got != 2 {
+		t.Fatalf("depth %d after failed drain, want 2", got)
+	}
+
+	publisher.setFail(false)
+	o.drain(context.Background())
+	assertValues(t, publisher.values(), "1", "2")
+	if got := o.Depth(); got != 0 {
+		t.Errorf("depth %d, want 0", got)
+	}
+}
+
+func TestOutboxQuarantinesDamagedSegment(t *testing.T) {
+	cfg := DefaultOutboxConfig(t.TempDir())
+	cfg.SegmentBytes = 1
+	publisher := &recordingPublisher{}
+	o := openTestOutbox(t, cfg, publisher)
+	appendValues(t, o, "1", "2")
+
+	// Damage the length of the first frame, as a bad disk block would
+	f, err := os.OpenFile(o.segmentPath(o.segments[0]), os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 0); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	o.drain(context.Background())
+	assertValues(t, publisher.values(), "2")
+	if got := o.Depth(); got != 0 {
+		t.Errorf("depth %d, want 0", got)
+	}
+	if got := len(segmentFiles(t, cfg.Dir, ".corrupt")); got != 1 {
+		t.Errorf("%d quarantined segments, want 1", got)
+	}
+}
+
+// failingWriter fails every write while fail is set.
+type failingWriter struct {
+	publisher *recordingPublisher
+}
+
+func (w *failingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, msg := range msgs {
+		if err := w.publisher.publish(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *failingWriter) Stats() kafka.WriterStats { return kafka.WriterStats{} }
+
+func (w *failingWriter) Close() error { return nil }
+
+func TestProducerBuffersInOutboxUntilBrokersRecover(t *testing.T) {
+	publisher := &recordingPublisher{fail: true}
+	cfg := DefaultProducerConfig(nil, "events")
+	cfg.MaxRetries = 0
+	cfg.Outbox = DefaultOutboxConfig(t.TempDir())
+	cfg.Outbox.DrainInterval = 10 * time.Millisecond
+	producer, err := newProducer(cfg, &failingWriter{publisher: publisher}, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	for i := 1; i <= 3; i++ {
+		if err := producer.Publish(context.Background(), nil, []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	if got := producer.outbox.Depth(); got != 3 {
+		t.Fatalf("depth %d while brokers are down, want 3", got)
+	}
+
+	publisher.setFail(false)
+	waitFor(t, "the outbox to drain", func() bool { return producer.outbox.Depth() == 0 })
+	if err := producer.Publish(context.Background(), nil, []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+	assertValues(t, publisher.values(), "1", "2", "3", "4")
+}
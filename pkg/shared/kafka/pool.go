@@ -0,0 +1,206 @@
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+)
+
+// finalCommitTimeout bounds the commit of the last completed offsets when a
+// consumer stops, as the consume context is usually done by then.
+const finalCommitTimeout = 5 * time.Second
+
+// keyedPool handles messages on a fixed set of workers. Messages with the
+// same key always go to the same worker, so they are handled in order;
+// keyless messages are spread round-robin. Each worker has a bounded queue,
+// and submitting to a full queue blocks, so fetching never runs ahead of
+// the workers by more than the queues hold.
+type keyedPool struct {
+	queues []chan kafka.Message
+	handle func(ctx context.Context, msg kafka.Message) error
+	wg     sync.WaitGroup
+	next   int // worker of the next keyless message
+
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+}
+
+// partitionOffsets tracks the in-flight messages of a partition, so only
+// offsets below which every message is done are committed.
+type partitionOffsets struct {
+	pending []int64 // fetched offsets not yet committable, in fetch order
+	done    map[int64]bool
+}
+
+func newKeyedPool(
+	ctx context.Context,
+	workers, queueSize int,
+	handle func(ctx context.Context, msg kafka.Message) error,
+) *keyedPool {
+	p := &keyedPool{
+		queues:     make([]chan kafka.Message, workers),
+		handle:     handle,
+		partitions: make(map[int]*partitionOffsets),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan kafka.Message, queueSize)
+		p.wg.Add(1)
+		go p.work(ctx, p.queues[i])
+	}
+	return p
+}
+
+func (p *keyedPool) work(ctx context.Context, queue <-chan kafka.Message) {
+	defer p.wg.Done()
+
+	for msg := range queue {
+		// Once stopped, queued messages are drained unhandled and never
+		// marked done, so they are redelivered
+		if ctx.Err() != nil {
+			continue
+		}
+		if err := p.handle(ctx, msg); err != nil {
+			continue
+		}
+		p.complete(msg)
+	}
+}
+
+// submit queues a message on its worker, blocking while the worker's queue
+// is full. It returns false if ctx is done first.
+func (p *keyedPool) submit(ctx context.Context, msg kafka.Message) bool {
+	p.mu.Lock()
+	po, ok := p.partitions[msg.Partition]
+	if !ok {
+		po = &partitionOffsets{done: make(map[int64]bool)}
+		p.partitions[msg.Partition] = po
+	}
+	po.pending = append(po.pending, msg.Offset)
+	p.mu.Unlock()
+
+	select {
+	case p.queues[p.worker(msg.Key)] <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *keyedPool) worker(key []byte) int {
+	if len(key) == 0 {
+		p.next = (p.next + 1) % len(p.queues)
+		return p.next
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+func (p *keyedPool) complete(msg kafka.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partitions[msg.Partition].done[msg.Offset] = true
+}
+
+// committable returns, per partition, the offset to commit for the longest
+// run of done messages since the last call, i.e. the offset after its last
+// message. Partitions without progress are left out.
+func (p *keyedPool) committable() map[int]int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	offsets := make(map[int]int64)
+	for partition, po := range p.partitions {
+		n := 0
+		for n < len(po.pending) && po.done[po.pending[n]] {
+			delete(po.done, po.pending[n])
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		offsets[partition] = po.pending[n-1] + 1
+		po.pending = po.pending[n:]
+	}
+	return offsets
+}
+
+// close stops accepting messages and waits for the workers to finish the
+// queued ones, or to drain them if the pool's context is done.
+func (p *keyedPool) close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// consumeConcurrently fetches messages with fetch and handles them on
+// opts.Workers workers, ordered per key. Every opts.CommitInterval, and once
+// fetch reports it stopped or ctx is done, the offsets of the completed
+// contiguous ranges are passed to commit. Messages still in flight when
+// consumption stops are redelivered.
+func consumeConcurrently(
+	ctx context.Context,
+	fetch func(ctx context.Context) (kafka.Message, bool),
+	handle func(ctx context.Context, msg kafka.Message) error,
+	commit func(ctx context.Context, offsets map[int]int64) error,
+	opts *ConsumeOptions,
+	logger *logging.Logger,
+) {
+	interval := opts.CommitInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	pool := newKeyedPool(ctx, opts.Workers, opts.QueueSize, handle)
+
+	commitOffsets := func(ctx context.Context) {
+		offsets := pool.committable()
+		if len(offsets) == 0 {
+			return
+		}
+		if err := commit(ctx, offsets); err != nil {
+			logger.Error("failed to commit kafka offsets",
+				zap.Error(err),
+				zap.Any("offsets", offsets),
+			)
+		}
+	}
+
+	stopCommits := make(chan struct{})
+	var commitWG sync.WaitGroup
+	commitWG.Add(1)
+	go func() {
+		defer commitWG.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCommits:
+				return
+			case <-ticker.C:
+				commitOffsets(ctx)
+			}
+		}
+	}()
+
+	for {
+		msg, ok := fetch(ctx)
+		if !ok || !pool.submit(ctx, msg) {
+			break
+		}
+	}
+
+	close(stopCommits)
+	commitWG.Wait()
+	pool.close()
+
+	finalCtx, cancel := context.WithTimeout(context.Background(), finalCommitTimeout)
+	defer cancel()
+	commitOffsets(finalCtx)
+}
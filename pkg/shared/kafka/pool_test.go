@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetPool returns a pool without workers, whose messages are completed
+// by the test.
+func offsetPool() *keyedPool {
+	return &keyedPool{
+		queues:     []chan kafka.Message{make(chan kafka.Message, 100)},
+		partitions: make(map[int]*partitionOffsets),
+	}
+}
+
+// at identifies a message by partition and offset.
+type at struct {
+	partition int
+	offset    int64
+}
+
+func TestKeyedPoolCommittable(t *testing.T) {
+	type step struct {
+		complete []at
+		want     map[int]int64
+	}
+	tests := []struct {
+		name    string
+		fetched []at
+		steps   []step
+	}{
+		{
+			name:    "in order",
+			fetched: []at{{0, 0}, {0, 1}, {0, 2}},
+			steps: []step{
+				{complete: []at{{0, 0}, {0, 1}, {0, 2}}, want: map[int]int64{0: 3}},
+				{want: map[int]int64{}},
+			},
+		},
+		{
+			name:    "out of order within a partition",
+			fetched: []at{{0, 0}, {0, 1}, {0, 2}, {0, 3}},
+			steps: []step{
+				{complete: []at{{0, 2}, {0, 1}}, want: map[int]int64{}},
+				{complete: []at{{0, 0}}, want: map[int]int64{0: 3}},
+				{complete: []at{{0, 3}}, want: map[int]int64{0: 4}},
+			},
+		},
+		{
+			name:    "interleaved partitions",
+			fetched: []at{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {2, 7}},
+			steps: []step{
+				{complete: []at{{1, 1}, {0, 0}}, want: map[int]int64{0: 1}},
+				{complete: []at{{1, 0}, {2, 7}}, want: map[int]int64{1: 2, 2: 8}},
+				{complete: []at{{0, 1}}, want: map[int]int64{0: 2}},
+			},
+		},
+		{
+			name:    "a message that never completes blocks later offsets",
+			fetched: []at{{0, 0}, {0, 1}, {0, 2}, {0, 3}, {1, 0}},
+			steps: []step{
+				{complete: []at{{0, 0}, {0, 2}, {0, 3}}, want: map[int]int64{0: 1}},
+				{complete: []at{{1, 0}}, want: map[int]int64{1: 1}},
+				{want: map[int]int64{}},
+			},
+		},
+		{
+			name:    "offsets with gaps",
+			fetched: []at{{0, 5}, {0, 7}, {0, 9}},
+			steps: []step{
+				{complete: []at{{0, 9}, {0, 5}}, want: map[int]int64{0: 6}},
+				{complete: []at{{0, 7}}, want: map[int]int64{0: 10}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := offsetPool()
+			for _, m := range tt.fetched {
+				if !p.submit(context.Background(), kafka.Message{Partition: m.partition, Offset: m.offset}) {
+					t.Fatalf("failed to submit %v", m)
+				}
+			}
+
+			for i, s := range tt.steps {
+				for _, m := range s.complete {
+					p.complete(kafka.Message{Partition: m.partition, Offset: m.offset})
+				}
+				got := p.committable()
+				if fmt.Sprint(got) != fmt.Sprint(s.want) {
+					t.Errorf("step %d: committable %v, want %v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
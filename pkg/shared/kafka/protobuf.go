@@ -0,0 +1,308 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// ProtobufCodec encodes events as Protobuf messages. Fields are read by
+// number, so values written with older or newer schemas decode without the
+// writer schema: unknown fields are skipped and missing ones left unset.
+type ProtobufCodec struct {
+	*schemaCodec
+}
+
+// NewProtobufCodec creates a ProtobufCodec registering its schemas in registry.
+func NewProtobufCodec(registry SchemaRegistry) *ProtobufCodec {
+	return &ProtobufCodec{schemaCodec: newSchemaCodec(registry, SchemaTypeProtobuf, ProtobufSchema)}
+}
+
+// ContentType returns the Protobuf content type.
+func (c *ProtobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+// Encode encodes an event with a schema as a Protobuf message.
+func (c *ProtobufCodec) Encode(ctx context.Context, topic string, v interface{}) ([]byte, error) {
+	schema, id, err := c.schemaOf(ctx, topic, v)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := schemaFields(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b := appendWireHeader(nil, id)
+	// Message indexes of the encoded message in the schema; 0 is the first
+	b = binary.AppendVarint(b, 0)
+	for _, f := range schema.Fields {
+		value, ok := fields[f.Name]
+		if !ok || value == nil {
+			continue
+		}
+		if b, err = appendProtoField(b, f, value); err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", f.Name, err)
+		}
+	}
+	return b, nil
+}
+
+// Decode decodes a Protobuf message into an event.
+func (c *ProtobufCodec) Decode(ctx context.Context, data []byte, v interface{}) error {
+	schema, ok := models.SchemaFor(v)
+	if !ok {
+		return fmt.Errorf("no schema for %T", v)
+	}
+	_, payload, err := readWireHeader(data)
+	if err != nil {
+		return err
+	}
+	if payload, err = skipMessageIndexes(payload); err != nil {
+		return Malformed(err)
+	}
+
+	fields := make(map[string]interface{})
+	for len(payload) > 0 {
+		number, wireType, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return Malformed(protowire.ParseError(n))
+		}
+		payload = payload[n:]
+
+		f, known := schema.FieldByNumber(int(number))
+		if !known || wireType != protoWireType(f.Type) {
+			// Written by another schema version; skip it
+			if n = protowire.ConsumeFieldValue(number, wireType, payload); n < 0 {
+				return Malformed(protowire.ParseError(n))
+			}
+			payload = payload[n:]
+			continue
+		}
+
+		value, n, err := consumeProtoValue(payload, f.Type, fields[f.Name])
+		if err != nil {
+			return Malformed(fmt.Errorf("failed to decode field %s: %w", f.Name, err))
+		}
+		payload = payload[n:]
+		fields[f.Name] = value
+	}
+	return setSchemaFields(fields, v)
+}
+
+func skipMessageIndexes(payload []byte) ([]byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, fmt.Errorf("invalid message indexes")
+	}
+	payload = payload[n:]
+	for i := int64(0); i < count; i++ {
+		if _, n = binary.Varint(payload); n <= 0 {
+			return nil, fmt.Errorf("invalid message indexes")
+		}
+		payload = payload[n:]
+	}
+	return payload, nil
+}
+
+var protoTypes = map[models.FieldType]string{
+	models.FieldString:    "string",
+	models.FieldLong:      "int64",
+	models.FieldDouble:    "double",
+	models.FieldBool:      "bool",
+	models.FieldTimestamp: "int64",
+	models.FieldLabels:    "map<string, string>",
+	models.FieldJSON:      "string",
+}
+
+// ProtobufSchema renders an event schema as a proto3 message definition.
+func ProtobufSchema(schema *models.Schema) string {
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n\n", models.SchemaNamespace)
+	fmt.Fprintf(&b, "message %s {\n", schema.Name)
+	for _, f := range schema.Fields {
+		fmt.Fprintf(&b, "  %s %s = %d;", protoTypes[f.Type], f.Name, f.Number)
+		switch f.Type {
+		case models.FieldTimestamp:
+			b.WriteString(" // microseconds since the Unix epoch")
+		case models.FieldJSON:
+			b.WriteString(" // JSON")
+		}
+		b.WriteString("\n")
+	}
+	if len(schema.Reserved) > 0 {
+		numbers := make([]string, 0, len(schema.Reserved))
+		names := make([]string, 0, len(schema.Reserved))
+		for _, f := range schema.Reserved {
+			numbers = append(numbers, fmt.Sprint(f.Number))
+			names = append(names, fmt.Sprintf("%q", f.Name))
+		}
+		fmt.Fprintf(&b, "  reserved %s;\n", strings.Join(numbers, ", "))
+		fmt.Fprintf(&b, "  reserved %s;\n", strings.Join(names, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func protoWireType(fieldType models.FieldType) protowire.Type {
+	switch fieldType {
+	case models.FieldLong, models.FieldBool, models.FieldTimestamp:
+		return protowire.VarintType
+	case models.FieldDouble:
+		return protowire.Fixed64Type
+	default:
+		return protowire.BytesType
+	}
+}
+
+func appendProtoField(b []byte, f models.SchemaField, value interface{}) ([]byte, error) {
+	number := protowire.Number(f.Number)
+
+	switch f.Type {
+	case models.FieldString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", value)
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendString(b, s), nil
+	case models.FieldLong:
+		i, err := toLong(value)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, number, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(i)), nil
+	case models.FieldDouble:
+		d, err := toDouble(value)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(d)), nil
+	case models.FieldBool:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %T", value)
+		}
+		b = protowire.AppendTag(b, number, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v)), nil
+	case models.FieldTimestamp:
+		micros, err := toTimestampMicros(value)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, number, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(micros)), nil
+	case models.FieldLabels:
+		labels, err := toLabels(value)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		// Map entries are messages with the key as field 1 and the value as field 2
+		for _, k := range keys {
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendString(entry, k)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendString(entry, labels[k])
+			b = protowire.AppendTag(b, number, protowire.BytesType)
+			b = protowire.AppendBytes(b, entry)
+		}
+		return b, nil
+	case models.FieldJSON:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		return protowire.AppendBytes(b, data), nil
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", f.Type)
+	}
+}
+
+// consumeProtoValue reads the value of a field as its JSON value. Labels
+// are merged into prev, as each map entry is a field of its own.
+func consumeProtoValue(b []byte, fieldType models.FieldType, prev interface{}) (interface{}, int, error) {
+	switch fieldType {
+	case models.FieldString:
+		s, n := protowire.ConsumeString(b)
+		return s, n, protoErr(n)
+	case models.FieldLong:
+		v, n := protowire.ConsumeVarint(b)
+		return int64(v), n, protoErr(n)
+	case models.FieldDouble:
+		v, n := protowire.ConsumeFixed64(b)
+		return math.Float64frombits(v), n, protoErr(n)
+	case models.FieldBool:
+		v, n := protowire.ConsumeVarint(b)
+		return protowire.DecodeBool(v), n, protoErr(n)
+	case models.FieldTimestamp:
+		v, n := protowire.ConsumeVarint(b)
+		return fromTimestampMicros(int64(v)), n, protoErr(n)
+	case models.FieldLabels:
+		entry, n := protowire.ConsumeBytes(b)
+		if err := protoErr(n); err != nil {
+			return nil, n, err
+		}
+		labels, _ := prev.(map[string]string)
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		var key, value string
+		for len(entry) > 0 {
+			num, typ, m := protowire.ConsumeTag(entry)
+			if err := protoErr(m); err != nil {
+				return nil, n, err
+			}
+			entry = entry[m:]
+			if typ != protowire.BytesType {
+				return nil, n, fmt.Errorf("invalid map entry")
+			}
+			s, m := protowire.ConsumeString(entry)
+			if err := protoErr(m); err != nil {
+				return nil, n, err
+			}
+			entry = entry[m:]
+			if num == 1 {
+				key = s
+			} else if num == 2 {
+				value = s
+			}
+		}
+		labels[key] = value
+		return labels, n, nil
+	case models.FieldJSON:
+		data, n := protowire.ConsumeBytes(b)
+		if err := protoErr(n); err != nil {
+			return nil, n, err
+		}
+		if !json.Valid(data) {
+			return nil, n, fmt.Errorf("invalid JSON value")
+		}
+		return json.RawMessage(data), n, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported field type %s", fieldType)
+	}
+}
+
+func protoErr(n int) error {
+	if n < 0 {
+		return protowire.ParseError(n)
+	}
+	return nil
+}
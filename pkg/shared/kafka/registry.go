@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaType is the type of a registered schema.
+type SchemaType string
+
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+)
+
+// RegisteredSchema is a schema as stored in a schema registry.
+type RegisteredSchema struct {
+	ID     int
+	Type   SchemaType
+	Schema string
+}
+
+// SchemaRegistry stores the schemas that encoded messages refer to by ID.
+type SchemaRegistry interface {
+	// Register registers a schema under a subject, e.g. "alerts-value", and
+	// returns its ID. Registering a known schema returns its existing ID.
+	Register(ctx context.Context, subject string, schemaType SchemaType, schema string) (int, error)
+	// SchemaByID returns a registered schema.
+	SchemaByID(ctx context.Context, id int) (*RegisteredSchema, error)
+}
+
+// SubjectForTopic returns the registry subject of the values of a topic.
+func SubjectForTopic(topic string) string {
+	return topic + "-value"
+}
+
+// NewSchemaRegistry returns a client of the schema registry at url, or nil
+// when url is empty so that only JSON is used.
+func NewSchemaRegistry(url string) SchemaRegistry {
+	if url == "" {
+		return nil
+	}
+	return NewRegistryClient(url)
+}
+
+// MemoryRegistry is an in-process schema registry for tests and for running
+// producers and consumers in one process without a registry server.
+type MemoryRegistry struct {
+	mu       sync.RWMutex
+	ids      map[string]int // by schema type and schema
+	schemas  map[int]*RegisteredSchema
+	subjects map[string][]int // versions by subject
+}
+
+// NewMemoryRegistry creates an empty MemoryRegistry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		ids:      make(map[string]int),
+		schemas:  make(map[int]*RegisteredSchema),
+		subjects: make(map[string][]int),
+	}
+}
+
+// Register registers a schema under a subject.
+func (r *MemoryRegistry) Register(ctx context.Context, subject string, schemaType SchemaType, schema string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := string(schemaType) + "\x00" + schema
+	id, ok := r.ids[key]
+	if !ok {
+		id = len(r.schemas) + 1
+		r.ids[key] = id
+		r.schemas[id] = &RegisteredSchema{ID: id, Type: schemaType, Schema: schema}
+	}
+	for _, version := range r.subjects[subject] {
+		if version == id {
+			return id, nil
+		}
+	}
+	r.subjects[subject] = append(r.subjects[subject], id)
+	return id, nil
+}
+
+// SchemaByID returns a registered schema.
+func (r *MemoryRegistry) SchemaByID(ctx context.Context, id int) (*RegisteredSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schema, ok := r.schemas[id]
+	if !ok {
+		return nil, fmt.Errorf("schema %d not found", id)
+	}
+	return schema, nil
+}
+
+// RegistryClient is a client of a Confluent-compatible schema registry.
+// Schemas are immutable, so they are cached once fetched or registered.
+type RegistryClient struct {
+	baseURL string
+	client  *http.Client
+
+	mu         sync.RWMutex
+	registered map[string]int // by subject, schema type and schema
+	byID       map[int]*RegisteredSchema
+}
+
+// NewRegistryClient creates a client of the schema registry at baseURL.
+func NewRegistryClient(baseURL string) *RegistryClient {
+	return &RegistryClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		registered: make(map[string]int),
+		byID:       make(map[int]*RegisteredSchema),
+	}
+}
+
+// registryContentType is the content type of the registry's REST API.
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+type registrySchema struct {
+	Schema     string     `json:"schema"`
+	SchemaType SchemaType `json:"schemaType,omitempty"` // Avro when empty
+	ID         int        `json:"id,omitempty"`
+}
+
+type registryError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// Register registers a schema under a subject. The registry rejects
+// schemas incompatible with the subject's compatibility level.
+func (c *RegistryClient) Register(ctx context.Context, subject string, schemaType SchemaType, schema string) (int, error) {
+	key := subject + "\x00" + string(schemaType) + "\x00" + schema
+	c.mu.RLock()
+	id, ok := c.registered[key]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	var resp registrySchema
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := c.do(ctx, http.MethodPost, path, registrySchema{Schema: schema, SchemaType: schemaType}, &resp); err != nil {
+		return 0, fmt.Errorf("failed to register schema for %s: %w", subject, err)
+	}
+
+	c.mu.Lock()
+	c.registered[key] = resp.ID
+	c.byID[resp.ID] = &RegisteredSchema{ID: resp.ID, Type: schemaType, Schema: schema}
+	c.mu.Unlock()
+	return resp.ID, nil
+}
+
+// SchemaByID returns a registered schema.
+func (c *RegistryClient) SchemaByID(ctx context.Context, id int) (*RegisteredSchema, error) {
+	c.mu.RLock()
+	schema, ok := c.byID[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var resp registrySchema
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+	schemaType := resp.SchemaType
+	if schemaType == "" {
+		schemaType = SchemaTypeAvro
+	}
+	schema = &RegisteredSchema{ID: id, Type: schemaType, Schema: resp.Schema}
+
+	c.mu.Lock()
+	c.byID[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *RegistryClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", registryContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", registryContentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var regErr registryError
+		if err := json.NewDecoder(resp.Body).Decode(&regErr); err == nil && regErr.Message != "" {
+			return fmt.Errorf("schema registry returned %d: %s", regErr.ErrorCode, regErr.Message)
+		}
+		return fmt.Errorf("schema registry returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry serves the registry API for one subject, rejecting schemas
+// that drop fields of the registered ones, the way a BACKWARD compatibility
+// level rejects them.
+type fakeRegistry struct {
+	mu       sync.Mutex
+	schemas  []registrySchema
+	requests int
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+
+	w.Header().Set("Content-Type", registryContentType)
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/subjects/alerts-value/versions":
+		var s registrySchema
+		if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, prev := range r.schemas {
+			if !strings.HasPrefix(s.Schema, prev.Schema) {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(registryError{
+					ErrorCode: 409,
+					Message:   "Schema being registered is incompatible with an earlier schema",
+				})
+				return
+			}
+		}
+		s.ID = len(r.schemas) + 1
+		r.schemas = append(r.schemas, s)
+		json.NewEncoder(w).Encode(registrySchema{ID: s.ID})
+	case req.Method == http.MethodGet && req.URL.Path == "/schemas/ids/1":
+		// The registry leaves out the type of Avro schemas
+		json.NewEncoder(w).Encode(registrySchema{Schema: r.schemas[0].Schema})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(registryError{ErrorCode: 40403, Message: "Schema not found"})
+	}
+}
+
+func (r *fakeRegistry) requestCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests
+}
+
+func TestRegistryClient(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRegistry{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewRegistryClient(server.URL + "/")
+	id, err := client.Register(ctx, "alerts-value", SchemaTypeAvro, `{"fields":["id"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 {
+		t.Errorf("registered ID %d, want 1", id)
+	}
+
+	// Registered schemas are cached
+	if again, err := client.Register(ctx, "alerts-value", SchemaTypeAvro, `{"fields":["id"`); err != nil || again != id {
+		t.Errorf("registering again: got %d, %v, want %d", again, err, id)
+	}
+	if schema, err := client.SchemaByID(ctx, id); err != nil || schema.Type != SchemaTypeAvro {
+		t.Errorf("schema by ID: got %+v, %v", schema, err)
+	}
+	if got := fake.requestCount(); got != 1 {
+		t.Errorf("%d requests, want 1", got)
+	}
+
+	// A compatible schema gets a new ID
+	if id, err := client.Register(ctx, "alerts-value", SchemaTypeAvro, `{"fields":["id","title"`); err != nil || id != 2 {
+		t.Errorf("compatible schema: got %d, %v, want 2", id, err)
+	}
+
+	// The registry rejects an incompatible schema with its reason
+	_, err = client.Register(ctx, "alerts-value", SchemaTypeAvro, `{"fields":["title"`)
+	if err == nil || !strings.Contains(err.Error(), "incompatible with an earlier schema") {
+		t.Errorf("incompatible schema: got %v, want the registry's reason", err)
+	}
+}
+
+func TestRegistryClientFetchesSchemas(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRegistry{schemas: []registrySchema{{ID: 1, Schema: `{"type":"record"}`}}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewRegistryClient(server.URL)
+	schema, err := client.SchemaByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Type != SchemaTypeAvro || schema.Schema != `{"type":"record"}` {
+		t.Errorf("schema %+v, want the Avro schema", schema)
+	}
+	if _, err := client.SchemaByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.requestCount(); got != 1 {
+		t.Errorf("%d requests, want the schema fetched once", got)
+	}
+
+	if _, err := client.SchemaByID(ctx, 7); err == nil || !strings.Contains(err.Error(), "Schema not found") {
+		t.Errorf("unknown schema: got %v, want the registry's error", err)
+	}
+}
+
+func TestMemoryRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+
+	a, _ := registry.Register(ctx, "alerts-value", SchemaTypeAvro, "a")
+	b, _ := registry.Register(ctx, "alerts-value", SchemaTypeAvro, "b")
+	again, _ := registry.Register(ctx, "logs-value", SchemaTypeAvro, "a")
+	proto, _ := registry.Register(ctx, "alerts-value", SchemaTypeProtobuf, "a")
+
+	if a == b || a != again || proto == a {
+		t.Errorf("IDs a=%d b=%d again=%d proto=%d, want one ID per schema type and schema", a, b, again, proto)
+	}
+	if versions := registry.subjects["alerts-value"]; len(versions) != 3 {
+		t.Errorf("alerts-value versions %v, want 3", versions)
+	}
+	if _, err := registry.SchemaByID(ctx, 42); err == nil {
+		t.Error("unknown schema found")
+	}
+}
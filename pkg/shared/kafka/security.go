@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/microservices-platform/pkg/shared/utils"
+)
+
+// SASL mechanisms supported by producers and consumers.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// dialTimeout bounds connecting and authenticating to a broker.
+const dialTimeout = 10 * time.Second
+
+// Security holds how producers and consumers connect to the brokers: over
+// TLS when TLS is set, authenticated with SASL when SASLMechanism is set.
+type Security struct {
+	TLS           *tls.Config `json:"-"`
+	SASLMechanism string      `json:"sasl_mechanism"`
+	SASLUsername  string      `json:"sasl_username"`
+	SASLPassword  string      `json:"-"`
+}
+
+// NewSecurity creates the Security of a service from its settings, loading
+// the TLS certificates and checking the SASL mechanism.
+func NewSecurity(cfg utils.KafkaSecurityConfig) (Security, error) {
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return Security{}, err
+	}
+
+	security := Security{
+		TLS:           tlsConfig,
+		SASLMechanism: strings.ToUpper(cfg.SASLMechanism),
+		SASLUsername:  cfg.SASLUsername,
+		SASLPassword:  cfg.SASLPassword,
+	}
+	if _, err := security.mechanism(); err != nil {
+		return Security{}, err
+	}
+	return security, nil
+}
+
+// mechanism returns the SASL mechanism, or nil without SASL.
+func (s Security) mechanism() (sasl.Mechanism, error) {
+	switch strings.ToUpper(s.SASLMechanism) {
+	case "":
+		return nil, nil
+	case SASLPlain:
+		return plain.Mechanism{Username: s.SASLUsername, Password: s.SASLPassword}, nil
+	case SASLScramSHA256, SASLScramSHA512:
+		algorithm := scram.SHA256
+		if strings.ToUpper(s.SASLMechanism) == SASLScramSHA512 {
+			algorithm = scram.SHA512
+		}
+		mechanism, err := scram.Mechanism(algorithm, s.SASLUsername, s.SASLPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SASL mechanism: %w", err)
+		}
+		return mechanism, nil
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism %q", s.SASLMechanism)
+	}
+}
+
+// transport returns the transport of writers and clients, or nil for the
+// default plaintext transport.
+func (s Security) transport() (*kafka.Transport, error) {
+	mechanism, err := s.mechanism()
+	if err != nil {
+		return nil, err
+	}
+	if s.TLS == nil && mechanism == nil {
+		return nil, nil
+	}
+	return &kafka.Transport{
+		TLS:         s.TLS,
+		SASL:        mechanism,
+		DialTimeout: dialTimeout,
+	}, nil
+}
+
+// dialer returns the dialer of readers and consumer groups, or nil for the
+// default plaintext dialer.
+func (s Security) dialer() (*kafka.Dialer, error) {
+	mechanism, err := s.mechanism()
+	if err != nil {
+		return nil, err
+	}
+	if s.TLS == nil && mechanism == nil {
+		return nil, nil
+	}
+	return &kafka.Dialer{
+		Timeout:       dialTimeout,
+		DualStack:     true,
+		TLS:           s.TLS,
+		SASLMechanism: mechanism,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/microservices-platform/pkg/shared/metrics"
+)
+
+// consumerStats accumulates the statistics of a consumer. kafka-go reports
+// counters since they were last read, so they are added up here.
+type consumerStats struct {
+	mu     sync.Mutex
+	totals metrics.KafkaConsumerStats
+}
+
+func newConsumerStats(topic, groupID string) *consumerStats {
+	return &consumerStats{totals: metrics.KafkaConsumerStats{
+		Topic:   topic,
+		GroupID: groupID,
+		Lag:     make(map[int]int64),
+	}}
+}
+
+// add adds the counters read from a reader.
+func (s *consumerStats) add(rs kafka.ReaderStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals.Fetches += rs.Fetches
+	s.totals.Messages += rs.Messages
+	s.totals.Bytes += rs.Bytes
+	s.totals.Rebalances += rs.Rebalances
+	s.totals.Errors += rs.Errors
+}
+
+// fetched updates the lag of the message's partition.
+func (s *consumerStats) fetched(msg kafka.Message) {
+	lag := msg.HighWaterMark - msg.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals.Lag[msg.Partition] = lag
+}
+
+func (s *consumerStats) committed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals.Commits++
+}
+
+func (s *consumerStats) rebalanced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals.Rebalances++
+}
+
+// revoked forgets the lag of partitions no longer consumed.
+func (s *consumerStats) revoked(partition int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.totals.Lag, partition)
+}
+
+func (s *consumerStats) snapshot() metrics.KafkaConsumerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.totals
+	stats.Lag = make(map[int]int64, len(s.totals.Lag))
+	for partition, lag := range s.totals.Lag {
+		stats.Lag[partition] = lag
+	}
+	return stats
+}
+
+// producerStats accumulates the statistics of a producer's writer.
+type producerStats struct {
+	mu     sync.Mutex
+	totals metrics.KafkaProducerStats
+}
+
+func (s *producerStats) add(ws kafka.WriterStats) metrics.KafkaProducerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals.Writes += ws.Writes
+	s.totals.Messages += ws.Messages
+	s.totals.Bytes += ws.Bytes
+	s.totals.Errors += ws.Errors
+	s.totals.Retries += ws.Retries
+	s.totals.Batches += ws.BatchSize.Count
+	s.totals.BatchMessages += ws.BatchSize.Sum
+	return s.totals
+}
+
+// ConsumerStats returns the statistics of the consumer since it was created,
+// excluding counters already read with Stats.
+func (c *Consumer) ConsumerStats() metrics.KafkaConsumerStats {
+	c.stats.add(c.reader.Stats())
+	return c.stats.snapshot()
+}
+
+// ConsumerStats returns the statistics of the consumer since it was created.
+func (c *GroupConsumer) ConsumerStats() metrics.KafkaConsumerStats {
+	c.readersMu.Lock()
+	for _, reader := range c.readers {
+		c.stats.add(reader.Stats())
+	}
+	c.readersMu.Unlock()
+	return c.stats.snapshot()
+}
+
+// ProducerStats returns the statistics of the producer since it was created,
+// excluding counters already read with Stats.
+func (p *Producer) ProducerStats() metrics.KafkaProducerStats {
+	stats := p.stats.add(p.writer.Stats())
+	stats.Topic = p.config.Topic
+	return stats
+}
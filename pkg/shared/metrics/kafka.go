@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KafkaConsumerStats are the statistics of a Kafka consumer since it was created.
+type KafkaConsumerStats struct {
+	Topic      string
+	GroupID    string
+	Fetches    int64
+	Messages   int64
+	Bytes      int64
+	Commits    int64
+	Rebalances int64
+	Errors     int64
+	// Lag is how many messages each assigned partition is behind its end,
+	// as of the last message fetched from it.
+	Lag map[int]int64
+}
+
+// TotalLag returns the lag of the consumer over all its partitions.
+func (s KafkaConsumerStats) TotalLag() int64 {
+	var total int64
+	for _, lag := range s.Lag {
+		total += lag
+	}
+	return total
+}
+
+// KafkaProducerStats are the statistics of a Kafka producer since it was created.
+type KafkaProducerStats struct {
+	Topic    string
+	Writes   int64
+	Messages int64
+	Bytes    int64
+	Errors   int64
+	Retries  int64
+	// Batches and BatchMessages count the batches written and their messages.
+	Batches       int64
+	BatchMessages int64
+}
+
+// KafkaConsumer is a consumer whose statistics are exported.
+type KafkaConsumer interface {
+	ConsumerStats() KafkaConsumerStats
+}
+
+// KafkaProducer is a producer whose statistics are exported.
+type KafkaProducer interface {
+	ProducerStats() KafkaProducerStats
+}
+
+// kafkaCollector exports the statistics of the registered consumers and
+// producers when scraped.
+type kafkaCollector struct {
+	mu        sync.Mutex
+	consumers map[KafkaConsumer]struct{}
+	producers map[KafkaProducer]struct{}
+
+	consumerLag        *prometheus.Desc
+	consumerFetches    *prometheus.Desc
+	consumerMessages   *prometheus.Desc
+	consumerBytes      *prometheus.Desc
+	consumerCommits    *prometheus.Desc
+	consumerRebalances *prometheus.Desc
+	consumerErrors     *prometheus.Desc
+	producerWrites     *prometheus.Desc
+	producerMessages   *prometheus.Desc
+	producerBytes      *prometheus.Desc
+	producerErrors     *prometheus.Desc
+	producerRetries    *prometheus.Desc
+	producerBatchSize  *prometheus.Desc
+}
+
+func newKafkaCollector(serviceName string) *kafkaCollector {
+	labels := prometheus.Labels{"service": serviceName}
+	consumerDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, []string{"topic", "group"}, labels)
+	}
+	producerDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, []string{"topic"}, labels)
+	}
+
+	return &kafkaCollector{
+		consumers: make(map[KafkaConsumer]struct{}),
+		producers: make(map[KafkaProducer]struct{}),
+
+		consumerLag: prometheus.NewDesc(
+			"kafka_consumer_lag",
+			"Number of messages a consumer partition is behind the end of the partition",
+			[]string{"topic", "group", "partition"}, labels,
+		),
+		consumerFetches:    consumerDesc("kafka_consumer_fetches_total", "Total number of Kafka fetch requests"),
+		consumerMessages:   consumerDesc("kafka_consumer_fetched_messages_total", "Total number of messages fetched from Kafka"),
+		consumerBytes:      consumerDesc("kafka_consumer_fetched_bytes_total", "Total bytes of messages fetched from Kafka"),
+		consumerCommits:    consumerDesc("kafka_consumer_commits_total", "Total number of Kafka offset commits"),
+		consumerRebalances: consumerDesc("kafka_consumer_rebalances_total", "Total number of consumer group rebalances"),
+		consumerErrors:     consumerDesc("kafka_consumer_errors_total", "Total number of Kafka fetch errors"),
+		producerWrites:     producerDesc("kafka_producer_writes_total", "Total number of Kafka write requests"),
+		producerMessages:   producerDesc("kafka_producer_written_messages_total", "Total number of messages written to Kafka"),
+		producerBytes:      producerDesc("kafka_producer_written_bytes_total", "Total bytes of messages written to Kafka"),
+		producerErrors:     producerDesc("kafka_producer_errors_total", "Total number of failed Kafka writes"),
+		producerRetries:    producerDesc("kafka_producer_retries_total", "Total number of retried Kafka writes"),
+		producerBatchSize:  producerDesc("kafka_producer_batch_size", "Number of messages per batch written to Kafka"),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *kafkaCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.consumerLag, c.consumerFetches, c.consumerMessages, c.consumerBytes,
+		c.consumerCommits, c.consumerRebalances, c.consumerErrors,
+		c.producerWrites, c.producerMessages, c.producerBytes,
+		c.producerErrors, c.producerRetries, c.producerBatchSize,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector. Consumers and producers of the
+// same topic and group are summed so that their series stay unique.
+func (c *kafkaCollector) Collect(ch chan<- prometheus.Metric) {
+	type consumerKey struct{ topic, group string }
+	type partitionKey struct {
+		consumerKey
+		partition int
+	}
+
+	consumers := make(map[consumerKey]*KafkaConsumerStats)
+	lags := make(map[partitionKey]int64)
+	for _, stats := range c.consumerStats() {
+		key := consumerKey{stats.Topic, stats.GroupID}
+		total, ok := consumers[key]
+		if !ok {
+			total = &KafkaConsumerStats{}
+			consumers[key] = total
+		}
+		total.Fetches += stats.Fetches
+		total.Messages += stats.Messages
+		total.Bytes += stats.Bytes
+		total.Commits += stats.Commits
+		total.Rebalances += stats.Rebalances
+		total.Errors += stats.Errors
+		for partition, lag := range stats.Lag {
+			lags[partitionKey{key, partition}] += lag
+		}
+	}
+	for key, stats := range consumers {
+		counter := func(desc *prometheus.Desc, value int64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), key.topic, key.group)
+		}
+		counter(c.consumerFetches, stats.Fetches)
+		counter(c.consumerMessages, stats.Messages)
+		counter(c.consumerBytes, stats.Bytes)
+		counter(c.consumerCommits, stats.Commits)
+		counter(c.consumerRebalances, stats.Rebalances)
+		counter(c.consumerErrors, stats.Errors)
+	}
+	for key, lag := range lags {
+		ch <- prometheus.MustNewConstMetric(c.consumerLag, prometheus.GaugeValue, float64(lag),
+			key.topic, key.group, strconv.Itoa(key.partition))
+	}
+
+	producers := make(map[string]*KafkaProducerStats)
+	for _, stats := range c.producerStats() {
+		total, ok := producers[stats.Topic]
+		if !ok {
+			total = &KafkaProducerStats{}
+			producers[stats.Topic] = total
+		}
+		total.Writes += stats.Writes
+		total.Messages += stats.Messages
+		total.Bytes += stats.Bytes
+		total.Errors += stats.Errors
+		total.Retries += stats.Retries
+		total.Batches += stats.Batches
+		total.BatchMessages += stats.BatchMessages
+	}
+	for topic, stats := range producers {
+		counter := func(desc *prometheus.Desc, value int64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), topic)
+		}
+		counter(c.producerWrites, stats.Writes)
+		counter(c.producerMessages, stats.Messages)
+		counter(c.producerBytes, stats.Bytes)
+		counter(c.producerErrors, stats.Errors)
+		counter(c.producerRetries, stats.Retries)
+		ch <- prometheus.MustNewConstSummary(c.producerBatchSize,
+			uint64(stats.Batches), float64(stats.BatchMessages), nil, topic)
+	}
+}
+
+func (c *kafkaCollector) consumerStats() []KafkaConsumerStats {
+	c.mu.Lock()
+	consumers := make([]KafkaConsumer, 0, len(c.consumers))
+	for consumer := range c.consumers {
+		consumers = append(consumers, consumer)
+	}
+	c.mu.Unlock()
+
+	stats := make([]KafkaConsumerStats, len(consumers))
+	for i, consumer := range consumers {
+		stats[i] = consumer.ConsumerStats()
+	}
+	return stats
+}
+
+func (c *kafkaCollector) producerStats() []KafkaProducerStats {
+	c.mu.Lock()
+	producers := make([]KafkaProducer, 0, len(c.producers))
+	for producer := range c.producers {
+		producers = append(producers, producer)
+	}
+	c.mu.Unlock()
+
+	stats := make([]KafkaProducerStats, len(producers))
+	for i, producer := range producers {
+		stats[i] = producer.ProducerStats()
+	}
+	return stats
+}
+
+// RegisterKafkaConsumer exports the statistics of a consumer until it is unregistered.
+func (m *Metrics) RegisterKafkaConsumer(c KafkaConsumer) {
+	m.kafka.mu.Lock()
+	defer m.kafka.mu.Unlock()
+	m.kafka.consumers[c] = struct{}{}
+}
+
+// UnregisterKafkaConsumer stops exporting the statistics of a consumer.
+func (m *Metrics) UnregisterKafkaConsumer(c KafkaConsumer) {
+	m.kafka.mu.Lock()
+	defer m.kafka.mu.Unlock()
+	delete(m.kafka.consumers, c)
+}
+
+// RegisterKafkaProducer exports the statistics of a producer until it is unregistered.
+func (m *Metrics) RegisterKafkaProducer(p KafkaProducer) {
+	m.kafka.mu.Lock()
+	defer m.kafka.mu.Unlock()
+	m.kafka.producers[p] = struct{}{}
+}
+
+// UnregisterKafkaProducer stops exporting the statistics of a producer.
+func (m *Metrics) UnregisterKafkaProducer(p KafkaProducer) {
+	m.kafka.mu.Lock()
+	defer m.kafka.mu.Unlock()
+	delete(m.kafka.producers, p)
+}
+
+// MaxKafkaConsumerLag returns the highest total lag of the registered
+// consumers and the topic it is on. Readiness probes use it to report a
+// service that fell behind.
+func (m *Metrics) MaxKafkaConsumerLag() (int64, string) {
+	var maxLag int64
+	var topic string
+	for _, stats := range m.kafka.consumerStats() {
+		if lag := stats.TotalLag(); lag > maxLag {
+			maxLag, topic = lag, stats.Topic
+		}
+	}
+	return maxLag, topic
+}
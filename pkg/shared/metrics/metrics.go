@@ -26,6 +26,16 @@ type Metrics struct {
 	KafkaMessagesConsumed  *prometheus.CounterVec
 	KafkaPublishDuration   *prometheus.HistogramVec
 	KafkaPublishErrors     *prometheus.CounterVec
+	KafkaConsumeRetries    *prometheus.CounterVec
+	KafkaDeadLettered      *prometheus.CounterVec
+	KafkaDuplicates        *prometheus.CounterVec
+
+	// Outbox metrics
+	OutboxDepth *prometheus.GaugeVec
+	OutboxAge   *prometheus.GaugeVec
+
+	// Ingest metrics
+	IngestDropped *prometheus.CounterVec
 
 	// Business metrics
 	OperationsTotal   *prometheus.CounterVec
@@ -37,6 +47,9 @@ type Metrics struct {
 	MemoryUsage prometheus.Gauge
 	Goroutines  prometheus.Gauge
 	Uptime      prometheus.Gauge
+
+	// Statistics of the registered Kafka consumers and producers
+	kafka *kafkaCollector
 }
 
 // NewMetrics creates a new Metrics instance for the given service.
@@ -122,6 +135,70 @@ func NewMetrics(serviceName string) *Metrics {
 			},
 			[]string{"topic"},
 		),
+		KafkaConsumeRetries: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kafka_consume_retries_total",
+				Help: "Total number of retries of Kafka messages whose handler failed",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"topic"},
+		),
+		KafkaDeadLettered: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kafka_messages_dead_lettered_total",
+				Help: "Total number of Kafka messages given up on, sent to the dead-letter topic or dropped",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"topic", "reason"},
+		),
+		KafkaDuplicates: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kafka_messages_deduplicated_total",
+				Help: "Total number of Kafka messages skipped because a message with the same ID was already handled",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"topic"},
+		),
+
+		// Outbox metrics
+		OutboxDepth: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "outbox_depth",
+				Help: "Number of messages buffered in the outbox while Kafka is unavailable",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"topic"},
+		),
+		OutboxAge: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "outbox_oldest_age_seconds",
+				Help: "Age of the oldest message buffered in the outbox in seconds",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"topic"},
+		),
+
+		// Ingest metrics
+		IngestDropped: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ingest_labels_dropped_total",
+				Help: "Total number of ingested metric labels dropped or truncated by the label policy",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"source", "reason"},
+		),
 
 		// Business metrics
 		OperationsTotal: factory.NewCounterVec(
@@ -199,6 +276,9 @@ func NewMetrics(serviceName string) *Metrics {
 	registry.MustRegister(prometheus.NewGoCollector())
 	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 
+	m.kafka = newKafkaCollector(serviceName)
+	registry.MustRegister(m.kafka)
+
 	return m
 }
 
@@ -229,6 +309,32 @@ func (m *Metrics) RecordKafkaConsume(topic string) {
 	m.KafkaMessagesConsumed.WithLabelValues(topic).Inc()
 }
 
+// RecordKafkaRetry records a retry of a Kafka message whose handler failed.
+func (m *Metrics) RecordKafkaRetry(topic string) {
+	m.KafkaConsumeRetries.WithLabelValues(topic).Inc()
+}
+
+// RecordKafkaDeadLetter records a Kafka message given up on for reason.
+func (m *Metrics) RecordKafkaDeadLetter(topic, reason string) {
+	m.KafkaDeadLettered.WithLabelValues(topic, reason).Inc()
+}
+
+// RecordKafkaDuplicate records a Kafka message skipped as a duplicate.
+func (m *Metrics) RecordKafkaDuplicate(topic string) {
+	m.KafkaDuplicates.WithLabelValues(topic).Inc()
+}
+
+// SetOutboxState sets the depth of a topic's outbox and the age of its oldest message.
+func (m *Metrics) SetOutboxState(topic string, depth int, oldestAge time.Duration) {
+	m.OutboxDepth.WithLabelValues(topic).Set(float64(depth))
+	m.OutboxAge.WithLabelValues(topic).Set(oldestAge.Seconds())
+}
+
+// RecordIngestDrop records a label dropped from a metric ingested from source.
+func (m *Metrics) RecordIngestDrop(source, reason string) {
+	m.IngestDropped.WithLabelValues(source, reason).Inc()
+}
+
 // RecordOperation records business operation metrics.
 func (m *Metrics) RecordOperation(operation, status string, duration time.Duration) {
 	m.OperationsTotal.WithLabelValues(operation, status).Inc()
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MetricKind describes how the samples of a metric are produced and aggregated.
+type MetricKind string
+
+const (
+	MetricKindGauge     MetricKind = "gauge"
+	MetricKindCounter   MetricKind = "counter"
+	MetricKindHistogram MetricKind = "histogram"
+)
+
+// MetricDefinition is the canonical description of a metric type.
+// Producers, the analyzer and the UI all resolve metric types through the catalogue
+// so that a rule on a metric reads the same value the producer published.
+type MetricDefinition struct {
+	Type        MetricType `json:"type"`
+	Unit        string     `json:"unit"`
+	Kind        MetricKind `json:"kind"`
+	Description string     `json:"description"`
+	// Aliases are legacy metric type names accepted on ingestion and normalized to Type.
+	Aliases []MetricType `json:"aliases,omitempty"`
+	// Source names the histogram metric this metric is computed from.
+	// Derived metrics are never published directly.
+	Source MetricType `json:"source,omitempty"`
+	// Quantile is the quantile computed over the Source samples.
+	Quantile float64 `json:"quantile,omitempty"`
+
+	// field returns the aggregate ServiceMetric field used for dashboard display.
+	field func(m *ServiceMetric) *float64
+}
+
+// Derived reports whether the metric is computed from another metric's samples.
+func (d *MetricDefinition) Derived() bool {
+	return d.Source != ""
+}
+
+// Extract computes the current value of the metric from a window of samples
+// grouped by canonical metric type (see GroupByMetricType).
+// Derived metrics compute their quantile over the source samples; all other
+// metrics use the value of the most recent sample.
+func (d *MetricDefinition) Extract(series map[MetricType][]*ServiceMetric) (float64, bool) {
+	if d.Derived() {
+		samples := series[d.Source]
+		if len(samples) == 0 {
+			return 0, false
+		}
+		return Quantile(SampleValues(samples), d.Quantile), true
+	}
+
+	samples := series[d.Type]
+	if len(samples) == 0 {
+		return 0, false
+	}
+	return samples[len(samples)-1].Value, true
+}
+
+// Apply copies value into the aggregate dashboard field for this metric.
+// It is a no-op for metrics without a dashboard field.
+func (d *MetricDefinition) Apply(aggregate *ServiceMetric, value float64) {
+	if d.field == nil {
+		return
+	}
+	*d.field(aggregate) = value
+}
+
+// metricCatalog is the canonical list of metric types, in display order.
+var metricCatalog = []*MetricDefinition{
+	{
+		Type:        MetricTypeCPU,
+		Unit:        "percent",
+		Kind:        MetricKindGauge,
+		Description: "CPU utilisation",
+		field:       func(m *ServiceMetric) *float64 { return &m.CPUUsage },
+	},
+	{
+		Type:        MetricTypeMemory,
+		Unit:        "percent",
+		Kind:        MetricKindGauge,
+		Description: "Memory utilisation",
+		field:       func(m *ServiceMetric) *float64 { return &m.MemoryUsage },
+	},
+	{
+		Type:        MetricTypeLatency,
+		Unit:        "ms",
+		Kind:        MetricKindHistogram,
+		Description: "Request latency sample",
+		field:       func(m *ServiceMetric) *float64 { return &m.LatencyP50 },
+	},
+	{
+		Type:        MetricTypeLatencyP95,
+		Unit:        "ms",
+		Kind:        MetricKindGauge,
+		Description: "95th percentile request latency",
+		Source:      MetricTypeLatency,
+		Quantile:    0.95,
+		field:       func(m *ServiceMetric) *float64 { return &m.LatencyP95 },
+	},
+	{
+		Type:        MetricTypeLatencyP99,
+		Unit:        "ms",
+		Kind:        MetricKindGauge,
+		Description: "99th percentile request latency",
+		Source:      MetricTypeLatency,
+		Quantile:    0.99,
+		field:       func(m *ServiceMetric) *float64 { return &m.LatencyP99 },
+	},
+	{
+		Type:        MetricTypeErrorRate,
+		Unit:        "percent",
+		Kind:        MetricKindGauge,
+		Description: "Percentage of failed requests",
+		Aliases:     []MetricType{MetricTypeError},
+		field:       func(m *ServiceMetric) *float64 { return &m.ErrorRate },
+	},
+	{
+		Type:        MetricTypeRequestRate,
+		Unit:        "req/s",
+		Kind:        MetricKindGauge,
+		Description: "Requests per second",
+		field:       func(m *ServiceMetric) *float64 { return &m.RequestCount },
+	},
+	{
+		Type:        MetricTypeStatus,
+		Unit:        "boolean",
+		Kind:        MetricKindGauge,
+		Description: "Service up (1) or down (0)",
+	},
+}
+
+var metricCatalogIndex = func() map[MetricType]*MetricDefinition {
+	index := make(map[MetricType]*MetricDefinition)
+	for _, def := range metricCatalog {
+		index[def.Type] = def
+		for _, alias := range def.Aliases {
+			index[alias] = def
+		}
+	}
+	return index
+}()
+
+// MetricCatalog returns the canonical metric definitions in display order.
+func MetricCatalog() []*MetricDefinition {
+	defs := make([]*MetricDefinition, len(metricCatalog))
+	copy(defs, metricCatalog)
+	return defs
+}
+
+// LookupMetric returns the definition for a metric type or one of its aliases.
+func LookupMetric(metricType MetricType) (*MetricDefinition, bool) {
+	def, ok := metricCatalogIndex[metricType]
+	return def, ok
+}
+
+// CanonicalMetricType resolves aliases to the canonical metric type.
+// Unknown types are returned unchanged.
+func CanonicalMetricType(metricType MetricType) MetricType {
+	if def, ok := metricCatalogIndex[metricType]; ok {
+		return def.Type
+	}
+	return metricType
+}
+
+// StoredMetricTypes returns every metric type that may appear on the wire,
+// including legacy aliases, excluding derived metrics.
+func StoredMetricTypes() []MetricType {
+	var types []MetricType
+	for _, def := range metricCatalog {
+		if def.Derived() {
+			continue
+		}
+		types = append(types, def.Type)
+		types = append(types, def.Aliases...)
+	}
+	return types
+}
+
+// ValidateMetric validates a metric against its struct tags and the catalogue.
+// Unknown metric types, derived metric types and unit mismatches are rejected.
+func ValidateMetric(metric *ServiceMetric) error {
+	if err := Validate(metric); err != nil {
+		return err
+	}
+
+	def, ok := LookupMetric(metric.MetricType)
+	if !ok {
+		return fmt.Errorf("unknown metric type %q", metric.MetricType)
+	}
+	if def.Derived() {
+		return fmt.Errorf("metric type %q is derived from %q and cannot be published", def.Type, def.Source)
+	}
+	if metric.Unit != def.Unit {
+		return fmt.Errorf("metric type %q must use unit %q, got %q", def.Type, def.Unit, metric.Unit)
+	}
+	if math.IsNaN(metric.Value) || math.IsInf(metric.Value, 0) {
+		return fmt.Errorf("metric type %q has non-finite value", def.Type)
+	}
+	return nil
+}
+
+// NormalizeMetric validates a metric and rewrites aliased metric types to their canonical name.
+func NormalizeMetric(metric *ServiceMetric) error {
+	if err := ValidateMetric(metric); err != nil {
+		return err
+	}
+	metric.MetricType = CanonicalMetricType(metric.MetricType)
+	return nil
+}
+
+// GroupByMetricType groups samples by canonical metric type, preserving order.
+// Samples with unknown metric types are dropped.
+func GroupByMetricType(metrics []*ServiceMetric) map[MetricType][]*ServiceMetric {
+	series := make(map[MetricType][]*ServiceMetric)
+	for _, m := range metrics {
+		def, ok := LookupMetric(m.MetricType)
+		if !ok {
+			continue
+		}
+		series[def.Type] = append(series[def.Type], m)
+	}
+	return series
+}
+
+// SampleValues returns the values of a slice of samples.
+func SampleValues(metrics []*ServiceMetric) []float64 {
+	values := make([]float64, len(metrics))
+	for i, m := range metrics {
+		values[i] = m.Value
+	}
+	return values
+}
+
+// Quantile returns the q-quantile (0..1) of values using linear interpolation.
+func Quantile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	if q <= 0 {
+		return sorted[0]
+	}
+	if q >= 1 {
+		return sorted[len(sorted)-1]
+	}
+
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	frac := pos - float64(lower)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*frac
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// AlertEnrichment is context added to an alert before it is published, for
+// responders and notifications.
+type AlertEnrichment struct {
+	OwnerTeam    string `json:"owner_team,omitempty"`
+	RunbookURL   string `json:"runbook_url,omitempty"`
+	DashboardURL string `json:"dashboard_url,omitempty"`
+	// RecentLogs are the latest error log lines of the service, newest first.
+	RecentLogs []string `json:"recent_logs,omitempty"`
+	// RecentSamples are the latest samples of the alert's metric, oldest
+	// first, e.g. for sparklines.
+	RecentSamples []MetricSample `json:"recent_samples,omitempty"`
+}
+
+// MetricSample is a single value of a metric series.
+type MetricSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// ServiceCatalogEntry describes who owns a service and how to operate it.
+type ServiceCatalogEntry struct {
+	Team    string `json:"team"`
+	Runbook string `json:"runbook,omitempty"`
+	// Runbooks override Runbook for alerts on specific metrics.
+	Runbooks map[MetricType]string `json:"runbooks,omitempty"`
+}
+
+// RunbookFor returns the runbook for alerts on a metric of the service.
+func (e *ServiceCatalogEntry) RunbookFor(metricType MetricType) string {
+	if url, ok := e.Runbooks[metricType]; ok {
+		return url
+	}
+	return e.Runbook
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// descriptiveLabels are alert labels that describe rather than identify the
+// problem: renaming a rule, moving a service to another team or resolving
+// the alert must not change the identity of its alerts.
+var descriptiveLabels = map[string]bool{
+	"rule":  true,
+	"state": true,
+	"team":  true,
+}
+
+// AlertFingerprint identifies the problem an alert reports: a hash of the
+// rule ID and the sorted identifying labels, such as the service, metric,
+// alert type and group. Every firing of the same problem has the same
+// fingerprint, while its ID is unique per firing.
+func AlertFingerprint(ruleID string, labels Labels) string {
+	identifying := make(Labels, len(labels))
+	for k, v := range labels {
+		if !descriptiveLabels[k] {
+			identifying[k] = v
+		}
+	}
+
+	sum := sha256.Sum256([]byte(ruleID + "\x00" + identifying.Fingerprint()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// EnsureFingerprint sets the alert's fingerprint from its rule ID and labels
+// unless it already has one, and returns it. Alerts are fingerprinted before
+// enrichment adds descriptive labels.
+func (a *Alert) EnsureFingerprint() string {
+	if a.Fingerprint == "" {
+		a.Fingerprint = AlertFingerprint(a.RuleID, a.Labels)
+	}
+	return a.Fingerprint
+}
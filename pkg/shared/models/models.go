@@ -36,7 +36,7 @@ const (
 	MetricTypeLatency     MetricType = "latency"
 	MetricTypeLatencyP95  MetricType = "latency_p95"
 	MetricTypeLatencyP99  MetricType = "latency_p99"
-	MetricTypeError       MetricType = "error"
+	MetricTypeError       MetricType = "error" // Deprecated: alias of MetricTypeErrorRate
 	MetricTypeErrorRate   MetricType = "error_rate"
 	MetricTypeStatus      MetricType = "status"
 	MetricTypeRequestRate MetricType = "request_rate"
@@ -61,6 +61,7 @@ const (
 	StatusDegraded  ServiceStatus = "degraded"
 	StatusUnhealthy ServiceStatus = "unhealthy"
 	StatusUnknown   ServiceStatus = "unknown"
+	StatusStale     ServiceStatus = "stale"
 )
 
 // AlertSeverity represents the severity level of an alert.
@@ -72,6 +73,18 @@ const (
 	AlertSeverityCritical AlertSeverity = "critical"
 )
 
+// Rank orders severities from info (0) to critical (2); unknown severities rank lowest.
+func (s AlertSeverity) Rank() int {
+	switch s {
+	case AlertSeverityWarning:
+		return 1
+	case AlertSeverityCritical:
+		return 2
+	default:
+		return 0
+	}
+}
+
 // AlertType represents the type of anomaly detected.
 type AlertType string
 
@@ -81,6 +94,9 @@ const (
 	AlertTypeLatencySpike       AlertType = "latency_spike"
 	AlertTypeDeviationAnomaly   AlertType = "deviation_anomaly"
 	AlertTypeMovingAvgAnomaly   AlertType = "moving_avg_anomaly"
+	AlertTypeAbsentData         AlertType = "absent_data"
+	AlertTypeComposite          AlertType = "composite"
+	AlertTypeForecast           AlertType = "forecast"
 )
 
 // ServiceMetric represents a metric data point from a service.
@@ -89,7 +105,7 @@ type ServiceMetric struct {
 	ID          string      `json:"id" validate:"required,uuid"`
 	ServiceName ServiceName `json:"service_name" validate:"required"`
 	MetricType  MetricType  `json:"metric_type" validate:"required"`
-	Value       float64     `json:"value" validate:"required"`
+	Value       float64     `json:"value"`
 	Unit        string      `json:"unit" validate:"required"`
 	Timestamp   time.Time   `json:"timestamp" validate:"required"`
 	Labels      Labels      `json:"labels,omitempty"`
@@ -148,26 +164,30 @@ func NewServiceLog(serviceName ServiceName, level LogLevel, message string) *Ser
 
 // Alert represents an alert event generated by the Analyzer.
 type Alert struct {
-	ID             string        `json:"id" validate:"required,uuid"`
-	Type           AlertType     `json:"type" validate:"required"`
-	Severity       AlertSeverity `json:"severity" validate:"required"`
-	ServiceName    ServiceName   `json:"service_name" validate:"required"`
-	MetricType     MetricType    `json:"metric_type,omitempty"`
-	Title          string        `json:"title" validate:"required"`
-	Description    string        `json:"description" validate:"required"`
-	Message        string        `json:"message,omitempty"`
-	Value          float64       `json:"value"`
-	CurrentValue   float64       `json:"current_value,omitempty"`
-	Threshold      float64       `json:"threshold,omitempty"`
-	Timestamp      time.Time     `json:"timestamp" validate:"required"`
-	ResolvedAt     *time.Time    `json:"resolved_at,omitempty"`
-	Acknowledged   bool          `json:"acknowledged"`
-	AcknowledgedBy string        `json:"acknowledged_by,omitempty"`
-	AcknowledgedAt *time.Time    `json:"acknowledged_at,omitempty"`
-	Labels         Labels        `json:"labels,omitempty"`
-	MetricID       string        `json:"metric_id,omitempty"`
-	RuleID         string        `json:"rule_id,omitempty"`
-	TraceID        string        `json:"trace_id,omitempty"`
+	ID             string           `json:"id" validate:"required,uuid"`
+	Fingerprint    string           `json:"fingerprint,omitempty"` // stable across re-fires of the same problem
+	Type           AlertType        `json:"type" validate:"required"`
+	Severity       AlertSeverity    `json:"severity" validate:"required"`
+	ServiceName    ServiceName      `json:"service_name" validate:"required"`
+	MetricType     MetricType       `json:"metric_type,omitempty"`
+	Title          string           `json:"title" validate:"required"`
+	Description    string           `json:"description" validate:"required"`
+	Message        string           `json:"message,omitempty"`
+	Value          float64          `json:"value"`
+	CurrentValue   float64          `json:"current_value,omitempty"`
+	Threshold      float64          `json:"threshold,omitempty"`
+	Timestamp      time.Time        `json:"timestamp" validate:"required"`
+	ResolvedAt     *time.Time       `json:"resolved_at,omitempty"`
+	Acknowledged   bool             `json:"acknowledged"`
+	AcknowledgedBy string           `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time       `json:"acknowledged_at,omitempty"`
+	Labels         Labels           `json:"labels,omitempty"`
+	MetricID       string           `json:"metric_id,omitempty"`
+	RuleID         string           `json:"rule_id,omitempty"`
+	TraceID        string           `json:"trace_id,omitempty"`
+	PredictedAt    *time.Time       `json:"predicted_at,omitempty"` // forecast alerts: predicted threshold crossing
+	Confidence     float64          `json:"confidence,omitempty"`   // forecast alerts: fit quality from 0 to 1
+	Enrichment     *AlertEnrichment `json:"enrichment,omitempty"`   // context added before publish
 }
 
 // NewAlert creates a new Alert with a generated ID.
@@ -190,9 +210,9 @@ type ThresholdRule struct {
 	Name            string        `json:"name" validate:"required,min=1,max=100"`
 	Description     string        `json:"description,omitempty"`
 	ServiceName     ServiceName   `json:"service_name" validate:"required"`
-	MetricType      MetricType    `json:"metric_type" validate:"required"`
-	Operator        string        `json:"operator" validate:"required,oneof=> < >= <= == !="`
-	Threshold       float64       `json:"threshold" validate:"required"`
+	MetricType      MetricType    `json:"metric_type" validate:"required_without=Expression"`
+	Operator        string        `json:"operator" validate:"required_without=Expression,omitempty,oneof=> < >= <= == !="`
+	Threshold       float64       `json:"threshold"` // zero is valid, e.g. status == 0
 	Severity        AlertSeverity `json:"severity" validate:"required"`
 	WindowSize      int           `json:"window_size" validate:"min=1,max=3600"`   // in seconds
 	CooldownSec     int           `json:"cooldown_sec" validate:"min=0,max=86400"` // alert cooldown
@@ -203,6 +223,77 @@ type ThresholdRule struct {
 	NotifySlack     bool          `json:"notify_slack"`
 	NotifyEmail     bool          `json:"notify_email"`
 	NotifyWebhook   bool          `json:"notify_webhook"`
+	Source          RuleSource    `json:"source,omitempty"`      // who manages the rule
+	SourceFile      string        `json:"source_file,omitempty"` // rule file for file-managed rules
+
+	// Expression makes the rule a composite of other rules, e.g.
+	// "payments.latency_high AND NOT auth.status_healthy". Rules are referenced
+	// by ID or as service.name. Composite rules have no metric or threshold.
+	Expression string `json:"expression,omitempty"`
+	// DependsOn lists rules, referenced like in Expression, whose breach
+	// suppresses this rule's alerts, e.g. orders rules depending on "auth.auth_down".
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Aggregation reduces the metric series to the value compared with the
+	// threshold; empty means AggregationLast.
+	Aggregation RuleAggregation `json:"aggregation,omitempty"`
+	// ForecastHorizon is how far ahead, in seconds, a forecast rule looks for
+	// a threshold crossing.
+	ForecastHorizon int `json:"forecast_horizon,omitempty" validate:"min=0,max=604800"`
+	// MinConfidence ignores forecasts whose fit is below it (0 to 1).
+	MinConfidence float64 `json:"min_confidence,omitempty" validate:"min=0,max=1"`
+
+	// GroupBy evaluates the rule separately per combination of these metric
+	// labels, e.g. ["endpoint"] raises one alert per breaching endpoint.
+	GroupBy []string `json:"group_by,omitempty" validate:"max=5,dive,required"`
+
+	// Escalations raise the severity once the value crosses further
+	// thresholds, e.g. warning at 80 escalating to critical at 95. An
+	// escalation fires even while the rule is in cooldown.
+	Escalations []SeverityThreshold `json:"escalations,omitempty" validate:"max=2,dive"`
+}
+
+// SeverityThreshold is a threshold at which a rule fires with a higher severity.
+type SeverityThreshold struct {
+	Severity  AlertSeverity `json:"severity" validate:"required"`
+	Threshold float64       `json:"threshold"`
+}
+
+// RuleAggregation selects how a rule reduces its metric series to a value.
+type RuleAggregation string
+
+const (
+	// AggregationLast compares the latest sample, or the metric's quantile for derived metrics.
+	AggregationLast RuleAggregation = "last"
+	// AggregationForecastLinear fits a least-squares line to the series and
+	// fires if it is predicted to cross the threshold within the horizon.
+	AggregationForecastLinear RuleAggregation = "forecast_linear"
+	// AggregationForecastHolt forecasts with Holt's double exponential
+	// smoothing, which follows changes in trend faster than a line.
+	AggregationForecastHolt RuleAggregation = "forecast_holt"
+)
+
+// Forecast reports whether the rule fires on a predicted threshold crossing.
+func (r *ThresholdRule) Forecast() bool {
+	return r.Aggregation == AggregationForecastLinear || r.Aggregation == AggregationForecastHolt
+}
+
+// Composite reports whether the rule combines other rules instead of reading a metric.
+func (r *ThresholdRule) Composite() bool {
+	return r.Expression != ""
+}
+
+// RuleSource identifies who manages a threshold rule.
+type RuleSource string
+
+const (
+	RuleSourceAPI  RuleSource = "api"
+	RuleSourceFile RuleSource = "file"
+)
+
+// ReadOnly reports whether the rule is managed outside the API and must not be edited through it.
+func (r *ThresholdRule) ReadOnly() bool {
+	return r.Source == RuleSourceFile
 }
 
 // NewThresholdRule creates a new ThresholdRule with defaults.
@@ -224,6 +315,62 @@ func NewThresholdRule(name string, serviceName ServiceName, metricType MetricTyp
 	}
 }
 
+// Breached reports whether value violates the rule according to its operator.
+// Both symbolic (">") and dashboard ("gt") operator spellings are accepted.
+func (r *ThresholdRule) Breached(value float64) bool {
+	return crosses(r.Operator, value, r.Threshold)
+}
+
+// SeverityFor returns the severity a breaching value fires with: that of the
+// last escalation it crosses, or else the rule's own.
+func (r *ThresholdRule) SeverityFor(value float64) AlertSeverity {
+	severity := r.Severity
+	for _, escalation := range r.Escalations {
+		if crosses(r.Operator, value, escalation.Threshold) && escalation.Severity.Rank() > severity.Rank() {
+			severity = escalation.Severity
+		}
+	}
+	return severity
+}
+
+// ThresholdFor returns the threshold at which the rule fires with severity.
+func (r *ThresholdRule) ThresholdFor(severity AlertSeverity) float64 {
+	for _, escalation := range r.Escalations {
+		if escalation.Severity == severity {
+			return escalation.Threshold
+		}
+	}
+	return r.Threshold
+}
+
+// CooldownPeriod returns the rule's alert cooldown, or zero when it uses the
+// analyzer's default.
+func (r *ThresholdRule) CooldownPeriod() time.Duration {
+	if r.CooldownSec > 0 {
+		return time.Duration(r.CooldownSec) * time.Second
+	}
+	return time.Duration(r.CooldownSeconds) * time.Second
+}
+
+func crosses(operator string, value, threshold float64) bool {
+	switch operator {
+	case ">", "gt":
+		return value > threshold
+	case ">=", "gte":
+		return value >= threshold
+	case "<", "lt":
+		return value < threshold
+	case "<=", "lte":
+		return value <= threshold
+	case "==", "eq":
+		return value == threshold
+	case "!=", "ne":
+		return value != threshold
+	default:
+		return false
+	}
+}
+
 // ServiceHealthStatus represents the current health status of a service.
 type ServiceHealthStatus struct {
 	ServiceName   ServiceName   `json:"service_name"`
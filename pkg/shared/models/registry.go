@@ -0,0 +1,183 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// InstanceLabelKeys are the metric labels that identify a service instance.
+var InstanceLabelKeys = []string{"instance", "host", "pod"}
+
+// ServiceLabelKeys are the metric labels describing where a service runs.
+var ServiceLabelKeys = []string{"environment", "region", "zone"}
+
+// VersionLabelKey is the metric label carrying the version of the reporting service.
+const VersionLabelKey = "version"
+
+// ServiceRegistration describes a service discovered from the metric stream.
+type ServiceRegistration struct {
+	ServiceName ServiceName                 `json:"service_name"`
+	Version     string                      `json:"version,omitempty"`
+	Labels      Labels                      `json:"labels,omitempty"`
+	FirstSeen   time.Time                   `json:"first_seen"`
+	LastSeen    time.Time                   `json:"last_seen"`
+	Instances   map[string]*ServiceInstance `json:"instances,omitempty"`
+}
+
+// ServiceInstance describes a single instance of a discovered service.
+type ServiceInstance struct {
+	ID        string    `json:"id"`
+	Version   string    `json:"version,omitempty"`
+	Labels    Labels    `json:"labels,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// NewServiceRegistration creates an empty registration for a service.
+func NewServiceRegistration(serviceName ServiceName) *ServiceRegistration {
+	return &ServiceRegistration{
+		ServiceName: serviceName,
+		Labels:      make(Labels),
+		Instances:   make(map[string]*ServiceInstance),
+	}
+}
+
+// InstanceID derives a stable instance identifier from the instance labels.
+// It returns an empty string when none of the instance labels are present.
+func InstanceID(labels Labels) string {
+	parts := make([]string, 0, len(InstanceLabelKeys))
+	for _, key := range InstanceLabelKeys {
+		if value := labels[key]; value != "" {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Observe records a metric against the registration, updating first/last seen,
+// version and labels for the service and the reporting instance.
+func (r *ServiceRegistration) Observe(metric *ServiceMetric) {
+	seen := metric.Timestamp
+	if seen.IsZero() {
+		seen = time.Now().UTC()
+	}
+
+	if r.FirstSeen.IsZero() || seen.Before(r.FirstSeen) {
+		r.FirstSeen = seen
+	}
+	if seen.After(r.LastSeen) {
+		r.LastSeen = seen
+	}
+	if r.Labels == nil {
+		r.Labels = make(Labels)
+	}
+	if r.Instances == nil {
+		r.Instances = make(map[string]*ServiceInstance)
+	}
+
+	version := metric.Labels[VersionLabelKey]
+	if version != "" {
+		r.Version = version
+	}
+	for _, key := range ServiceLabelKeys {
+		if value := metric.Labels[key]; value != "" {
+			r.Labels[key] = value
+		}
+	}
+
+	id := InstanceID(metric.Labels)
+	if id == "" {
+		return
+	}
+
+	instance, exists := r.Instances[id]
+	if !exists {
+		instance = &ServiceInstance{
+			ID:        id,
+			Labels:    make(Labels),
+			FirstSeen: seen,
+		}
+		r.Instances[id] = instance
+	}
+	if seen.After(instance.LastSeen) {
+		instance.LastSeen = seen
+	}
+	if version != "" {
+		instance.Version = version
+	}
+	for _, keys := range [][]string{InstanceLabelKeys, ServiceLabelKeys} {
+		for _, key := range keys {
+			if value := metric.Labels[key]; value != "" {
+				instance.Labels[key] = value
+			}
+		}
+	}
+}
+
+// PruneInstances removes instances that have not reported since the cutoff.
+func (r *ServiceRegistration) PruneInstances(cutoff time.Time) {
+	for id, instance := range r.Instances {
+		if instance.LastSeen.Before(cutoff) {
+			delete(r.Instances, id)
+		}
+	}
+}
+
+// Merge folds a registration observed elsewhere into r, such as the
+// registrations a replica observed since it last wrote the registry. The most
+// recently seen version and labels win. other is not modified.
+func (r *ServiceRegistration) Merge(other *ServiceRegistration) {
+	newer := !other.LastSeen.Before(r.LastSeen)
+
+	if r.FirstSeen.IsZero() || (!other.FirstSeen.IsZero() && other.FirstSeen.Before(r.FirstSeen)) {
+		r.FirstSeen = other.FirstSeen
+	}
+	if other.LastSeen.After(r.LastSeen) {
+		r.LastSeen = other.LastSeen
+	}
+	if r.Labels == nil {
+		r.Labels = make(Labels)
+	}
+	if r.Instances == nil {
+		r.Instances = make(map[string]*ServiceInstance)
+	}
+
+	if newer {
+		if other.Version != "" {
+			r.Version = other.Version
+		}
+		for key, value := range other.Labels {
+			r.Labels[key] = value
+		}
+	}
+
+	for id, observed := range other.Instances {
+		instance, exists := r.Instances[id]
+		if !exists {
+			instance = &ServiceInstance{
+				ID:        id,
+				Labels:    make(Labels),
+				FirstSeen: observed.FirstSeen,
+			}
+			r.Instances[id] = instance
+		}
+		if instance.Labels == nil {
+			instance.Labels = make(Labels)
+		}
+		if observed.FirstSeen.Before(instance.FirstSeen) {
+			instance.FirstSeen = observed.FirstSeen
+		}
+		if observed.LastSeen.Before(instance.LastSeen) {
+			continue
+		}
+		instance.LastSeen = observed.LastSeen
+		if observed.Version != "" {
+			instance.Version = observed.Version
+		}
+		for key, value := range observed.Labels {
+			instance.Labels[key] = value
+		}
+	}
+}
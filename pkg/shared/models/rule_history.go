@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// RuleAction is the kind of mutation recorded in a rule version.
+type RuleAction string
+
+const (
+	RuleActionCreate   RuleAction = "create"
+	RuleActionUpdate   RuleAction = "update"
+	RuleActionDelete   RuleAction = "delete"
+	RuleActionRollback RuleAction = "rollback"
+)
+
+// RuleVersion is one entry in the audit history of a threshold rule.
+// Versions of a rule are numbered from 1 without gaps.
+type RuleVersion struct {
+	RuleID     string         `json:"rule_id"`
+	Version    int            `json:"version"`
+	Action     RuleAction     `json:"action"`
+	Author     string         `json:"author"`
+	Timestamp  time.Time      `json:"timestamp"`
+	Rule       *ThresholdRule `json:"rule"`                  // rule after the change; the deleted rule for deletes
+	Changes    []RuleChange   `json:"changes"`               // changes from the previous version
+	RollbackTo int            `json:"rollback_to,omitempty"` // version restored by a rollback
+}
+
+// State returns the rule as it existed after this version, or nil if the
+// version deleted it.
+func (v *RuleVersion) State() *ThresholdRule {
+	if v == nil || v.Action == RuleActionDelete {
+		return nil
+	}
+	return v.Rule
+}
+
+// RuleChange is a single field changed between two rule versions.
+type RuleChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// ruleDiffIgnored lists the fields that change on every write and are left out of diffs.
+var ruleDiffIgnored = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// DiffRules returns the fields that differ between two rules, keyed by their
+// JSON names and sorted by field. A nil rule diffs as a rule with no fields,
+// so creates and deletes list every field.
+func DiffRules(from, to *ThresholdRule) []RuleChange {
+	a, b := ruleFields(from), ruleFields(to)
+
+	fields := make(map[string]bool, len(a)+len(b))
+	for field := range a {
+		fields[field] = true
+	}
+	for field := range b {
+		fields[field] = true
+	}
+
+	changes := make([]RuleChange, 0)
+	for field := range fields {
+		if ruleDiffIgnored[field] || reflect.DeepEqual(a[field], b[field]) {
+			continue
+		}
+		changes = append(changes, RuleChange{Field: field, Old: a[field], New: b[field]})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+func ruleFields(rule *ThresholdRule) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if rule == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+
+	return fields
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaNamespace is the Avro namespace and Protobuf package of the platform events.
+const SchemaNamespace = "platform.events"
+
+// FieldType is the type of an event schema field. Each maps to an Avro and
+// a Protobuf type, so one schema describes an event in every encoding.
+type FieldType string
+
+const (
+	FieldString    FieldType = "string"
+	FieldLong      FieldType = "long"
+	FieldDouble    FieldType = "double"
+	FieldBool      FieldType = "bool"
+	FieldTimestamp FieldType = "timestamp" // RFC 3339 in JSON, microseconds since the epoch otherwise
+	FieldLabels    FieldType = "labels"    // string to string map
+	FieldJSON      FieldType = "json"      // nested value carried as a JSON string
+)
+
+// SchemaField is a field of an event schema.
+type SchemaField struct {
+	Name   string    `json:"name"`   // JSON name of the model field
+	Number int       `json:"number"` // Protobuf field number
+	Type   FieldType `json:"type"`
+}
+
+// Schema describes an event sent over Kafka. Fields are optional in every
+// encoding, so consumers of older and newer versions read each other's
+// events as long as fields keep their names, numbers and types.
+type Schema struct {
+	Name   string        `json:"name"`
+	Fields []SchemaField `json:"fields"`
+	// Reserved are removed fields. Their names and numbers are never reused,
+	// so old events are never misread.
+	Reserved []SchemaField `json:"reserved,omitempty"`
+
+	model reflect.Type
+}
+
+// Field returns the field with the given name.
+func (s *Schema) Field(name string) (SchemaField, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return SchemaField{}, false
+}
+
+// FieldByNumber returns the field with the given Protobuf number.
+func (s *Schema) FieldByNumber(number int) (SchemaField, bool) {
+	for _, f := range s.Fields {
+		if f.Number == number {
+			return f, true
+		}
+	}
+	return SchemaField{}, false
+}
+
+// ServiceMetricSchema is the schema of ServiceMetric events.
+var ServiceMetricSchema = &Schema{
+	Name: "ServiceMetric",
+	Fields: []SchemaField{
+		{Name: "id", Number: 1, Type: FieldString},
+		{Name: "service_name", Number: 2, Type: FieldString},
+		{Name: "metric_type", Number: 3, Type: FieldString},
+		{Name: "value", Number: 4, Type: FieldDouble},
+		{Name: "unit", Number: 5, Type: FieldString},
+		{Name: "timestamp", Number: 6, Type: FieldTimestamp},
+		{Name: "labels", Number: 7, Type: FieldLabels},
+		{Name: "trace_id", Number: 8, Type: FieldString},
+		{Name: "span_id", Number: 9, Type: FieldString},
+		{Name: "cpu_usage", Number: 10, Type: FieldDouble},
+		{Name: "memory_usage", Number: 11, Type: FieldDouble},
+		{Name: "latency_p50", Number: 12, Type: FieldDouble},
+		{Name: "latency_p95", Number: 13, Type: FieldDouble},
+		{Name: "latency_p99", Number: 14, Type: FieldDouble},
+		{Name: "error_rate", Number: 15, Type: FieldDouble},
+		{Name: "request_count", Number: 16, Type: FieldDouble},
+	},
+	model: reflect.TypeOf(ServiceMetric{}),
+}
+
+// ServiceLogSchema is the schema of ServiceLog events.
+var ServiceLogSchema = &Schema{
+	Name: "ServiceLog",
+	Fields: []SchemaField{
+		{Name: "id", Number: 1, Type: FieldString},
+		{Name: "service_name", Number: 2, Type: FieldString},
+		{Name: "level", Number: 3, Type: FieldString},
+		{Name: "message", Number: 4, Type: FieldString},
+		{Name: "timestamp", Number: 5, Type: FieldTimestamp},
+		{Name: "caller", Number: 6, Type: FieldString},
+		{Name: "stack_trace", Number: 7, Type: FieldString},
+		{Name: "fields", Number: 8, Type: FieldJSON},
+		{Name: "trace_id", Number: 9, Type: FieldString},
+		{Name: "span_id", Number: 10, Type: FieldString},
+		{Name: "request_id", Number: 11, Type: FieldString},
+	},
+	model: reflect.TypeOf(ServiceLog{}),
+}
+
+// AlertSchema is the schema of Alert events.
+var AlertSchema = &Schema{
+	Name: "Alert",
+	Fields: []SchemaField{
+		{Name: "id", Number: 1, Type: FieldString},
+		{Name: "fingerprint", Number: 2, Type: FieldString},
+		{Name: "type", Number: 3, Type: FieldString},
+		{Name: "severity", Number: 4, Type: FieldString},
+		{Name: "service_name", Number: 5, Type: FieldString},
+		{Name: "metric_type", Number: 6, Type: FieldString},
+		{Name: "title", Number: 7, Type: FieldString},
+		{Name: "description", Number: 8, Type: FieldString},
+		{Name: "message", Number: 9, Type: FieldString},
+		{Name: "value", Number: 10, Type: FieldDouble},
+		{Name: "current_value", Number: 11, Type: FieldDouble},
+		{Name: "threshold", Number: 12, Type: FieldDouble},
+		{Name: "timestamp", Number: 13, Type: FieldTimestamp},
+		{Name: "resolved_at", Number: 14, Type: FieldTimestamp},
+		{Name: "acknowledged", Number: 15, Type: FieldBool},
+		{Name: "acknowledged_by", Number: 16, Type: FieldString},
+		{Name: "acknowledged_at", Number: 17, Type: FieldTimestamp},
+		{Name: "labels", Number: 18, Type: FieldLabels},
+		{Name: "metric_id", Number: 19, Type: FieldString},
+		{Name: "rule_id", Number: 20, Type: FieldString},
+		{Name: "trace_id", Number: 21, Type: FieldString},
+		{Name: "predicted_at", Number: 22, Type: FieldTimestamp},
+		{Name: "confidence", Number: 23, Type: FieldDouble},
+		{Name: "enrichment", Number: 24, Type: FieldJSON},
+	},
+	model: reflect.TypeOf(Alert{}),
+}
+
+// Schemas returns the schemas of all platform events.
+func Schemas() []*Schema {
+	return []*Schema{ServiceMetricSchema, ServiceLogSchema, AlertSchema}
+}
+
+// SchemaFor returns the schema of an event value or pointer.
+func SchemaFor(v interface{}) (*Schema, bool) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, s := range Schemas() {
+		if s.model == t {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// CheckModel reports the differences between the schema and the JSON
+// fields of its model, e.g. a model field added without a schema field.
+func (s *Schema) CheckModel() error {
+	if s.model == nil {
+		return nil
+	}
+
+	modelFields := make(map[string]bool)
+	for i := 0; i < s.model.NumField(); i++ {
+		name := strings.Split(s.model.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		modelFields[name] = true
+		if _, ok := s.Field(name); !ok {
+			return fmt.Errorf("%s: model field %q is missing from the schema", s.Name, name)
+		}
+	}
+	for _, f := range s.Fields {
+		if !modelFields[f.Name] {
+			return fmt.Errorf("%s: schema field %q is not in the model; move it to the reserved fields", s.Name, f.Name)
+		}
+	}
+	return nil
+}
+
+// CheckCompatibility reports the changes from old to s that break consumers
+// of either version: removed fields that are not reserved, changed field
+// types and numbers, and reused names or numbers of reserved fields.
+func (s *Schema) CheckCompatibility(old *Schema) []string {
+	problems := make(map[string]bool)
+
+	current := make(map[string]SchemaField)
+	numbers := make(map[int]SchemaField)
+	for _, f := range append(append([]SchemaField{}, s.Fields...), s.Reserved...) {
+		if prev, ok := numbers[f.Number]; ok && prev.Name != f.Name {
+			problems[fmt.Sprintf("fields %q and %q share number %d", prev.Name, f.Name, f.Number)] = true
+		}
+		current[f.Name] = f
+		numbers[f.Number] = f
+	}
+
+	for _, f := range append(append([]SchemaField{}, old.Fields...), old.Reserved...) {
+		cur, ok := current[f.Name]
+		switch {
+		case !ok:
+			problems[fmt.Sprintf("field %q was removed; reserve it instead", f.Name)] = true
+		case cur.Number != f.Number:
+			problems[fmt.Sprintf("field %q changed number from %d to %d", f.Name, f.Number, cur.Number)] = true
+		case cur.Type != f.Type:
+			problems[fmt.Sprintf("field %q changed type from %s to %s", f.Name, f.Type, cur.Type)] = true
+		}
+		if byNumber, ok := numbers[f.Number]; ok && byNumber.Name != f.Name {
+			problems[fmt.Sprintf("number %d of field %q is reused by %q", f.Number, f.Name, byNumber.Name)] = true
+		}
+	}
+
+	for _, f := range old.Reserved {
+		if _, ok := s.Field(f.Name); ok {
+			problems[fmt.Sprintf("reserved field %q is used again", f.Name)] = true
+		}
+	}
+
+	result := make([]string, 0, len(problems))
+	for problem := range problems {
+		result = append(result, problem)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fingerprint returns a canonical form of the label set, identical for equal
+// sets regardless of map order, e.g. `endpoint="/pay",host="a"`.
+func (l Labels) Fingerprint() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[k]))
+	}
+	return b.String()
+}
+
+// Project returns the subset of the label set with the given keys. Missing
+// labels are kept with an empty value, so every sample belongs to a group.
+func (l Labels) Project(keys []string) Labels {
+	projected := make(Labels, len(keys))
+	for _, k := range keys {
+		projected[k] = l[k]
+	}
+	return projected
+}
+
+// SeriesID identifies a metric series of a service by its metric type and
+// label set, e.g. `latency{endpoint="/pay"}`. A series without labels is
+// identified by its metric type alone.
+func SeriesID(metricType MetricType, labels Labels) string {
+	fingerprint := labels.Fingerprint()
+	if fingerprint == "" {
+		return string(metricType)
+	}
+	return string(metricType) + "{" + fingerprint + "}"
+}
+
+// SeriesInfo describes a labelled series of a service metric.
+type SeriesInfo struct {
+	ID       string     `json:"id"`
+	Metric   MetricType `json:"metric"`
+	Labels   Labels     `json:"labels"`
+	LastSeen time.Time  `json:"last_seen"`
+}
+
+// SeriesIndexKey is the Redis sorted set indexing the label sets seen for a
+// service metric: members are the JSON label sets, scores the Unix time they
+// were last seen.
+func SeriesIndexKey(serviceName ServiceName, metricType MetricType) string {
+	return "metrics:series:" + string(serviceName) + ":" + string(metricType)
+}
@@ -0,0 +1,94 @@
+// Package rulestore writes threshold rules and their version history to
+// Redis. The analyzer and the ui-backend both write rules, so the layout of
+// the keys below is a contract between them.
+package rulestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/utils"
+)
+
+// RulesKey is the hash of rules by ID the analyzer evaluates.
+const RulesKey = "analyzer:rules"
+
+// VersionsKey is the list holding the version history of a rule, oldest first.
+func VersionsKey(ruleID string) string {
+	return fmt.Sprintf("%s:versions:%s", RulesKey, ruleID)
+}
+
+// MaxWriteRetries bounds the retries of a rule write racing another writer.
+const MaxWriteRetries = 5
+
+// Write applies a rule mutation and appends it to the rule's version history
+// in one transaction. The version number, timestamp and changes are filled
+// in from the previous version.
+func Write(ctx context.Context, client *redis.Client, version *models.RuleVersion) error {
+	rule := version.Rule
+	key := VersionsKey(rule.ID)
+
+	txf := func(tx *redis.Tx) error {
+		var previous *models.RuleVersion
+		last, err := tx.LIndex(ctx, key, -1).Result()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("failed to get rule history: %w", err)
+		}
+		if err == nil {
+			previous = &models.RuleVersion{}
+			if err := json.Unmarshal([]byte(last), previous); err != nil {
+				return fmt.Errorf("failed to deserialize rule version: %w", err)
+			}
+		}
+
+		version.RuleID = rule.ID
+		version.Version = 1
+		version.Timestamp = time.Now().UTC()
+		if previous != nil {
+			version.Version = previous.Version + 1
+		}
+		if version.Action == models.RuleActionDelete {
+			version.Changes = models.DiffRules(rule, nil)
+		} else {
+			version.Changes = models.DiffRules(previous.State(), rule)
+		}
+
+		ruleData, err := rule.ToJSON()
+		if err != nil {
+			return fmt.Errorf("failed to serialize rule: %w", err)
+		}
+		versionData, err := json.Marshal(version)
+		if err != nil {
+			return fmt.Errorf("failed to serialize rule version: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if version.Action == models.RuleActionDelete {
+				pipe.HDel(ctx, RulesKey, rule.ID)
+			} else {
+				pipe.HSet(ctx, RulesKey, rule.ID, string(ruleData))
+			}
+			pipe.RPush(ctx, key, string(versionData))
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < MaxWriteRetries; i++ {
+		err := client.Watch(ctx, txf, key)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write rule: %w", err)
+		}
+		return nil
+	}
+
+	return utils.ErrConflict(fmt.Sprintf("rule %s was modified concurrently", rule.ID))
+}
@@ -0,0 +1,175 @@
+package rulestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+func testClient(t *testing.T) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// history returns the stored version history of a rule.
+func history(t *testing.T, client *redis.Client, ruleID string) []*models.RuleVersion {
+	t.Helper()
+	results, err := client.LRange(context.Background(), VersionsKey(ruleID), 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make([]*models.RuleVersion, 0, len(results))
+	for _, r := range results {
+		var v models.RuleVersion
+		if err := json.Unmarshal([]byte(r), &v); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, &v)
+	}
+	return versions
+}
+
+// stored returns the rule the analyzer evaluates, or nil if there is none.
+func stored(t *testing.T, client *redis.Client, ruleID string) *models.ThresholdRule {
+	t.Helper()
+	data, err := client.HGet(context.Background(), RulesKey, ruleID).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rule models.ThresholdRule
+	if err := json.Unmarshal([]byte(data), &rule); err != nil {
+		t.Fatal(err)
+	}
+	return &rule
+}
+
+func fields(changes []models.RuleChange) []string {
+	names := make([]string, 0, len(changes))
+	for _, c := range changes {
+		names = append(names, c.Field)
+	}
+	return names
+}
+
+func TestWriteHistory(t *testing.T) {
+	ctx := context.Background()
+	client := testClient(t)
+
+	rule := &models.ThresholdRule{
+		ID:          "error-rate",
+		Name:        "High error rate",
+		ServiceName: models.ServiceOrders,
+		MetricType:  models.MetricTypeErrorRate,
+		Operator:    ">",
+		Threshold:   0.05,
+		Severity:    models.AlertSeverityWarning,
+		WindowSize:  60,
+		Enabled:     true,
+	}
+	created := *rule
+	if err := Write(ctx, client, &models.RuleVersion{Action: models.RuleActionCreate, Author: "alice", Rule: &created}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := *rule
+	updated.Threshold = 0.1
+	updated.Severity = models.AlertSeverityCritical
+	if err := Write(ctx, client, &models.RuleVersion{Action: models.RuleActionUpdate, Author: "bob", Rule: &updated}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(t, client, rule.ID); got.Threshold != 0.1 || got.Severity != models.AlertSeverityCritical {
+		t.Errorf("stored %+v after the update, want the updated rule", got)
+	}
+
+	// Roll back to the created version the way the ui-backend does
+	restored := *history(t, client, rule.ID)[0].State()
+	rollback := &models.RuleVersion{Action: models.RuleActionRollback, Author: "alice", Rule: &restored, RollbackTo: 1}
+	if err := Write(ctx, client, rollback); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(t, client, rule.ID); got.Threshold != 0.05 || got.Severity != models.AlertSeverityWarning {
+		t.Errorf("stored %+v after the rollback, want the created rule", got)
+	}
+
+	if err := Write(ctx, client, &models.RuleVersion{Action: models.RuleActionDelete, Author: "bob", Rule: stored(t, client, rule.ID)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(t, client, rule.ID); got != nil {
+		t.Errorf("stored %+v after the delete, want none", got)
+	}
+
+	versions := history(t, client, rule.ID)
+	want := []struct {
+		action  models.RuleAction
+		author  string
+		changes []string
+	}{
+		{models.RuleActionCreate, "alice", fields(models.DiffRules(nil, rule))},
+		{models.RuleActionUpdate, "bob", []string{"severity", "threshold"}},
+		{models.RuleActionRollback, "alice", []string{"severity", "threshold"}},
+		// The history of a deleted rule is kept
+		{models.RuleActionDelete, "bob", fields(models.DiffRules(rule, nil))},
+	}
+	if len(versions) != len(want) {
+		t.Fatalf("%d versions, want %d", len(versions), len(want))
+	}
+	for i, w := range want {
+		v := versions[i]
+		if v.RuleID != rule.ID || v.Version != i+1 || v.Action != w.action || v.Author != w.author {
+			t.Errorf("version %d is %s %d %s by %s, want %s %d %s by %s",
+				i, v.RuleID, v.Version, v.Action, v.Author, rule.ID, i+1, w.action, w.author)
+		}
+		if got := fields(v.Changes); fmt.Sprint(got) != fmt.Sprint(w.changes) {
+			t.Errorf("version %d changed %v, want %v", v.Version, got, w.changes)
+		}
+		if v.Timestamp.IsZero() {
+			t.Errorf("version %d has no timestamp", v.Version)
+		}
+	}
+	if versions[2].RollbackTo != 1 {
+		t.Errorf("rollback restored version %d, want 1", versions[2].RollbackTo)
+	}
+	if c := versions[2].Changes[1]; c.Old != 0.1 || c.New != 0.05 {
+		t.Errorf("rollback changed the threshold from %v to %v, want 0.1 to 0.05", c.Old, c.New)
+	}
+	if versions[3].State() != nil {
+		t.Error("delete version has a state")
+	}
+}
+
+// TestWriteRecreatesDeletedRule checks that a rule written after its delete
+// diffs against no rule and continues the numbering of its history.
+func TestWriteRecreatesDeletedRule(t *testing.T) {
+	ctx := context.Background()
+	client := testClient(t)
+	rule := &models.ThresholdRule{ID: "latency", Name: "Latency", Threshold: 500}
+
+	for _, action := range []models.RuleAction{models.RuleActionCreate, models.RuleActionDelete, models.RuleActionRollback} {
+		r := *rule
+		if err := Write(ctx, client, &models.RuleVersion{Action: action, Rule: &r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions := history(t, client, rule.ID)
+	if len(versions) != 3 || versions[2].Version != 3 {
+		t.Fatalf("history %+v, want 3 versions", versions)
+	}
+	if got, want := fields(versions[2].Changes), fields(models.DiffRules(nil, rule)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("recreation changed %v, want every field %v", got, want)
+	}
+	if got := stored(t, client, rule.ID); got == nil || got.Threshold != 500 {
+		t.Errorf("stored %+v, want the recreated rule", got)
+	}
+}
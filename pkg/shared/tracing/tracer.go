@@ -5,6 +5,7 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -53,8 +54,21 @@ type Provider interface {
 	Tracer() trace.Tracer
 }
 
+// InitPropagation sets the global propagator to W3C trace context and
+// baggage. Services call it at startup whether or not they export traces,
+// so the trace context of HTTP requests and Kafka messages passes through
+// them intact.
+func InitPropagation() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
 // NewTracer creates a new Tracer instance.
 func NewTracer(cfg *Config) (*Tracer, error) {
+	InitPropagation()
+
 	if !cfg.Enabled {
 		return &Tracer{
 			tracer: otel.Tracer(cfg.ServiceName),
@@ -109,12 +123,8 @@ func NewTracer(cfg *Config) (*Tracer, error) {
 		sdktrace.WithSampler(sampler),
 	)
 
-	// Set global trace provider and propagator
+	// Set global trace provider
 	otel.SetTracerProvider(provider)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	))
 
 	return &Tracer{
 		tracer:   provider.Tracer(cfg.ServiceName),
@@ -187,6 +197,26 @@ func GetSpanID(ctx context.Context) string {
 	return ""
 }
 
+// InjectMap returns the trace context and baggage of ctx as propagation
+// fields, e.g. to keep them with work that is handled later.
+func InjectMap(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractMap returns ctx with the trace context and baggage of fields
+// returned by InjectMap.
+func ExtractMap(ctx context.Context, fields map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(fields))
+}
+
+// InjectHTTPHeaders adds the trace context and baggage of ctx to outgoing
+// HTTP request headers.
+func InjectHTTPHeaders(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
 // Attribute helpers for common attributes.
 
 // ServiceAttr creates a service name attribute.
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// KafkaSecurityConfig holds the settings services use to authenticate to
+// Kafka, loaded the same way by every service.
+type KafkaSecurityConfig struct {
+	// SASLMechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; empty disables SASL.
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+
+	// TLS is enabled by TLSEnabled or by any of the PEM files. With a
+	// certificate and key the client authenticates with them (mTLS).
+	TLSEnabled            bool
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+}
+
+// LoadKafkaSecurityConfig loads the Kafka security settings from the
+// KAFKA_SASL_* and KAFKA_TLS_* environment variables.
+func LoadKafkaSecurityConfig() KafkaSecurityConfig {
+	return KafkaSecurityConfig{
+		SASLMechanism:         GetEnv("KAFKA_SASL_MECHANISM", ""),
+		SASLUsername:          GetEnv("KAFKA_SASL_USERNAME", ""),
+		SASLPassword:          GetEnv("KAFKA_SASL_PASSWORD", ""),
+		TLSEnabled:            GetEnvBool("KAFKA_TLS_ENABLED", false),
+		TLSCAFile:             GetEnv("KAFKA_TLS_CA_FILE", ""),
+		TLSCertFile:           GetEnv("KAFKA_TLS_CERT_FILE", ""),
+		TLSKeyFile:            GetEnv("KAFKA_TLS_KEY_FILE", ""),
+		TLSInsecureSkipVerify: GetEnvBool("KAFKA_TLS_INSECURE_SKIP_VERIFY", false),
+	}
+}
+
+// TLSConfig builds the TLS configuration from the PEM files. It returns nil
+// when TLS is disabled.
+func (c KafkaSecurityConfig) TLSConfig() (*tls.Config, error) {
+	if !c.TLSEnabled && c.TLSCAFile == "" && c.TLSCertFile == "" && c.TLSKeyFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS client certificate and key must be set together")
+	}
+	if c.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// KafkaTopicsConfig holds how services verify their topics on startup.
+type KafkaTopicsConfig struct {
+	// Create creates missing topics instead of only reporting them.
+	Create bool
+	// ReplicationFactor is the declared replication factor of the topics.
+	ReplicationFactor int
+}
+
+// LoadKafkaTopicsConfig loads the topic settings from the
+// KAFKA_CREATE_TOPICS and KAFKA_REPLICATION_FACTOR environment variables.
+func LoadKafkaTopicsConfig() KafkaTopicsConfig {
+	return KafkaTopicsConfig{
+		Create:            GetEnvBool("KAFKA_CREATE_TOPICS", true),
+		ReplicationFactor: GetEnvInt("KAFKA_REPLICATION_FACTOR", 1),
+	}
+}
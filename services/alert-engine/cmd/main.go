@@ -3,17 +3,19 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
+	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/metrics"
+	"github.com/microservices-platform/pkg/shared/tracing"
 	"github.com/microservices-platform/services/alert-engine/internal/config"
 	"github.com/microservices-platform/services/alert-engine/internal/core"
 	"github.com/microservices-platform/services/alert-engine/internal/dispatchers"
@@ -42,6 +44,29 @@ func main() {
 		zap.String("version", cfg.Version),
 	)
 
+	// Initialize tracing; trace context propagates even when not exported
+	tracing.InitPropagation()
+	if cfg.TracingEnabled {
+		tracingConfig := &tracing.Config{
+			ServiceName:    cfg.ServiceName,
+			ServiceVersion: cfg.Version,
+			Environment:    cfg.Environment,
+			Endpoint:       cfg.TracingEndpoint,
+			SampleRate:     cfg.SampleRate,
+			Enabled:        true,
+		}
+		tracer, err := tracing.NewTracer(tracingConfig)
+		if err != nil {
+			logger.Warn("failed to initialize tracing", zap.Error(err))
+		} else {
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				tracer.Shutdown(ctx)
+			}()
+		}
+	}
+
 	// Initialize metrics
 	m := metrics.NewMetrics(cfg.ServiceName)
 
@@ -90,6 +115,7 @@ func main() {
 		MaxAlertsPerGroup:        cfg.MaxAlertsPerGroup,
 		BatchSize:                cfg.BatchSize,
 		BatchTimeout:             cfg.BatchTimeout,
+		DedupWindowSeconds:       cfg.DedupWindowSeconds,
 	}
 
 	// Check if Kafka is available
@@ -101,12 +127,24 @@ func main() {
 	}
 
 	if kafkaAvailable {
+		security, err := sharedkafka.NewSecurity(cfg.KafkaSecurity)
+		if err != nil {
+			logger.Fatal("invalid Kafka security configuration", zap.Error(err))
+		}
+		broker := sharedkafka.NewBroker(cfg.KafkaBrokers, security)
+		// Verify the topics the service uses
+		topicSpecs := sharedkafka.TopicSpecsFor(cfg.KafkaTopics.ReplicationFactor, cfg.AlertsTopic, cfg.DLQTopic)
+		if _, err := broker.EnsureTopics(context.Background(), topicSpecs, cfg.KafkaTopics.Create, logger); err != nil {
+			logger.Fatal("invalid Kafka topics", zap.Error(err))
+		}
+
 		kafkaProcessor, err := core.NewAlertProcessor(
 			processorConfig,
-			cfg.KafkaBrokers,
+			broker,
 			cfg.AlertsTopic,
 			cfg.DLQTopic,
 			cfg.ConsumerGroup,
+			sharedkafka.NewDecoder(sharedkafka.NewSchemaRegistry(cfg.SchemaRegistryURL)),
 			dispatcherList,
 			logger,
 			m,
@@ -136,7 +174,7 @@ func main() {
 	// Start metrics HTTP server
 	metricsServer := &http.Server{
 		Addr:    cfg.MetricsAddr,
-		Handler: promhttp.Handler(),
+		Handler: m.Handler(),
 	}
 
 	go func() {
@@ -154,6 +192,15 @@ func main() {
 		w.Write([]byte(`{"status":"healthy","service":"alert-engine"}`))
 	})
 	healthMux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		// Not ready while a consumer is too far behind
+		if cfg.ReadyMaxLag > 0 {
+			if lag, topic := m.MaxKafkaConsumerLag(); lag > cfg.ReadyMaxLag {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, `{"status":"not_ready","reason":"kafka_lag","topic":%q,"lag":%d}`, topic, lag)
+				return
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ready","service":"alert-engine"}`))
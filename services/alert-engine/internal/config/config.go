@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/microservices-platform/pkg/shared/utils"
 )
 
 // Config holds the configuration for the alert-engine service.
@@ -21,6 +23,12 @@ type Config struct {
 	AlertsTopic   string
 	DLQTopic      string
 	ConsumerGroup string
+	// Decodes Avro and Protobuf alerts when set
+	SchemaRegistryURL string
+	KafkaSecurity     utils.KafkaSecurityConfig
+	KafkaTopics       utils.KafkaTopicsConfig
+	// Readiness fails above this consumer lag; 0 disables
+	ReadyMaxLag int64
 
 	// Server ports
 	MetricsAddr string
@@ -53,6 +61,12 @@ type Config struct {
 	GroupingWindowSeconds    int
 	SuppressionWindowSeconds int
 	MaxAlertsPerGroup        int
+	DedupWindowSeconds       int
+
+	// Tracing
+	TracingEnabled  bool
+	TracingEndpoint string
+	SampleRate      float64
 }
 
 // LoadConfig loads configuration from environment variables.
@@ -68,6 +82,11 @@ func LoadConfig() *Config {
 		DLQTopic:      getEnv("DLQ_TOPIC", "alerts-dlq"),
 		ConsumerGroup: getEnv("CONSUMER_GROUP", "alert-engine-group"),
 
+		SchemaRegistryURL: getEnv("SCHEMA_REGISTRY_URL", ""),
+		KafkaSecurity:     utils.LoadKafkaSecurityConfig(),
+		KafkaTopics:       utils.LoadKafkaTopicsConfig(),
+		ReadyMaxLag:       int64(getEnvInt("READY_MAX_KAFKA_LAG", 0)),
+
 		MetricsAddr: ":" + getEnv("METRICS_PORT", "9094"),
 		HealthAddr:  ":" + getEnv("HEALTH_PORT", "8084"),
 
@@ -98,6 +117,12 @@ func LoadConfig() *Config {
 		GroupingWindowSeconds:    getEnvInt("GROUPING_WINDOW_SECONDS", 60),
 		SuppressionWindowSeconds: getEnvInt("SUPPRESSION_WINDOW_SECONDS", 300),
 		MaxAlertsPerGroup:        getEnvInt("MAX_ALERTS_PER_GROUP", 10),
+		DedupWindowSeconds:       getEnvInt("DEDUP_WINDOW_SECONDS", 3600),
+
+		// Tracing
+		TracingEnabled:  getEnvBool("TRACING_ENABLED", false),
+		TracingEndpoint: getEnv("TRACING_ENDPOINT", "localhost:4317"),
+		SampleRate:      getEnvFloat("TRACING_SAMPLE_RATE", 1.0),
 	}
 }
 
@@ -117,6 +142,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if b, err := strconv.ParseBool(value); err == nil {
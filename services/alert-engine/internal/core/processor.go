@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/metrics"
 	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/tracing"
 	"github.com/microservices-platform/services/alert-engine/internal/ports"
 )
 
@@ -28,6 +30,9 @@ type ProcessorConfig struct {
 	MaxAlertsPerGroup        int
 	BatchSize                int
 	BatchTimeout             time.Duration
+	// DedupWindowSeconds is how long alert message IDs are remembered to
+	// skip duplicates; 0 disables deduplication.
+	DedupWindowSeconds int
 }
 
 // DefaultProcessorConfig returns the default configuration.
@@ -40,14 +45,17 @@ func DefaultProcessorConfig() *ProcessorConfig {
 		MaxAlertsPerGroup:        10,
 		BatchSize:                10,
 		BatchTimeout:             5 * time.Second,
+		DedupWindowSeconds:       3600,
 	}
 }
 
 // AlertProcessor processes alerts from Kafka.
 type AlertProcessor struct {
 	config      *ProcessorConfig
-	consumer    *sharedkafka.Consumer
-	dlqProducer *sharedkafka.Producer
+	consumer    sharedkafka.MessageConsumer
+	dlqProducer sharedkafka.MessageProducer
+	decoder     *sharedkafka.Decoder
+	dedup       *sharedkafka.Deduplicator // nil when disabled
 	dispatchers []ports.AlertDispatcher
 	logger      *logging.Logger
 	metrics     *metrics.Metrics
@@ -66,11 +74,13 @@ type AlertProcessor struct {
 	wg      sync.WaitGroup
 }
 
-// NewAlertProcessor creates a new AlertProcessor.
+// NewAlertProcessor creates a new AlertProcessor. Alerts are decoded by
+// decoder according to their content type.
 func NewAlertProcessor(
 	config *ProcessorConfig,
-	brokers []string,
+	broker sharedkafka.Broker,
 	alertsTopic, dlqTopic, consumerGroup string,
+	decoder *sharedkafka.Decoder,
 	dispatchers []ports.AlertDispatcher,
 	logger *logging.Logger,
 	m *metrics.Metrics,
@@ -80,25 +90,34 @@ func NewAlertProcessor(
 	}
 
 	// Create consumer
-	consumerConfig := sharedkafka.DefaultConsumerConfig(brokers, alertsTopic, consumerGroup)
+	consumerConfig := sharedkafka.DefaultConsumerConfig(nil, alertsTopic, consumerGroup)
 	consumerConfig.StartOffset = kafka.LastOffset
-	consumer, err := sharedkafka.NewConsumer(consumerConfig, logger)
+	consumerConfig.Metrics = m
+	consumer, err := broker.NewConsumer(consumerConfig, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
 
 	// Create DLQ producer
-	dlqConfig := sharedkafka.DefaultProducerConfig(brokers, dlqTopic)
-	dlqProducer, err := sharedkafka.NewProducer(dlqConfig, logger)
+	dlqConfig := sharedkafka.DefaultProducerConfig(nil, dlqTopic)
+	dlqConfig.Metrics = m
+	dlqProducer, err := broker.NewProducer(dlqConfig, logger)
 	if err != nil {
 		consumer.Close()
 		return nil, fmt.Errorf("failed to create DLQ producer: %w", err)
 	}
 
+	var dedup *sharedkafka.Deduplicator
+	if config.DedupWindowSeconds > 0 {
+		dedup = sharedkafka.NewDeduplicator(time.Duration(config.DedupWindowSeconds)*time.Second, sharedkafka.DefaultDedupCapacity)
+	}
+
 	return &AlertProcessor{
 		config:       config,
 		consumer:     consumer,
 		dlqProducer:  dlqProducer,
+		decoder:      decoder,
+		dedup:        dedup,
 		dispatchers:  dispatchers,
 		logger:       logger,
 		metrics:      m,
@@ -146,11 +165,13 @@ func (p *AlertProcessor) Stop() error {
 	close(p.stopCh)
 	p.mu.Unlock()
 
-	p.wg.Wait()
-
+	// Closing the consumer ends the consume loop
 	if err := p.consumer.Close(); err != nil {
 		p.logger.Error("failed to close consumer", zap.Error(err))
 	}
+
+	p.wg.Wait()
+
 	if err := p.dlqProducer.Close(); err != nil {
 		p.logger.Error("failed to close DLQ producer", zap.Error(err))
 	}
@@ -159,40 +180,26 @@ func (p *AlertProcessor) Stop() error {
 	return nil
 }
 
+// consumeLoop consumes alerts until the consumer is closed. Alerts that fail
+// to deserialize are dead-lettered to the DLQ topic with the DLQ headers, and
+// alerts whose message ID was already handled are skipped.
 func (p *AlertProcessor) consumeLoop(ctx context.Context) {
 	defer p.wg.Done()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-p.stopCh:
-			return
-		default:
-			msg, err := p.consumer.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
-				}
-				p.logger.Error("failed to fetch message", zap.Error(err))
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
+	opts := sharedkafka.DefaultConsumeOptions()
+	opts.DLQ = p.dlqProducer
+	opts.Metrics = p.metrics
+	opts.Deduplicator = p.dedup
 
-			p.processMessage(ctx, msg)
-			p.consumer.CommitMessages(ctx, msg)
-		}
+	if err := p.consumer.Consume(ctx, p.processMessage, opts); err != nil && ctx.Err() == nil {
+		p.logger.Error("alert consumer stopped", zap.Error(err))
 	}
 }
 
-func (p *AlertProcessor) processMessage(ctx context.Context, msg kafka.Message) {
+func (p *AlertProcessor) processMessage(ctx context.Context, msg kafka.Message) error {
 	var alert models.Alert
-	if err := json.Unmarshal(msg.Value, &alert); err != nil {
-		p.logger.Warn("failed to deserialize alert",
-			zap.Error(err),
-			zap.String("value", string(msg.Value)),
-		)
-		return
+	if err := p.decoder.Decode(ctx, msg, &alert); err != nil {
+		return fmt.Errorf("failed to deserialize alert: %w", err)
 	}
 
 	p.logger.Debug("processing alert",
@@ -200,16 +207,18 @@ func (p *AlertProcessor) processMessage(ctx context.Context, msg kafka.Message)
 		zap.String("service", string(alert.ServiceName)),
 	)
 
-	// Check suppression
-	if p.isSuppressed(&alert) {
+	// Check suppression; resolutions share the identity of the alert they
+	// resolve, so they are never suppressed
+	if alert.ResolvedAt == nil && p.isSuppressed(&alert) {
 		p.logger.Debug("alert suppressed",
 			zap.String("alert_id", alert.ID),
 		)
-		return
+		return nil
 	}
 
 	// Add to group
-	p.addToGroup(&alert)
+	p.addToGroup(ctx, &alert)
+	return nil
 }
 
 func (p *AlertProcessor) isSuppressed(alert *models.Alert) bool {
@@ -237,11 +246,27 @@ func (p *AlertProcessor) addSuppression(alert *models.Alert) {
 	p.suppressions[key] = time.Now().Add(duration)
 }
 
+// removeSuppression lifts the suppression of a resolved alert, so the
+// problem notifies again if it comes back.
+func (p *AlertProcessor) removeSuppression(alert *models.Alert) {
+	key := p.getSuppressionKey(alert)
+
+	p.suppressMu.Lock()
+	defer p.suppressMu.Unlock()
+
+	delete(p.suppressions, key)
+}
+
 func (p *AlertProcessor) getSuppressionKey(alert *models.Alert) string {
-	return fmt.Sprintf("%s:%s:%s", alert.ServiceName, alert.MetricType, alert.Severity)
+	return suppressionKey(alert)
+}
+
+// suppressionKey suppresses re-fires of the same problem at the same severity.
+func suppressionKey(alert *models.Alert) string {
+	return fmt.Sprintf("%s:%s", alert.EnsureFingerprint(), alert.Severity)
 }
 
-func (p *AlertProcessor) addToGroup(alert *models.Alert) {
+func (p *AlertProcessor) addToGroup(ctx context.Context, alert *models.Alert) {
 	groupKey := p.getGroupKey(alert)
 
 	p.groupMu.Lock()
@@ -256,6 +281,9 @@ func (p *AlertProcessor) addToGroup(alert *models.Alert) {
 			FirstSeen:   time.Now().Unix(),
 			ServiceName: alert.ServiceName,
 			Severity:    alert.Severity,
+			Resolved:    alert.ResolvedAt != nil,
+			// The consumer span of the alert, continuing the analyzer's trace
+			TraceContext: tracing.InjectMap(ctx),
 		}
 		p.alertGroups[groupKey] = group
 	}
@@ -272,8 +300,18 @@ func (p *AlertProcessor) addToGroup(alert *models.Alert) {
 	}
 }
 
+// getGroupKey groups the firings of the same problem by their fingerprint.
 func (p *AlertProcessor) getGroupKey(alert *models.Alert) string {
-	return fmt.Sprintf("%s:%s", alert.ServiceName, alert.MetricType)
+	return groupKey(alert)
+}
+
+// groupKey groups alerts by their fingerprint, keeping the resolutions of a
+// problem apart from its firings.
+func groupKey(alert *models.Alert) string {
+	if alert.ResolvedAt != nil {
+		return alert.EnsureFingerprint() + ":resolved"
+	}
+	return alert.EnsureFingerprint()
 }
 
 func (p *AlertProcessor) groupingFlushLoop(ctx context.Context) {
@@ -302,6 +340,7 @@ func (p *AlertProcessor) flushGroups(ctx context.Context) {
 	}
 	p.alertGroups = make(map[string]*ports.AlertGroup)
 	p.groupMu.Unlock()
+	sortResolutionsLast(groups)
 
 	for _, group := range groups {
 		if len(group.Alerts) == 0 {
@@ -311,11 +350,15 @@ func (p *AlertProcessor) flushGroups(ctx context.Context) {
 		// Create a summary alert for the group
 		summaryAlert := p.createGroupSummary(group)
 
-		// Dispatch to all enabled dispatchers
-		p.dispatchAlert(ctx, summaryAlert)
+		// Dispatch to all enabled dispatchers, continuing the trace of the group
+		p.dispatchAlert(tracing.ExtractMap(ctx, group.TraceContext), summaryAlert)
 
-		// Add suppression for this group
-		p.addSuppression(summaryAlert)
+		// Suppress re-fires of the group, or notify again once it resolved
+		if summaryAlert.ResolvedAt != nil {
+			p.removeSuppression(summaryAlert)
+		} else {
+			p.addSuppression(summaryAlert)
+		}
 	}
 }
 
@@ -330,8 +373,20 @@ func (p *AlertProcessor) createGroupSummary(group *ports.AlertGroup) *models.Ale
 		message = fmt.Sprintf("%s\n\n--- %d similar alerts were grouped ---", firstAlert.Message, group.Count)
 	}
 
+	// The summary keeps the labels of the alert, so receivers see the same
+	// labels as the analyzer, plus the grouping labels
+	labels := make(models.Labels, len(firstAlert.Labels)+3)
+	for k, v := range firstAlert.Labels {
+		labels[k] = v
+	}
+	labels["group_id"] = group.ID
+	labels["group_count"] = fmt.Sprintf("%d", group.Count)
+	labels["first_seen"] = time.Unix(group.FirstSeen, 0).Format(time.RFC3339)
+
 	return &models.Alert{
 		ID:           group.ID,
+		Fingerprint:  firstAlert.EnsureFingerprint(),
+		Type:         firstAlert.Type,
 		ServiceName:  group.ServiceName,
 		MetricType:   firstAlert.MetricType,
 		Severity:     group.Severity,
@@ -340,11 +395,11 @@ func (p *AlertProcessor) createGroupSummary(group *ports.AlertGroup) *models.Ale
 		CurrentValue: firstAlert.CurrentValue,
 		Threshold:    firstAlert.Threshold,
 		Timestamp:    time.Now(),
-		Labels: map[string]string{
-			"group_id":    group.ID,
-			"group_count": fmt.Sprintf("%d", group.Count),
-			"first_seen":  time.Unix(group.FirstSeen, 0).Format(time.RFC3339),
-		},
+		ResolvedAt:   firstAlert.ResolvedAt,
+		RuleID:       firstAlert.RuleID,
+		TraceID:      firstAlert.TraceID,
+		Enrichment:   firstAlert.Enrichment,
+		Labels:       labels,
 	}
 }
 
@@ -354,9 +409,15 @@ func (p *AlertProcessor) dispatchAlert(ctx context.Context, alert *models.Alert)
 			continue
 		}
 
+		dispatchCtx, span := tracing.StartSpanFromContext(ctx, "AlertProcessor.dispatch")
+		tracing.SetAttributes(dispatchCtx,
+			tracing.StringAttr("dispatcher", dispatcher.Name()),
+			tracing.StringAttr("alert.fingerprint", alert.Fingerprint),
+		)
+
 		var lastErr error
 		for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
-			err := dispatcher.Dispatch(ctx, alert)
+			err := dispatcher.Dispatch(dispatchCtx, alert)
 			if err == nil {
 				p.logger.Info("alert dispatched successfully",
 					zap.String("alert_id", alert.ID),
@@ -385,8 +446,10 @@ func (p *AlertProcessor) dispatchAlert(ctx context.Context, alert *models.Alert)
 				zap.String("dispatcher", dispatcher.Name()),
 				zap.Error(lastErr),
 			)
-			p.sendToDLQ(ctx, alert, dispatcher.Name(), lastErr)
+			tracing.RecordError(dispatchCtx, lastErr)
+			p.sendToDLQ(dispatchCtx, alert, dispatcher.Name(), lastErr)
 		}
+		span.End()
 	}
 }
 
@@ -442,13 +505,16 @@ func (p *AlertProcessor) cleanupSuppressions() {
 	}
 }
 
+// sortResolutionsLast orders resolved groups after the firings, so a
+// resolution lifts the suppression its firing adds in the same flush.
+func sortResolutionsLast(groups []*ports.AlertGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		return !groups[i].Resolved && groups[j].Resolved
+	})
+}
+
 func compareSeverity(a, b models.AlertSeverity) int {
-	order := map[models.AlertSeverity]int{
-		models.AlertSeverityInfo:     0,
-		models.AlertSeverityWarning:  1,
-		models.AlertSeverityCritical: 2,
-	}
-	return order[a] - order[b]
+	return a.Rank() - b.Rank()
 }
 
 // MockAlertProcessor for testing without Kafka.
@@ -527,7 +593,7 @@ func (p *MockAlertProcessor) Stop() error {
 
 // ProcessAlert processes a single alert directly.
 func (p *MockAlertProcessor) ProcessAlert(ctx context.Context, alert *models.Alert) error {
-	if p.isSuppressed(alert) {
+	if alert.ResolvedAt == nil && p.isSuppressed(alert) {
 		p.logger.Debug("alert suppressed",
 			zap.String("alert_id", alert.ID),
 		)
@@ -539,7 +605,7 @@ func (p *MockAlertProcessor) ProcessAlert(ctx context.Context, alert *models.Ale
 }
 
 func (p *MockAlertProcessor) isSuppressed(alert *models.Alert) bool {
-	key := fmt.Sprintf("%s:%s:%s", alert.ServiceName, alert.MetricType, alert.Severity)
+	key := suppressionKey(alert)
 
 	p.suppressMu.Lock()
 	defer p.suppressMu.Unlock()
@@ -554,22 +620,23 @@ func (p *MockAlertProcessor) isSuppressed(alert *models.Alert) bool {
 }
 
 func (p *MockAlertProcessor) addToGroup(alert *models.Alert) {
-	groupKey := fmt.Sprintf("%s:%s", alert.ServiceName, alert.MetricType)
+	key := groupKey(alert)
 
 	p.groupMu.Lock()
 	defer p.groupMu.Unlock()
 
-	group, exists := p.alertGroups[groupKey]
+	group, exists := p.alertGroups[key]
 	if !exists {
 		group = &ports.AlertGroup{
 			ID:          uuid.New().String(),
-			GroupKey:    groupKey,
+			GroupKey:    key,
 			Alerts:      make([]*models.Alert, 0),
 			FirstSeen:   time.Now().Unix(),
 			ServiceName: alert.ServiceName,
 			Severity:    alert.Severity,
+			Resolved:    alert.ResolvedAt != nil,
 		}
-		p.alertGroups[groupKey] = group
+		p.alertGroups[key] = group
 	}
 
 	if len(group.Alerts) < p.config.MaxAlertsPerGroup {
@@ -609,6 +676,7 @@ func (p *MockAlertProcessor) flushGroups(ctx context.Context) {
 	}
 	p.alertGroups = make(map[string]*ports.AlertGroup)
 	p.groupMu.Unlock()
+	sortResolutionsLast(groups)
 
 	for _, group := range groups {
 		if len(group.Alerts) == 0 {
@@ -618,6 +686,8 @@ func (p *MockAlertProcessor) flushGroups(ctx context.Context) {
 		firstAlert := group.Alerts[0]
 		summaryAlert := &models.Alert{
 			ID:           group.ID,
+			Fingerprint:  firstAlert.EnsureFingerprint(),
+			Type:         firstAlert.Type,
 			ServiceName:  group.ServiceName,
 			MetricType:   firstAlert.MetricType,
 			Severity:     group.Severity,
@@ -626,6 +696,10 @@ func (p *MockAlertProcessor) flushGroups(ctx context.Context) {
 			CurrentValue: firstAlert.CurrentValue,
 			Threshold:    firstAlert.Threshold,
 			Timestamp:    time.Now(),
+			ResolvedAt:   firstAlert.ResolvedAt,
+			RuleID:       firstAlert.RuleID,
+			TraceID:      firstAlert.TraceID,
+			Enrichment:   firstAlert.Enrichment,
 		}
 
 		if group.Count > 1 {
@@ -638,10 +712,14 @@ func (p *MockAlertProcessor) flushGroups(ctx context.Context) {
 			}
 		}
 
-		// Add suppression
-		key := fmt.Sprintf("%s:%s:%s", summaryAlert.ServiceName, summaryAlert.MetricType, summaryAlert.Severity)
+		// Suppress re-fires, or notify again once resolved
+		key := suppressionKey(summaryAlert)
 		p.suppressMu.Lock()
-		p.suppressions[key] = time.Now().Add(time.Duration(p.config.SuppressionWindowSeconds) * time.Second)
+		if summaryAlert.ResolvedAt != nil {
+			delete(p.suppressions, key)
+		} else {
+			p.suppressions[key] = time.Now().Add(time.Duration(p.config.SuppressionWindowSeconds) * time.Second)
+		}
 		p.suppressMu.Unlock()
 	}
 }
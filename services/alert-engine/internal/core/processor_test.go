@@ -0,0 +1,281 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/alert-engine/internal/ports"
+)
+
+// recordingDispatcher records the alerts it dispatches.
+type recordingDispatcher struct {
+	mu     sync.Mutex
+	alerts []*models.Alert
+}
+
+func (d *recordingDispatcher) Dispatch(ctx context.Context, alert *models.Alert) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.alerts = append(d.alerts, alert)
+	return nil
+}
+
+func (d *recordingDispatcher) Name() string { return "recording" }
+
+func (d *recordingDispatcher) Enabled() bool { return true }
+
+func (d *recordingDispatcher) dispatched() []*models.Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*models.Alert(nil), d.alerts...)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startPipeline starts a processor consuming the alerts topic of a
+// MemoryBroker and returns the broker, a producer publishing alerts the way
+// the analyzer does and the dispatcher of the processor.
+func startPipeline(t *testing.T, ctx context.Context) (*sharedkafka.MemoryBroker, sharedkafka.MessageProducer, *recordingDispatcher) {
+	t.Helper()
+	logger, err := logging.NewLogger(logging.DefaultConfig("alert-engine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := sharedkafka.NewMemoryBroker()
+	dispatcher := &recordingDispatcher{}
+
+	config := DefaultProcessorConfig()
+	config.GroupingWindowSeconds = 1
+	processor, err := NewAlertProcessor(
+		config,
+		broker,
+		"alerts",
+		"alerts-dlq",
+		"alert-engine",
+		sharedkafka.NewDecoder(nil),
+		[]ports.AlertDispatcher{dispatcher},
+		logger,
+		metrics.NewMetrics("alert-engine"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { processor.Stop() })
+
+	producerConfig := sharedkafka.DefaultProducerConfig(nil, "alerts")
+	producerConfig.Idempotent = true
+	producer, err := broker.NewProducer(producerConfig, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { producer.Close() })
+
+	return broker, producer, dispatcher
+}
+
+// TestAlertPipeline publishes alerts the way the analyzer does and checks
+// that the processor groups and dispatches them with their enrichment,
+// suppresses re-fires and dead-letters the malformed ones, all against a
+// MemoryBroker.
+func TestAlertPipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker, producer, dispatcher := startPipeline(t, ctx)
+
+	enrichment := &models.AlertEnrichment{
+		OwnerTeam:  "checkout",
+		RunbookURL: "https://runbooks.example.com/orders",
+		RecentLogs: []string{"payment declined"},
+	}
+
+	fire := func(severity models.AlertSeverity) {
+		t.Helper()
+		alert := &models.Alert{
+			ID:          uuid.New().String(),
+			Type:        models.AlertTypeThresholdViolation,
+			Severity:    severity,
+			ServiceName: models.ServiceOrders,
+			Title:       "High error rate",
+			Description: "error rate above threshold",
+			Timestamp:   time.Now(),
+			RuleID:      "error-rate",
+			TraceID:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			Labels:      models.Labels{"service": "orders"},
+			Enrichment:  enrichment,
+		}
+		if err := producer.PublishValue(ctx, []byte(alert.ServiceName), alert); err != nil {
+			t.Fatalf("failed to publish alert: %v", err)
+		}
+	}
+
+	// Two firings of the same problem are grouped into one dispatch
+	fire(models.AlertSeverityCritical)
+	fire(models.AlertSeverityCritical)
+	if err := producer.Publish(ctx, []byte("orders"), []byte("not an alert")); err != nil {
+		t.Fatalf("failed to publish malformed alert: %v", err)
+	}
+
+	waitFor(t, "the grouped alert", func() bool { return len(dispatcher.dispatched()) > 0 })
+	alerts := dispatcher.dispatched()
+	if len(alerts) != 1 {
+		t.Fatalf("dispatched %d alerts, want 1", len(alerts))
+	}
+	summary := alerts[0]
+	if got := summary.Labels["group_count"]; got != "2" {
+		t.Errorf("group count %s, want 2", got)
+	}
+	if got := summary.Labels["service"]; got != "orders" {
+		t.Errorf("service label %q, want the label of the alert", got)
+	}
+	if want := models.AlertFingerprint("error-rate", models.Labels{"service": "orders"}); summary.Fingerprint != want {
+		t.Errorf("fingerprint %s, want %s", summary.Fingerprint, want)
+	}
+	if summary.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID %q, want the trace ID of the alert", summary.TraceID)
+	}
+	if summary.Enrichment == nil || summary.Enrichment.OwnerTeam != "checkout" ||
+		summary.Enrichment.RunbookURL != enrichment.RunbookURL || len(summary.Enrichment.RecentLogs) != 1 {
+		t.Errorf("enrichment %+v, want %+v", summary.Enrichment, enrichment)
+	}
+
+	// A re-fire at the same severity is suppressed, while the same problem
+	// at another severity is not
+	fire(models.AlertSeverityCritical)
+	fire(models.AlertSeverityWarning)
+	waitFor(t, "the warning", func() bool { return len(dispatcher.dispatched()) > 1 })
+	alerts = dispatcher.dispatched()
+	if len(alerts) != 2 {
+		t.Fatalf("dispatched %d alerts, want 2", len(alerts))
+	}
+	if got := alerts[1]; got.Severity != models.AlertSeverityWarning || got.Labels["group_count"] != "1" {
+		t.Errorf("dispatched a %s alert of %s alerts, want only the warning", got.Severity, got.Labels["group_count"])
+	}
+
+	waitFor(t, "the dead letter", func() bool { return len(broker.Messages("alerts-dlq")) == 1 })
+	if got := string(broker.Messages("alerts-dlq")[0].Value); got != "not an alert" {
+		t.Errorf("dead letter %q, want the malformed alert", got)
+	}
+}
+
+// TestMockProcessorSuppression checks that the mock processor suppresses
+// re-fires of dispatched alerts the same way the processor does.
+func TestMockProcessorSuppression(t *testing.T) {
+	logger, err := logging.NewLogger(logging.DefaultConfig("alert-engine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := &recordingDispatcher{}
+	processor := NewMockAlertProcessor(nil, []ports.AlertDispatcher{dispatcher}, logger)
+
+	alert := func(severity models.AlertSeverity) *models.Alert {
+		return &models.Alert{
+			ID:          uuid.New().String(),
+			Severity:    severity,
+			ServiceName: models.ServiceOrders,
+			MetricType:  models.MetricTypeErrorRate,
+			Title:       "High error rate",
+			RuleID:      "error-rate",
+			Labels:      models.Labels{"service": "orders"},
+		}
+	}
+
+	ctx := context.Background()
+	processor.ProcessAlert(ctx, alert(models.AlertSeverityCritical))
+	processor.flushGroups(ctx)
+	processor.ProcessAlert(ctx, alert(models.AlertSeverityCritical))
+	processor.ProcessAlert(ctx, alert(models.AlertSeverityWarning))
+	processor.flushGroups(ctx)
+
+	alerts := dispatcher.dispatched()
+	if len(alerts) != 2 {
+		t.Fatalf("dispatched %d alerts, want 2", len(alerts))
+	}
+	if alerts[1].Severity != models.AlertSeverityWarning || alerts[1].Title != "High error rate" {
+		t.Errorf("dispatched %s %q, want the warning alone", alerts[1].Severity, alerts[1].Title)
+	}
+}
+
+// TestAlertPipelineResolution checks that the resolution of an alert is
+// dispatched on its own rather than suppressed or grouped with the alert it
+// resolves, and that the problem notifies again once resolved.
+func TestAlertPipelineResolution(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, producer, dispatcher := startPipeline(t, ctx)
+
+	publish := func(resolved bool) {
+		t.Helper()
+		alert := &models.Alert{
+			ID:          uuid.New().String(),
+			Type:        models.AlertTypeAbsentData,
+			Severity:    models.AlertSeverityCritical,
+			ServiceName: models.ServiceOrders,
+			Title:       "No data from orders",
+			Timestamp:   time.Now(),
+			RuleID:      "absent",
+			Labels:      models.Labels{"service": "orders", "state": "firing"},
+		}
+		if resolved {
+			now := time.Now()
+			alert.Title = "Data from orders restored"
+			alert.ResolvedAt = &now
+			alert.Labels["state"] = "resolved"
+		}
+		if err := producer.PublishValue(ctx, []byte(alert.ServiceName), alert); err != nil {
+			t.Fatalf("failed to publish alert: %v", err)
+		}
+	}
+
+	// The firing and its resolution in the same window are dispatched apart
+	publish(false)
+	publish(true)
+	waitFor(t, "the firing and the resolution", func() bool { return len(dispatcher.dispatched()) == 2 })
+
+	var firing, resolution *models.Alert
+	for _, alert := range dispatcher.dispatched() {
+		if alert.ResolvedAt != nil {
+			resolution = alert
+		} else {
+			firing = alert
+		}
+	}
+	if firing == nil || resolution == nil {
+		t.Fatal("want a firing and a resolution dispatched")
+	}
+	if resolution.Fingerprint != firing.Fingerprint {
+		t.Errorf("resolution fingerprint %s, want the fingerprint of the firing %s", resolution.Fingerprint, firing.Fingerprint)
+	}
+	if got := resolution.Labels["state"]; got != "resolved" {
+		t.Errorf("resolution state label %q, want resolved", got)
+	}
+	if resolution.Title != "Data from orders restored" || resolution.Labels["group_count"] != "1" {
+		t.Errorf("resolution %q of %s alerts, want the resolution alone", resolution.Title, resolution.Labels["group_count"])
+	}
+
+	// Once resolved the problem notifies again
+	publish(false)
+	waitFor(t, "the second firing", func() bool { return len(dispatcher.dispatched()) == 3 })
+	if got := dispatcher.dispatched()[2]; got.ResolvedAt != nil {
+		t.Errorf("dispatched a resolution, want the second firing")
+	}
+}
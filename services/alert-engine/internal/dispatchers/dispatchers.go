@@ -14,6 +14,7 @@ import (
 
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/tracing"
 )
 
 // SlackDispatcher dispatches alerts to Slack.
@@ -83,6 +84,9 @@ func (d *SlackDispatcher) Dispatch(ctx context.Context, alert *models.Alert) err
 
 	color := d.severityToColor(alert.Severity)
 	emoji := d.severityToEmoji(alert.Severity)
+	if alert.ResolvedAt != nil {
+		color, emoji = "#28a745", "✅" // Green
+	}
 
 	message := SlackMessage{
 		Channel:   d.channel,
@@ -93,13 +97,13 @@ func (d *SlackDispatcher) Dispatch(ctx context.Context, alert *models.Alert) err
 				Color: color,
 				Title: fmt.Sprintf("%s %s", emoji, alert.Title),
 				Text:  alert.Message,
-				Fields: []SlackField{
+				Fields: append([]SlackField{
 					{Title: "Service", Value: string(alert.ServiceName), Short: true},
 					{Title: "Severity", Value: string(alert.Severity), Short: true},
 					{Title: "Metric", Value: string(alert.MetricType), Short: true},
 					{Title: "Value", Value: fmt.Sprintf("%.2f", alert.CurrentValue), Short: true},
-				},
-				Footer:     fmt.Sprintf("Alert ID: %s", alert.ID),
+				}, slackEnrichmentFields(alert.Enrichment)...),
+				Footer:     fmt.Sprintf("Alert ID: %s | Fingerprint: %s", alert.ID, alert.Fingerprint),
 				Timestamp:  alert.Timestamp.Unix(),
 				MarkdownIn: []string{"text"},
 			},
@@ -322,8 +326,10 @@ func (d *EmailDispatcher) buildEmailBody(alert *models.Alert) string {
             <div class="detail-row"><span class="detail-label">Threshold:</span> %.2f</div>
             <div class="detail-row"><span class="detail-label">Timestamp:</span> %s</div>
         </div>
+%s
         <div class="footer">
             Alert ID: %s<br>
+            Fingerprint: %s<br>
             This alert was generated by the Microservices Platform Alert Engine.
         </div>
     </div>
@@ -339,7 +345,9 @@ func (d *EmailDispatcher) buildEmailBody(alert *models.Alert) string {
 		alert.CurrentValue,
 		alert.Threshold,
 		alert.Timestamp.Format(time.RFC3339),
+		emailEnrichmentHTML(alert.Enrichment),
 		alert.ID,
+		alert.Fingerprint,
 	)
 }
 
@@ -355,6 +363,7 @@ type WebhookDispatcher struct {
 // WebhookPayload represents the webhook payload.
 type WebhookPayload struct {
 	ID           string            `json:"id"`
+	Fingerprint  string            `json:"fingerprint"` // identical across re-fires, for receiver-side dedup
 	ServiceName  string            `json:"service_name"`
 	MetricType   string            `json:"metric_type"`
 	Severity     string            `json:"severity"`
@@ -363,7 +372,12 @@ type WebhookPayload struct {
 	CurrentValue float64           `json:"current_value"`
 	Threshold    float64           `json:"threshold"`
 	Timestamp    string            `json:"timestamp"`
+	ResolvedAt   string            `json:"resolved_at,omitempty"`
 	Labels       map[string]string `json:"labels"`
+	TraceID      string            `json:"trace_id,omitempty"`
+	// Enrichment is the owner, runbook, dashboard link and recent logs and
+	// samples the analyzer added to the alert
+	Enrichment *models.AlertEnrichment `json:"enrichment,omitempty"`
 }
 
 // NewWebhookDispatcher creates a new WebhookDispatcher.
@@ -389,7 +403,8 @@ func (d *WebhookDispatcher) Enabled() bool {
 	return d.enabled && len(d.urls) > 0 && d.urls[0] != ""
 }
 
-// Dispatch sends an alert to all configured webhooks.
+// Dispatch sends an alert to all configured webhooks. Requests carry the W3C
+// trace context headers, so receivers can continue the alert's trace.
 func (d *WebhookDispatcher) Dispatch(ctx context.Context, alert *models.Alert) error {
 	if !d.Enabled() {
 		d.logger.Debug("webhook dispatcher disabled, skipping")
@@ -398,6 +413,7 @@ func (d *WebhookDispatcher) Dispatch(ctx context.Context, alert *models.Alert) e
 
 	payload := WebhookPayload{
 		ID:           alert.ID,
+		Fingerprint:  alert.Fingerprint,
 		ServiceName:  string(alert.ServiceName),
 		MetricType:   string(alert.MetricType),
 		Severity:     string(alert.Severity),
@@ -407,6 +423,11 @@ func (d *WebhookDispatcher) Dispatch(ctx context.Context, alert *models.Alert) e
 		Threshold:    alert.Threshold,
 		Timestamp:    alert.Timestamp.Format(time.RFC3339),
 		Labels:       alert.Labels,
+		TraceID:      alert.TraceID,
+		Enrichment:   alert.Enrichment,
+	}
+	if alert.ResolvedAt != nil {
+		payload.ResolvedAt = alert.ResolvedAt.Format(time.RFC3339)
 	}
 
 	data, err := json.Marshal(payload)
@@ -433,6 +454,7 @@ func (d *WebhookDispatcher) Dispatch(ctx context.Context, alert *models.Alert) e
 		}
 
 		req.Header.Set("Content-Type", "application/json")
+		tracing.InjectHTTPHeaders(ctx, req.Header)
 		for k, v := range d.headers {
 			req.Header.Set(k, v)
 		}
@@ -0,0 +1,152 @@
+package dispatchers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+func testLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	logger, err := logging.NewLogger(logging.DefaultConfig("alert-engine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
+
+func enrichedAlert() *models.Alert {
+	now := time.Now()
+	return &models.Alert{
+		ID:           "alert-1",
+		Fingerprint:  "fp-1",
+		Severity:     models.AlertSeverityCritical,
+		ServiceName:  models.ServiceOrders,
+		MetricType:   models.MetricTypeErrorRate,
+		Title:        "High error rate",
+		Message:      "error rate above threshold",
+		CurrentValue: 0.2,
+		Threshold:    0.05,
+		Timestamp:    now,
+		TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		Labels:       models.Labels{"service": "orders"},
+		Enrichment: &models.AlertEnrichment{
+			OwnerTeam:    "checkout",
+			RunbookURL:   "https://runbooks.example.com/orders",
+			DashboardURL: "http://localhost:3000/?alert=fp-1",
+			RecentLogs:   []string{"<error> payment declined"},
+			RecentSamples: []models.MetricSample{
+				{Timestamp: now.Add(-time.Minute), Value: 0.01},
+				{Timestamp: now, Value: 0.2},
+			},
+		},
+	}
+}
+
+// capture starts a server that records the body of the last request.
+func capture(t *testing.T) (*httptest.Server, func() []byte) {
+	t.Helper()
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []byte {
+		select {
+		case body := <-bodies:
+			return body
+		default:
+			t.Fatal("no request received")
+			return nil
+		}
+	}
+}
+
+func TestSlackDispatchRendersEnrichment(t *testing.T) {
+	server, body := capture(t)
+	d := NewSlackDispatcher(server.URL, "#alerts", testLogger(t), true)
+
+	if err := d.Dispatch(context.Background(), enrichedAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg SlackMessage
+	if err := json.Unmarshal(body(), &msg); err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]string)
+	for _, f := range msg.Attachments[0].Fields {
+		fields[f.Title] = f.Value
+	}
+	for title, want := range map[string]string{
+		"Owner":         "checkout",
+		"Runbook":       "https://runbooks.example.com/orders",
+		"Dashboard":     "http://localhost:3000/?alert=fp-1",
+		"Recent values": "▁█",
+		"Recent logs":   "<error> payment declined",
+	} {
+		if !strings.Contains(fields[title], want) {
+			t.Errorf("field %s = %q, want it to contain %q", title, fields[title], want)
+		}
+	}
+}
+
+func TestWebhookDispatchCarriesEnrichment(t *testing.T) {
+	server, body := capture(t)
+	d := NewWebhookDispatcher([]string{server.URL}, nil, testLogger(t), true)
+
+	alert := enrichedAlert()
+	if err := d.Dispatch(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body(), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.TraceID != alert.TraceID {
+		t.Errorf("trace ID %q, want %q", payload.TraceID, alert.TraceID)
+	}
+	if payload.Enrichment == nil {
+		t.Fatal("enrichment missing from the webhook payload")
+	}
+	if payload.Enrichment.OwnerTeam != "checkout" || payload.Enrichment.DashboardURL != alert.Enrichment.DashboardURL {
+		t.Errorf("enrichment %+v, want %+v", payload.Enrichment, alert.Enrichment)
+	}
+	if len(payload.Enrichment.RecentLogs) != 1 || len(payload.Enrichment.RecentSamples) != 2 {
+		t.Errorf("enrichment has %d logs and %d samples, want 1 and 2",
+			len(payload.Enrichment.RecentLogs), len(payload.Enrichment.RecentSamples))
+	}
+}
+
+func TestEmailBodyRendersEnrichment(t *testing.T) {
+	d := NewEmailDispatcher("key", "alerts@example.com", "Alerts", []string{"oncall@example.com"}, testLogger(t), true)
+
+	body := d.buildEmailBody(enrichedAlert())
+	for _, want := range []string{
+		"checkout",
+		`<a href="https://runbooks.example.com/orders">`,
+		`<a href="http://localhost:3000/?alert=fp-1">`,
+		"▁█",
+		"&lt;error&gt; payment declined",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("email body does not contain %q", want)
+		}
+	}
+
+	alert := enrichedAlert()
+	alert.Enrichment = nil
+	if body := d.buildEmailBody(alert); strings.Contains(body, "Owner:") {
+		t.Error("email body of an alert without enrichment has an owner")
+	}
+}
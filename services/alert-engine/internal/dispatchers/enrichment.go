@@ -0,0 +1,107 @@
+package dispatchers
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// sparkTicks are the bars of a sparkline, from lowest to highest.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders the values of the samples as a line of bars, scaled
+// between their minimum and maximum.
+func sparkline(samples []models.MetricSample) string {
+	if len(samples) == 0 {
+		return ""
+	}
+
+	lo, hi := samples[0].Value, samples[0].Value
+	for _, s := range samples {
+		if s.Value < lo {
+			lo = s.Value
+		}
+		if s.Value > hi {
+			hi = s.Value
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range samples {
+		i := 0
+		if hi > lo {
+			i = int((s.Value - lo) / (hi - lo) * float64(len(sparkTicks)-1))
+		}
+		b.WriteRune(sparkTicks[i])
+	}
+	return fmt.Sprintf("%s (%.2f – %.2f)", b.String(), lo, hi)
+}
+
+// slackEnrichmentFields returns the Slack fields for the enrichment of an
+// alert.
+func slackEnrichmentFields(e *models.AlertEnrichment) []SlackField {
+	if e == nil {
+		return nil
+	}
+
+	var fields []SlackField
+	if e.OwnerTeam != "" {
+		fields = append(fields, SlackField{Title: "Owner", Value: e.OwnerTeam, Short: true})
+	}
+	if e.RunbookURL != "" {
+		fields = append(fields, SlackField{Title: "Runbook", Value: fmt.Sprintf("<%s|Open runbook>", e.RunbookURL), Short: true})
+	}
+	if e.DashboardURL != "" {
+		fields = append(fields, SlackField{Title: "Dashboard", Value: fmt.Sprintf("<%s|Open dashboard>", e.DashboardURL), Short: true})
+	}
+	if len(e.RecentSamples) > 0 {
+		fields = append(fields, SlackField{Title: "Recent values", Value: sparkline(e.RecentSamples), Short: false})
+	}
+	if len(e.RecentLogs) > 0 {
+		fields = append(fields, SlackField{Title: "Recent logs", Value: "```" + strings.Join(e.RecentLogs, "\n") + "```", Short: false})
+	}
+	return fields
+}
+
+// emailEnrichmentHTML returns the HTML section for the enrichment of an
+// alert, or an empty string if the alert was not enriched.
+func emailEnrichmentHTML(e *models.AlertEnrichment) string {
+	if e == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	row := func(label, value string) {
+		fmt.Fprintf(&b, `            <div class="detail-row"><span class="detail-label">%s:</span> %s</div>`+"\n", label, value)
+	}
+	link := func(url, text string) string {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), text)
+	}
+
+	if e.OwnerTeam != "" {
+		row("Owner", html.EscapeString(e.OwnerTeam))
+	}
+	if e.RunbookURL != "" {
+		row("Runbook", link(e.RunbookURL, "Open runbook"))
+	}
+	if e.DashboardURL != "" {
+		row("Dashboard", link(e.DashboardURL, "Open dashboard"))
+	}
+	if len(e.RecentSamples) > 0 {
+		row("Recent values", html.EscapeString(sparkline(e.RecentSamples)))
+	}
+	if len(e.RecentLogs) > 0 {
+		logs := make([]string, len(e.RecentLogs))
+		for i, line := range e.RecentLogs {
+			logs[i] = html.EscapeString(line)
+		}
+		fmt.Fprintf(&b, "            <pre>%s</pre>\n", strings.Join(logs, "\n"))
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return "        <div class=\"details\">\n" + b.String() + "        </div>"
+}
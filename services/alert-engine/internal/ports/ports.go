@@ -40,6 +40,12 @@ type AlertGroup struct {
 	Count       int
 	Severity    models.AlertSeverity
 	ServiceName models.ServiceName
+	// Resolved groups hold resolutions, which are kept apart from the
+	// firings of the same problem.
+	Resolved bool
+	// TraceContext is the trace context of the first alert, which the
+	// group's dispatch continues.
+	TraceContext map[string]string
 }
 
 // DLQHandler handles dead letter queue operations.
@@ -3,19 +3,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/metrics"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/tracing"
 	"github.com/microservices-platform/services/analyzer/internal/adapters"
 	"github.com/microservices-platform/services/analyzer/internal/config"
 	"github.com/microservices-platform/services/analyzer/internal/core"
@@ -26,6 +31,15 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	checkOnly := flag.Bool("check", false, "validate the rule files and exit")
+	flag.StringVar(&cfg.RulesPath, "rules", cfg.RulesPath, "rule file or directory of rule files")
+	flag.Parse()
+
+	// Lint mode for CI: validate rule files without starting the service
+	if *checkOnly {
+		os.Exit(checkRuleFiles(cfg.RulesPath))
+	}
+
 	// Initialize logger
 	logConfig := &logging.Config{
 		Level:       cfg.LogLevel,
@@ -44,6 +58,29 @@ func main() {
 		zap.String("version", cfg.Version),
 	)
 
+	// Initialize tracing; trace context propagates even when not exported
+	tracing.InitPropagation()
+	if cfg.TracingEnabled {
+		tracingConfig := &tracing.Config{
+			ServiceName:    cfg.ServiceName,
+			ServiceVersion: cfg.Version,
+			Environment:    cfg.Environment,
+			Endpoint:       cfg.TracingEndpoint,
+			SampleRate:     cfg.SampleRate,
+			Enabled:        true,
+		}
+		tracer, err := tracing.NewTracer(tracingConfig)
+		if err != nil {
+			logger.Warn("failed to initialize tracing", zap.Error(err))
+		} else {
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				tracer.Shutdown(ctx)
+			}()
+		}
+	}
+
 	// Initialize metrics
 	m := metrics.NewMetrics(cfg.ServiceName)
 
@@ -62,18 +99,56 @@ func main() {
 	logger.Info("connected to Redis", zap.String("addr", cfg.RedisAddr))
 
 	// Initialize Redis stores
-	metricsStore := adapters.NewRedisMetricsStore(redisClient, logger)
+	metricsStore := adapters.NewRedisMetricsStore(redisClient, logger, cfg.MaxSeriesPerMetric)
 	rulesStore := adapters.NewRedisRuleStore(redisClient, logger)
+	registry := adapters.NewRedisServiceRegistry(redisClient, logger, cfg.RegistryTTL)
+	logStore := adapters.NewRedisLogStore(redisClient, logger, cfg.RecentLogsKept, cfg.SlidingWindowSize)
+
+	// Initialize Kafka authentication and the codec of published alerts;
+	// consumed events are decoded by their content type
+	security, err := sharedkafka.NewSecurity(cfg.KafkaSecurity)
+	if err != nil {
+		logger.Fatal("invalid Kafka security configuration", zap.Error(err))
+	}
+	broker := sharedkafka.NewBroker(cfg.KafkaBrokers, security)
+	schemaRegistry := sharedkafka.NewSchemaRegistry(cfg.SchemaRegistryURL)
+	codec, err := sharedkafka.NewCodec(sharedkafka.Encoding(cfg.KafkaEncoding), schemaRegistry)
+	if err != nil {
+		logger.Fatal("failed to create Kafka codec", zap.Error(err))
+	}
 
 	// Initialize Kafka alert publisher
 	var alertPublisher ports.AlertPublisher
 
+	// Services are mapped to the partitions of the metrics topic the way
+	// producers key them, so ownership needs its actual partition count
+	metricsPartitions := sharedkafka.EventTopicSpec(cfg.KafkaMetricsTopic, cfg.KafkaTopics.ReplicationFactor).Partitions
+
 	// Check if Kafka is available
 	kafkaAvailable := len(cfg.KafkaBrokers) > 0 && cfg.KafkaBrokers[0] != ""
 	if kafkaAvailable {
+		// Verify the topics the service uses
+		topicSpecs := sharedkafka.TopicSpecsFor(cfg.KafkaTopics.ReplicationFactor,
+			cfg.KafkaMetricsTopic,
+			cfg.KafkaLogsTopic,
+			cfg.KafkaAlertsTopic,
+			sharedkafka.DLQTopic(cfg.KafkaMetricsTopic),
+			sharedkafka.DLQTopic(cfg.KafkaLogsTopic),
+		)
+		topicStates, err := broker.EnsureTopics(context.Background(), topicSpecs, cfg.KafkaTopics.Create, logger)
+		if err != nil {
+			logger.Fatal("invalid Kafka topics", zap.Error(err))
+		}
+		for _, state := range topicStates {
+			if state.Name == cfg.KafkaMetricsTopic && state.Exists {
+				metricsPartitions = state.Partitions
+			}
+		}
+
 		kafkaPublisher, err := adapters.NewKafkaAlertPublisher(
-			cfg.KafkaBrokers,
+			broker,
 			cfg.KafkaAlertsTopic,
+			codec,
 			logger,
 			m,
 		)
@@ -95,11 +170,20 @@ func main() {
 	}
 	defer alertPublisher.Close()
 
+	// Enrich alerts between detection and publishing
+	alertPublisher = core.NewEnrichingPublisher(alertPublisher, alertEnrichers(cfg, metricsStore, logStore, logger), cfg.EnrichmentTimeout, logger)
+
 	// Initialize analyzer
 	analyzerConfig := &core.AnalysisConfig{
+		EvaluationMode:            cfg.EvaluationMode,
 		SlidingWindowSize:         cfg.SlidingWindowSize,
 		RollingWindowSize:         cfg.SlidingWindowSize,
 		AnalysisInterval:          cfg.AnalysisInterval,
+		MaxWindowSamples:          cfg.MaxWindowSamples,
+		MaxGroupsPerRule:          cfg.MaxGroupsPerRule,
+		CheckpointInterval:        cfg.CheckpointInterval,
+		ForecastWindow:            cfg.ForecastWindow,
+		ForecastInterval:          cfg.ForecastInterval,
 		DefaultCPUThreshold:       80.0,
 		DefaultMemoryThreshold:    85.0,
 		DefaultLatencyThreshold:   1000.0,
@@ -107,28 +191,65 @@ func main() {
 		DeviationMultiplier:       2.0,
 		MinSamplesForDeviation:    10,
 		DefaultCooldownPeriod:     cfg.AlertCooldown,
+		HeartbeatTimeout:          cfg.HeartbeatTimeout,
+		HeartbeatMetricType:       models.MetricType(cfg.HeartbeatMetricType),
 	}
 
 	analyzer := core.NewAnalyzer(
 		analyzerConfig,
 		metricsStore,
 		rulesStore,
+		registry,
 		alertPublisher,
 		logger,
 	)
 
-	// Create context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// In streaming mode the consumer drives evaluation on every metric
+	var metricAnalyzer ports.MetricAnalyzer
+	if cfg.EvaluationMode == core.EvaluationModeStreaming {
+		metricAnalyzer = analyzer
+	}
+
+	// With partition affinity each replica only evaluates the services it consumes
+	var partitionOwner ports.PartitionOwner
+	if cfg.PartitionAffinity {
+		partitionOwner = analyzer
+	}
+
+	// Guard Redis and the windows against high-cardinality labels
+	limiter := core.NewCardinalityLimiter(core.CardinalityConfig{
+		MaxSeriesPerMetric:  cfg.MaxSeriesPerMetric,
+		LabelAllowlist:      cfg.LabelAllowlist,
+		LabelDenylist:       cfg.LabelDenylist,
+		MaxLabelValueLength: cfg.MaxLabelValueLength,
+		SeriesTTL:           cfg.SeriesTTL,
+	}, logger, m)
+
+	// Heartbeats reach Redis once per checkpoint, late by up to an interval
+	if cfg.HeartbeatTimeout > 0 && cfg.CheckpointInterval >= cfg.HeartbeatTimeout {
+		logger.Warn("checkpoint interval is not below the heartbeat timeout, services may be reported absent",
+			zap.Duration("checkpoint_interval", cfg.CheckpointInterval),
+			zap.Duration("heartbeat_timeout", cfg.HeartbeatTimeout),
+		)
+	}
 
-	// Start metrics consumer if Kafka is available
+	// Create the metrics consumer if Kafka is available
+	var consumer *adapters.KafkaMetricsConsumer
 	if kafkaAvailable {
-		consumer, err := adapters.NewKafkaMetricsConsumer(
-			cfg.KafkaBrokers,
+		consumer, err = adapters.NewKafkaMetricsConsumer(
+			broker,
 			cfg.KafkaMetricsTopic,
 			cfg.KafkaLogsTopic,
 			cfg.KafkaConsumerGroup,
+			cfg.IngestWorkers,
+			cfg.CheckpointInterval,
+			sharedkafka.NewDecoder(schemaRegistry),
 			metricsStore,
+			registry,
+			metricAnalyzer,
+			partitionOwner,
+			limiter,
+			logStore,
 			logger,
 			m,
 		)
@@ -136,27 +257,66 @@ func main() {
 			logger.Warn("failed to initialize Kafka metrics consumer",
 				zap.Error(err),
 			)
-		} else {
-			if err := consumer.Start(ctx); err != nil {
-				logger.Error("failed to start metrics consumer", zap.Error(err))
-			} else {
-				defer consumer.Stop()
-				logger.Info("Kafka metrics consumer started")
-			}
 		}
 	}
 
-	// Start analyzer
+	// Partitions are only ever assigned through the consumer; without one
+	// this replica evaluates every service
+	analyzerConfig.PartitionAffinity = consumer != nil && cfg.PartitionAffinity
+	analyzerConfig.MetricsPartitions = metricsPartitions
+	if cfg.PartitionAffinity && !analyzerConfig.PartitionAffinity {
+		logger.Warn("partition affinity disabled without a metrics consumer")
+	}
+
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Reconcile file-managed rules before the analyzer loads its rules
+	if cfg.RulesPath != "" {
+		reconciler := core.NewRuleReconciler(
+			adapters.NewFileRuleSource(cfg.RulesPath),
+			rulesStore,
+			cfg.RulesReloadInterval,
+			logger,
+		)
+		if err := reconciler.Start(ctx); err != nil {
+			logger.Fatal("failed to load rule files",
+				zap.String("path", cfg.RulesPath),
+				zap.Error(err),
+			)
+		}
+		defer reconciler.Stop()
+		logger.Info("rule file reconciliation started",
+			zap.String("path", cfg.RulesPath),
+			zap.Duration("reload_interval", cfg.RulesReloadInterval),
+		)
+	}
+
+	// Start analyzer before the consumer so rules and windows are loaded first
 	if err := analyzer.Start(ctx); err != nil {
 		logger.Fatal("failed to start analyzer", zap.Error(err))
 	}
 	defer analyzer.Stop()
 
+	// Start the metrics consumer once rules and windows are loaded
+	if consumer != nil {
+		if err := consumer.Start(ctx); err != nil {
+			if analyzerConfig.PartitionAffinity {
+				logger.Fatal("failed to start metrics consumer", zap.Error(err))
+			}
+			logger.Error("failed to start metrics consumer", zap.Error(err))
+		} else {
+			defer consumer.Stop()
+			logger.Info("Kafka metrics consumer started")
+		}
+	}
+
 	// Start metrics HTTP server
 	metricsAddr := fmt.Sprintf(":%d", cfg.MetricsPort)
 	metricsServer := &http.Server{
 		Addr:    metricsAddr,
-		Handler: promhttp.Handler(),
+		Handler: m.Handler(),
 	}
 
 	go func() {
@@ -181,11 +341,39 @@ func main() {
 			w.Write([]byte(`{"status":"not_ready","reason":"redis_unavailable"}`))
 			return
 		}
+		// Not ready until the consumer group assigned this replica its partitions
+		if !analyzer.HasPartitions() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"not_ready","reason":"no_partitions_assigned"}`))
+			return
+		}
+		// Not ready while a consumer is too far behind
+		if cfg.ReadyMaxLag > 0 {
+			if lag, topic := m.MaxKafkaConsumerLag(); lag > cfg.ReadyMaxLag {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, `{"status":"not_ready","reason":"kafka_lag","topic":%q,"lag":%d}`, topic, lag)
+				return
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ready","service":"analyzer"}`))
 	})
 
+	// Admin: the labels with the most distinct values seen by this replica
+	healthMux.HandleFunc("/admin/cardinality", func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(limiter.TopLabels(limit))
+	})
+
 	healthAddr := fmt.Sprintf(":%d", cfg.HTTPPort)
 	healthServer := &http.Server{
 		Addr:    healthAddr,
@@ -227,3 +415,48 @@ func main() {
 
 	logger.Info("analyzer service stopped")
 }
+
+// alertEnrichers returns the enrichers configured for alerts.
+func alertEnrichers(cfg *config.Config, metricsStore ports.MetricsStore, logStore ports.LogStore, logger *logging.Logger) []ports.AlertEnricher {
+	var enrichers []ports.AlertEnricher
+
+	if cfg.ServiceCatalogPath != "" {
+		catalog, err := adapters.LoadServiceCatalog(cfg.ServiceCatalogPath)
+		if err != nil {
+			logger.Warn("failed to load service catalog, alerts are not enriched with owners",
+				zap.String("path", cfg.ServiceCatalogPath),
+				zap.Error(err),
+			)
+		} else {
+			enrichers = append(enrichers, core.NewCatalogEnricher(catalog))
+		}
+	}
+
+	enrichers = append(enrichers,
+		core.NewRecentLogsEnricher(logStore, cfg.EnrichmentLogLines),
+		core.NewRecentSamplesEnricher(metricsStore, cfg.EnrichmentSamples, cfg.SlidingWindowSize),
+		core.NewExampleTraceEnricher(metricsStore, logStore, cfg.SlidingWindowSize),
+	)
+	if cfg.DashboardURL != "" {
+		enrichers = append(enrichers, core.NewDashboardLinkEnricher(cfg.DashboardURL))
+	}
+
+	return enrichers
+}
+
+// checkRuleFiles validates the rule files at path and returns the process exit code.
+func checkRuleFiles(path string) int {
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "no rule path given: set RULES_PATH or --rules")
+		return 2
+	}
+
+	rules, _, err := adapters.NewFileRuleSource(path).LoadRules()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%s: %d rules OK\n", path, len(rules))
+	return 0
+}
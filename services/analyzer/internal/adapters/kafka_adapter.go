@@ -3,7 +3,7 @@ package adapters
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"sync"
 	"time"
 
@@ -19,11 +19,24 @@ import (
 
 // KafkaMetricsConsumer consumes metrics from Kafka.
 type KafkaMetricsConsumer struct {
-	metricsConsumer *sharedkafka.Consumer
-	logsConsumer    *sharedkafka.Consumer
+	metricsConsumer sharedkafka.MessageConsumer
+	logsConsumer    sharedkafka.MessageConsumer
+	metricsOptions  *sharedkafka.ConsumeOptions
+	logsOptions     *sharedkafka.ConsumeOptions
 	metricsStore    ports.MetricsStore
+	registry        ports.ServiceRegistry
+	analyzer        ports.MetricAnalyzer
+	owner           ports.PartitionOwner
+	limiter         ports.MetricLimiter
+	logStore        ports.LogStore
+	decoder         *sharedkafka.Decoder
 	logger          *logging.Logger
-	metrics         *metrics.Metrics
+
+	// Heartbeats and registrations observed since the last flush
+	flushInterval time.Duration
+	stateMu       sync.Mutex
+	heartbeats    map[models.ServiceName]map[models.MetricType]time.Time
+	registrations map[models.ServiceName]*models.ServiceRegistration
 
 	mu      sync.Mutex
 	running bool
@@ -32,37 +45,102 @@ type KafkaMetricsConsumer struct {
 }
 
 // NewKafkaMetricsConsumer creates a new KafkaMetricsConsumer.
+// When analyzer is non-nil every consumed metric is evaluated as it arrives.
+// When owner is non-nil it is told which metrics partitions this replica owns.
+// When limiter is non-nil it applies the label policy before metrics are stored.
+// When logStore is non-nil warning and error logs are kept for alert enrichment.
+// Messages that keep failing are dead-lettered to each topic's DLQ topic.
+// Metrics are handled on ingestWorkers workers per partition.
+// Heartbeats and service registrations are kept in memory and written to
+// Redis every flushInterval, or per metric when it is not positive.
+// Messages are decoded by decoder according to their content type.
 func NewKafkaMetricsConsumer(
-	brokers []string,
+	broker sharedkafka.Broker,
 	metricsTopic, logsTopic, consumerGroup string,
+	ingestWorkers int,
+	flushInterval time.Duration,
+	decoder *sharedkafka.Decoder,
 	metricsStore ports.MetricsStore,
+	registry ports.ServiceRegistry,
+	analyzer ports.MetricAnalyzer,
+	owner ports.PartitionOwner,
+	limiter ports.MetricLimiter,
+	logStore ports.LogStore,
 	logger *logging.Logger,
 	m *metrics.Metrics,
 ) (*KafkaMetricsConsumer, error) {
-	metricsConfig := sharedkafka.DefaultConsumerConfig(brokers, metricsTopic, consumerGroup+"-metrics")
+	metricsConfig := sharedkafka.DefaultConsumerConfig(nil, metricsTopic, consumerGroup+"-metrics")
 	metricsConfig.StartOffset = kafka.LastOffset
-	metricsConsumer, err := sharedkafka.NewConsumer(metricsConfig, logger)
+	metricsConfig.Metrics = m
+
+	var listener sharedkafka.RebalanceListener
+	if owner != nil {
+		listener = owner
+	}
+	metricsConsumer, err := broker.NewGroupConsumer(metricsConfig, listener, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	logsConfig := sharedkafka.DefaultConsumerConfig(brokers, logsTopic, consumerGroup+"-logs")
+	logsConfig := sharedkafka.DefaultConsumerConfig(nil, logsTopic, consumerGroup+"-logs")
 	logsConfig.StartOffset = kafka.LastOffset
-	logsConsumer, err := sharedkafka.NewConsumer(logsConfig, logger)
+	logsConfig.Metrics = m
+	logsConsumer, err := broker.NewConsumer(logsConfig, logger)
+	if err != nil {
+		metricsConsumer.Close()
+		return nil, err
+	}
+
+	metricsOptions, err := consumeOptions(broker, metricsTopic, logger, m)
+	if err != nil {
+		metricsConsumer.Close()
+		logsConsumer.Close()
+		return nil, err
+	}
+	// Metrics are keyed by service, so each service's metrics stay in order
+	metricsOptions.Workers = ingestWorkers
+	logsOptions, err := consumeOptions(broker, logsTopic, logger, m)
 	if err != nil {
 		metricsConsumer.Close()
+		logsConsumer.Close()
+		metricsOptions.DLQ.Close()
 		return nil, err
 	}
 
 	return &KafkaMetricsConsumer{
 		metricsConsumer: metricsConsumer,
 		logsConsumer:    logsConsumer,
+		metricsOptions:  metricsOptions,
+		logsOptions:     logsOptions,
 		metricsStore:    metricsStore,
+		registry:        registry,
+		analyzer:        analyzer,
+		owner:           owner,
+		limiter:         limiter,
+		logStore:        logStore,
+		decoder:         decoder,
 		logger:          logger,
-		metrics:         m,
+		flushInterval:   flushInterval,
+		heartbeats:      make(map[models.ServiceName]map[models.MetricType]time.Time),
+		registrations:   make(map[models.ServiceName]*models.ServiceRegistration),
 	}, nil
 }
 
+// consumeOptions returns the consume options of a topic, dead-lettering to its DLQ topic.
+func consumeOptions(broker sharedkafka.Broker, topic string, logger *logging.Logger, m *metrics.Metrics) (*sharedkafka.ConsumeOptions, error) {
+	dlqConfig := sharedkafka.DefaultProducerConfig(nil, sharedkafka.DLQTopic(topic))
+	dlqConfig.Metrics = m
+	dlq, err := broker.NewProducer(dlqConfig, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DLQ producer: %w", err)
+	}
+
+	opts := sharedkafka.DefaultConsumeOptions()
+	opts.DLQ = dlq
+	opts.Metrics = m
+	return opts, nil
+}
+
 // Start starts consuming metrics.
 func (c *KafkaMetricsConsumer) Start(ctx context.Context) error {
 	c.mu.Lock()
@@ -84,6 +162,11 @@ func (c *KafkaMetricsConsumer) Start(ctx context.Context) error {
 	c.wg.Add(1)
 	go c.consumeLogs(ctx)
 
+	if c.flushInterval > 0 {
+		c.wg.Add(1)
+		go c.flushLoop(ctx)
+	}
+
 	return nil
 }
 
@@ -98,8 +181,8 @@ func (c *KafkaMetricsConsumer) Stop() error {
 	close(c.stopCh)
 	c.mu.Unlock()
 
-	c.wg.Wait()
-
+	// Leaving the group revokes our partitions and ends the metrics loop,
+	// closing the logs consumer ends the logs loop
 	if err := c.metricsConsumer.Close(); err != nil {
 		c.logger.Error("failed to close metrics consumer", zap.Error(err))
 	}
@@ -107,6 +190,19 @@ func (c *KafkaMetricsConsumer) Stop() error {
 		c.logger.Error("failed to close logs consumer", zap.Error(err))
 	}
 
+	c.wg.Wait()
+
+	// Write what was observed since the last flush
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	c.flush(ctx)
+	cancel()
+
+	for _, opts := range []*sharedkafka.ConsumeOptions{c.metricsOptions, c.logsOptions} {
+		if err := opts.DLQ.Close(); err != nil {
+			c.logger.Error("failed to close DLQ producer", zap.Error(err))
+		}
+	}
+
 	c.logger.Info("Kafka metrics consumer stopped")
 	return nil
 }
@@ -114,95 +210,205 @@ func (c *KafkaMetricsConsumer) Stop() error {
 func (c *KafkaMetricsConsumer) consumeMetrics(ctx context.Context) {
 	defer c.wg.Done()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-c.stopCh:
-			return
-		default:
-			msg, err := c.metricsConsumer.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
-				}
-				c.logger.Error("failed to fetch metrics message", zap.Error(err))
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
+	if err := c.metricsConsumer.Consume(ctx, c.handleMetric, c.metricsOptions); err != nil && ctx.Err() == nil {
+		c.logger.Error("metrics consumer stopped", zap.Error(err))
+	}
+}
 
-			var metric models.ServiceMetric
-			if err := json.Unmarshal(msg.Value, &metric); err != nil {
-				c.logger.Warn("failed to deserialize metric",
-					zap.Error(err),
-					zap.String("value", string(msg.Value)),
-				)
-				c.metricsConsumer.CommitMessages(ctx, msg)
-				continue
-			}
+// handleMetric processes a single metrics message. Metrics outside the
+// catalogue are logged and skipped; messages that fail to deserialize are
+// dead-lettered and failures to store the metric retried.
+func (c *KafkaMetricsConsumer) handleMetric(ctx context.Context, msg kafka.Message) error {
+	var metric models.ServiceMetric
+	if err := c.decoder.Decode(ctx, msg, &metric); err != nil {
+		return fmt.Errorf("failed to deserialize metric: %w", err)
+	}
 
-			if err := c.metricsStore.AddMetric(ctx, &metric); err != nil {
-				c.logger.Error("failed to store metric",
-					zap.Error(err),
-					zap.String("metric_id", metric.ID),
-				)
-			}
+	// Reject metrics outside the catalogue and store aliases under their canonical type
+	if err := models.NormalizeMetric(&metric); err != nil {
+		c.logger.Warn("rejected metric",
+			zap.Error(err),
+			zap.String("service", string(metric.ServiceName)),
+			zap.String("metric_type", string(metric.MetricType)),
+		)
+		return nil
+	}
 
-			if c.metrics != nil {
-				c.metrics.RecordKafkaConsume(sharedkafka.TopicServiceMetrics)
-			}
+	if c.limiter != nil {
+		c.limiter.Limit(&metric)
+	}
+
+	if err := c.metricsStore.AddMetric(ctx, &metric); err != nil {
+		return fmt.Errorf("failed to store metric %s: %w", metric.ID, err)
+	}
 
-			c.metricsConsumer.CommitMessages(ctx, msg)
+	c.observe(&metric)
+	if c.flushInterval <= 0 {
+		c.flush(ctx)
+	}
+
+	if c.owner != nil {
+		c.owner.TrackServicePartition(ctx, metric.ServiceName, msg.Partition)
+	}
+
+	if c.analyzer != nil {
+		if err := c.analyzer.AnalyzeMetric(ctx, &metric); err != nil {
+			c.logger.Warn("failed to analyze metric",
+				zap.Error(err),
+				zap.String("metric_id", metric.ID),
+			)
 		}
 	}
+
+	return nil
+}
+
+// observe records the heartbeat and registration of a stored metric.
+func (c *KafkaMetricsConsumer) observe(metric *models.ServiceMetric) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.recordHeartbeat(metric.ServiceName, metric.MetricType, metric.Timestamp)
+
+	registration, ok := c.registrations[metric.ServiceName]
+	if !ok {
+		registration = models.NewServiceRegistration(metric.ServiceName)
+		c.registrations[metric.ServiceName] = registration
+	}
+	registration.Observe(metric)
 }
 
-func (c *KafkaMetricsConsumer) consumeLogs(ctx context.Context) {
+// recordHeartbeat keeps the latest report of a service metric. The caller
+// must hold stateMu.
+func (c *KafkaMetricsConsumer) recordHeartbeat(service models.ServiceName, metricType models.MetricType, seen time.Time) {
+	byType, ok := c.heartbeats[service]
+	if !ok {
+		byType = make(map[models.MetricType]time.Time)
+		c.heartbeats[service] = byType
+	}
+	if seen.After(byType[metricType]) {
+		byType[metricType] = seen
+	}
+}
+
+func (c *KafkaMetricsConsumer) flushLoop(ctx context.Context) {
 	defer c.wg.Done()
 
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-c.stopCh:
 			return
-		default:
-			msg, err := c.logsConsumer.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
+		case <-ticker.C:
+			c.flush(ctx)
+		}
+	}
+}
+
+// flush writes the heartbeats and registrations observed since the last
+// flush. What fails to be written is kept for the next flush.
+func (c *KafkaMetricsConsumer) flush(ctx context.Context) {
+	c.stateMu.Lock()
+	heartbeats, registrations := c.heartbeats, c.registrations
+	c.heartbeats = make(map[models.ServiceName]map[models.MetricType]time.Time)
+	c.registrations = make(map[models.ServiceName]*models.ServiceRegistration)
+	c.stateMu.Unlock()
+
+	if len(heartbeats) > 0 {
+		if err := c.metricsStore.RecordHeartbeats(ctx, heartbeats); err != nil {
+			c.logger.Warn("failed to record heartbeats",
+				zap.Error(err),
+				zap.Int("services", len(heartbeats)),
+			)
+			c.stateMu.Lock()
+			for service, byType := range heartbeats {
+				for metricType, seen := range byType {
+					c.recordHeartbeat(service, metricType, seen)
 				}
-				c.logger.Error("failed to fetch logs message", zap.Error(err))
-				time.Sleep(100 * time.Millisecond)
-				continue
 			}
+			c.stateMu.Unlock()
+		}
+	}
 
-			// For now, just acknowledge logs - they could be stored or analyzed
-			if c.metrics != nil {
-				c.metrics.RecordKafkaConsume(sharedkafka.TopicServiceLogs)
+	if len(registrations) > 0 {
+		observed := make([]*models.ServiceRegistration, 0, len(registrations))
+		for _, registration := range registrations {
+			observed = append(observed, registration)
+		}
+		if err := c.registry.Register(ctx, observed); err != nil {
+			c.logger.Warn("failed to register services",
+				zap.Error(err),
+				zap.Int("services", len(registrations)),
+			)
+			c.stateMu.Lock()
+			for service, registration := range registrations {
+				if current, ok := c.registrations[service]; ok {
+					registration.Merge(current)
+				}
+				c.registrations[service] = registration
 			}
-
-			c.logsConsumer.CommitMessages(ctx, msg)
+			c.stateMu.Unlock()
 		}
 	}
 }
 
+func (c *KafkaMetricsConsumer) consumeLogs(ctx context.Context) {
+	defer c.wg.Done()
+
+	if err := c.logsConsumer.Consume(ctx, c.handleLog, c.logsOptions); err != nil && ctx.Err() == nil {
+		c.logger.Error("logs consumer stopped", zap.Error(err))
+	}
+}
+
+// handleLog keeps warning and error logs for alert enrichment.
+func (c *KafkaMetricsConsumer) handleLog(ctx context.Context, msg kafka.Message) error {
+	if c.logStore == nil {
+		return nil
+	}
+
+	var log models.ServiceLog
+	if err := c.decoder.Decode(ctx, msg, &log); err != nil {
+		return fmt.Errorf("failed to deserialize log: %w", err)
+	}
+
+	switch log.Level {
+	case models.LogLevelWarn, models.LogLevelError, models.LogLevelFatal:
+	default:
+		return nil
+	}
+
+	if err := c.logStore.AddLog(ctx, &log); err != nil {
+		return fmt.Errorf("failed to store log of %s: %w", log.ServiceName, err)
+	}
+	return nil
+}
+
 // KafkaAlertPublisher publishes alerts to Kafka.
 type KafkaAlertPublisher struct {
-	producer *sharedkafka.Producer
+	producer sharedkafka.MessageProducer
 	logger   *logging.Logger
 	metrics  *metrics.Metrics
 }
 
-// NewKafkaAlertPublisher creates a new KafkaAlertPublisher.
+// NewKafkaAlertPublisher creates a new KafkaAlertPublisher. Alerts are
+// published idempotently: each carries its ID in the message-id header,
+// which the alert-engine deduplicates on.
 func NewKafkaAlertPublisher(
-	brokers []string,
+	broker sharedkafka.Broker,
 	alertsTopic string,
+	codec sharedkafka.Codec,
 	logger *logging.Logger,
 	m *metrics.Metrics,
 ) (*KafkaAlertPublisher, error) {
-	config := sharedkafka.DefaultProducerConfig(brokers, alertsTopic)
-	producer, err := sharedkafka.NewProducer(config, logger)
+	config := sharedkafka.DefaultProducerConfig(nil, alertsTopic)
+	config.Metrics = m
+	config.Codec = codec
+	config.Idempotent = true
+	producer, err := broker.NewProducer(config, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -214,16 +420,17 @@ func NewKafkaAlertPublisher(
 	}, nil
 }
 
-// PublishAlert publishes an alert to Kafka.
+// PublishAlert publishes an alert to Kafka. An alert raised while handling a
+// consumed metric gets an ID derived from that metric, so an alert raised
+// again when the metric is redelivered is published as a duplicate of the
+// first one.
 func (p *KafkaAlertPublisher) PublishAlert(ctx context.Context, alert *models.Alert) error {
 	timer := metrics.NewTimer()
 
-	data, err := alert.ToJSON()
-	if err != nil {
-		return err
+	if id, ok := sharedkafka.DerivedMessageID(ctx, alert.EnsureFingerprint()+":"+string(alert.Severity)); ok {
+		alert.ID = id
 	}
-
-	err = p.producer.Publish(ctx, []byte(alert.ID), data)
+	err := p.producer.PublishValue(sharedkafka.WithMessageID(ctx, alert.ID), []byte(alert.ID), alert)
 	if p.metrics != nil {
 		p.metrics.RecordKafkaPublish(sharedkafka.TopicAlerts, timer.Elapsed(), err)
 	}
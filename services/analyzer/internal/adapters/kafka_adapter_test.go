@@ -0,0 +1,285 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+
+	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/analyzer/internal/ports"
+)
+
+// recordingStore records the metrics it stores. Other methods are not used
+// by the consumer.
+type recordingStore struct {
+	ports.MetricsStore
+
+	mu         sync.Mutex
+	metrics    []*models.ServiceMetric
+	heartbeats map[models.ServiceName]map[models.MetricType]time.Time
+}
+
+func (s *recordingStore) AddMetric(ctx context.Context, metric *models.ServiceMetric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = append(s.metrics, metric)
+	return nil
+}
+
+func (s *recordingStore) RecordHeartbeats(ctx context.Context, heartbeats map[models.ServiceName]map[models.MetricType]time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.heartbeats == nil {
+		s.heartbeats = make(map[models.ServiceName]map[models.MetricType]time.Time)
+	}
+	for service, byType := range heartbeats {
+		s.heartbeats[service] = byType
+	}
+	return nil
+}
+
+func (s *recordingStore) heartbeatCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.heartbeats)
+}
+
+func (s *recordingStore) stored() []*models.ServiceMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.ServiceMetric(nil), s.metrics...)
+}
+
+// nopRegistry registers nothing.
+type nopRegistry struct {
+	ports.ServiceRegistry
+}
+
+func (nopRegistry) Register(ctx context.Context, registrations []*models.ServiceRegistration) error {
+	return nil
+}
+
+func testLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	logger, err := logging.NewLogger(logging.DefaultConfig("analyzer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestMetricsIngest publishes metrics the way the services do and checks
+// that the consumer stores them, flushes their heartbeats when it stops and
+// dead-letters malformed messages.
+func TestMetricsIngest(t *testing.T) {
+	logger := testLogger(t)
+	broker := sharedkafka.NewMemoryBroker()
+	store := &recordingStore{}
+
+	consumer, err := NewKafkaMetricsConsumer(
+		broker,
+		"metrics",
+		"logs",
+		"analyzer",
+		1,
+		time.Hour,
+		sharedkafka.NewDecoder(nil),
+		store,
+		nopRegistry{},
+		nil,
+		nil,
+		nil,
+		nil,
+		logger,
+		metrics.NewMetrics("analyzer"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := consumer.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Stop()
+
+	producer, err := broker.NewProducer(sharedkafka.DefaultProducerConfig(nil, "metrics"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	for _, service := range []models.ServiceName{models.ServiceOrders, models.ServicePayments, models.ServiceAuth} {
+		metric := models.NewServiceMetric(service, models.MetricTypeCPU, 42, "percent")
+		if err := producer.PublishValue(ctx, []byte(service), metric); err != nil {
+			t.Fatalf("failed to publish metric: %v", err)
+		}
+	}
+	if err := producer.Publish(ctx, []byte(models.ServiceOrders), []byte("{")); err != nil {
+		t.Fatalf("failed to publish malformed metric: %v", err)
+	}
+
+	waitFor(t, "the stored metrics", func() bool { return len(store.stored()) == 3 })
+	waitFor(t, "the dead letter", func() bool { return len(broker.Messages("metrics-dlq")) == 1 })
+
+	if got := store.heartbeatCount(); got != 0 {
+		t.Errorf("%d heartbeats recorded before the flush, want none", got)
+	}
+	consumer.Stop()
+	if got := store.heartbeatCount(); got != 3 {
+		t.Errorf("%d heartbeats recorded on stop, want 3", got)
+	}
+}
+
+// TestAlertPublisher checks that alerts are published idempotently, with
+// the message-id header the alert-engine deduplicates on.
+func TestAlertPublisher(t *testing.T) {
+	logger := testLogger(t)
+	broker := sharedkafka.NewMemoryBroker()
+
+	publisher, err := NewKafkaAlertPublisher(broker, "alerts", sharedkafka.JSONCodec{}, logger, metrics.NewMetrics("analyzer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+
+	alert := &models.Alert{
+		ID:          uuid.New().String(),
+		Type:        models.AlertTypeThresholdViolation,
+		Severity:    models.AlertSeverityWarning,
+		ServiceName: models.ServicePayments,
+		Title:       "High latency",
+		Description: "latency above threshold",
+		Timestamp:   time.Now(),
+	}
+	if err := publisher.PublishAlert(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := broker.Messages("alerts")
+	if len(msgs) != 1 {
+		t.Fatalf("published %d messages, want 1", len(msgs))
+	}
+	var published models.Alert
+	if err := json.Unmarshal(msgs[0].Value, &published); err != nil {
+		t.Fatalf("failed to decode alert: %v", err)
+	}
+	if published.ID != alert.ID {
+		t.Errorf("published alert %s, want %s", published.ID, alert.ID)
+	}
+	var messageID string
+	for _, h := range msgs[0].Headers {
+		if h.Key == sharedkafka.HeaderMessageID {
+			messageID = string(h.Value)
+		}
+	}
+	if messageID == "" {
+		t.Error("alert published without a message-id header")
+	}
+}
+
+// TestAlertPublisherDerivesIDs raises alerts while handling a metric, twice
+// as when the metric is redelivered, and checks that each problem and
+// severity gets the same message ID every time.
+func TestAlertPublisherDerivesIDs(t *testing.T) {
+	logger := testLogger(t)
+	broker := sharedkafka.NewMemoryBroker()
+
+	publisher, err := NewKafkaAlertPublisher(broker, "alerts", sharedkafka.JSONCodec{}, logger, metrics.NewMetrics("analyzer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+
+	producer, err := broker.NewProducer(sharedkafka.DefaultProducerConfig(nil, "metrics"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	if err := producer.Publish(context.Background(), []byte("payments"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	consumerConfig := sharedkafka.DefaultConsumerConfig(nil, "metrics", "analyzer")
+	consumerConfig.StartOffset = kafka.FirstOffset
+	consumer, err := broker.NewConsumer(consumerConfig, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	raise := func(ctx context.Context, severity models.AlertSeverity) error {
+		return publisher.PublishAlert(ctx, &models.Alert{
+			ID:          uuid.New().String(),
+			Type:        models.AlertTypeThresholdViolation,
+			Severity:    severity,
+			ServiceName: models.ServicePayments,
+			Title:       "High latency",
+			Timestamp:   time.Now(),
+			RuleID:      "latency",
+			Labels:      models.Labels{"service": "payments"},
+		})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go consumer.Consume(ctx, func(ctx context.Context, msg kafka.Message) error {
+		for _, severity := range []models.AlertSeverity{
+			models.AlertSeverityWarning,
+			models.AlertSeverityWarning, // raised again on redelivery
+			models.AlertSeverityCritical,
+		} {
+			if err := raise(ctx, severity); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, sharedkafka.DefaultConsumeOptions())
+
+	waitFor(t, "the alerts", func() bool { return len(broker.Messages("alerts")) == 3 })
+
+	// Outside a handler every alert keeps its own ID
+	if err := raise(context.Background(), models.AlertSeverityWarning); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, msg := range broker.Messages("alerts") {
+		var published models.Alert
+		if err := json.Unmarshal(msg.Value, &published); err != nil {
+			t.Fatalf("failed to decode alert: %v", err)
+		}
+		if want := models.AlertFingerprint("latency", models.Labels{"service": "payments"}); published.Fingerprint != want {
+			t.Errorf("fingerprint %s, want %s", published.Fingerprint, want)
+		}
+		if id := sharedkafka.Header(msg, sharedkafka.HeaderMessageID); id != published.ID {
+			t.Errorf("message ID %s, want the alert ID %s", id, published.ID)
+		}
+		ids = append(ids, published.ID)
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("redelivery published IDs %s and %s, want the same", ids[0], ids[1])
+	}
+	if ids[2] == ids[0] || ids[3] == ids[0] {
+		t.Errorf("IDs %v, want other severities and alerts outside handlers to differ", ids)
+	}
+}
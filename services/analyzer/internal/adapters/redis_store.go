@@ -12,24 +12,76 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	"github.com/microservices-platform/pkg/shared/evaluation"
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/rulestore"
 	"github.com/microservices-platform/pkg/shared/utils"
 	"github.com/microservices-platform/services/analyzer/internal/ports"
 )
 
 // RedisMetricsStore implements MetricsStore using Redis sorted sets.
 type RedisMetricsStore struct {
-	client *redis.Client
-	logger *logging.Logger
+	client    *redis.Client
+	logger    *logging.Logger
+	maxSeries int // labelled series indexed per service metric
 }
 
-// NewRedisMetricsStore creates a new RedisMetricsStore.
-func NewRedisMetricsStore(client *redis.Client, logger *logging.Logger) ports.MetricsStore {
+// NewRedisMetricsStore creates a new RedisMetricsStore. At most maxSeries
+// label sets are indexed per service metric; samples of further series are
+// stored but not indexed.
+func NewRedisMetricsStore(client *redis.Client, logger *logging.Logger, maxSeries int) ports.MetricsStore {
 	return &RedisMetricsStore{
-		client: client,
-		logger: logger,
+		client:    client,
+		logger:    logger,
+		maxSeries: maxSeries,
+	}
+}
+
+// seriesRetention is how long an unseen series stays in the series index.
+const seriesRetention = 24 * time.Hour
+
+// indexSeriesScript adds a label set to a series index unless the index is
+// full, after dropping series not seen within the retention.
+// KEYS[1]: index, ARGV: label set, now, stale cutoff, limit.
+var indexSeriesScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[3])
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) or redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[4]) then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// indexSeries records the label set of a sample's series, bounded by maxSeries.
+func (s *RedisMetricsStore) indexSeries(ctx context.Context, metric *models.ServiceMetric) error {
+	if len(metric.Labels) == 0 || s.maxSeries <= 0 {
+		return nil
+	}
+
+	// JSON encodes maps with sorted keys, so equal label sets are equal members
+	labels, err := json.Marshal(metric.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to serialize labels: %w", err)
 	}
+
+	now := metric.Timestamp.Unix()
+	indexed, err := indexSeriesScript.Run(ctx, s.client,
+		[]string{models.SeriesIndexKey(metric.ServiceName, models.CanonicalMetricType(metric.MetricType))},
+		string(labels), now, now-int64(seriesRetention.Seconds()), s.maxSeries,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to index series: %w", err)
+	}
+	if indexed == 0 {
+		s.logger.Debug("series limit reached, series not indexed",
+			zap.String("service", string(metric.ServiceName)),
+			zap.String("series", models.SeriesID(metric.MetricType, metric.Labels)),
+			zap.Int("max_series", s.maxSeries),
+		)
+	}
+
+	return nil
 }
 
 // metricsKey generates a Redis key for metrics.
@@ -37,7 +89,8 @@ func (s *RedisMetricsStore) metricsKey(serviceName models.ServiceName, metricTyp
 	return fmt.Sprintf("metrics:%s:%s", serviceName, metricType)
 }
 
-// AddMetric adds a metric to the sliding window.
+// AddMetric adds a metric to the sliding window. Heartbeats are recorded
+// separately, in batches, by RecordHeartbeats.
 func (s *RedisMetricsStore) AddMetric(ctx context.Context, metric *models.ServiceMetric) error {
 	key := s.metricsKey(metric.ServiceName, metric.MetricType)
 
@@ -46,24 +99,26 @@ func (s *RedisMetricsStore) AddMetric(ctx context.Context, metric *models.Servic
 		return fmt.Errorf("failed to serialize metric: %w", err)
 	}
 
-	// Use timestamp as score for sorted set
-	score := float64(metric.Timestamp.UnixNano())
-
-	err = s.client.ZAdd(ctx, key, redis.Z{
-		Score:  score,
+	// Store the sample, scored by timestamp, and the latest metric for quick
+	// access in one round trip
+	latestKey := fmt.Sprintf("metrics:latest:%s:%s", metric.ServiceName, metric.MetricType)
+	pipe := s.client.Pipeline()
+	add := pipe.ZAdd(ctx, key, redis.Z{
+		Score:  float64(metric.Timestamp.UnixNano()),
 		Member: string(data),
-	}).Err()
-	if err != nil {
-		return fmt.Errorf("failed to add metric to Redis: %w", err)
+	})
+	latest := pipe.Set(ctx, latestKey, string(data), 10*time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil && add.Err() != nil {
+		return fmt.Errorf("failed to add metric to Redis: %w", add.Err())
 	}
-
-	// Also store the latest metric separately for quick access
-	latestKey := fmt.Sprintf("metrics:latest:%s:%s", metric.ServiceName, metric.MetricType)
-	err = s.client.Set(ctx, latestKey, string(data), 10*time.Minute).Err()
-	if err != nil {
+	if err := latest.Err(); err != nil {
 		s.logger.Warn("failed to set latest metric", zap.Error(err))
 	}
 
+	if err := s.indexSeries(ctx, metric); err != nil {
+		s.logger.Warn("failed to index series", zap.Error(err))
+	}
+
 	return nil
 }
 
@@ -169,18 +224,9 @@ func (s *RedisMetricsStore) CleanupOldMetrics(ctx context.Context, retentionPeri
 
 // GetMetricsWindow retrieves all metrics for a service within a time window.
 func (s *RedisMetricsStore) GetMetricsWindow(ctx context.Context, serviceName models.ServiceName, windowSize time.Duration) ([]*models.ServiceMetric, error) {
-	// Get metrics for all metric types for this service
-	metricTypes := []models.MetricType{
-		models.MetricTypeCPU,
-		models.MetricTypeMemory,
-		models.MetricTypeLatencyP95,
-		models.MetricTypeLatencyP99,
-		models.MetricTypeErrorRate,
-		models.MetricTypeRequestRate,
-	}
-
+	// Get metrics for every stored metric type in the catalogue, including legacy aliases
 	var allMetrics []*models.ServiceMetric
-	for _, metricType := range metricTypes {
+	for _, metricType := range models.StoredMetricTypes() {
 		metrics, err := s.GetMetricsInWindow(ctx, serviceName, metricType, windowSize)
 		if err != nil {
 			s.logger.Warn("failed to get metrics for type",
@@ -232,23 +278,37 @@ func (s *RedisMetricsStore) CheckAndSetAlertSent(ctx context.Context, deduplicat
 	return !result, nil
 }
 
-// CheckCooldown checks if a service/metric is in cooldown.
-func (s *RedisMetricsStore) CheckCooldown(ctx context.Context, serviceName, metricType string) (bool, error) {
-	key := fmt.Sprintf("cooldown:%s:%s", serviceName, metricType)
+// ClearAlertSent releases a deduplication key, for an alert that failed to publish.
+func (s *RedisMetricsStore) ClearAlertSent(ctx context.Context, deduplicationKey string) error {
+	key := fmt.Sprintf("alert:sent:%s", deduplicationKey)
 
-	exists, err := s.client.Exists(ctx, key).Result()
+	if err := s.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to clear alert sent: %w", err)
+	}
+	return nil
+}
+
+// GetCooldown returns the severity alerted for a cooldown key of a service,
+// or an empty severity when the key is not in cooldown.
+func (s *RedisMetricsStore) GetCooldown(ctx context.Context, serviceName, key string) (models.AlertSeverity, error) {
+	redisKey := fmt.Sprintf("cooldown:%s:%s", serviceName, key)
+
+	severity, err := s.client.Get(ctx, redisKey).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
 	if err != nil {
-		return false, fmt.Errorf("failed to check cooldown: %w", err)
+		return "", fmt.Errorf("failed to check cooldown: %w", err)
 	}
 
-	return exists > 0, nil
+	return models.AlertSeverity(severity), nil
 }
 
-// SetCooldown sets a cooldown period for a service/metric.
-func (s *RedisMetricsStore) SetCooldown(ctx context.Context, serviceName, metricType string, duration time.Duration) error {
-	key := fmt.Sprintf("cooldown:%s:%s", serviceName, metricType)
+// SetCooldown starts a cooldown for a key of a service, recording the severity alerted.
+func (s *RedisMetricsStore) SetCooldown(ctx context.Context, serviceName, key string, severity models.AlertSeverity, duration time.Duration) error {
+	redisKey := fmt.Sprintf("cooldown:%s:%s", serviceName, key)
 
-	err := s.client.Set(ctx, key, "1", duration).Err()
+	err := s.client.Set(ctx, redisKey, string(severity), duration).Err()
 	if err != nil {
 		return fmt.Errorf("failed to set cooldown: %w", err)
 	}
@@ -256,6 +316,250 @@ func (s *RedisMetricsStore) SetCooldown(ctx context.Context, serviceName, metric
 	return nil
 }
 
+const heartbeatsKey = "analyzer:heartbeats"
+
+// heartbeatKey generates a Redis key for the per-metric heartbeats of a service.
+func (s *RedisMetricsStore) heartbeatKey(serviceName models.ServiceName) string {
+	return fmt.Sprintf("%s:%s", heartbeatsKey, serviceName)
+}
+
+// GetLastHeartbeat returns when a service last reported a metric of the given type.
+func (s *RedisMetricsStore) GetLastHeartbeat(ctx context.Context, serviceName models.ServiceName, metricType models.MetricType) (time.Time, error) {
+	var data string
+	var err error
+	if metricType == "" {
+		data, err = s.client.HGet(ctx, heartbeatsKey, string(serviceName)).Result()
+	} else {
+		data, err = s.client.HGet(ctx, s.heartbeatKey(serviceName), string(metricType)).Result()
+	}
+	if err == redis.Nil {
+		return time.Time{}, utils.ErrNotFound("heartbeat")
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get heartbeat: %w", err)
+	}
+
+	nanos, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse heartbeat: %w", err)
+	}
+
+	return time.Unix(0, nanos).UTC(), nil
+}
+
+// RecordHeartbeats stores when services last reported each metric type, and
+// any metric, in one round trip.
+func (s *RedisMetricsStore) RecordHeartbeats(ctx context.Context, heartbeats map[models.ServiceName]map[models.MetricType]time.Time) error {
+	pipe := s.client.Pipeline()
+	for service, byType := range heartbeats {
+		var last time.Time
+		values := make([]interface{}, 0, 2*len(byType))
+		for metricType, seen := range byType {
+			values = append(values, string(metricType), strconv.FormatInt(seen.UnixNano(), 10))
+			if seen.After(last) {
+				last = seen
+			}
+		}
+		pipe.HSet(ctx, heartbeatsKey, string(service), strconv.FormatInt(last.UnixNano(), 10))
+		pipe.HSet(ctx, s.heartbeatKey(service), values...)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record heartbeats: %w", err)
+	}
+	return nil
+}
+
+// GetHeartbeats returns the last report time of every service that has published metrics.
+func (s *RedisMetricsStore) GetHeartbeats(ctx context.Context) (map[models.ServiceName]time.Time, error) {
+	data, err := s.client.HGetAll(ctx, heartbeatsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeats: %w", err)
+	}
+
+	heartbeats := make(map[models.ServiceName]time.Time, len(data))
+	for service, value := range data {
+		nanos, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			s.logger.Warn("failed to parse heartbeat",
+				zap.String("service", service),
+				zap.Error(err),
+			)
+			continue
+		}
+		heartbeats[models.ServiceName(service)] = time.Unix(0, nanos).UTC()
+	}
+
+	return heartbeats, nil
+}
+
+// SetServiceHealth stores the current health status of a service.
+func (s *RedisMetricsStore) SetServiceHealth(ctx context.Context, status *models.ServiceHealthStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to serialize health status: %w", err)
+	}
+
+	key := fmt.Sprintf("health:%s", status.ServiceName)
+	if err := s.client.Set(ctx, key, string(data), 24*time.Hour).Err(); err != nil {
+		return fmt.Errorf("failed to set health status: %w", err)
+	}
+
+	return nil
+}
+
+// absentAlertKey is the firing absent-data alert of a service, next to its health.
+func absentAlertKey(serviceName models.ServiceName) string {
+	return fmt.Sprintf("health:%s:absent", serviceName)
+}
+
+// ClaimAbsentAlert stores the absent-data alert firing for its service unless
+// one already is, reporting whether it was stored. The alert stays until it
+// is released, so the replica evaluating the service next resolves it.
+func (s *RedisMetricsStore) ClaimAbsentAlert(ctx context.Context, alert *models.Alert) (bool, error) {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize absent alert: %w", err)
+	}
+
+	claimed, err := s.client.SetNX(ctx, absentAlertKey(alert.ServiceName), string(data), 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to store absent alert: %w", err)
+	}
+	return claimed, nil
+}
+
+// GetAbsentAlerts returns the absent-data alerts firing for the given services.
+func (s *RedisMetricsStore) GetAbsentAlerts(ctx context.Context, services []models.ServiceName) (map[models.ServiceName]*models.Alert, error) {
+	firing := make(map[models.ServiceName]*models.Alert)
+	if len(services) == 0 {
+		return firing, nil
+	}
+
+	keys := make([]string, len(services))
+	for i, service := range services {
+		keys[i] = absentAlertKey(service)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absent alerts: %w", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var alert models.Alert
+		if err := json.Unmarshal([]byte(data), &alert); err != nil {
+			s.logger.Warn("failed to deserialize absent alert",
+				zap.String("service", string(services[i])),
+				zap.Error(err),
+			)
+			continue
+		}
+		firing[services[i]] = &alert
+	}
+
+	return firing, nil
+}
+
+// ReleaseAbsentAlert removes the absent-data alert firing for a service,
+// reporting whether one was.
+func (s *RedisMetricsStore) ReleaseAbsentAlert(ctx context.Context, serviceName models.ServiceName) (bool, error) {
+	deleted, err := s.client.Del(ctx, absentAlertKey(serviceName)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to release absent alert: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// SaveWindowCheckpoint stores the in-memory analysis window of a service.
+func (s *RedisMetricsStore) SaveWindowCheckpoint(ctx context.Context, serviceName models.ServiceName, samples []*models.ServiceMetric, ttl time.Duration) error {
+	key := fmt.Sprintf("analyzer:checkpoint:%s", serviceName)
+	if len(samples) == 0 {
+		return s.client.Del(ctx, key).Err()
+	}
+
+	data, err := json.Marshal(samples)
+	if err != nil {
+		return fmt.Errorf("failed to serialize window checkpoint: %w", err)
+	}
+
+	if err := s.client.Set(ctx, key, string(data), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save window checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// LoadWindowCheckpoint retrieves the last checkpointed analysis window of a service.
+func (s *RedisMetricsStore) LoadWindowCheckpoint(ctx context.Context, serviceName models.ServiceName) ([]*models.ServiceMetric, error) {
+	key := fmt.Sprintf("analyzer:checkpoint:%s", serviceName)
+	data, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load window checkpoint: %w", err)
+	}
+
+	var samples []*models.ServiceMetric
+	if err := json.Unmarshal([]byte(data), &samples); err != nil {
+		return nil, fmt.Errorf("failed to deserialize window checkpoint: %w", err)
+	}
+
+	return samples, nil
+}
+
+// ruleStatesKey is the hash of the latest rule evaluations shared between replicas.
+const ruleStatesKey = "analyzer:rule_states"
+
+// SaveRuleStates stores the latest evaluation of rules, keyed by rule ID, for other replicas.
+func (s *RedisMetricsStore) SaveRuleStates(ctx context.Context, states map[string]evaluation.RuleState, ttl time.Duration) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(states))
+	for id, state := range states {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("failed to serialize rule state: %w", err)
+		}
+		values[id] = string(data)
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.HSet(ctx, ruleStatesKey, values)
+	pipe.Expire(ctx, ruleStatesKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save rule states: %w", err)
+	}
+
+	return nil
+}
+
+// GetRuleStates retrieves the latest evaluation of every rule, keyed by rule ID.
+func (s *RedisMetricsStore) GetRuleStates(ctx context.Context) (map[string]evaluation.RuleState, error) {
+	data, err := s.client.HGetAll(ctx, ruleStatesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule states: %w", err)
+	}
+
+	states := make(map[string]evaluation.RuleState, len(data))
+	for id, value := range data {
+		var state evaluation.RuleState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			s.logger.Warn("failed to deserialize rule state", zap.Error(err))
+			continue
+		}
+		states[id] = state
+	}
+
+	return states, nil
+}
+
 // RedisRuleStore implements RuleStore using Redis.
 type RedisRuleStore struct {
 	client *redis.Client
@@ -270,11 +574,9 @@ func NewRedisRuleStore(client *redis.Client, logger *logging.Logger) ports.RuleS
 	}
 }
 
-const rulesKey = "analyzer:rules"
-
 // GetRule retrieves a rule by ID.
 func (s *RedisRuleStore) GetRule(ctx context.Context, id string) (*models.ThresholdRule, error) {
-	data, err := s.client.HGet(ctx, rulesKey, id).Result()
+	data, err := s.client.HGet(ctx, rulestore.RulesKey, id).Result()
 	if err == redis.Nil {
 		return nil, utils.ErrNotFound("rule")
 	}
@@ -292,7 +594,7 @@ func (s *RedisRuleStore) GetRule(ctx context.Context, id string) (*models.Thresh
 
 // GetAllRules retrieves all rules.
 func (s *RedisRuleStore) GetAllRules(ctx context.Context) ([]*models.ThresholdRule, error) {
-	data, err := s.client.HGetAll(ctx, rulesKey).Result()
+	data, err := s.client.HGetAll(ctx, rulestore.RulesKey).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rules: %w", err)
 	}
@@ -345,24 +647,18 @@ func (s *RedisRuleStore) GetRulesForService(ctx context.Context, serviceName mod
 }
 
 // CreateRule creates a new rule.
-func (s *RedisRuleStore) CreateRule(ctx context.Context, rule *models.ThresholdRule) error {
-	data, err := rule.ToJSON()
-	if err != nil {
-		return fmt.Errorf("failed to serialize rule: %w", err)
-	}
-
-	err = s.client.HSet(ctx, rulesKey, rule.ID, string(data)).Err()
-	if err != nil {
-		return fmt.Errorf("failed to create rule: %w", err)
-	}
-
-	return nil
+func (s *RedisRuleStore) CreateRule(ctx context.Context, rule *models.ThresholdRule, author string) error {
+	return rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action: models.RuleActionCreate,
+		Author: author,
+		Rule:   rule,
+	})
 }
 
 // UpdateRule updates an existing rule.
-func (s *RedisRuleStore) UpdateRule(ctx context.Context, rule *models.ThresholdRule) error {
+func (s *RedisRuleStore) UpdateRule(ctx context.Context, rule *models.ThresholdRule, author string) error {
 	// Check if rule exists
-	exists, err := s.client.HExists(ctx, rulesKey, rule.ID).Result()
+	exists, err := s.client.HExists(ctx, rulestore.RulesKey, rule.ID).Result()
 	if err != nil {
 		return fmt.Errorf("failed to check rule existence: %w", err)
 	}
@@ -371,21 +667,183 @@ func (s *RedisRuleStore) UpdateRule(ctx context.Context, rule *models.ThresholdR
 	}
 
 	rule.UpdatedAt = time.Now().UTC()
-	return s.CreateRule(ctx, rule)
+	return rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action: models.RuleActionUpdate,
+		Author: author,
+		Rule:   rule,
+	})
 }
 
-// DeleteRule deletes a rule.
-func (s *RedisRuleStore) DeleteRule(ctx context.Context, id string) error {
-	result, err := s.client.HDel(ctx, rulesKey, id).Result()
+// DeleteRule deletes a rule. Its version history is kept.
+func (s *RedisRuleStore) DeleteRule(ctx context.Context, id string, author string) error {
+	rule, err := s.GetRule(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete rule: %w", err)
+		return err
 	}
-	if result == 0 {
-		return utils.ErrNotFound("rule")
+
+	return rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action: models.RuleActionDelete,
+		Author: author,
+		Rule:   rule,
+	})
+}
+
+// RedisServiceRegistry implements ServiceRegistry using a Redis hash.
+type RedisServiceRegistry struct {
+	client *redis.Client
+	logger *logging.Logger
+	ttl    time.Duration
+}
+
+// NewRedisServiceRegistry creates a new RedisServiceRegistry. Services and
+// instances that have not reported within ttl are dropped from the registry.
+func NewRedisServiceRegistry(client *redis.Client, logger *logging.Logger, ttl time.Duration) ports.ServiceRegistry {
+	return &RedisServiceRegistry{
+		client: client,
+		logger: logger,
+		ttl:    ttl,
+	}
+}
+
+const registryKey = "registry:services"
+
+// maxRegisterRetries bounds the retries of a registration racing another replica.
+const maxRegisterRetries = 5
+
+// Register merges registrations observed from the metric stream into the
+// registry. Replicas register concurrently, so the read-modify-write of the
+// registrations runs in a transaction retried when another one wins.
+func (r *RedisServiceRegistry) Register(ctx context.Context, registrations []*models.ServiceRegistration) error {
+	if len(registrations) == 0 {
+		return nil
+	}
+	services := make([]string, len(registrations))
+	for i, observed := range registrations {
+		services[i] = string(observed.ServiceName)
+	}
+
+	txf := func(tx *redis.Tx) error {
+		stored, err := tx.HMGet(ctx, registryKey, services...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get service registrations: %w", err)
+		}
+
+		values := make([]interface{}, 0, 2*len(registrations))
+		for i, observed := range registrations {
+			registration := models.NewServiceRegistration(observed.ServiceName)
+			if data, ok := stored[i].(string); ok {
+				if err := json.Unmarshal([]byte(data), registration); err != nil {
+					r.logger.Warn("failed to deserialize service registration, replacing it",
+						zap.String("service", services[i]),
+						zap.Error(err),
+					)
+					registration = models.NewServiceRegistration(observed.ServiceName)
+				}
+			}
+
+			registration.Merge(observed)
+			if r.ttl > 0 {
+				registration.PruneInstances(time.Now().Add(-r.ttl))
+			}
+
+			updated, err := json.Marshal(registration)
+			if err != nil {
+				return fmt.Errorf("failed to serialize service registration: %w", err)
+			}
+			values = append(values, services[i], string(updated))
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, registryKey, values...)
+			return nil
+		})
+		return err
+	}
+
+	// The registry is one hash, so a write to any service fails the transaction
+	for i := 0; i < maxRegisterRetries; i++ {
+		err := r.client.Watch(ctx, txf, registryKey)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to register services: %w", err)
+		}
+		return nil
+	}
+
+	return utils.ErrConflict("service registry was modified concurrently")
+}
+
+// GetServices retrieves all services that reported within the registry TTL.
+func (r *RedisServiceRegistry) GetServices(ctx context.Context) ([]*models.ServiceRegistration, error) {
+	data, err := r.client.HGetAll(ctx, registryKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service registry: %w", err)
+	}
+
+	cutoff := time.Now().Add(-r.ttl)
+	services := make([]*models.ServiceRegistration, 0, len(data))
+	for name, value := range data {
+		var registration models.ServiceRegistration
+		if err := json.Unmarshal([]byte(value), &registration); err != nil {
+			r.logger.Warn("failed to deserialize service registration",
+				zap.String("service", name),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if r.ttl > 0 && registration.LastSeen.Before(cutoff) {
+			r.logger.Info("removing expired service from registry",
+				zap.String("service", name),
+				zap.Time("last_seen", registration.LastSeen),
+			)
+			if err := r.client.HDel(ctx, registryKey, name).Err(); err != nil {
+				r.logger.Warn("failed to remove expired service", zap.Error(err))
+			}
+			continue
+		}
+
+		services = append(services, &registration)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ServiceName < services[j].ServiceName
+	})
+
+	return services, nil
+}
+
+const partitionsKey = "registry:partitions"
+
+// SetServicePartition records the metrics partition a service is published to.
+func (r *RedisServiceRegistry) SetServicePartition(ctx context.Context, serviceName models.ServiceName, partition int) error {
+	if err := r.client.HSet(ctx, partitionsKey, string(serviceName), partition).Err(); err != nil {
+		return fmt.Errorf("failed to set service partition: %w", err)
 	}
 	return nil
 }
 
+// GetServicePartitions retrieves the metrics partition of every known service.
+func (r *RedisServiceRegistry) GetServicePartitions(ctx context.Context) (map[models.ServiceName]int, error) {
+	data, err := r.client.HGetAll(ctx, partitionsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service partitions: %w", err)
+	}
+
+	partitions := make(map[models.ServiceName]int, len(data))
+	for name, value := range data {
+		partition, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		partitions[models.ServiceName(name)] = partition
+	}
+
+	return partitions, nil
+}
+
 // RedisAlertStore implements AlertStore using Redis.
 type RedisAlertStore struct {
 	client *redis.Client
@@ -464,3 +922,65 @@ func (s *RedisAlertStore) GetRecentAlerts(ctx context.Context, limit int) ([]*mo
 
 	return alerts, nil
 }
+
+// RedisLogStore implements LogStore using a capped Redis list per service.
+type RedisLogStore struct {
+	client  *redis.Client
+	logger  *logging.Logger
+	maxLogs int
+	ttl     time.Duration
+}
+
+// NewRedisLogStore creates a new RedisLogStore keeping the maxLogs most
+// recent logs of each service. A service's logs expire ttl after its last log.
+func NewRedisLogStore(client *redis.Client, logger *logging.Logger, maxLogs int, ttl time.Duration) ports.LogStore {
+	return &RedisLogStore{
+		client:  client,
+		logger:  logger,
+		maxLogs: maxLogs,
+		ttl:     ttl,
+	}
+}
+
+func (s *RedisLogStore) logsKey(serviceName models.ServiceName) string {
+	return fmt.Sprintf("logs:recent:%s", serviceName)
+}
+
+// AddLog records a log entry, keeping only the most recent ones per service.
+func (s *RedisLogStore) AddLog(ctx context.Context, log *models.ServiceLog) error {
+	data, err := json.Marshal(log)
+	if err != nil {
+		return fmt.Errorf("failed to serialize log: %w", err)
+	}
+
+	key := s.logsKey(log.ServiceName)
+	pipe := s.client.Pipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, int64(s.maxLogs-1))
+	pipe.Expire(ctx, key, s.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store log: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecentLogs returns up to limit of the most recent logs of a service, newest first.
+func (s *RedisLogStore) GetRecentLogs(ctx context.Context, serviceName models.ServiceName, limit int) ([]*models.ServiceLog, error) {
+	results, err := s.client.LRange(ctx, s.logsKey(serviceName), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent logs: %w", err)
+	}
+
+	logs := make([]*models.ServiceLog, 0, len(results))
+	for _, data := range results {
+		var log models.ServiceLog
+		if err := json.Unmarshal([]byte(data), &log); err != nil {
+			s.logger.Warn("failed to deserialize log", zap.Error(err))
+			continue
+		}
+		logs = append(logs, &log)
+	}
+
+	return logs, nil
+}
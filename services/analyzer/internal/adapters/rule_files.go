@@ -0,0 +1,228 @@
+package adapters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/analyzer/internal/ports"
+)
+
+// ruleNamespace seeds the deterministic IDs of file-managed rules, so a rule
+// keeps its ID across reloads and replicas.
+var ruleNamespace = uuid.MustParse("6f1c2b1e-5c1a-4f0e-9a51-3d2f0c7b8e21")
+
+// ruleFile is the on-disk format of a rule file.
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+// ruleGroup groups rules, typically per service.
+type ruleGroup struct {
+	Name    string     `yaml:"name"`
+	Service string     `yaml:"service"`
+	Rules   []fileRule `yaml:"rules"`
+}
+
+// fileRule is a single rule in a rule file.
+type fileRule struct {
+	Name          string        `yaml:"name"`
+	Description   string        `yaml:"description"`
+	Service       string        `yaml:"service"` // overrides the group service
+	Metric        string        `yaml:"metric"`
+	Operator      string        `yaml:"operator"`
+	Threshold     float64       `yaml:"threshold"`
+	Severity      string        `yaml:"severity"`
+	Window        time.Duration `yaml:"window"`
+	Cooldown      time.Duration `yaml:"cooldown"`
+	Enabled       *bool         `yaml:"enabled"`
+	NotifySlack   bool          `yaml:"notify_slack"`
+	NotifyEmail   bool          `yaml:"notify_email"`
+	NotifyWebhook bool          `yaml:"notify_webhook"`
+	Expression    string        `yaml:"expression"` // makes the rule a composite
+	DependsOn     []string      `yaml:"depends_on"`
+	Aggregation   string        `yaml:"aggregation"`
+	Horizon       time.Duration `yaml:"horizon"` // forecast horizon
+	MinConfidence float64       `yaml:"min_confidence"`
+	GroupBy       []string      `yaml:"group_by"` // alert per label combination
+	Escalations   []struct {
+		Severity  string  `yaml:"severity"`
+		Threshold float64 `yaml:"threshold"`
+	} `yaml:"escalations"`
+}
+
+// FileRuleSource loads rules from a YAML file or a directory of YAML files.
+type FileRuleSource struct {
+	path string
+}
+
+// NewFileRuleSource creates a new FileRuleSource.
+func NewFileRuleSource(path string) ports.RuleSource {
+	return &FileRuleSource{path: path}
+}
+
+// LoadRules loads and validates every rule. All validation errors are returned
+// together so a single lint run reports every problem.
+func (s *FileRuleSource) LoadRules() ([]*models.ThresholdRule, string, error) {
+	files, err := s.ruleFiles()
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := sha256.New()
+	var rules []*models.ThresholdRule
+	var errs []error
+	seen := make(map[string]string)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to read: %w", file, err))
+			continue
+		}
+		digest.Write([]byte(file))
+		digest.Write(data)
+
+		fileRules, fileErrs := parseRuleFile(file, data)
+		errs = append(errs, fileErrs...)
+
+		for _, rule := range fileRules {
+			if other, dup := seen[rule.ID]; dup {
+				errs = append(errs, fmt.Errorf("%s: rule %q duplicates a rule in %s", file, rule.Name, other))
+				continue
+			}
+			seen[rule.ID] = file
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, "", errors.Join(errs...)
+	}
+
+	return rules, hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// ruleFiles lists the rule files under the source path in a stable order.
+// Hidden entries are skipped, which ignores the ..data links of mounted ConfigMaps.
+func (s *FileRuleSource) ruleFiles() ([]string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat rule path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{s.path}, nil
+	}
+
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || entry.IsDir() {
+			continue
+		}
+		if ext := filepath.Ext(name); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		files = append(files, filepath.Join(s.path, name))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func parseRuleFile(file string, data []byte) ([]*models.ThresholdRule, []error) {
+	var parsed ruleFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&parsed); err != nil {
+		// An empty file is a valid file without rules
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("%s: %w", file, err)}
+	}
+
+	var rules []*models.ThresholdRule
+	var errs []error
+	for _, group := range parsed.Groups {
+		if group.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: group name is required", file))
+			continue
+		}
+		for _, fr := range group.Rules {
+			rule := fr.toRule(file, group)
+			if err := models.Validate(rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s: group %q: rule %q: %w", file, group.Name, fr.Name, err))
+				continue
+			}
+			if err := evaluation.ValidateRule(rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s: group %q: rule %q: %w", file, group.Name, fr.Name, err))
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, errs
+}
+
+func (fr *fileRule) toRule(file string, group ruleGroup) *models.ThresholdRule {
+	service := fr.Service
+	if service == "" {
+		service = group.Service
+	}
+
+	rule := models.NewThresholdRule(fr.Name, models.ServiceName(service), models.MetricType(fr.Metric), fr.Operator, fr.Threshold)
+	rule.ID = uuid.NewSHA1(ruleNamespace, []byte(service+"/"+group.Name+"/"+fr.Name)).String()
+	rule.Description = fr.Description
+	rule.MetricType = models.CanonicalMetricType(rule.MetricType)
+	rule.Severity = models.AlertSeverity(fr.Severity)
+	if fr.Window > 0 {
+		rule.WindowSize = int(fr.Window.Seconds())
+	}
+	if fr.Cooldown > 0 {
+		rule.CooldownSec = int(fr.Cooldown.Seconds())
+	}
+	if fr.Enabled != nil {
+		rule.Enabled = *fr.Enabled
+	}
+	rule.NotifySlack = fr.NotifySlack
+	rule.NotifyEmail = fr.NotifyEmail
+	rule.NotifyWebhook = fr.NotifyWebhook
+	rule.Expression = fr.Expression
+	rule.DependsOn = fr.DependsOn
+	rule.Aggregation = models.RuleAggregation(fr.Aggregation)
+	rule.ForecastHorizon = int(fr.Horizon.Seconds())
+	rule.MinConfidence = fr.MinConfidence
+	rule.GroupBy = fr.GroupBy
+	for _, escalation := range fr.Escalations {
+		rule.Escalations = append(rule.Escalations, models.SeverityThreshold{
+			Severity:  models.AlertSeverity(escalation.Severity),
+			Threshold: escalation.Threshold,
+		})
+	}
+	rule.Source = models.RuleSourceFile
+	rule.SourceFile = filepath.Base(file)
+	rule.CreatedAt = time.Time{}
+	rule.UpdatedAt = time.Time{}
+
+	return rule
+}
@@ -0,0 +1,62 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// serviceCatalogFile is the on-disk format of the service catalogue:
+//
+//	services:
+//	  payments:
+//	    team: payments-team
+//	    runbook: https://runbooks.example.com/payments
+//	    runbooks:
+//	      latency: https://runbooks.example.com/payments/latency
+type serviceCatalogFile struct {
+	Services map[string]catalogService `yaml:"services"`
+}
+
+type catalogService struct {
+	Team     string            `yaml:"team"`
+	Runbook  string            `yaml:"runbook"`
+	Runbooks map[string]string `yaml:"runbooks"` // by metric type
+}
+
+// LoadServiceCatalog reads the service catalogue file at path.
+func LoadServiceCatalog(path string) (map[models.ServiceName]*models.ServiceCatalogEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service catalog: %w", err)
+	}
+
+	var file serviceCatalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse service catalog %s: %w", path, err)
+	}
+
+	catalog := make(map[models.ServiceName]*models.ServiceCatalogEntry, len(file.Services))
+	for name, svc := range file.Services {
+		entry := &models.ServiceCatalogEntry{
+			Team:    svc.Team,
+			Runbook: svc.Runbook,
+		}
+		if len(svc.Runbooks) > 0 {
+			entry.Runbooks = make(map[models.MetricType]string, len(svc.Runbooks))
+			for metric, url := range svc.Runbooks {
+				def, ok := models.LookupMetric(models.MetricType(metric))
+				if !ok {
+					return nil, fmt.Errorf("service catalog %s: service %s: unknown metric type %q", path, name, metric)
+				}
+				entry.Runbooks[def.Type] = url
+			}
+		}
+		catalog[models.ServiceName(name)] = entry
+	}
+
+	return catalog, nil
+}
@@ -22,6 +22,13 @@ type Config struct {
 	KafkaLogsTopic     string
 	KafkaAlertsTopic   string
 	KafkaConsumerGroup string
+	KafkaEncoding      string
+	SchemaRegistryURL  string
+	KafkaSecurity      utils.KafkaSecurityConfig
+	KafkaTopics        utils.KafkaTopicsConfig
+	IngestWorkers      int // metrics handled concurrently, ordered per service
+	PartitionAffinity  bool
+	ReadyMaxLag        int64 // readiness fails above this consumer lag; 0 disables
 
 	// Redis configuration
 	RedisAddr     string
@@ -29,9 +36,41 @@ type Config struct {
 	RedisDB       int
 
 	// Analysis configuration
-	SlidingWindowSize time.Duration
-	AnalysisInterval  time.Duration
-	AlertCooldown     time.Duration
+	EvaluationMode     string
+	SlidingWindowSize  time.Duration
+	AnalysisInterval   time.Duration
+	AlertCooldown      time.Duration
+	MaxWindowSamples   int
+	CheckpointInterval time.Duration
+	ForecastWindow     time.Duration
+	ForecastInterval   time.Duration
+
+	// Cardinality limits and label policy for labelled series
+	MaxSeriesPerMetric  int
+	MaxGroupsPerRule    int
+	LabelAllowlist      []string
+	LabelDenylist       []string
+	MaxLabelValueLength int
+	SeriesTTL           time.Duration
+
+	// Alert enrichment
+	ServiceCatalogPath string // empty disables owner and runbook enrichment
+	DashboardURL       string
+	EnrichmentTimeout  time.Duration
+	EnrichmentLogLines int
+	EnrichmentSamples  int
+	RecentLogsKept     int
+
+	// Service discovery
+	RegistryTTL time.Duration
+
+	// Rule files (empty disables file-managed rules)
+	RulesPath           string
+	RulesReloadInterval time.Duration
+
+	// Absent-data detection
+	HeartbeatTimeout    time.Duration
+	HeartbeatMetricType string
 
 	// Logging
 	LogLevel    string
@@ -58,14 +97,47 @@ func Load() *Config {
 		KafkaLogsTopic:     utils.GetEnv("KAFKA_LOGS_TOPIC", "service-logs"),
 		KafkaAlertsTopic:   utils.GetEnv("KAFKA_ALERTS_TOPIC", "alerts"),
 		KafkaConsumerGroup: utils.GetEnv("KAFKA_CONSUMER_GROUP", "analyzer-group"),
+		KafkaEncoding:      utils.GetEnv("KAFKA_ENCODING", "json"),
+		SchemaRegistryURL:  utils.GetEnv("SCHEMA_REGISTRY_URL", ""),
+		KafkaSecurity:      utils.LoadKafkaSecurityConfig(),
+		KafkaTopics:        utils.LoadKafkaTopicsConfig(),
+		IngestWorkers:      utils.GetEnvInt("INGEST_WORKERS", 8),
+		PartitionAffinity:  utils.GetEnvBool("PARTITION_AFFINITY", true),
+		ReadyMaxLag:        utils.GetEnvInt64("READY_MAX_KAFKA_LAG", 0),
 
 		RedisAddr:     utils.GetEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword: utils.GetEnv("REDIS_PASSWORD", ""),
 		RedisDB:       utils.GetEnvInt("REDIS_DB", 0),
 
-		SlidingWindowSize: utils.GetEnvDuration("SLIDING_WINDOW_SIZE", 5*time.Minute),
-		AnalysisInterval:  utils.GetEnvDuration("ANALYSIS_INTERVAL", 10*time.Second),
-		AlertCooldown:     utils.GetEnvDuration("ALERT_COOLDOWN", 5*time.Minute),
+		EvaluationMode:      utils.GetEnv("EVALUATION_MODE", "streaming"),
+		SlidingWindowSize:   utils.GetEnvDuration("SLIDING_WINDOW_SIZE", 5*time.Minute),
+		AnalysisInterval:    utils.GetEnvDuration("ANALYSIS_INTERVAL", 10*time.Second),
+		AlertCooldown:       utils.GetEnvDuration("ALERT_COOLDOWN", 5*time.Minute),
+		MaxWindowSamples:    utils.GetEnvInt("MAX_WINDOW_SAMPLES", 2048),
+		CheckpointInterval:  utils.GetEnvDuration("CHECKPOINT_INTERVAL", 30*time.Second),
+		ForecastWindow:      utils.GetEnvDuration("FORECAST_WINDOW", time.Hour),
+		ForecastInterval:    utils.GetEnvDuration("FORECAST_INTERVAL", 30*time.Second),
+		MaxSeriesPerMetric:  utils.GetEnvInt("MAX_SERIES_PER_METRIC", 1000),
+		MaxGroupsPerRule:    utils.GetEnvInt("MAX_GROUPS_PER_RULE", 100),
+		LabelAllowlist:      utils.GetEnvStringSlice("LABEL_ALLOWLIST", nil),
+		LabelDenylist:       utils.GetEnvStringSlice("LABEL_DENYLIST", []string{"user_id", "request_id", "trace_id"}),
+		MaxLabelValueLength: utils.GetEnvInt("MAX_LABEL_VALUE_LENGTH", 128),
+		SeriesTTL:           utils.GetEnvDuration("SERIES_TTL", 24*time.Hour),
+
+		ServiceCatalogPath: utils.GetEnv("SERVICE_CATALOG_PATH", ""),
+		DashboardURL:       utils.GetEnv("DASHBOARD_URL", "http://localhost:3000"),
+		EnrichmentTimeout:  utils.GetEnvDuration("ENRICHMENT_TIMEOUT", 500*time.Millisecond),
+		EnrichmentLogLines: utils.GetEnvInt("ENRICHMENT_LOG_LINES", 5),
+		EnrichmentSamples:  utils.GetEnvInt("ENRICHMENT_SAMPLES", 30),
+		RecentLogsKept:     utils.GetEnvInt("RECENT_LOGS_KEPT", 100),
+
+		RegistryTTL: utils.GetEnvDuration("REGISTRY_TTL", 24*time.Hour),
+
+		RulesPath:           utils.GetEnv("RULES_PATH", ""),
+		RulesReloadInterval: utils.GetEnvDuration("RULES_RELOAD_INTERVAL", 30*time.Second),
+
+		HeartbeatTimeout:    utils.GetEnvDuration("HEARTBEAT_TIMEOUT", time.Minute),
+		HeartbeatMetricType: utils.GetEnv("HEARTBEAT_METRIC_TYPE", ""),
 
 		LogLevel:    utils.GetEnv("LOG_LEVEL", "info"),
 		Development: utils.GetEnvBool("DEVELOPMENT", true),
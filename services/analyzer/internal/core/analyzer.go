@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/microservices-platform/pkg/shared/evaluation"
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/models"
 	"github.com/microservices-platform/services/analyzer/internal/ports"
@@ -19,11 +20,23 @@ import (
 
 // AnalysisConfig holds configuration for the analyzer.
 type AnalysisConfig struct {
+	// Evaluation mode: EvaluationModeStreaming or EvaluationModeInterval
+	EvaluationMode string
+
 	// Window sizes
 	SlidingWindowSize time.Duration
 	RollingWindowSize time.Duration
 	AnalysisInterval  time.Duration
 
+	// Streaming window state
+	MaxWindowSamples   int           // per metric series
+	MaxGroupsPerRule   int           // label combinations evaluated per grouped rule
+	CheckpointInterval time.Duration // how often windows are checkpointed to Redis
+
+	// Forecast rules
+	ForecastWindow   time.Duration // minimum history a forecast is fitted to
+	ForecastInterval time.Duration // how often forecast rules are evaluated
+
 	// Default thresholds
 	DefaultCPUThreshold       float64
 	DefaultMemoryThreshold    float64
@@ -36,14 +49,31 @@ type AnalysisConfig struct {
 
 	// Alert settings
 	DefaultCooldownPeriod time.Duration
+
+	// Absent-data detection
+	HeartbeatTimeout    time.Duration
+	HeartbeatMetricType models.MetricType // empty matches any metric
+
+	// PartitionAffinity restricts evaluation to the services whose metric
+	// partitions are assigned to this replica by the consumer group
+	PartitionAffinity bool
+	// MetricsPartitions is the partition count of the metrics topic, which
+	// places services whose metrics were never seen
+	MetricsPartitions int
 }
 
 // DefaultAnalysisConfig returns the default configuration.
 func DefaultAnalysisConfig() *AnalysisConfig {
 	return &AnalysisConfig{
+		EvaluationMode:            EvaluationModeStreaming,
 		SlidingWindowSize:         5 * time.Minute,
 		RollingWindowSize:         15 * time.Minute,
 		AnalysisInterval:          5 * time.Second,
+		MaxWindowSamples:          2048,
+		MaxGroupsPerRule:          100,
+		CheckpointInterval:        30 * time.Second,
+		ForecastWindow:            time.Hour,
+		ForecastInterval:          30 * time.Second,
 		DefaultCPUThreshold:       80.0,
 		DefaultMemoryThreshold:    80.0,
 		DefaultLatencyThreshold:   1000.0, // 1 second
@@ -51,6 +81,7 @@ func DefaultAnalysisConfig() *AnalysisConfig {
 		DeviationMultiplier:       2.0,    // 2 standard deviations
 		MinSamplesForDeviation:    10,
 		DefaultCooldownPeriod:     5 * time.Minute,
+		HeartbeatTimeout:          time.Minute,
 	}
 }
 
@@ -59,9 +90,33 @@ type Analyzer struct {
 	config         *AnalysisConfig
 	metricsStore   ports.MetricsStore
 	rulesStore     ports.RulesStore
+	registry       ports.ServiceRegistry
 	alertPublisher ports.AlertPublisher
 	logger         *logging.Logger
 
+	// Absent-data detection grace period for services that never reported
+	startedAt time.Time
+
+	// Forecast state: when forecast rules were last evaluated
+	lastForecast time.Time
+
+	// Streaming state: in-memory windows and the cached enabled rules
+	windowsMu sync.Mutex
+	windows   map[models.ServiceName]*serviceWindow
+	rules     []*models.ThresholdRule
+
+	// Rule graph state, guarded by windowsMu: the latest evaluation of our
+	// rules, those not yet shared with other replicas, and theirs
+	graph         *evaluation.RuleGraph
+	ruleStates    map[string]evaluation.RuleState
+	pendingStates map[string]evaluation.RuleState
+	sharedStates  map[string]evaluation.RuleState
+
+	// Partition ownership when PartitionAffinity is enabled
+	ownershipMu       sync.RWMutex
+	ownedPartitions   map[int]bool
+	servicePartitions map[models.ServiceName]int
+
 	mu      sync.Mutex
 	running bool
 	stopCh  chan struct{}
@@ -73,19 +128,32 @@ func NewAnalyzer(
 	config *AnalysisConfig,
 	metricsStore ports.MetricsStore,
 	rulesStore ports.RulesStore,
+	registry ports.ServiceRegistry,
 	alertPublisher ports.AlertPublisher,
 	logger *logging.Logger,
 ) *Analyzer {
 	if config == nil {
 		config = DefaultAnalysisConfig()
 	}
+	if config.MaxWindowSamples <= 0 {
+		config.MaxWindowSamples = DefaultAnalysisConfig().MaxWindowSamples
+	}
+	if config.MaxGroupsPerRule <= 0 {
+		config.MaxGroupsPerRule = DefaultAnalysisConfig().MaxGroupsPerRule
+	}
 
 	return &Analyzer{
-		config:         config,
-		metricsStore:   metricsStore,
-		rulesStore:     rulesStore,
-		alertPublisher: alertPublisher,
-		logger:         logger,
+		config:            config,
+		metricsStore:      metricsStore,
+		rulesStore:        rulesStore,
+		registry:          registry,
+		alertPublisher:    alertPublisher,
+		logger:            logger,
+		windows:           make(map[models.ServiceName]*serviceWindow),
+		ruleStates:        make(map[string]evaluation.RuleState),
+		pendingStates:     make(map[string]evaluation.RuleState),
+		ownedPartitions:   make(map[int]bool),
+		servicePartitions: make(map[models.ServiceName]int),
 	}
 }
 
@@ -98,12 +166,23 @@ func (a *Analyzer) Start(ctx context.Context) error {
 	}
 	a.running = true
 	a.stopCh = make(chan struct{})
+	a.startedAt = time.Now()
 	a.mu.Unlock()
 
 	a.logger.Info("starting analyzer",
+		zap.String("evaluation_mode", a.config.EvaluationMode),
 		zap.Duration("analysis_interval", a.config.AnalysisInterval),
+		zap.Duration("heartbeat_timeout", a.config.HeartbeatTimeout),
 	)
 
+	if a.streaming() {
+		a.refreshRules(ctx)
+		// With partition affinity windows are restored as partitions are assigned
+		if !a.config.PartitionAffinity {
+			a.restoreWindows(ctx, a.registeredServices(ctx))
+		}
+	}
+
 	a.wg.Add(1)
 	go a.runAnalysisLoop(ctx)
 
@@ -122,6 +201,14 @@ func (a *Analyzer) Stop() error {
 	a.mu.Unlock()
 
 	a.wg.Wait()
+
+	// Persist the final window state so a restart resumes where we stopped
+	if a.streaming() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		a.checkpointWindows(ctx, nil)
+		cancel()
+	}
+
 	a.logger.Info("analyzer stopped")
 	return nil
 }
@@ -132,6 +219,13 @@ func (a *Analyzer) runAnalysisLoop(ctx context.Context) {
 	ticker := time.NewTicker(a.config.AnalysisInterval)
 	defer ticker.Stop()
 
+	var checkpointC <-chan time.Time
+	if a.streaming() && a.config.CheckpointInterval > 0 {
+		checkpointTicker := time.NewTicker(a.config.CheckpointInterval)
+		defer checkpointTicker.Stop()
+		checkpointC = checkpointTicker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -139,36 +233,71 @@ func (a *Analyzer) runAnalysisLoop(ctx context.Context) {
 		case <-a.stopCh:
 			return
 		case <-ticker.C:
-			a.performAnalysis(ctx)
+			if a.streaming() {
+				a.performStreamingMaintenance(ctx)
+			} else {
+				a.performAnalysis(ctx)
+			}
+		case <-checkpointC:
+			a.checkpointWindows(ctx, nil)
 		}
 	}
 }
 
+func (a *Analyzer) streaming() bool {
+	return a.config.EvaluationMode == EvaluationModeStreaming
+}
+
 func (a *Analyzer) performAnalysis(ctx context.Context) {
 	// Get all enabled rules
-	rules, err := a.rulesStore.GetEnabledRules(ctx)
+	rules, err := a.loadRules(ctx)
 	if err != nil {
 		a.logger.Error("failed to get enabled rules", zap.Error(err))
 		return
 	}
 
-	// Track which services we need to analyze
+	servicesToAnalyze := a.servicesToAnalyze(ctx, rules)
+	for service := range servicesToAnalyze {
+		a.analyzeService(ctx, service, rules)
+	}
+
+	a.evaluateForecasts(ctx, rules)
+	a.evaluateRuleGraph(ctx)
+	a.checkHeartbeats(ctx, servicesToAnalyze)
+}
+
+// servicesToAnalyze returns the services referenced by rules or discovered from the metric stream.
+func (a *Analyzer) servicesToAnalyze(ctx context.Context, rules []*models.ThresholdRule) map[models.ServiceName]bool {
 	servicesToAnalyze := make(map[models.ServiceName]bool)
 	for _, rule := range rules {
-		servicesToAnalyze[rule.ServiceName] = true
+		if !rule.Composite() && a.ownsService(rule.ServiceName) {
+			servicesToAnalyze[rule.ServiceName] = true
+		}
 	}
 
-	// If no rules, analyze all known services from metrics
-	if len(servicesToAnalyze) == 0 {
-		servicesToAnalyze[models.ServiceNameAuth] = true
-		servicesToAnalyze[models.ServiceNameOrders] = true
-		servicesToAnalyze[models.ServiceNamePayments] = true
-		servicesToAnalyze[models.ServiceNameNotification] = true
+	// Analyze every service discovered from the metric stream
+	for _, service := range a.registeredServices(ctx) {
+		if a.ownsService(service) {
+			servicesToAnalyze[service] = true
+		}
 	}
 
-	for service := range servicesToAnalyze {
-		a.analyzeService(ctx, service, rules)
+	return servicesToAnalyze
+}
+
+// registeredServices returns the services discovered from the metric stream.
+func (a *Analyzer) registeredServices(ctx context.Context) []models.ServiceName {
+	services, err := a.registry.GetServices(ctx)
+	if err != nil {
+		a.logger.Warn("failed to get registered services", zap.Error(err))
+		return nil
 	}
+
+	names := make([]models.ServiceName, 0, len(services))
+	for _, service := range services {
+		names = append(names, service.ServiceName)
+	}
+	return names
 }
 
 func (a *Analyzer) analyzeService(ctx context.Context, service models.ServiceName, rules []*models.ThresholdRule) {
@@ -186,93 +315,128 @@ func (a *Analyzer) analyzeService(ctx context.Context, service models.ServiceNam
 		return
 	}
 
-	// Get the most recent metric
-	latest := metrics[len(metrics)-1]
+	// Resolve the window through the metric catalogue
+	series := models.GroupByMetricType(metrics)
 
 	// Check threshold rules
 	for _, rule := range rules {
-		if rule.ServiceName != service || !rule.Enabled {
+		if rule.ServiceName != service || !rule.Enabled || rule.Composite() || rule.Forecast() {
 			continue
 		}
-		a.checkThresholdRule(ctx, rule, latest, metrics)
+		a.checkThresholdRule(ctx, rule, series)
 	}
 
 	// Perform deviation analysis
-	a.checkDeviations(ctx, service, latest, metrics)
+	a.checkDeviations(ctx, service, series)
 }
 
-func (a *Analyzer) checkThresholdRule(ctx context.Context, rule *models.ThresholdRule, latest *models.ServiceMetric, history []*models.ServiceMetric) {
-	var currentValue float64
-	var exceeded bool
-
-	switch rule.MetricType {
-	case models.MetricTypeCPU:
-		currentValue = latest.CPUUsage
-		exceeded = currentValue > rule.Threshold
-	case models.MetricTypeMemory:
-		currentValue = latest.MemoryUsage
-		exceeded = currentValue > rule.Threshold
-	case models.MetricTypeLatencyP95:
-		currentValue = latest.LatencyP95
-		exceeded = currentValue > rule.Threshold
-	case models.MetricTypeLatencyP99:
-		currentValue = latest.LatencyP99
-		exceeded = currentValue > rule.Threshold
-	case models.MetricTypeErrorRate:
-		currentValue = latest.ErrorRate
-		exceeded = currentValue > rule.Threshold
-	case models.MetricTypeRequestRate:
-		currentValue = latest.RequestCount
-		exceeded = currentValue > rule.Threshold
-	default:
+func (a *Analyzer) checkThresholdRule(ctx context.Context, rule *models.ThresholdRule, series map[models.MetricType][]*models.ServiceMetric) {
+	if err := evaluation.ValidateRule(rule); err != nil {
+		a.logger.Warn("skipping invalid rule",
+			zap.String("rule_id", rule.ID),
+			zap.Error(err),
+		)
 		return
 	}
 
-	if exceeded {
-		a.generateAlert(ctx, rule.ServiceName, rule.MetricType, rule.Severity, currentValue, rule.Threshold, "threshold_violation")
-	}
-}
+	// Replay the window through the same evaluator used in streaming mode,
+	// per group for grouped rules
+	metricType, _ := evaluation.SeriesFor(rule)
+	var breaches []thresholdBreach
+	var evaluated bool
+	var value float64
+
+	for _, group := range a.limitGroups(rule, evaluation.GroupSamples(rule, series[metricType])) {
+		w := evaluation.NewWindow(a.config.MaxWindowSamples, a.config.SlidingWindowSize)
+		for _, sample := range group.Samples {
+			w.Add(sample)
+		}
 
-func (a *Analyzer) checkDeviations(ctx context.Context, service models.ServiceName, latest *models.ServiceMetric, history []*models.ServiceMetric) {
-	if len(history) < a.config.MinSamplesForDeviation {
+		result, ok := evaluation.Evaluate(rule, w)
+		if !ok {
+			continue
+		}
+		if !evaluated || result.Breached {
+			value = result.Value
+		}
+		evaluated = true
+		if result.Breached {
+			breaches = append(breaches, thresholdBreach{rule: rule, result: result, group: group.Labels})
+		}
+	}
+	if !evaluated {
 		return
 	}
 
-	// Check CPU deviation
-	a.checkMetricDeviation(ctx, service, models.MetricTypeCPU, latest.CPUUsage, history, func(m *models.ServiceMetric) float64 { return m.CPUUsage })
+	a.windowsMu.Lock()
+	a.recordRuleState(rule, len(breaches) > 0, value)
+	a.windowsMu.Unlock()
+
+	if len(breaches) == 0 || a.suppressed(rule) {
+		return
+	}
+	for _, b := range breaches {
+		a.generateAlert(ctx, rule, rule.ServiceName, b.result.MetricType, b.group, b.result.Severity, b.result.Value, rule.ThresholdFor(b.result.Severity), "threshold_violation")
+	}
+}
 
-	// Check memory deviation
-	a.checkMetricDeviation(ctx, service, models.MetricTypeMemory, latest.MemoryUsage, history, func(m *models.ServiceMetric) float64 { return m.MemoryUsage })
+// limitGroups drops the groups of a rule beyond MaxGroupsPerRule.
+func (a *Analyzer) limitGroups(rule *models.ThresholdRule, groups []evaluation.Group) []evaluation.Group {
+	if len(groups) <= a.config.MaxGroupsPerRule {
+		return groups
+	}
+	a.logger.Warn("group limit reached, groups not evaluated",
+		zap.String("rule_id", rule.ID),
+		zap.Int("groups", len(groups)),
+		zap.Int("max_groups", a.config.MaxGroupsPerRule),
+	)
+	return groups[:a.config.MaxGroupsPerRule]
+}
 
-	// Check latency P95 deviation
-	a.checkMetricDeviation(ctx, service, models.MetricTypeLatencyP95, latest.LatencyP95, history, func(m *models.ServiceMetric) float64 { return m.LatencyP95 })
+// deviationMetrics are the metric types checked for statistical deviation.
+var deviationMetrics = []models.MetricType{
+	models.MetricTypeCPU,
+	models.MetricTypeMemory,
+	models.MetricTypeLatency,
+	models.MetricTypeErrorRate,
+}
 
-	// Check error rate deviation
-	a.checkMetricDeviation(ctx, service, models.MetricTypeErrorRate, latest.ErrorRate, history, func(m *models.ServiceMetric) float64 { return m.ErrorRate })
+func (a *Analyzer) checkDeviations(ctx context.Context, service models.ServiceName, series map[models.MetricType][]*models.ServiceMetric) {
+	for _, metricType := range deviationMetrics {
+		history := series[metricType]
+		if len(history) < a.config.MinSamplesForDeviation {
+			continue
+		}
+		values := models.SampleValues(history)
+		mean, stdDev := meanStdDev(values)
+		a.checkMetricDeviation(ctx, service, metricType, values[len(values)-1], mean, stdDev)
+	}
 }
 
-func (a *Analyzer) checkMetricDeviation(
-	ctx context.Context,
-	service models.ServiceName,
-	metricType models.MetricType,
-	currentValue float64,
-	history []*models.ServiceMetric,
-	extractor func(*models.ServiceMetric) float64,
-) {
-	// Calculate mean and standard deviation
+// meanStdDev returns the mean and population standard deviation of values.
+func meanStdDev(values []float64) (mean, stdDev float64) {
 	var sum, sumSquares float64
-	n := float64(len(history))
+	n := float64(len(values))
 
-	for _, m := range history {
-		v := extractor(m)
+	for _, v := range values {
 		sum += v
 		sumSquares += v * v
 	}
 
-	mean := sum / n
+	mean = sum / n
 	variance := (sumSquares / n) - (mean * mean)
-	stdDev := math.Sqrt(variance)
+	if variance < 0 {
+		variance = 0
+	}
+	return mean, math.Sqrt(variance)
+}
 
+func (a *Analyzer) checkMetricDeviation(
+	ctx context.Context,
+	service models.ServiceName,
+	metricType models.MetricType,
+	currentValue, mean, stdDev float64,
+) {
 	// Check if current value deviates significantly
 	if stdDev > 0 {
 		deviation := math.Abs(currentValue-mean) / stdDev
@@ -281,56 +445,88 @@ func (a *Analyzer) checkMetricDeviation(
 			if deviation > a.config.DeviationMultiplier*2 {
 				severity = models.AlertSeverityCritical
 			}
-			a.generateAlert(ctx, service, metricType, severity, currentValue, mean, "deviation_detected")
+			a.generateAlert(ctx, nil, service, metricType, nil, severity, currentValue, mean, "deviation_detected")
 		}
 	}
 }
 
+// generateAlert publishes an alert for a metric series. group holds the
+// labels of the breached group of a grouped rule; each group is deduplicated
+// and cooled down separately.
 func (a *Analyzer) generateAlert(
 	ctx context.Context,
+	rule *models.ThresholdRule, // nil for deviations
 	service models.ServiceName,
 	metricType models.MetricType,
+	group models.Labels,
 	severity models.AlertSeverity,
 	currentValue, threshold float64,
 	alertType string,
 ) {
-	// Generate deduplication key
-	deduplicationKey := fmt.Sprintf("%s:%s:%s", service, metricType, alertType)
+	series := models.SeriesID(metricType, group)
 
-	// Check if we already sent this alert recently
-	alreadySent, err := a.metricsStore.CheckAndSetAlertSent(ctx, deduplicationKey, 5*time.Minute)
-	if err != nil {
-		a.logger.Warn("failed to check alert deduplication", zap.Error(err))
+	alert := &models.Alert{
+		ID:           uuid.New().String(),
+		ServiceName:  service,
+		MetricType:   metricType,
+		Severity:     severity,
+		Title:        a.generateAlertTitle(alertType, service, series),
+		Message:      a.generateAlertMessage(alertType, service, series, currentValue, threshold),
+		CurrentValue: currentValue,
+		Threshold:    threshold,
+		Timestamp:    time.Now(),
+		Labels:       groupLabels(group),
 	}
-	if alreadySent {
+	alert.Labels["alert_type"] = alertType
+	alert.Labels["service"] = string(service)
+	alert.Labels["metric"] = string(metricType)
+	if rule != nil {
+		alert.RuleID = rule.ID
+		alert.Labels["rule"] = rule.Name
+	}
+
+	if !a.emitAlert(ctx, alert, a.cooldownFor(rule)) {
 		return
 	}
 
+	a.logger.Info("alert generated",
+		zap.String("alert_id", alert.ID),
+		zap.String("fingerprint", alert.Fingerprint),
+		zap.String("service", string(service)),
+		zap.String("series", series),
+		zap.String("severity", string(severity)),
+		zap.Float64("current_value", currentValue),
+		zap.Float64("threshold", threshold),
+	)
+}
+
+// emitAlert fingerprints and publishes an alert unless the same problem is
+// in cooldown, then starts a cooldown of the given length. Within the
+// cooldown only an escalation to a higher severity is published. It reports
+// whether the alert was published.
+func (a *Analyzer) emitAlert(ctx context.Context, alert *models.Alert, cooldown time.Duration) bool {
+	service := string(alert.ServiceName)
+	fingerprint := alert.EnsureFingerprint()
+
 	// Check cooldown
-	inCooldown, err := a.metricsStore.CheckCooldown(ctx, string(service), string(metricType))
+	cooledSeverity, err := a.metricsStore.GetCooldown(ctx, service, fingerprint)
 	if err != nil {
 		a.logger.Warn("failed to check cooldown", zap.Error(err))
 	}
-	if inCooldown {
-		return
+	if cooledSeverity != "" && alert.Severity.Rank() <= cooledSeverity.Rank() {
+		return false
 	}
 
-	// Create alert
-	alert := &models.Alert{
-		ID:           uuid.New().String(),
-		ServiceName:  service,
-		MetricType:   metricType,
-		Severity:     severity,
-		Title:        a.generateAlertTitle(alertType, service, metricType),
-		Message:      a.generateAlertMessage(alertType, service, metricType, currentValue, threshold),
-		CurrentValue: currentValue,
-		Threshold:    threshold,
-		Timestamp:    time.Now(),
-		Labels: map[string]string{
-			"alert_type": alertType,
-			"service":    string(service),
-			"metric":     string(metricType),
-		},
+	// Check if another replica just sent this alert; per severity, so an
+	// escalation is not mistaken for a re-fire
+	dedupKey := fingerprint + ":" + string(alert.Severity)
+	alreadySent, err := a.metricsStore.CheckAndSetAlertSent(ctx, dedupKey, cooldown)
+	claimed := err == nil
+	if err != nil {
+		a.logger.Warn("failed to check alert deduplication", zap.Error(err))
+	}
+	if alreadySent {
+		return false
 	}
 
 	// Publish alert
@@ -339,25 +535,42 @@ func (a *Analyzer) generateAlert(
 			zap.String("alert_id", alert.ID),
 			zap.Error(err),
 		)
-		return
+		// Let the next evaluation, on any replica, publish it
+		if claimed {
+			if err := a.metricsStore.ClearAlertSent(ctx, dedupKey); err != nil {
+				a.logger.Warn("failed to clear alert deduplication", zap.Error(err))
+			}
+		}
+		return false
 	}
 
 	// Set cooldown
-	if err := a.metricsStore.SetCooldown(ctx, string(service), string(metricType), a.config.DefaultCooldownPeriod); err != nil {
+	if err := a.metricsStore.SetCooldown(ctx, service, fingerprint, alert.Severity, cooldown); err != nil {
 		a.logger.Warn("failed to set cooldown", zap.Error(err))
 	}
 
-	a.logger.Info("alert generated",
-		zap.String("alert_id", alert.ID),
-		zap.String("service", string(service)),
-		zap.String("metric_type", string(metricType)),
-		zap.String("severity", string(severity)),
-		zap.Float64("current_value", currentValue),
-		zap.Float64("threshold", threshold),
-	)
+	return true
+}
+
+// cooldownFor returns the alert cooldown of a rule; deviations pass a nil rule
+// and use the default.
+func (a *Analyzer) cooldownFor(rule *models.ThresholdRule) time.Duration {
+	if rule != nil && rule.CooldownPeriod() > 0 {
+		return rule.CooldownPeriod()
+	}
+	return a.config.DefaultCooldownPeriod
+}
+
+// groupLabels returns a copy of a group's labels to extend into alert labels.
+func groupLabels(group models.Labels) models.Labels {
+	labels := make(models.Labels, len(group)+4)
+	for k, v := range group {
+		labels[k] = v
+	}
+	return labels
 }
 
-func (a *Analyzer) generateAlertTitle(alertType string, service models.ServiceName, metricType models.MetricType) string {
+func (a *Analyzer) generateAlertTitle(alertType string, service models.ServiceName, metricType string) string {
 	switch alertType {
 	case "threshold_violation":
 		return fmt.Sprintf("[%s] %s threshold exceeded for %s", strings.ToUpper(string(service)), metricType, service)
@@ -368,7 +581,7 @@ func (a *Analyzer) generateAlertTitle(alertType string, service models.ServiceNa
 	}
 }
 
-func (a *Analyzer) generateAlertMessage(alertType string, service models.ServiceName, metricType models.MetricType, currentValue, threshold float64) string {
+func (a *Analyzer) generateAlertMessage(alertType string, service models.ServiceName, metricType string, currentValue, threshold float64) string {
 	switch alertType {
 	case "threshold_violation":
 		return fmt.Sprintf(
@@ -384,38 +597,3 @@ func (a *Analyzer) generateAlertMessage(alertType string, service models.Service
 		return fmt.Sprintf("Alert for %s: %s (current: %.2f, reference: %.2f)", service, metricType, currentValue, threshold)
 	}
 }
-
-// AnalyzeMetric immediately analyzes a single metric (useful for real-time processing).
-func (a *Analyzer) AnalyzeMetric(ctx context.Context, metric *models.ServiceMetric) error {
-	// Get relevant rules for this service
-	rules, err := a.rulesStore.GetRulesForService(ctx, metric.ServiceName)
-	if err != nil {
-		return err
-	}
-
-	// Store the metric first
-	if err := a.metricsStore.AddMetric(ctx, metric); err != nil {
-		a.logger.Warn("failed to store metric for analysis", zap.Error(err))
-	}
-
-	// Get historical metrics for deviation analysis
-	history, err := a.metricsStore.GetMetricsWindow(ctx, metric.ServiceName, a.config.SlidingWindowSize)
-	if err != nil {
-		a.logger.Warn("failed to get historical metrics", zap.Error(err))
-		history = []*models.ServiceMetric{metric}
-	}
-
-	// Check threshold rules
-	for _, rule := range rules {
-		if rule.Enabled {
-			a.checkThresholdRule(ctx, rule, metric, history)
-		}
-	}
-
-	// Check deviations if we have enough history
-	if len(history) >= a.config.MinSamplesForDeviation {
-		a.checkDeviations(ctx, metric.ServiceName, metric, history)
-	}
-
-	return nil
-}
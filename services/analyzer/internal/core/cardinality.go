@@ -0,0 +1,240 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/utils"
+)
+
+// Reasons metric labels are dropped at ingest.
+const (
+	DropReasonDenied      = "label_denied"
+	DropReasonTruncated   = "value_truncated"
+	DropReasonSeriesLimit = "series_limit"
+)
+
+// CardinalityConfig configures the label policy applied to ingested metrics.
+type CardinalityConfig struct {
+	// MaxSeriesPerMetric caps the label sets tracked per service metric.
+	// Samples of further series are kept without their labels.
+	MaxSeriesPerMetric int
+	// LabelAllowlist keeps only these labels when non-empty.
+	LabelAllowlist []string
+	// LabelDenylist drops these labels, e.g. user_id or request_id.
+	LabelDenylist []string
+	// MaxLabelValueLength truncates longer label values; zero disables it.
+	MaxLabelValueLength int
+	// SeriesTTL forgets series not seen for this long, freeing their slot.
+	SeriesTTL time.Duration
+}
+
+// LabelCardinality is the number of distinct values of a label across the
+// series of a service metric.
+type LabelCardinality struct {
+	ServiceName models.ServiceName `json:"service_name"`
+	MetricType  models.MetricType  `json:"metric_type"`
+	Label       string             `json:"label"`
+	Values      int                `json:"values"`
+	Series      int                `json:"series"`  // series of the service metric
+	Dropped     int64              `json:"dropped"` // samples whose labels were dropped by the series limit
+}
+
+// CardinalityLimiter guards Redis and the analysis windows against label
+// explosions by enforcing the label policy on every ingested metric.
+// Series are tracked per replica; metrics partitions are keyed by service,
+// so each replica sees every series of the services it consumes.
+type CardinalityLimiter struct {
+	config  CardinalityConfig
+	allow   map[string]bool
+	deny    map[string]bool
+	logger  *logging.Logger
+	metrics *metrics.Metrics
+
+	mu     sync.Mutex
+	series map[string]*metricSeries // by service and metric type
+}
+
+// metricSeries is the set of series tracked for a service metric.
+type metricSeries struct {
+	service    models.ServiceName
+	metricType models.MetricType
+	lastSeen   map[string]time.Time     // by label fingerprint
+	labels     map[string]models.Labels // by label fingerprint
+	dropped    int64
+}
+
+// NewCardinalityLimiter creates a new CardinalityLimiter.
+func NewCardinalityLimiter(config CardinalityConfig, logger *logging.Logger, m *metrics.Metrics) *CardinalityLimiter {
+	return &CardinalityLimiter{
+		config:  config,
+		allow:   labelSet(config.LabelAllowlist),
+		deny:    labelSet(config.LabelDenylist),
+		logger:  logger,
+		metrics: m,
+		series:  make(map[string]*metricSeries),
+	}
+}
+
+func labelSet(labels []string) map[string]bool {
+	set := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if label = strings.TrimSpace(label); label != "" {
+			set[label] = true
+		}
+	}
+	return set
+}
+
+// Limit applies the label policy to a metric in place: denied labels are
+// removed, long values truncated, and the labels of a new series beyond
+// MaxSeriesPerMetric dropped so the sample still counts for the service.
+func (l *CardinalityLimiter) Limit(metric *models.ServiceMetric) {
+	if len(metric.Labels) == 0 {
+		return
+	}
+
+	for key, value := range metric.Labels {
+		if (len(l.allow) > 0 && !l.allow[key]) || l.deny[key] {
+			delete(metric.Labels, key)
+			l.recordDrop(metric.ServiceName, DropReasonDenied)
+			continue
+		}
+		if l.config.MaxLabelValueLength > 0 && len(value) > l.config.MaxLabelValueLength {
+			metric.Labels[key] = utils.TruncateString(value, l.config.MaxLabelValueLength)
+			l.recordDrop(metric.ServiceName, DropReasonTruncated)
+		}
+	}
+
+	if len(metric.Labels) == 0 || l.config.MaxSeriesPerMetric <= 0 {
+		return
+	}
+
+	if !l.track(metric) {
+		l.logger.Debug("series limit reached, dropping labels",
+			zap.String("service", string(metric.ServiceName)),
+			zap.String("series", models.SeriesID(metric.MetricType, metric.Labels)),
+			zap.Int("max_series", l.config.MaxSeriesPerMetric),
+		)
+		metric.Labels = models.Labels{}
+		l.recordDrop(metric.ServiceName, DropReasonSeriesLimit)
+	}
+}
+
+// track records the series of a metric and reports whether it is within the limit.
+func (l *CardinalityLimiter) track(metric *models.ServiceMetric) bool {
+	key := string(metric.ServiceName) + ":" + string(metric.MetricType)
+	fingerprint := metric.Labels.Fingerprint()
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ms, ok := l.series[key]
+	if !ok {
+		ms = &metricSeries{
+			service:    metric.ServiceName,
+			metricType: metric.MetricType,
+			lastSeen:   make(map[string]time.Time),
+			labels:     make(map[string]models.Labels),
+		}
+		l.series[key] = ms
+	}
+
+	if _, ok := ms.lastSeen[fingerprint]; !ok && len(ms.lastSeen) >= l.config.MaxSeriesPerMetric {
+		// Free the slots of series that stopped reporting before refusing
+		l.prune(ms, now)
+		if len(ms.lastSeen) >= l.config.MaxSeriesPerMetric {
+			ms.dropped++
+			return false
+		}
+	}
+
+	if _, ok := ms.labels[fingerprint]; !ok {
+		labels := make(models.Labels, len(metric.Labels))
+		for k, v := range metric.Labels {
+			labels[k] = v
+		}
+		ms.labels[fingerprint] = labels
+	}
+	ms.lastSeen[fingerprint] = now
+	return true
+}
+
+// prune forgets the series not seen within SeriesTTL of now.
+func (l *CardinalityLimiter) prune(ms *metricSeries, now time.Time) {
+	if l.config.SeriesTTL <= 0 {
+		return
+	}
+	cutoff := now.Add(-l.config.SeriesTTL)
+	for fingerprint, seen := range ms.lastSeen {
+		if seen.Before(cutoff) {
+			delete(ms.lastSeen, fingerprint)
+			delete(ms.labels, fingerprint)
+		}
+	}
+}
+
+func (l *CardinalityLimiter) recordDrop(service models.ServiceName, reason string) {
+	if l.metrics != nil {
+		l.metrics.RecordIngestDrop(string(service), reason)
+	}
+}
+
+// TopLabels returns the labels with the most distinct values across the
+// tracked series, highest first, at most limit of them.
+func (l *CardinalityLimiter) TopLabels(limit int) []LabelCardinality {
+	now := time.Now()
+
+	l.mu.Lock()
+	result := []LabelCardinality{}
+	for _, ms := range l.series {
+		l.prune(ms, now)
+
+		values := make(map[string]map[string]bool)
+		for _, labels := range ms.labels {
+			for k, v := range labels {
+				if values[k] == nil {
+					values[k] = make(map[string]bool)
+				}
+				values[k][v] = true
+			}
+		}
+		for label, distinct := range values {
+			result = append(result, LabelCardinality{
+				ServiceName: ms.service,
+				MetricType:  ms.metricType,
+				Label:       label,
+				Values:      len(distinct),
+				Series:      len(ms.labels),
+				Dropped:     ms.dropped,
+			})
+		}
+	}
+	l.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Values != result[j].Values {
+			return result[i].Values > result[j].Values
+		}
+		if result[i].ServiceName != result[j].ServiceName {
+			return result[i].ServiceName < result[j].ServiceName
+		}
+		if result[i].MetricType != result[j].MetricType {
+			return result[i].MetricType < result[j].MetricType
+		}
+		return result[i].Label < result[j].Label
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
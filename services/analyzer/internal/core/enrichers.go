@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/analyzer/internal/ports"
+)
+
+// recentLogScan is how many of a service's latest logs are searched for
+// error lines and example traces.
+const recentLogScan = 100
+
+// catalogEnricher adds the owner team and runbook from the service catalogue.
+type catalogEnricher struct {
+	catalog map[models.ServiceName]*models.ServiceCatalogEntry
+}
+
+// NewCatalogEnricher creates an enricher adding the owner team and runbook
+// URL of the alert's service. The team is also added as the "team" label.
+func NewCatalogEnricher(catalog map[models.ServiceName]*models.ServiceCatalogEntry) ports.AlertEnricher {
+	return &catalogEnricher{catalog: catalog}
+}
+
+func (e *catalogEnricher) Name() string { return "catalog" }
+
+func (e *catalogEnricher) Enrich(ctx context.Context, alert *models.Alert) (func(*models.Alert), error) {
+	entry, ok := e.catalog[alert.ServiceName]
+	if !ok {
+		return nil, nil
+	}
+	runbook := entry.RunbookFor(alert.MetricType)
+
+	return func(a *models.Alert) {
+		enrichment := enrichmentOf(a)
+		enrichment.OwnerTeam = entry.Team
+		enrichment.RunbookURL = runbook
+		if entry.Team != "" {
+			if a.Labels == nil {
+				a.Labels = make(models.Labels)
+			}
+			a.Labels["team"] = entry.Team
+		}
+	}, nil
+}
+
+// recentLogsEnricher adds the latest error log lines of the service.
+type recentLogsEnricher struct {
+	logs  ports.LogStore
+	limit int
+}
+
+// NewRecentLogsEnricher creates an enricher adding up to limit of the
+// service's latest error log lines.
+func NewRecentLogsEnricher(logs ports.LogStore, limit int) ports.AlertEnricher {
+	return &recentLogsEnricher{logs: logs, limit: limit}
+}
+
+func (e *recentLogsEnricher) Name() string { return "recent_logs" }
+
+func (e *recentLogsEnricher) Enrich(ctx context.Context, alert *models.Alert) (func(*models.Alert), error) {
+	logs, err := e.logs.GetRecentLogs(ctx, alert.ServiceName, recentLogScan)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, log := range logs {
+		if log.Level != models.LogLevelError && log.Level != models.LogLevelFatal {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s",
+			log.Timestamp.UTC().Format(time.RFC3339), strings.ToUpper(string(log.Level)), log.Message))
+		if len(lines) == e.limit {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	return func(a *models.Alert) {
+		enrichmentOf(a).RecentLogs = lines
+	}, nil
+}
+
+// recentSamplesEnricher adds the latest samples of the alert's metric.
+type recentSamplesEnricher struct {
+	metrics ports.MetricsStore
+	limit   int
+	window  time.Duration
+}
+
+// NewRecentSamplesEnricher creates an enricher adding up to limit of the
+// latest samples within window of the alert's metric, e.g. for sparklines.
+// Derived metrics use the samples they are computed from.
+func NewRecentSamplesEnricher(metrics ports.MetricsStore, limit int, window time.Duration) ports.AlertEnricher {
+	return &recentSamplesEnricher{metrics: metrics, limit: limit, window: window}
+}
+
+func (e *recentSamplesEnricher) Name() string { return "recent_samples" }
+
+func (e *recentSamplesEnricher) Enrich(ctx context.Context, alert *models.Alert) (func(*models.Alert), error) {
+	def, ok := models.LookupMetric(alert.MetricType)
+	if !ok {
+		return nil, nil
+	}
+	metricType := def.Type
+	if def.Derived() {
+		metricType = def.Source
+	}
+
+	metrics, err := e.metrics.GetMetricsInWindow(ctx, alert.ServiceName, metricType, e.window)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) > e.limit {
+		metrics = metrics[len(metrics)-e.limit:]
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	samples := make([]models.MetricSample, 0, len(metrics))
+	for _, m := range metrics {
+		samples = append(samples, models.MetricSample{Timestamp: m.Timestamp, Value: m.Value})
+	}
+
+	return func(a *models.Alert) {
+		enrichmentOf(a).RecentSamples = samples
+	}, nil
+}
+
+// exampleTraceEnricher sets the trace ID of an example slow request.
+type exampleTraceEnricher struct {
+	metrics ports.MetricsStore
+	logs    ports.LogStore
+	window  time.Duration
+}
+
+// NewExampleTraceEnricher creates an enricher setting the alert's trace ID to
+// that of the slowest traced latency sample within window, or else of the
+// service's latest traced warning or error log.
+func NewExampleTraceEnricher(metrics ports.MetricsStore, logs ports.LogStore, window time.Duration) ports.AlertEnricher {
+	return &exampleTraceEnricher{metrics: metrics, logs: logs, window: window}
+}
+
+func (e *exampleTraceEnricher) Name() string { return "example_trace" }
+
+func (e *exampleTraceEnricher) Enrich(ctx context.Context, alert *models.Alert) (func(*models.Alert), error) {
+	if alert.TraceID != "" {
+		return nil, nil
+	}
+
+	traceID, err := e.slowestTrace(ctx, alert.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	if traceID == "" && e.logs != nil {
+		if traceID, err = e.loggedTrace(ctx, alert.ServiceName); err != nil {
+			return nil, err
+		}
+	}
+	if traceID == "" {
+		return nil, nil
+	}
+
+	return func(a *models.Alert) {
+		a.TraceID = traceID
+	}, nil
+}
+
+func (e *exampleTraceEnricher) slowestTrace(ctx context.Context, service models.ServiceName) (string, error) {
+	metrics, err := e.metrics.GetMetricsInWindow(ctx, service, models.MetricTypeLatency, e.window)
+	if err != nil {
+		return "", err
+	}
+
+	var slowest *models.ServiceMetric
+	for _, m := range metrics {
+		if m.TraceID != "" && (slowest == nil || m.Value > slowest.Value) {
+			slowest = m
+		}
+	}
+	if slowest == nil {
+		return "", nil
+	}
+	return slowest.TraceID, nil
+}
+
+func (e *exampleTraceEnricher) loggedTrace(ctx context.Context, service models.ServiceName) (string, error) {
+	logs, err := e.logs.GetRecentLogs(ctx, service, recentLogScan)
+	if err != nil {
+		return "", err
+	}
+
+	for _, log := range logs {
+		if log.TraceID != "" {
+			return log.TraceID, nil
+		}
+		if traceID, ok := log.Fields["trace_id"].(string); ok && traceID != "" {
+			return traceID, nil
+		}
+	}
+	return "", nil
+}
+
+// dashboardLinkEnricher adds a link to the alert on the dashboard.
+type dashboardLinkEnricher struct {
+	baseURL string
+}
+
+// NewDashboardLinkEnricher creates an enricher adding a link to the
+// dashboard at baseURL, opening the alert. Alerts are linked by their
+// fingerprint, which survives re-publishing and is how the UI backend
+// stores them.
+func NewDashboardLinkEnricher(baseURL string) ports.AlertEnricher {
+	return &dashboardLinkEnricher{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (e *dashboardLinkEnricher) Name() string { return "dashboard_link" }
+
+func (e *dashboardLinkEnricher) Enrich(ctx context.Context, alert *models.Alert) (func(*models.Alert), error) {
+	query := url.Values{}
+	query.Set("alert", alert.EnsureFingerprint())
+	link := e.baseURL + "/?" + query.Encode()
+
+	return func(a *models.Alert) {
+		enrichmentOf(a).DashboardURL = link
+	}, nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/analyzer/internal/ports"
+)
+
+// EnrichingPublisher adds context to every alert before handing it to the
+// next publisher. Enrichers run concurrently on a snapshot of the alert and
+// share a deadline: an enricher that fails or is late only loses its own
+// context, and never delays the alert by more than the timeout.
+type EnrichingPublisher struct {
+	next      ports.AlertPublisher
+	enrichers []ports.AlertEnricher
+	timeout   time.Duration
+	logger    *logging.Logger
+}
+
+// NewEnrichingPublisher creates a new EnrichingPublisher. Enrichments are
+// applied in the order of enrichers.
+func NewEnrichingPublisher(
+	next ports.AlertPublisher,
+	enrichers []ports.AlertEnricher,
+	timeout time.Duration,
+	logger *logging.Logger,
+) *EnrichingPublisher {
+	return &EnrichingPublisher{
+		next:      next,
+		enrichers: enrichers,
+		timeout:   timeout,
+		logger:    logger,
+	}
+}
+
+// PublishAlert enriches the alert and publishes it.
+func (p *EnrichingPublisher) PublishAlert(ctx context.Context, alert *models.Alert) error {
+	p.enrich(ctx, alert)
+	return p.next.PublishAlert(ctx, alert)
+}
+
+// Close closes the next publisher.
+func (p *EnrichingPublisher) Close() error {
+	return p.next.Close()
+}
+
+// enrichResult is the outcome of a single enricher.
+type enrichResult struct {
+	apply func(*models.Alert)
+	err   error
+}
+
+func (p *EnrichingPublisher) enrich(ctx context.Context, alert *models.Alert) {
+	if len(p.enrichers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	// Fingerprint before the enrichers add descriptive labels, and so that
+	// they can read the fingerprint of the snapshot without setting it
+	alert.EnsureFingerprint()
+
+	// Enrichers read a snapshot, so late ones never race with the applied enrichments
+	snapshot := *alert
+	snapshot.Labels = make(models.Labels, len(alert.Labels))
+	for k, v := range alert.Labels {
+		snapshot.Labels[k] = v
+	}
+	snapshot.Enrichment = nil
+
+	results := make([]chan enrichResult, len(p.enrichers))
+	for i, enricher := range p.enrichers {
+		results[i] = make(chan enrichResult, 1)
+		go func(enricher ports.AlertEnricher, result chan<- enrichResult) {
+			apply, err := enricher.Enrich(ctx, &snapshot)
+			result <- enrichResult{apply: apply, err: err}
+		}(enricher, results[i])
+	}
+
+	for i, result := range results {
+		var r enrichResult
+		select {
+		case r = <-result:
+		case <-ctx.Done():
+			// Once the deadline passes take only the results already in
+			select {
+			case r = <-result:
+			default:
+				p.logger.Warn("alert enricher timed out",
+					zap.String("enricher", p.enrichers[i].Name()),
+					zap.String("alert_id", alert.ID),
+					zap.Duration("timeout", p.timeout),
+				)
+				continue
+			}
+		}
+
+		if r.err != nil {
+			p.logger.Warn("alert enricher failed",
+				zap.String("enricher", p.enrichers[i].Name()),
+				zap.String("alert_id", alert.ID),
+				zap.Error(r.err),
+			)
+			continue
+		}
+		if r.apply != nil {
+			r.apply(alert)
+		}
+	}
+}
+
+// enrichmentOf returns the enrichment of an alert, creating it if needed.
+func enrichmentOf(alert *models.Alert) *models.AlertEnrichment {
+	if alert.Enrichment == nil {
+		alert.Enrichment = &models.AlertEnrichment{}
+	}
+	return alert.Enrichment
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// evaluateForecasts evaluates the forecast rules of the services we own.
+// Forecasts fit a longer history than the sliding window, so they run every
+// ForecastInterval against the metrics store rather than per message.
+func (a *Analyzer) evaluateForecasts(ctx context.Context, rules []*models.ThresholdRule) {
+	now := time.Now()
+	if now.Sub(a.lastForecast) < a.config.ForecastInterval {
+		return
+	}
+	a.lastForecast = now
+
+	for _, rule := range rules {
+		if !rule.Enabled || !rule.Forecast() || !a.ownsService(rule.ServiceName) {
+			continue
+		}
+		a.evaluateForecast(ctx, rule)
+	}
+}
+
+func (a *Analyzer) evaluateForecast(ctx context.Context, rule *models.ThresholdRule) {
+	if err := evaluation.ValidateRule(rule); err != nil {
+		a.logger.Warn("skipping invalid rule",
+			zap.String("rule_id", rule.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	history, err := a.forecastHistory(ctx, rule)
+	if err != nil {
+		a.logger.Warn("failed to get forecast history",
+			zap.String("rule_id", rule.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	// Grouped rules fit a forecast per group
+	var breaches []thresholdBreach
+	var evaluated bool
+	var value float64
+
+	for _, group := range a.limitGroups(rule, evaluation.GroupSamples(rule, history)) {
+		w := evaluation.NewWindow(len(group.Samples), a.forecastWindow(rule))
+		for _, sample := range group.Samples {
+			w.Add(sample)
+		}
+
+		result, ok := evaluation.Evaluate(rule, w)
+		if !ok {
+			continue
+		}
+		if !evaluated || result.Breached {
+			value = result.Value
+		}
+		evaluated = true
+		if result.Breached {
+			breaches = append(breaches, thresholdBreach{rule: rule, result: result, group: group.Labels})
+		}
+	}
+	if !evaluated {
+		return
+	}
+
+	a.windowsMu.Lock()
+	a.recordRuleState(rule, len(breaches) > 0, value)
+	a.windowsMu.Unlock()
+
+	if len(breaches) == 0 || a.suppressed(rule) {
+		return
+	}
+	for _, b := range breaches {
+		a.generateForecastAlert(ctx, rule, b.result, b.group)
+	}
+}
+
+// forecastWindow is the history a forecast rule is fitted to: its own window
+// when longer than ForecastWindow.
+func (a *Analyzer) forecastWindow(rule *models.ThresholdRule) time.Duration {
+	window := time.Duration(rule.WindowSize) * time.Second
+	if window < a.config.ForecastWindow {
+		window = a.config.ForecastWindow
+	}
+	return window
+}
+
+// forecastHistory reads the history of the series a forecast rule reads,
+// including samples stored under legacy aliases, ordered by time.
+func (a *Analyzer) forecastHistory(ctx context.Context, rule *models.ThresholdRule) ([]*models.ServiceMetric, error) {
+	def, _ := models.LookupMetric(rule.MetricType)
+	window := a.forecastWindow(rule)
+
+	var history []*models.ServiceMetric
+	for _, metricType := range append([]models.MetricType{def.Type}, def.Aliases...) {
+		metrics, err := a.metricsStore.GetMetricsInWindow(ctx, rule.ServiceName, metricType, window)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, metrics...)
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	return history, nil
+}
+
+// generateForecastAlert publishes the alert of a forecast rule predicted to
+// breach, for one group of a grouped rule.
+func (a *Analyzer) generateForecastAlert(ctx context.Context, rule *models.ThresholdRule, result evaluation.Result, group models.Labels) {
+	series := models.SeriesID(result.MetricType, group)
+
+	forecast := result.Forecast
+	predictedAt := result.PredictedAt
+	horizon := time.Duration(rule.ForecastHorizon) * time.Second
+
+	alert := &models.Alert{
+		ID:          uuid.New().String(),
+		Type:        models.AlertTypeForecast,
+		ServiceName: rule.ServiceName,
+		MetricType:  result.MetricType,
+		Severity:    rule.Severity,
+		Title: fmt.Sprintf("[%s] %s predicted to cross %.2f within %s",
+			strings.ToUpper(string(rule.ServiceName)), series, rule.Threshold, horizon),
+		Message: fmt.Sprintf(
+			"The %s metric for service %s is predicted to cross the threshold.\n\nCurrent Value: %.2f\nThreshold: %s %.2f\nPredicted Crossing: %s\nPredicted Value in %s: %.2f\nConfidence: %.0f%% (%s)",
+			series, rule.ServiceName, forecast.Level, rule.Operator, rule.Threshold,
+			predictedAt.UTC().Format(time.RFC3339), horizon, result.Value, forecast.Confidence*100, forecast.Method,
+		),
+		CurrentValue: forecast.Level,
+		Value:        result.Value,
+		Threshold:    rule.Threshold,
+		Timestamp:    time.Now(),
+		RuleID:       rule.ID,
+		PredictedAt:  &predictedAt,
+		Confidence:   forecast.Confidence,
+		Labels:       groupLabels(group),
+	}
+	alert.Labels["alert_type"] = string(models.AlertTypeForecast)
+	alert.Labels["service"] = string(rule.ServiceName)
+	alert.Labels["metric"] = string(result.MetricType)
+	alert.Labels["rule"] = rule.Name
+
+	if !a.emitAlert(ctx, alert, a.cooldownFor(rule)) {
+		return
+	}
+
+	a.logger.Info("forecast alert generated",
+		zap.String("alert_id", alert.ID),
+		zap.String("service", string(rule.ServiceName)),
+		zap.String("series", series),
+		zap.Time("predicted_at", predictedAt),
+		zap.Float64("confidence", forecast.Confidence),
+	)
+}
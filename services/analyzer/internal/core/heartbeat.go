@@ -0,0 +1,199 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// checkHeartbeats detects services that stopped reporting metrics. An absent-data
+// alert fires once when a service goes stale and is resolved when data returns.
+// Firing alerts are kept in Redis, so whichever replica evaluates the service
+// when data returns resolves them.
+func (a *Analyzer) checkHeartbeats(ctx context.Context, services map[models.ServiceName]bool) {
+	if a.config.HeartbeatTimeout <= 0 {
+		return
+	}
+
+	heartbeats, err := a.metricsStore.GetHeartbeats(ctx)
+	if err != nil {
+		a.logger.Warn("failed to get heartbeats", zap.Error(err))
+		return
+	}
+
+	names := make([]models.ServiceName, 0, len(services))
+	for service := range services {
+		names = append(names, service)
+	}
+	absentAlerts, err := a.metricsStore.GetAbsentAlerts(ctx, names)
+	if err != nil {
+		a.logger.Warn("failed to get absent alerts", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for service := range services {
+		lastHeartbeat := heartbeats[service]
+		if a.config.HeartbeatMetricType != "" {
+			lastHeartbeat, err = a.metricsStore.GetLastHeartbeat(ctx, service, a.config.HeartbeatMetricType)
+			if err != nil {
+				lastHeartbeat = time.Time{}
+			}
+		}
+
+		// Services that never reported get a grace period from analyzer startup
+		reference := lastHeartbeat
+		if reference.IsZero() {
+			reference = a.startedAt
+		}
+		silentFor := now.Sub(reference)
+		stale := silentFor > a.config.HeartbeatTimeout
+
+		status := models.StatusHealthy
+		if stale {
+			status = models.StatusStale
+		}
+		if err := a.metricsStore.SetServiceHealth(ctx, &models.ServiceHealthStatus{
+			ServiceName:   service,
+			Status:        status,
+			LastHeartbeat: lastHeartbeat,
+		}); err != nil {
+			a.logger.Warn("failed to set service health",
+				zap.String("service", string(service)),
+				zap.Error(err),
+			)
+		}
+
+		firing, isFiring := absentAlerts[service]
+		switch {
+		case stale && !isFiring:
+			a.fireAbsentAlert(ctx, service, lastHeartbeat, silentFor)
+		case !stale && isFiring:
+			a.resolveAbsentAlert(ctx, firing, lastHeartbeat)
+		}
+	}
+}
+
+func (a *Analyzer) fireAbsentAlert(ctx context.Context, service models.ServiceName, lastHeartbeat time.Time, silentFor time.Duration) {
+	what := "any metrics"
+	if a.config.HeartbeatMetricType != "" {
+		what = fmt.Sprintf("%s metrics", a.config.HeartbeatMetricType)
+	}
+	last := "never"
+	if !lastHeartbeat.IsZero() {
+		last = lastHeartbeat.Format(time.RFC3339)
+	}
+
+	alert := &models.Alert{
+		ID:          uuid.New().String(),
+		Type:        models.AlertTypeAbsentData,
+		ServiceName: service,
+		MetricType:  a.config.HeartbeatMetricType,
+		Severity:    models.AlertSeverityCritical,
+		Title:       fmt.Sprintf("[%s] No data received from %s", strings.ToUpper(string(service)), service),
+		Message: fmt.Sprintf(
+			"Service %s has not reported %s for %s.\n\nLast Heartbeat: %s\nTimeout: %s\n\nThe service may be down or unable to reach Kafka.",
+			service, what, silentFor.Round(time.Second), last, a.config.HeartbeatTimeout,
+		),
+		CurrentValue: silentFor.Seconds(),
+		Threshold:    a.config.HeartbeatTimeout.Seconds(),
+		Timestamp:    time.Now(),
+		Labels: map[string]string{
+			"alert_type": string(models.AlertTypeAbsentData),
+			"service":    string(service),
+			"metric":     string(a.config.HeartbeatMetricType),
+		},
+	}
+	alert.EnsureFingerprint()
+
+	// Claim the alert first so replicas racing on the service fire it once
+	claimed, err := a.metricsStore.ClaimAbsentAlert(ctx, alert)
+	if err != nil {
+		a.logger.Error("failed to store absent-data alert",
+			zap.String("service", string(service)),
+			zap.Error(err),
+		)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if err := a.alertPublisher.PublishAlert(ctx, alert); err != nil {
+		a.logger.Error("failed to publish absent-data alert",
+			zap.String("service", string(service)),
+			zap.Error(err),
+		)
+		// Fire again on the next check
+		if _, err := a.metricsStore.ReleaseAbsentAlert(ctx, service); err != nil {
+			a.logger.Error("failed to release absent-data alert",
+				zap.String("service", string(service)),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	a.logger.Warn("service stopped reporting",
+		zap.String("alert_id", alert.ID),
+		zap.String("service", string(service)),
+		zap.String("last_heartbeat", last),
+	)
+}
+
+func (a *Analyzer) resolveAbsentAlert(ctx context.Context, firing *models.Alert, lastHeartbeat time.Time) {
+	now := time.Now()
+	resolved := *firing
+	resolved.Title = fmt.Sprintf("[%s] Data from %s restored", strings.ToUpper(string(firing.ServiceName)), firing.ServiceName)
+	resolved.Message = fmt.Sprintf(
+		"Service %s is reporting again.\n\nLast Heartbeat: %s\nFired At: %s",
+		firing.ServiceName, lastHeartbeat.Format(time.RFC3339), firing.Timestamp.Format(time.RFC3339),
+	)
+	resolved.CurrentValue = now.Sub(lastHeartbeat).Seconds()
+	resolved.Timestamp = now
+	resolved.ResolvedAt = &now
+	resolved.Labels = make(map[string]string, len(firing.Labels)+1)
+	for k, v := range firing.Labels {
+		resolved.Labels[k] = v
+	}
+	resolved.Labels["state"] = "resolved"
+
+	// Release the alert first so replicas racing on the service resolve it once
+	released, err := a.metricsStore.ReleaseAbsentAlert(ctx, firing.ServiceName)
+	if err != nil {
+		a.logger.Error("failed to release absent-data alert",
+			zap.String("service", string(firing.ServiceName)),
+			zap.Error(err),
+		)
+		return
+	}
+	if !released {
+		return
+	}
+
+	if err := a.alertPublisher.PublishAlert(ctx, &resolved); err != nil {
+		a.logger.Error("failed to publish absent-data resolution",
+			zap.String("service", string(firing.ServiceName)),
+			zap.Error(err),
+		)
+		// Resolve again on the next check
+		if _, err := a.metricsStore.ClaimAbsentAlert(ctx, firing); err != nil {
+			a.logger.Error("failed to restore absent-data alert",
+				zap.String("service", string(firing.ServiceName)),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	a.logger.Info("service reporting again",
+		zap.String("alert_id", firing.ID),
+		zap.String("service", string(firing.ServiceName)),
+	)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// ownsService reports whether this replica evaluates the given service.
+// Without partition affinity every replica owns every service. A service
+// whose metrics were never seen, such as one that died before any replica
+// started, is owned by the replica of the partition its publishers key it to.
+func (a *Analyzer) ownsService(service models.ServiceName) bool {
+	if !a.config.PartitionAffinity {
+		return true
+	}
+
+	a.ownershipMu.RLock()
+	defer a.ownershipMu.RUnlock()
+
+	partition, ok := a.servicePartitions[service]
+	if !ok {
+		partition = sharedkafka.KeyPartition([]byte(service), a.config.MetricsPartitions)
+	}
+	return a.ownedPartitions[partition]
+}
+
+// HasPartitions reports whether the consumer group assigned this replica
+// metrics partitions. Without partition affinity it always has them.
+func (a *Analyzer) HasPartitions() bool {
+	if !a.config.PartitionAffinity {
+		return true
+	}
+
+	a.ownershipMu.RLock()
+	defer a.ownershipMu.RUnlock()
+	return len(a.ownedPartitions) > 0
+}
+
+// TrackServicePartition records the partition a service's metrics arrive on.
+// Publishers key metrics by service, so the mapping only changes when the
+// topic's partition count changes.
+func (a *Analyzer) TrackServicePartition(ctx context.Context, service models.ServiceName, partition int) {
+	a.ownershipMu.Lock()
+	current, ok := a.servicePartitions[service]
+	if ok && current == partition {
+		a.ownershipMu.Unlock()
+		return
+	}
+	a.servicePartitions[service] = partition
+	a.ownershipMu.Unlock()
+
+	if err := a.registry.SetServicePartition(ctx, service, partition); err != nil {
+		a.logger.Warn("failed to store service partition",
+			zap.String("service", string(service)),
+			zap.Int("partition", partition),
+			zap.Error(err),
+		)
+	}
+}
+
+// PartitionsAssigned takes ownership of the services on the assigned partitions
+// and restores their windows from the checkpoints left by the previous owner.
+func (a *Analyzer) PartitionsAssigned(ctx context.Context, topic string, partitions []int) {
+	// Services seen by other replicas are known from the shared mapping
+	known, err := a.registry.GetServicePartitions(ctx)
+	if err != nil {
+		a.logger.Warn("failed to get service partitions", zap.Error(err))
+	}
+
+	a.ownershipMu.Lock()
+	for service, partition := range known {
+		a.servicePartitions[service] = partition
+	}
+	a.ownedPartitions = make(map[int]bool, len(partitions))
+	for _, p := range partitions {
+		a.ownedPartitions[p] = true
+	}
+	acquired := a.servicesOnPartitions(partitions)
+	a.ownershipMu.Unlock()
+
+	a.logger.Info("analyzer partitions assigned",
+		zap.String("topic", topic),
+		zap.Ints("partitions", partitions),
+		zap.Int("services", len(acquired)),
+	)
+
+	if a.streaming() {
+		a.restoreWindows(ctx, acquired)
+	}
+}
+
+// PartitionsRevoked checkpoints and releases the state of the services on the
+// revoked partitions so the next owner can resume from it.
+func (a *Analyzer) PartitionsRevoked(ctx context.Context, topic string, partitions []int) {
+	a.ownershipMu.Lock()
+	released := a.servicesOnPartitions(partitions)
+	for _, p := range partitions {
+		delete(a.ownedPartitions, p)
+	}
+	a.ownershipMu.Unlock()
+
+	release := make(map[models.ServiceName]bool, len(released))
+	for _, service := range released {
+		release[service] = true
+	}
+
+	if a.streaming() {
+		a.checkpointWindows(ctx, release)
+	}
+
+	a.logger.Info("analyzer partitions revoked",
+		zap.String("topic", topic),
+		zap.Ints("partitions", partitions),
+		zap.Int("services", len(released)),
+	)
+}
+
+// servicesOnPartitions returns the services mapped to the given partitions.
+// The caller must hold ownershipMu.
+func (a *Analyzer) servicesOnPartitions(partitions []int) []models.ServiceName {
+	wanted := make(map[int]bool, len(partitions))
+	for _, p := range partitions {
+		wanted[p] = true
+	}
+
+	var services []models.ServiceName
+	for service, partition := range a.servicePartitions {
+		if wanted[partition] {
+			services = append(services, service)
+		}
+	}
+	return services
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// loadRules fetches every rule, rebuilds the rule graph from the valid rules
+// and returns the enabled rules. Disabled rules stay in the graph so references to them
+// resolve; they never get a state, so composites reading them stay unknown.
+func (a *Analyzer) loadRules(ctx context.Context) ([]*models.ThresholdRule, error) {
+	all, err := a.rulesStore.GetAllRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	graph, errs := evaluation.BuildRuleGraph(all)
+	for _, err := range errs {
+		a.logger.Error("invalid rule excluded from composite rules and dependencies", zap.Error(err))
+	}
+
+	enabled := make([]*models.ThresholdRule, 0, len(all))
+	for _, rule := range all {
+		if rule.Enabled {
+			enabled = append(enabled, rule)
+		}
+	}
+
+	a.windowsMu.Lock()
+	a.graph = graph
+	a.windowsMu.Unlock()
+
+	return enabled, nil
+}
+
+// recordRuleState stores the latest evaluation of a rule for composite rules
+// and dependencies. The caller must hold windowsMu.
+func (a *Analyzer) recordRuleState(rule *models.ThresholdRule, breached bool, value float64) {
+	state := evaluation.RuleState{
+		Breached:    breached,
+		Value:       value,
+		EvaluatedAt: time.Now(),
+	}
+	a.ruleStates[rule.ID] = state
+	a.pendingStates[rule.ID] = state
+}
+
+// ruleStateView returns the current rule states: the states shared by other
+// replicas overlaid with our own. States older than the sliding window are
+// unknown, as the rule would have nothing left to evaluate. The caller must
+// hold windowsMu.
+func (a *Analyzer) ruleStateView() map[string]evaluation.RuleState {
+	cutoff := time.Now().Add(-a.config.SlidingWindowSize)
+	view := make(map[string]evaluation.RuleState, len(a.sharedStates)+len(a.ruleStates))
+
+	for id, state := range a.sharedStates {
+		if state.EvaluatedAt.After(cutoff) {
+			view[id] = state
+		}
+	}
+	for id, state := range a.ruleStates {
+		if !state.EvaluatedAt.After(cutoff) {
+			delete(a.ruleStates, id)
+			continue
+		}
+		view[id] = state
+	}
+
+	return view
+}
+
+// inhibitor returns the breached dependency suppressing a rule's alerts, if any.
+func (a *Analyzer) inhibitor(rule *models.ThresholdRule) (*models.ThresholdRule, bool) {
+	if len(rule.DependsOn) == 0 {
+		return nil, false
+	}
+
+	a.windowsMu.Lock()
+	defer a.windowsMu.Unlock()
+
+	if a.graph == nil {
+		return nil, false
+	}
+	return a.graph.Inhibitor(rule, a.ruleStateView())
+}
+
+// suppressed reports whether a breached rule's alert is suppressed by a dependency.
+func (a *Analyzer) suppressed(rule *models.ThresholdRule) bool {
+	by, ok := a.inhibitor(rule)
+	if ok {
+		a.logger.Debug("alert suppressed by dependency",
+			zap.String("rule_id", rule.ID),
+			zap.String("rule", rule.Name),
+			zap.String("dependency", evaluation.RuleReference(by)),
+		)
+	}
+	return ok
+}
+
+// evaluateRuleGraph shares our rule states with the other replicas, reads
+// theirs and evaluates the composite rules of the services we own.
+func (a *Analyzer) evaluateRuleGraph(ctx context.Context) {
+	a.windowsMu.Lock()
+	pending := a.pendingStates
+	a.pendingStates = make(map[string]evaluation.RuleState)
+	graph := a.graph
+	a.windowsMu.Unlock()
+
+	if err := a.metricsStore.SaveRuleStates(ctx, pending, 2*a.config.SlidingWindowSize); err != nil {
+		a.logger.Warn("failed to save rule states", zap.Error(err))
+	}
+
+	shared, err := a.metricsStore.GetRuleStates(ctx)
+	if err != nil {
+		a.logger.Warn("failed to get rule states", zap.Error(err))
+	}
+
+	if graph == nil {
+		return
+	}
+
+	var composites []*models.ThresholdRule
+	for _, rule := range graph.Composites() {
+		if rule.Enabled && a.ownsService(rule.ServiceName) {
+			composites = append(composites, rule)
+		}
+	}
+
+	var firing []*models.ThresholdRule
+	a.windowsMu.Lock()
+	if shared != nil {
+		a.sharedStates = shared
+	}
+	view := a.ruleStateView()
+	// Composites come after the rules they read, so nested composites see fresh states
+	for _, rule := range composites {
+		breached, ok := graph.EvaluateComposite(rule, view)
+		if !ok {
+			continue
+		}
+		a.recordRuleState(rule, breached, 0)
+		view[rule.ID] = a.ruleStates[rule.ID]
+
+		if breached {
+			if _, inhibited := graph.Inhibitor(rule, view); !inhibited {
+				firing = append(firing, rule)
+			}
+		}
+	}
+	a.windowsMu.Unlock()
+
+	for _, rule := range firing {
+		a.generateCompositeAlert(ctx, rule)
+	}
+}
+
+// generateCompositeAlert publishes the alert of a breached composite rule.
+func (a *Analyzer) generateCompositeAlert(ctx context.Context, rule *models.ThresholdRule) {
+	alert := &models.Alert{
+		ID:          uuid.New().String(),
+		Type:        models.AlertTypeComposite,
+		ServiceName: rule.ServiceName,
+		Severity:    rule.Severity,
+		Title:       fmt.Sprintf("[%s] %s", strings.ToUpper(string(rule.ServiceName)), rule.Name),
+		Message: fmt.Sprintf(
+			"Composite rule %s for service %s is breached.\n\nExpression: %s\n\nPlease investigate immediately.",
+			rule.Name, rule.ServiceName, rule.Expression,
+		),
+		Timestamp: time.Now(),
+		RuleID:    rule.ID,
+		Labels: map[string]string{
+			"alert_type": string(models.AlertTypeComposite),
+			"service":    string(rule.ServiceName),
+			"rule":       rule.Name,
+		},
+	}
+
+	if !a.emitAlert(ctx, alert, a.cooldownFor(rule)) {
+		return
+	}
+
+	a.logger.Info("composite alert generated",
+		zap.String("alert_id", alert.ID),
+		zap.String("service", string(rule.ServiceName)),
+		zap.String("rule", rule.Name),
+		zap.String("expression", rule.Expression),
+	)
+}
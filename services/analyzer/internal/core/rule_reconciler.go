@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/analyzer/internal/ports"
+)
+
+// RuleReconciler keeps the file-managed rules in the rule store in sync with
+// a RuleSource. Rules removed from the source are deleted from the store and
+// drift in the store is overwritten; rules created through the API are left alone.
+type RuleReconciler struct {
+	source   ports.RuleSource
+	store    ports.RulesStore
+	interval time.Duration
+	logger   *logging.Logger
+
+	// Last valid rule set; kept when a reload fails validation
+	digest  string
+	desired []*models.ThresholdRule
+
+	mu      sync.Mutex
+	running bool
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRuleReconciler creates a new RuleReconciler.
+func NewRuleReconciler(source ports.RuleSource, store ports.RulesStore, interval time.Duration, logger *logging.Logger) *RuleReconciler {
+	return &RuleReconciler{
+		source:   source,
+		store:    store,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start reconciles once and then reloads the source every interval.
+// It fails if the initial rule set is invalid.
+func (r *RuleReconciler) Start(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = true
+	r.stopCh = make(chan struct{})
+	r.mu.Unlock()
+
+	if err := r.Reconcile(ctx); err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go r.run(ctx)
+
+	return nil
+}
+
+// Stop stops reloading the source.
+func (r *RuleReconciler) Stop() error {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = false
+	close(r.stopCh)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return nil
+}
+
+func (r *RuleReconciler) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			if err := r.Reconcile(ctx); err != nil {
+				r.logger.Error("failed to reconcile rule files", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Reconcile loads the source and applies it to the rule store. An invalid
+// source keeps the last valid rule set in place.
+func (r *RuleReconciler) Reconcile(ctx context.Context) error {
+	rules, digest, err := r.source.LoadRules()
+	switch {
+	case err != nil && r.desired == nil:
+		return fmt.Errorf("failed to load rule files: %w", err)
+	case err != nil:
+		r.logger.Error("invalid rule files, keeping previous rules", zap.Error(err))
+	case digest != r.digest:
+		// References may point at rules created through the API
+		if err := r.validateGraph(ctx, rules); err != nil {
+			if r.desired == nil {
+				return fmt.Errorf("invalid rule files: %w", err)
+			}
+			r.logger.Error("invalid rule files, keeping previous rules", zap.Error(err))
+			break
+		}
+		r.logger.Info("rule files loaded",
+			zap.Int("rules", len(rules)),
+			zap.String("digest", digest),
+		)
+		r.digest = digest
+		r.desired = rules
+	}
+
+	return r.apply(ctx)
+}
+
+// validateGraph checks the references of the file rules against the rules
+// they would be reconciled with.
+func (r *RuleReconciler) validateGraph(ctx context.Context, rules []*models.ThresholdRule) error {
+	existing, err := r.store.GetAllRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	combined := append([]*models.ThresholdRule{}, rules...)
+	for _, rule := range existing {
+		if rule.Source != models.RuleSourceFile {
+			combined = append(combined, rule)
+		}
+	}
+
+	_, err = evaluation.NewRuleGraph(combined)
+	return err
+}
+
+func (r *RuleReconciler) apply(ctx context.Context) error {
+	existing, err := r.store.GetAllRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	current := make(map[string]*models.ThresholdRule, len(existing))
+	for _, rule := range existing {
+		current[rule.ID] = rule
+	}
+
+	now := time.Now().UTC()
+	wanted := make(map[string]bool, len(r.desired))
+	for _, desired := range r.desired {
+		wanted[desired.ID] = true
+		rule := *desired
+
+		stored, ok := current[rule.ID]
+		if ok && sameRule(stored, &rule) {
+			continue
+		}
+
+		if ok {
+			rule.CreatedAt = stored.CreatedAt
+			rule.UpdatedAt = now
+			err = r.store.UpdateRule(ctx, &rule, ruleFileAuthor(&rule))
+		} else {
+			rule.CreatedAt = now
+			rule.UpdatedAt = now
+			err = r.store.CreateRule(ctx, &rule, ruleFileAuthor(&rule))
+		}
+		if err != nil {
+			r.logger.Warn("failed to apply file rule",
+				zap.String("rule_id", rule.ID),
+				zap.String("rule", rule.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		r.logger.Info("applied file rule",
+			zap.String("rule_id", rule.ID),
+			zap.String("rule", rule.Name),
+			zap.String("file", rule.SourceFile),
+		)
+	}
+
+	// Delete file-managed rules that are no longer in any file
+	for _, stored := range existing {
+		if stored.Source != models.RuleSourceFile || wanted[stored.ID] {
+			continue
+		}
+		if err := r.store.DeleteRule(ctx, stored.ID, ruleFileAuthor(stored)); err != nil {
+			r.logger.Warn("failed to delete removed file rule",
+				zap.String("rule_id", stored.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		r.logger.Info("deleted file rule",
+			zap.String("rule_id", stored.ID),
+			zap.String("rule", stored.Name),
+		)
+	}
+
+	return nil
+}
+
+// ruleFileAuthor is the author recorded in the version history for changes made by the reconciler.
+func ruleFileAuthor(rule *models.ThresholdRule) string {
+	return "rule-file:" + rule.SourceFile
+}
+
+// sameRule compares two rules ignoring timestamps.
+func sameRule(a, b *models.ThresholdRule) bool {
+	x, y := *a, *b
+	x.CreatedAt, x.UpdatedAt = time.Time{}, time.Time{}
+	y.CreatedAt, y.UpdatedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,335 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// Evaluation modes.
+const (
+	// EvaluationModeStreaming evaluates each metric as it is consumed against
+	// in-memory windows; Redis is only used to checkpoint the windows.
+	EvaluationModeStreaming = "streaming"
+	// EvaluationModeInterval re-reads the full window from Redis for every
+	// service on each analysis tick.
+	EvaluationModeInterval = "interval"
+)
+
+// thresholdBreach is a rule violation found while holding the window lock.
+type thresholdBreach struct {
+	rule   *models.ThresholdRule
+	result evaluation.Result
+	group  models.Labels // breached group of a grouped rule
+}
+
+// AnalyzeMetric evaluates a single consumed metric in streaming mode. The metric
+// is added to the in-memory window of its series and only the rules and
+// deviation checks that read that series are evaluated; grouped rules only for
+// the metric's group. The caller is responsible for persisting the metric.
+func (a *Analyzer) AnalyzeMetric(ctx context.Context, metric *models.ServiceMetric) error {
+	def, ok := models.LookupMetric(metric.MetricType)
+	if !ok {
+		return fmt.Errorf("unknown metric type %q", metric.MetricType)
+	}
+	metricType := def.Type
+
+	var breaches []thresholdBreach
+	var checkDeviation bool
+	var mean, stdDev float64
+
+	a.windowsMu.Lock()
+	w := a.addSample(metric, metricType)
+	sw := a.windows[metric.ServiceName]
+
+	for _, rule := range a.rules {
+		// Forecasts fit a longer history and are evaluated periodically
+		if rule.ServiceName != metric.ServiceName || !rule.Enabled || rule.Forecast() {
+			continue
+		}
+		if series, ok := evaluation.SeriesFor(rule); !ok || series != metricType {
+			continue
+		}
+
+		if len(rule.GroupBy) > 0 {
+			if b, ok := a.evaluateGroup(sw, rule, metricType, metric.Labels); ok {
+				breaches = append(breaches, b)
+			}
+			continue
+		}
+
+		result, ok := evaluation.Evaluate(rule, w)
+		if !ok {
+			continue
+		}
+		a.recordRuleState(rule, result.Breached, result.Value)
+		if result.Breached {
+			breaches = append(breaches, thresholdBreach{rule: rule, result: result})
+		}
+	}
+
+	if isDeviationMetric(metricType) && w.Len() >= a.config.MinSamplesForDeviation {
+		checkDeviation = true
+		mean, stdDev = w.Stats()
+	}
+	a.windowsMu.Unlock()
+
+	for _, b := range breaches {
+		if a.suppressed(b.rule) {
+			continue
+		}
+		a.generateAlert(ctx, b.rule, b.rule.ServiceName, b.result.MetricType, b.group, b.result.Severity, b.result.Value, b.rule.ThresholdFor(b.result.Severity), "threshold_violation")
+	}
+	if checkDeviation {
+		a.checkMetricDeviation(ctx, metric.ServiceName, metricType, metric.Value, mean, stdDev)
+	}
+
+	return nil
+}
+
+// evaluateGroup evaluates a grouped rule on the window of the group a sample
+// belongs to and returns the breach, if any. The rule is breached while any
+// of its groups is. The caller must hold windowsMu.
+func (a *Analyzer) evaluateGroup(sw *serviceWindow, rule *models.ThresholdRule, metricType models.MetricType, labels models.Labels) (thresholdBreach, bool) {
+	groups := sw.groups[groupingKey(metricType, rule.GroupBy)]
+	gw, ok := groups[labels.Project(rule.GroupBy).Fingerprint()]
+	if !ok {
+		// Beyond the group limit
+		return thresholdBreach{}, false
+	}
+
+	result, ok := evaluation.Evaluate(rule, gw.window)
+	if !ok {
+		return thresholdBreach{}, false
+	}
+	gw.breached[rule.ID] = result.Breached
+
+	breached := false
+	for _, g := range groups {
+		breached = breached || g.breached[rule.ID]
+	}
+	a.recordRuleState(rule, breached, result.Value)
+
+	if !result.Breached {
+		return thresholdBreach{}, false
+	}
+	return thresholdBreach{rule: rule, result: result, group: gw.labels}, true
+}
+
+// addSample adds a sample to the window of its series and to the group
+// windows of the grouped rules reading that series, and returns the series
+// window. The caller must hold windowsMu.
+func (a *Analyzer) addSample(sample *models.ServiceMetric, metricType models.MetricType) *evaluation.Window {
+	w := a.sampleWindowFor(sample.ServiceName, metricType)
+	w.Add(sample)
+
+	sw := a.windows[sample.ServiceName]
+	var added map[string]bool
+	for _, rule := range a.rules {
+		if len(rule.GroupBy) == 0 || rule.ServiceName != sample.ServiceName || !rule.Enabled || rule.Forecast() {
+			continue
+		}
+		if series, ok := evaluation.SeriesFor(rule); !ok || series != metricType {
+			continue
+		}
+
+		// Rules sharing a grouping share its windows
+		key := groupingKey(metricType, rule.GroupBy)
+		if added[key] {
+			continue
+		}
+		if added == nil {
+			added = make(map[string]bool)
+		}
+		added[key] = true
+
+		if gw := a.groupWindowFor(sw, key, sample.Labels.Project(rule.GroupBy)); gw != nil {
+			gw.window.Add(sample)
+		}
+	}
+
+	return w
+}
+
+// groupWindowFor returns the window of a group, creating it if the grouping
+// has fewer than MaxGroupsPerRule groups. Samples of groups beyond the limit
+// are not evaluated by the grouping's rules. The caller must hold windowsMu.
+func (a *Analyzer) groupWindowFor(sw *serviceWindow, key string, labels models.Labels) *groupWindow {
+	groups, ok := sw.groups[key]
+	if !ok {
+		groups = make(map[string]*groupWindow)
+		sw.groups[key] = groups
+	}
+
+	fingerprint := labels.Fingerprint()
+	if gw, ok := groups[fingerprint]; ok {
+		return gw
+	}
+	if len(groups) >= a.config.MaxGroupsPerRule {
+		a.logger.Debug("group limit reached, sample not evaluated by grouped rules",
+			zap.String("grouping", key),
+			zap.String("group", fingerprint),
+			zap.Int("max_groups", a.config.MaxGroupsPerRule),
+		)
+		return nil
+	}
+
+	gw := &groupWindow{
+		labels:   labels,
+		window:   evaluation.NewWindow(a.config.MaxWindowSamples, a.config.SlidingWindowSize),
+		breached: make(map[string]bool),
+	}
+	groups[fingerprint] = gw
+	return gw
+}
+
+// sampleWindowFor returns the window of a series, creating it if needed.
+// The caller must hold windowsMu.
+func (a *Analyzer) sampleWindowFor(service models.ServiceName, metricType models.MetricType) *evaluation.Window {
+	sw, ok := a.windows[service]
+	if !ok {
+		sw = newServiceWindow()
+		a.windows[service] = sw
+	}
+	sw.dirty = true
+
+	w, ok := sw.series[metricType]
+	if !ok {
+		w = evaluation.NewWindow(a.config.MaxWindowSamples, a.config.SlidingWindowSize)
+		sw.series[metricType] = w
+	}
+	return w
+}
+
+// refreshRules reloads the enabled rules used by streaming evaluation.
+func (a *Analyzer) refreshRules(ctx context.Context) []*models.ThresholdRule {
+	rules, err := a.loadRules(ctx)
+	if err != nil {
+		a.logger.Error("failed to get enabled rules", zap.Error(err))
+		a.windowsMu.Lock()
+		rules = a.rules
+		a.windowsMu.Unlock()
+		return rules
+	}
+
+	a.windowsMu.Lock()
+	a.rules = rules
+	a.windowsMu.Unlock()
+	return rules
+}
+
+// performStreamingMaintenance runs the periodic work that streaming evaluation
+// cannot do per message: reloading rules, evaluating forecast and composite
+// rules and detecting absent data.
+func (a *Analyzer) performStreamingMaintenance(ctx context.Context) {
+	rules := a.refreshRules(ctx)
+	a.evaluateForecasts(ctx, rules)
+	a.evaluateRuleGraph(ctx)
+	a.checkHeartbeats(ctx, a.servicesToAnalyze(ctx, rules))
+}
+
+// checkpointWindows persists the windows changed since the last checkpoint.
+// Services in release are checkpointed even if unchanged and dropped from
+// memory, handing their state to the replica that takes them over.
+func (a *Analyzer) checkpointWindows(ctx context.Context, release map[models.ServiceName]bool) {
+	cutoff := time.Now().Add(-a.config.SlidingWindowSize)
+	checkpoints := make(map[models.ServiceName][]*models.ServiceMetric)
+
+	a.windowsMu.Lock()
+	for service, sw := range a.windows {
+		if !sw.dirty && !release[service] {
+			continue
+		}
+		var samples []*models.ServiceMetric
+		for _, w := range sw.series {
+			w.EvictBefore(cutoff)
+			samples = append(samples, w.Samples()...)
+		}
+		// Group windows are rebuilt from the samples, so only need evicting
+		for key, groups := range sw.groups {
+			for fingerprint, gw := range groups {
+				gw.window.EvictBefore(cutoff)
+				if gw.window.Len() == 0 {
+					delete(groups, fingerprint)
+				}
+			}
+			if len(groups) == 0 {
+				delete(sw.groups, key)
+			}
+		}
+		sw.dirty = false
+		checkpoints[service] = samples
+		if len(samples) == 0 || release[service] {
+			delete(a.windows, service)
+		}
+	}
+	a.windowsMu.Unlock()
+
+	for service, samples := range checkpoints {
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i].Timestamp.Before(samples[j].Timestamp)
+		})
+		if err := a.metricsStore.SaveWindowCheckpoint(ctx, service, samples, a.config.SlidingWindowSize); err != nil {
+			a.logger.Warn("failed to checkpoint window",
+				zap.String("service", string(service)),
+				zap.Error(err),
+			)
+			a.windowsMu.Lock()
+			if sw, ok := a.windows[service]; ok {
+				sw.dirty = true
+			}
+			a.windowsMu.Unlock()
+		}
+	}
+}
+
+// restoreWindows rebuilds the in-memory windows of services from their last checkpoints.
+func (a *Analyzer) restoreWindows(ctx context.Context, services []models.ServiceName) {
+	cutoff := time.Now().Add(-a.config.SlidingWindowSize)
+	restored := 0
+	for _, service := range services {
+		samples, err := a.metricsStore.LoadWindowCheckpoint(ctx, service)
+		if err != nil {
+			a.logger.Warn("failed to load window checkpoint",
+				zap.String("service", string(service)),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		a.windowsMu.Lock()
+		// Replace any partial window built before the handoff completed
+		delete(a.windows, service)
+		for _, sample := range samples {
+			if sample.Timestamp.Before(cutoff) {
+				continue
+			}
+			a.addSample(sample, models.CanonicalMetricType(sample.MetricType))
+			restored++
+		}
+		if sw, ok := a.windows[service]; ok {
+			sw.dirty = false
+		}
+		a.windowsMu.Unlock()
+	}
+
+	a.logger.Info("restored analysis windows from checkpoint",
+		zap.Int("services", len(services)),
+		zap.Int("samples", restored),
+	)
+}
+
+func isDeviationMetric(metricType models.MetricType) bool {
+	for _, t := range deviationMetrics {
+		if t == metricType {
+			return true
+		}
+	}
+	return false
+}
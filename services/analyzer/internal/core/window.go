@@ -0,0 +1,43 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// serviceWindow holds the sample windows of every metric series of a service.
+type serviceWindow struct {
+	series map[models.MetricType]*evaluation.Window
+	// groups holds the windows of grouped rules: per grouping, as returned
+	// by groupingKey, a window per label combination keyed by fingerprint.
+	// They are derived from the series windows and never checkpointed.
+	groups map[string]map[string]*groupWindow
+	dirty  bool
+}
+
+// groupWindow is the window of one label combination of a grouping.
+type groupWindow struct {
+	labels models.Labels
+	window *evaluation.Window
+	// breached records, per rule ID, whether the rule was breached for this
+	// group when last evaluated.
+	breached map[string]bool
+}
+
+func newServiceWindow() *serviceWindow {
+	return &serviceWindow{
+		series: make(map[models.MetricType]*evaluation.Window),
+		groups: make(map[string]map[string]*groupWindow),
+	}
+}
+
+// groupingKey identifies the grouping of a series by a set of labels, e.g.
+// "latency by (endpoint,host)". Label order does not matter.
+func groupingKey(metricType models.MetricType, groupBy []string) string {
+	labels := append([]string(nil), groupBy...)
+	sort.Strings(labels)
+	return string(metricType) + " by (" + strings.Join(labels, ",") + ")"
+}
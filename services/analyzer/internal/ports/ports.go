@@ -5,6 +5,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/kafka"
 	"github.com/microservices-platform/pkg/shared/models"
 )
 
@@ -24,10 +26,38 @@ type MetricsStore interface {
 	CleanupOldMetrics(ctx context.Context, retentionPeriod time.Duration) error
 	// CheckAndSetAlertSent checks if an alert was recently sent and marks it as sent.
 	CheckAndSetAlertSent(ctx context.Context, deduplicationKey string, ttl time.Duration) (bool, error)
-	// CheckCooldown checks if a service/metric is in cooldown.
-	CheckCooldown(ctx context.Context, serviceName, metricType string) (bool, error)
-	// SetCooldown sets a cooldown period for a service/metric.
-	SetCooldown(ctx context.Context, serviceName, metricType string, duration time.Duration) error
+	// ClearAlertSent releases a deduplication key, for an alert that failed to publish.
+	ClearAlertSent(ctx context.Context, deduplicationKey string) error
+	// GetCooldown returns the severity alerted for a cooldown key of a service,
+	// or an empty severity when the key is not in cooldown.
+	GetCooldown(ctx context.Context, serviceName, key string) (models.AlertSeverity, error)
+	// SetCooldown starts a cooldown for a key of a service, recording the severity alerted.
+	SetCooldown(ctx context.Context, serviceName, key string, severity models.AlertSeverity, duration time.Duration) error
+	// GetLastHeartbeat returns when a service last reported a metric of the given type.
+	// An empty metric type matches any metric.
+	GetLastHeartbeat(ctx context.Context, serviceName models.ServiceName, metricType models.MetricType) (time.Time, error)
+	// RecordHeartbeats stores when services last reported each metric type.
+	RecordHeartbeats(ctx context.Context, heartbeats map[models.ServiceName]map[models.MetricType]time.Time) error
+	// GetHeartbeats returns the last report time of every service that has published metrics.
+	GetHeartbeats(ctx context.Context) (map[models.ServiceName]time.Time, error)
+	// SetServiceHealth stores the current health status of a service.
+	SetServiceHealth(ctx context.Context, status *models.ServiceHealthStatus) error
+	// ClaimAbsentAlert stores the absent-data alert firing for its service
+	// unless one already is, reporting whether it was stored.
+	ClaimAbsentAlert(ctx context.Context, alert *models.Alert) (bool, error)
+	// GetAbsentAlerts returns the absent-data alerts firing for the given services.
+	GetAbsentAlerts(ctx context.Context, services []models.ServiceName) (map[models.ServiceName]*models.Alert, error)
+	// ReleaseAbsentAlert removes the absent-data alert firing for a service,
+	// reporting whether one was.
+	ReleaseAbsentAlert(ctx context.Context, serviceName models.ServiceName) (bool, error)
+	// SaveWindowCheckpoint stores the in-memory analysis window of a service.
+	SaveWindowCheckpoint(ctx context.Context, serviceName models.ServiceName, samples []*models.ServiceMetric, ttl time.Duration) error
+	// LoadWindowCheckpoint retrieves the last checkpointed analysis window of a service.
+	LoadWindowCheckpoint(ctx context.Context, serviceName models.ServiceName) ([]*models.ServiceMetric, error)
+	// SaveRuleStates stores the latest evaluation of rules, keyed by rule ID, for other replicas.
+	SaveRuleStates(ctx context.Context, states map[string]evaluation.RuleState, ttl time.Duration) error
+	// GetRuleStates retrieves the latest evaluation of every rule, keyed by rule ID.
+	GetRuleStates(ctx context.Context) (map[string]evaluation.RuleState, error)
 }
 
 // RulesStore defines the interface for managing threshold rules (plural for compatibility).
@@ -40,17 +70,35 @@ type RulesStore interface {
 	GetEnabledRules(ctx context.Context) ([]*models.ThresholdRule, error)
 	// GetRulesForService retrieves all rules for a specific service.
 	GetRulesForService(ctx context.Context, serviceName models.ServiceName) ([]*models.ThresholdRule, error)
-	// CreateRule creates a new rule.
-	CreateRule(ctx context.Context, rule *models.ThresholdRule) error
-	// UpdateRule updates an existing rule.
-	UpdateRule(ctx context.Context, rule *models.ThresholdRule) error
-	// DeleteRule deletes a rule.
-	DeleteRule(ctx context.Context, id string) error
+	// CreateRule creates a new rule and records it in the rule's version history.
+	CreateRule(ctx context.Context, rule *models.ThresholdRule, author string) error
+	// UpdateRule updates an existing rule and records the change in its version history.
+	UpdateRule(ctx context.Context, rule *models.ThresholdRule, author string) error
+	// DeleteRule deletes a rule and records the deletion in its version history.
+	DeleteRule(ctx context.Context, id string, author string) error
 }
 
 // RuleStore is an alias for RulesStore for compatibility.
 type RuleStore = RulesStore
 
+// RuleSource defines the interface for rules managed outside the API, such as rule files.
+type RuleSource interface {
+	// LoadRules loads and validates every rule. The digest changes whenever the source changes.
+	LoadRules() (rules []*models.ThresholdRule, digest string, err error)
+}
+
+// ServiceRegistry defines the interface for services discovered from the metric stream.
+type ServiceRegistry interface {
+	// Register merges registrations observed from the metric stream into the registry.
+	Register(ctx context.Context, registrations []*models.ServiceRegistration) error
+	// GetServices retrieves all services that reported within the registry TTL.
+	GetServices(ctx context.Context) ([]*models.ServiceRegistration, error)
+	// SetServicePartition records the metrics partition a service is published to.
+	SetServicePartition(ctx context.Context, serviceName models.ServiceName, partition int) error
+	// GetServicePartitions retrieves the metrics partition of every known service.
+	GetServicePartitions(ctx context.Context) (map[models.ServiceName]int, error)
+}
+
 // AlertStore defines the interface for managing alerts and cooldowns.
 type AlertStore interface {
 	// RecordAlert records an alert for deduplication.
@@ -71,12 +119,49 @@ type AlertPublisher interface {
 	Close() error
 }
 
+// LogStore defines the interface for the recent logs of services, kept to
+// enrich alerts.
+type LogStore interface {
+	// AddLog records a log entry, keeping only the most recent ones per service.
+	AddLog(ctx context.Context, log *models.ServiceLog) error
+	// GetRecentLogs returns up to limit of the most recent logs of a service, newest first.
+	GetRecentLogs(ctx context.Context, serviceName models.ServiceName, limit int) ([]*models.ServiceLog, error)
+}
+
+// AlertEnricher defines the interface for adding context to alerts before they are published.
+type AlertEnricher interface {
+	// Name identifies the enricher in logs.
+	Name() string
+	// Enrich looks up context for an alert, which it must not modify, and
+	// returns a function adding it. It must return once ctx is done.
+	Enrich(ctx context.Context, alert *models.Alert) (func(*models.Alert), error)
+}
+
 // AnomalyDetector defines the interface for anomaly detection.
 type AnomalyDetector interface {
 	// DetectAnomalies analyzes metrics and returns detected anomalies.
 	DetectAnomalies(ctx context.Context, serviceName models.ServiceName) ([]*models.Alert, error)
 }
 
+// MetricAnalyzer defines the interface for evaluating metrics as they are consumed.
+type MetricAnalyzer interface {
+	// AnalyzeMetric evaluates a single metric against the in-memory windows.
+	AnalyzeMetric(ctx context.Context, metric *models.ServiceMetric) error
+}
+
+// MetricLimiter defines the interface for guarding the cardinality of ingested metrics.
+type MetricLimiter interface {
+	// Limit applies the label policy to a metric's labels in place.
+	Limit(metric *models.ServiceMetric)
+}
+
+// PartitionOwner defines the interface for analysis state sharded by metrics partition.
+type PartitionOwner interface {
+	kafka.RebalanceListener
+	// TrackServicePartition records the partition a service's metrics arrive on.
+	TrackServicePartition(ctx context.Context, serviceName models.ServiceName, partition int)
+}
+
 // MetricsConsumer defines the interface for consuming metrics from Kafka.
 type MetricsConsumer interface {
 	// Start starts consuming metrics.
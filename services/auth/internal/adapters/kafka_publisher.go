@@ -15,29 +15,37 @@ import (
 
 // KafkaMetricsPublisher publishes metrics and logs to Kafka.
 type KafkaMetricsPublisher struct {
-	metricsProducer *kafka.Producer
-	logsProducer    *kafka.Producer
+	metricsProducer kafka.MessageProducer
+	logsProducer    kafka.MessageProducer
 	logger          *logging.Logger
 	metrics         *sharedmetrics.Metrics
 }
 
 // NewKafkaMetricsPublisher creates a new KafkaMetricsPublisher.
 func NewKafkaMetricsPublisher(
-	brokers []string,
+	broker kafka.Broker,
 	metricsTopic, logsTopic string,
+	codec kafka.Codec,
+	outbox *kafka.OutboxConfig,
 	logger *logging.Logger,
 	metrics *sharedmetrics.Metrics,
 ) (ports.MetricsPublisher, error) {
 	// Create metrics producer
-	metricsConfig := kafka.DefaultProducerConfig(brokers, metricsTopic)
-	metricsProducer, err := kafka.NewProducer(metricsConfig, logger)
+	metricsConfig := kafka.DefaultProducerConfig(nil, metricsTopic)
+	metricsConfig.Metrics = metrics
+	metricsConfig.Codec = codec
+	metricsConfig.Outbox = outbox
+	metricsProducer, err := broker.NewProducer(metricsConfig, logger)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create logs producer
-	logsConfig := kafka.DefaultProducerConfig(brokers, logsTopic)
-	logsProducer, err := kafka.NewProducer(logsConfig, logger)
+	logsConfig := kafka.DefaultProducerConfig(nil, logsTopic)
+	logsConfig.Metrics = metrics
+	logsConfig.Codec = codec
+	logsConfig.Outbox = outbox
+	logsProducer, err := broker.NewProducer(logsConfig, logger)
 	if err != nil {
 		metricsProducer.Close()
 		return nil, err
@@ -55,8 +63,8 @@ func NewKafkaMetricsPublisher(
 func (p *KafkaMetricsPublisher) PublishMetric(ctx context.Context, metric *models.ServiceMetric) error {
 	timer := sharedmetrics.NewTimer()
 
-	// Validate metric
-	if err := models.Validate(metric); err != nil {
+	// Validate metric against the catalogue
+	if err := models.ValidateMetric(metric); err != nil {
 		p.logger.Warn("invalid metric",
 			zap.String("metric_id", metric.ID),
 			zap.Error(err),
@@ -64,18 +72,8 @@ func (p *KafkaMetricsPublisher) PublishMetric(ctx context.Context, metric *model
 		return err
 	}
 
-	// Serialize metric
-	data, err := metric.ToJSON()
-	if err != nil {
-		p.logger.Error("failed to serialize metric",
-			zap.String("metric_id", metric.ID),
-			zap.Error(err),
-		)
-		return err
-	}
-
-	// Publish to Kafka
-	err = p.metricsProducer.Publish(ctx, []byte(metric.ID), data)
+	// Publish to Kafka, keyed by service so each service stays on one partition
+	err := p.metricsProducer.PublishValue(ctx, []byte(metric.ServiceName), metric)
 
 	// Record metrics
 	if p.metrics != nil {
@@ -112,18 +110,8 @@ func (p *KafkaMetricsPublisher) PublishLog(ctx context.Context, log *models.Serv
 		return err
 	}
 
-	// Serialize log
-	data, err := log.ToJSON()
-	if err != nil {
-		p.logger.Error("failed to serialize log",
-			zap.String("log_id", log.ID),
-			zap.Error(err),
-		)
-		return err
-	}
-
 	// Publish to Kafka
-	err = p.logsProducer.Publish(ctx, []byte(log.ID), data)
+	err := p.logsProducer.PublishValue(ctx, []byte(log.ServiceName), log)
 
 	// Record metrics
 	if p.metrics != nil {
@@ -253,7 +253,7 @@ func (g *MetricsGeneratorImpl) generateErrorMetric() *models.ServiceMetric {
 		errorRate = 100
 	}
 
-	metric := models.NewServiceMetric(g.serviceName, models.MetricTypeError, errorRate, "percent")
+	metric := models.NewServiceMetric(g.serviceName, models.MetricTypeErrorRate, errorRate, "percent")
 	metric.Labels["error_type"] = g.randomErrorType()
 	return metric
 }
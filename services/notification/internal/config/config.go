@@ -19,6 +19,15 @@ type Config struct {
 	KafkaBrokers      []string
 	KafkaMetricsTopic string
 	KafkaLogsTopic    string
+	KafkaEncoding     string
+	SchemaRegistryURL string
+	KafkaSecurity     utils.KafkaSecurityConfig
+	KafkaTopics       utils.KafkaTopicsConfig
+
+	// Outbox buffering events on disk while Kafka is unreachable
+	OutboxEnabled  bool
+	OutboxDir      string
+	OutboxMaxBytes int64
 
 	MetricsInterval time.Duration
 	LogsInterval    time.Duration
@@ -44,6 +53,13 @@ func Load() *Config {
 		KafkaBrokers:      utils.GetEnvStringSlice("KAFKA_BROKERS", []string{"localhost:9092"}),
 		KafkaMetricsTopic: utils.GetEnv("KAFKA_METRICS_TOPIC", "service-metrics"),
 		KafkaLogsTopic:    utils.GetEnv("KAFKA_LOGS_TOPIC", "service-logs"),
+		KafkaEncoding:     utils.GetEnv("KAFKA_ENCODING", "json"),
+		SchemaRegistryURL: utils.GetEnv("SCHEMA_REGISTRY_URL", ""),
+		KafkaSecurity:     utils.LoadKafkaSecurityConfig(),
+		KafkaTopics:       utils.LoadKafkaTopicsConfig(),
+		OutboxEnabled:     utils.GetEnvBool("OUTBOX_ENABLED", true),
+		OutboxDir:         utils.GetEnv("OUTBOX_DIR", "/tmp/notification-outbox"),
+		OutboxMaxBytes:    utils.GetEnvInt64("OUTBOX_MAX_BYTES", 256<<20),
 
 		MetricsInterval: utils.GetEnvDuration("METRICS_INTERVAL", 5*time.Second),
 		LogsInterval:    utils.GetEnvDuration("LOGS_INTERVAL", 3*time.Second),
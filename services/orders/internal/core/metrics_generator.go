@@ -257,7 +257,7 @@ func (g *MetricsGenerator) generateErrorMetric() *models.ServiceMetric {
 		errorRate = 100
 	}
 
-	metric := models.NewServiceMetric(g.serviceName, models.MetricTypeError, errorRate, "percent")
+	metric := models.NewServiceMetric(g.serviceName, models.MetricTypeErrorRate, errorRate, "percent")
 	metric.Labels["error_type"] = g.randomErrorType()
 	return metric
 }
@@ -431,27 +431,35 @@ func randomHex(length int) string {
 
 // KafkaPublisher publishes metrics and logs to Kafka.
 type KafkaPublisher struct {
-	metricsProducer *kafka.Producer
-	logsProducer    *kafka.Producer
+	metricsProducer kafka.MessageProducer
+	logsProducer    kafka.MessageProducer
 	logger          *logging.Logger
 	metrics         *metrics.Metrics
 }
 
 // NewKafkaPublisher creates a new KafkaPublisher.
 func NewKafkaPublisher(
-	brokers []string,
+	broker kafka.Broker,
 	metricsTopic, logsTopic string,
+	codec kafka.Codec,
+	outbox *kafka.OutboxConfig,
 	logger *logging.Logger,
 	m *metrics.Metrics,
 ) (*KafkaPublisher, error) {
-	metricsConfig := kafka.DefaultProducerConfig(brokers, metricsTopic)
-	metricsProducer, err := kafka.NewProducer(metricsConfig, logger)
+	metricsConfig := kafka.DefaultProducerConfig(nil, metricsTopic)
+	metricsConfig.Metrics = m
+	metricsConfig.Codec = codec
+	metricsConfig.Outbox = outbox
+	metricsProducer, err := broker.NewProducer(metricsConfig, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	logsConfig := kafka.DefaultProducerConfig(brokers, logsTopic)
-	logsProducer, err := kafka.NewProducer(logsConfig, logger)
+	logsConfig := kafka.DefaultProducerConfig(nil, logsTopic)
+	logsConfig.Metrics = m
+	logsConfig.Codec = codec
+	logsConfig.Outbox = outbox
+	logsProducer, err := broker.NewProducer(logsConfig, logger)
 	if err != nil {
 		metricsProducer.Close()
 		return nil, err
@@ -469,12 +477,13 @@ func NewKafkaPublisher(
 func (p *KafkaPublisher) PublishMetric(ctx context.Context, metric *models.ServiceMetric) error {
 	timer := metrics.NewTimer()
 
-	data, err := metric.ToJSON()
-	if err != nil {
+	// Reject metrics that are not in the catalogue
+	if err := models.ValidateMetric(metric); err != nil {
 		return err
 	}
 
-	err = p.metricsProducer.Publish(ctx, []byte(metric.ID), data)
+	// Key by service so each service stays on one partition
+	err := p.metricsProducer.PublishValue(ctx, []byte(metric.ServiceName), metric)
 	if p.metrics != nil {
 		p.metrics.RecordKafkaPublish(kafka.TopicServiceMetrics, timer.Elapsed(), err)
 	}
@@ -486,12 +495,7 @@ func (p *KafkaPublisher) PublishMetric(ctx context.Context, metric *models.Servi
 func (p *KafkaPublisher) PublishLog(ctx context.Context, log *models.ServiceLog) error {
 	timer := metrics.NewTimer()
 
-	data, err := log.ToJSON()
-	if err != nil {
-		return err
-	}
-
-	err = p.logsProducer.Publish(ctx, []byte(log.ID), data)
+	err := p.logsProducer.PublishValue(ctx, []byte(log.ServiceName), log)
 	if p.metrics != nil {
 		p.metrics.RecordKafkaPublish(kafka.TopicServiceLogs, timer.Elapsed(), err)
 	}
@@ -13,6 +13,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/microservices-platform/pkg/shared/kafka"
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/metrics"
 	"github.com/microservices-platform/pkg/shared/models"
@@ -42,6 +43,8 @@ func main() {
 		zap.String("environment", cfg.Environment),
 	)
 
+	// Initialize tracing; trace context propagates even when not exported
+	tracing.InitPropagation()
 	if cfg.TracingEnabled {
 		tracingConfig := &tracing.Config{
 			ServiceName:    cfg.ServiceName,
@@ -65,11 +68,37 @@ func main() {
 
 	promMetrics := metrics.NewMetrics(cfg.ServiceName)
 
+	// Initialize Kafka authentication and the codec of published events
+	security, err := kafka.NewSecurity(cfg.KafkaSecurity)
+	if err != nil {
+		logger.Fatal("invalid Kafka security configuration", zap.Error(err))
+	}
+	broker := kafka.NewBroker(cfg.KafkaBrokers, security)
+	// Verify the topics the service uses
+	topicSpecs := kafka.TopicSpecsFor(cfg.KafkaTopics.ReplicationFactor, cfg.KafkaMetricsTopic, cfg.KafkaLogsTopic)
+	if _, err := broker.EnsureTopics(context.Background(), topicSpecs, cfg.KafkaTopics.Create, logger); err != nil {
+		logger.Fatal("invalid Kafka topics", zap.Error(err))
+	}
+	codec, err := kafka.NewCodec(kafka.Encoding(cfg.KafkaEncoding), kafka.NewSchemaRegistry(cfg.SchemaRegistryURL))
+	if err != nil {
+		logger.Fatal("failed to create Kafka codec", zap.Error(err))
+	}
+
+	// Buffer events on disk while Kafka is unreachable
+	var outbox *kafka.OutboxConfig
+	if cfg.OutboxEnabled {
+		outbox = kafka.DefaultOutboxConfig(cfg.OutboxDir)
+		outbox.MaxBytes = cfg.OutboxMaxBytes
+		outbox.Metrics = promMetrics
+	}
+
 	var publisher core.MetricsPublisher
 	publisher, err = core.NewKafkaPublisher(
-		cfg.KafkaBrokers,
+		broker,
 		cfg.KafkaMetricsTopic,
 		cfg.KafkaLogsTopic,
+		codec,
+		outbox,
 		logger,
 		promMetrics,
 	)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,11 +12,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/microservices-platform/pkg/shared/jwt"
+	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
 	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
+	"github.com/microservices-platform/pkg/shared/tracing"
 	"github.com/microservices-platform/services/ui-backend/internal/config"
 	"github.com/microservices-platform/services/ui-backend/internal/handlers"
 	"github.com/microservices-platform/services/ui-backend/internal/store"
@@ -41,6 +44,11 @@ func main() {
 		zap.String("version", cfg.Version),
 	)
 
+	// Consumers continue the trace context of the messages they read
+	tracing.InitPropagation()
+
+	m := metrics.NewMetrics(cfg.ServiceName)
+
 	jwtService := jwt.NewTokenService(cfg.JWTSecret)
 
 	redisStore, err := store.NewRedisStore(
@@ -56,6 +64,14 @@ func main() {
 
 	logger.Info("connected to Redis", zap.String("addr", cfg.RedisAddr))
 
+	// Rules used to live in their own hash; the analyzer only reads its own
+	migrated, err := redisStore.MigrateLegacyRules(context.Background())
+	if err != nil {
+		logger.Error("failed to migrate legacy rules", zap.Error(err))
+	} else if migrated > 0 {
+		logger.Info("migrated legacy rules", zap.Int("rules", migrated))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -67,15 +83,31 @@ func main() {
 
 	kafkaAvailable := len(cfg.KafkaBrokers) > 0 && cfg.KafkaBrokers[0] != ""
 	if kafkaAvailable {
-		streamer := handlers.NewMetricsStreamer(
-			cfg.KafkaBrokers,
+		security, err := sharedkafka.NewSecurity(cfg.KafkaSecurity)
+		if err != nil {
+			logger.Fatal("invalid Kafka security configuration", zap.Error(err))
+		}
+		broker := sharedkafka.NewBroker(cfg.KafkaBrokers, security)
+		// Verify the topics the service uses
+		topicSpecs := sharedkafka.TopicSpecsFor(cfg.KafkaTopics.ReplicationFactor, cfg.MetricsTopic, cfg.AlertsTopic)
+		if _, err := broker.EnsureTopics(context.Background(), topicSpecs, cfg.KafkaTopics.Create, logger); err != nil {
+			logger.Fatal("invalid Kafka topics", zap.Error(err))
+		}
+
+		streamer, err := handlers.NewMetricsStreamer(
+			broker,
 			cfg.MetricsTopic,
 			cfg.AlertsTopic,
 			cfg.ConsumerGroup,
+			sharedkafka.NewDecoder(sharedkafka.NewSchemaRegistry(cfg.SchemaRegistryURL)),
 			wsHub,
+			redisStore,
 			logger,
+			m,
 		)
-		if err := streamer.Start(ctx); err != nil {
+		if err != nil {
+			logger.Warn("failed to initialize metrics streamer", zap.Error(err))
+		} else if err := streamer.Start(ctx); err != nil {
 			logger.Warn("failed to start metrics streamer", zap.Error(err))
 		} else {
 			defer streamer.Stop()
@@ -114,17 +146,24 @@ func main() {
 
 		r.Get("/api/services", handler.GetServices)
 		r.Get("/api/services/{service}/metrics", handler.GetServiceMetrics)
+		r.Get("/api/services/{service}/series", handler.GetServiceSeries)
 
 		r.Get("/api/metrics/latest", handler.GetLatestMetrics)
+		r.Get("/api/metrics/catalog", handler.GetMetricCatalog)
 
 		r.Get("/api/alerts", handler.GetAlerts)
-		r.Get("/api/alerts/{id}", handler.GetAlert)
-		r.Post("/api/alerts/{id}/acknowledge", handler.AcknowledgeAlert)
+		r.Get("/api/alerts/{fingerprint}", handler.GetAlert)
+		r.Post("/api/alerts/{fingerprint}/acknowledge", handler.AcknowledgeAlert)
 
 		r.Get("/api/rules", handler.GetRules)
 		r.Post("/api/rules", handler.CreateRule)
+		r.Post("/api/rules/backtest", handler.BacktestRule)
 		r.Put("/api/rules/{id}", handler.UpdateRule)
 		r.Delete("/api/rules/{id}", handler.DeleteRule)
+		r.Get("/api/rules/{id}/versions", handler.GetRuleVersions)
+		r.Get("/api/rules/{id}/versions/{version}", handler.GetRuleVersion)
+		r.Get("/api/rules/{id}/diff", handler.DiffRuleVersions)
+		r.Post("/api/rules/{id}/rollback", handler.RollbackRule)
 
 		r.Get("/api/dashboard/stats", handler.GetDashboardStats)
 
@@ -145,7 +184,7 @@ func main() {
 
 	metricsServer := &http.Server{
 		Addr:    cfg.MetricsAddr,
-		Handler: promhttp.Handler(),
+		Handler: m.Handler(),
 	}
 
 	go func() {
@@ -155,6 +194,45 @@ func main() {
 		}
 	}()
 
+	healthMux := http.NewServeMux()
+	healthMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"healthy","service":"ui-backend"}`))
+	})
+	healthMux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if err := redisStore.Ping(r.Context()); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"not_ready","reason":"redis_unavailable"}`))
+			return
+		}
+		// Not ready while a consumer is too far behind
+		if cfg.ReadyMaxLag > 0 {
+			if lag, topic := m.MaxKafkaConsumerLag(); lag > cfg.ReadyMaxLag {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, `{"status":"not_ready","reason":"kafka_lag","topic":%q,"lag":%d}`, topic, lag)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ready","service":"ui-backend"}`))
+	})
+
+	healthServer := &http.Server{
+		Addr:    cfg.HealthAddr,
+		Handler: healthMux,
+	}
+
+	go func() {
+		logger.Info("starting health server", zap.String("addr", cfg.HealthAddr))
+		if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("health server error", zap.Error(err))
+		}
+	}()
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
@@ -177,6 +255,10 @@ func main() {
 		logger.Error("metrics server shutdown error", zap.Error(err))
 	}
 
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("health server shutdown error", zap.Error(err))
+	}
+
 	cancel()
 
 	logger.Info("ui-backend service stopped")
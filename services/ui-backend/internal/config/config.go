@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/microservices-platform/pkg/shared/utils"
 )
 
 // Config holds the configuration for the ui-backend service.
@@ -31,6 +33,12 @@ type Config struct {
 	MetricsTopic  string
 	AlertsTopic   string
 	ConsumerGroup string
+	// Decodes Avro and Protobuf events when set
+	SchemaRegistryURL string
+	KafkaSecurity     utils.KafkaSecurityConfig
+	KafkaTopics       utils.KafkaTopicsConfig
+	// Readiness fails above this consumer lag; 0 disables
+	ReadyMaxLag int64
 
 	// JWT settings
 	JWTSecret     string
@@ -68,6 +76,11 @@ func LoadConfig() *Config {
 		AlertsTopic:   getEnv("ALERTS_TOPIC", "alerts"),
 		ConsumerGroup: getEnv("CONSUMER_GROUP", "ui-backend-group"),
 
+		SchemaRegistryURL: getEnv("SCHEMA_REGISTRY_URL", ""),
+		KafkaSecurity:     utils.LoadKafkaSecurityConfig(),
+		KafkaTopics:       utils.LoadKafkaTopicsConfig(),
+		ReadyMaxLag:       int64(getEnvInt("READY_MAX_KAFKA_LAG", 0)),
+
 		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 		JWTExpiration: time.Duration(getEnvInt("JWT_EXPIRATION_HOURS", 24)) * time.Hour,
 
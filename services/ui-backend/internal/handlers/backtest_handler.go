@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/evaluation"
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// maxBacktestRange bounds how much stored history a single backtest may replay.
+const maxBacktestRange = 7 * 24 * time.Hour
+
+// BacktestRequest represents a request to backtest a rule.
+// Either RuleID (an existing rule) or Rule (a draft) must be set.
+type BacktestRequest struct {
+	RuleID string             `json:"rule_id,omitempty"`
+	Rule   *CreateRuleRequest `json:"rule,omitempty"`
+
+	// History range; defaults to the last 24 hours
+	From time.Time `json:"from,omitempty"`
+	To   time.Time `json:"to,omitempty"`
+
+	// Metrics replays an exported metric history instead of the stored one
+	Metrics []*models.ServiceMetric `json:"metrics,omitempty"`
+
+	// Evaluation settings; default to the analyzer's
+	WindowSeconds   int `json:"window_seconds,omitempty"`
+	CooldownSeconds int `json:"cooldown_seconds,omitempty"`
+}
+
+// BacktestRule replays metric history through the analyzer's evaluator and
+// reports when the rule would have fired.
+func (h *Handler) BacktestRule(w http.ResponseWriter, r *http.Request) {
+	var req BacktestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	var rule *models.ThresholdRule
+	switch {
+	case req.Rule != nil:
+		rule = req.Rule.toRule()
+		rule.ID = req.RuleID
+	case req.RuleID != "":
+		stored, err := h.store.GetRule(ctx, req.RuleID)
+		if err != nil {
+			h.logger.Error("failed to get rule", zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "failed to get rule")
+			return
+		}
+		if stored == nil {
+			writeError(w, http.StatusNotFound, "rule not found")
+			return
+		}
+		rule = stored
+	default:
+		writeError(w, http.StatusBadRequest, "rule or rule_id required")
+		return
+	}
+
+	if err := evaluation.ValidateRule(rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if req.From.IsZero() {
+		req.From = req.To.Add(-24 * time.Hour)
+	}
+	if !req.From.Before(req.To) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	history := req.Metrics
+	if len(history) == 0 {
+		if req.To.Sub(req.From) > maxBacktestRange {
+			writeError(w, http.StatusBadRequest, "backtest range must not exceed 7 days")
+			return
+		}
+
+		series, _ := evaluation.SeriesFor(rule)
+		stored, err := h.store.GetMetricsRange(ctx, rule.ServiceName, series, req.From, req.To)
+		if err != nil {
+			h.logger.Error("failed to get metric history", zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "failed to get metric history")
+			return
+		}
+		history = stored
+	}
+
+	opts := evaluation.DefaultBacktestOptions()
+	if req.WindowSeconds > 0 {
+		opts.WindowSize = time.Duration(req.WindowSeconds) * time.Second
+	}
+	if req.CooldownSeconds > 0 {
+		opts.Cooldown = time.Duration(req.CooldownSeconds) * time.Second
+	}
+
+	result, err := evaluation.Backtest(rule, history, opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: result})
+}
@@ -5,13 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/microservices-platform/pkg/shared/evaluation"
 	"github.com/microservices-platform/pkg/shared/jwt"
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/models"
@@ -62,17 +66,64 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, Response{Success: false, Error: message})
 }
 
-// GetServices returns all monitored services.
+// GetServices returns all services discovered from the metric stream.
 func (h *Handler) GetServices(w http.ResponseWriter, r *http.Request) {
-	services := []map[string]interface{}{
-		{"name": "auth", "display_name": "Auth Service", "status": "healthy"},
-		{"name": "orders", "display_name": "Orders Service", "status": "healthy"},
-		{"name": "payments", "display_name": "Payments Service", "status": "healthy"},
-		{"name": "notification", "display_name": "Notification Service", "status": "healthy"},
+	ctx := r.Context()
+	registered, err := h.store.GetServices(ctx)
+	if err != nil {
+		h.logger.Error("failed to get services", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get services")
+		return
 	}
+
+	services := make([]map[string]interface{}, 0, len(registered))
+	for _, registration := range registered {
+		status := string(models.StatusUnknown)
+		health, err := h.store.GetServiceHealth(ctx, registration.ServiceName)
+		if err != nil {
+			h.logger.Warn("failed to get service health",
+				zap.String("service", string(registration.ServiceName)),
+				zap.Error(err),
+			)
+		}
+		if health != nil {
+			status = string(health.Status)
+		}
+
+		instances := make([]*models.ServiceInstance, 0, len(registration.Instances))
+		for _, instance := range registration.Instances {
+			instances = append(instances, instance)
+		}
+		sort.Slice(instances, func(i, j int) bool {
+			return instances[i].ID < instances[j].ID
+		})
+
+		services = append(services, map[string]interface{}{
+			"name":         registration.ServiceName,
+			"display_name": displayName(registration.ServiceName),
+			"status":       status,
+			"version":      registration.Version,
+			"labels":       registration.Labels,
+			"first_seen":   registration.FirstSeen,
+			"last_seen":    registration.LastSeen,
+			"instances":    instances,
+		})
+	}
+
 	writeJSON(w, http.StatusOK, Response{Success: true, Data: services})
 }
 
+// displayName turns a service name such as "alert-engine" into "Alert Engine Service".
+func displayName(service models.ServiceName) string {
+	words := strings.FieldsFunc(string(service), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(append(words, "Service"), " ")
+}
+
 // GetServiceMetrics returns metrics for a service.
 func (h *Handler) GetServiceMetrics(w http.ResponseWriter, r *http.Request) {
 	serviceName := chi.URLParam(r, "service")
@@ -100,18 +151,58 @@ func (h *Handler) GetServiceMetrics(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{Success: true, Data: metrics})
 }
 
+// GetServiceSeries returns the labelled series of a service, for one metric
+// when "metric" is given. Series are what group_by rules evaluate separately.
+func (h *Handler) GetServiceSeries(w http.ResponseWriter, r *http.Request) {
+	serviceName := models.ServiceName(chi.URLParam(r, "service"))
+	if serviceName == "" {
+		writeError(w, http.StatusBadRequest, "service name required")
+		return
+	}
+
+	var metricTypes []models.MetricType
+	if metric := r.URL.Query().Get("metric"); metric != "" {
+		def, ok := models.LookupMetric(models.MetricType(metric))
+		if !ok {
+			writeError(w, http.StatusBadRequest, "unknown metric type")
+			return
+		}
+		metricTypes = append(metricTypes, def.Type)
+	} else {
+		for _, def := range models.MetricCatalog() {
+			if !def.Derived() {
+				metricTypes = append(metricTypes, def.Type)
+			}
+		}
+	}
+
+	series := []*models.SeriesInfo{}
+	for _, metricType := range metricTypes {
+		s, err := h.store.GetSeries(r.Context(), serviceName, metricType)
+		if err != nil {
+			h.logger.Error("failed to get series", zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "failed to get series")
+			return
+		}
+		series = append(series, s...)
+	}
+
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: series})
+}
+
 // GetLatestMetrics returns the latest metric for each service.
 func (h *Handler) GetLatestMetrics(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	services := []models.ServiceName{
-		models.ServiceNameAuth,
-		models.ServiceNameOrders,
-		models.ServiceNamePayments,
-		models.ServiceNameNotification,
+	registered, err := h.store.GetServices(ctx)
+	if err != nil {
+		h.logger.Error("failed to get services", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get services")
+		return
 	}
 
 	result := make(map[string]*models.ServiceMetric)
-	for _, service := range services {
+	for _, registration := range registered {
+		service := registration.ServiceName
 		metric, err := h.store.GetLatestMetric(ctx, service)
 		if err != nil {
 			h.logger.Warn("failed to get latest metric",
@@ -168,16 +259,16 @@ func (h *Handler) GetAlerts(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetAlert returns a single alert.
+// GetAlert returns the latest firing of an alert by fingerprint.
 func (h *Handler) GetAlert(w http.ResponseWriter, r *http.Request) {
-	alertID := chi.URLParam(r, "id")
-	if alertID == "" {
-		writeError(w, http.StatusBadRequest, "alert ID required")
+	fingerprint := chi.URLParam(r, "fingerprint")
+	if fingerprint == "" {
+		writeError(w, http.StatusBadRequest, "alert fingerprint required")
 		return
 	}
 
 	ctx := r.Context()
-	alert, err := h.store.GetAlert(ctx, alertID)
+	alert, err := h.store.GetAlert(ctx, fingerprint)
 	if err != nil {
 		h.logger.Error("failed to get alert", zap.Error(err))
 		writeError(w, http.StatusInternalServerError, "failed to get alert")
@@ -192,18 +283,18 @@ func (h *Handler) GetAlert(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{Success: true, Data: alert})
 }
 
-// AcknowledgeAlert acknowledges an alert.
+// AcknowledgeAlert acknowledges the latest firing of an alert by fingerprint.
 func (h *Handler) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
-	alertID := chi.URLParam(r, "id")
-	if alertID == "" {
-		writeError(w, http.StatusBadRequest, "alert ID required")
+	fingerprint := chi.URLParam(r, "fingerprint")
+	if fingerprint == "" {
+		writeError(w, http.StatusBadRequest, "alert fingerprint required")
 		return
 	}
 
 	userID, _ := r.Context().Value("user_id").(string)
 
 	ctx := r.Context()
-	if err := h.store.AcknowledgeAlert(ctx, alertID, userID); err != nil {
+	if err := h.store.AcknowledgeAlert(ctx, fingerprint, userID); err != nil {
 		h.logger.Error("failed to acknowledge alert", zap.Error(err))
 		writeError(w, http.StatusInternalServerError, "failed to acknowledge alert")
 		return
@@ -212,6 +303,11 @@ func (h *Handler) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{Success: true})
 }
 
+// GetMetricCatalog returns the canonical metric definitions.
+func (h *Handler) GetMetricCatalog(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: models.MetricCatalog()})
+}
+
 // GetRules returns threshold rules.
 func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -227,13 +323,104 @@ func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
 
 // CreateRuleRequest represents a request to create a rule.
 type CreateRuleRequest struct {
-	ServiceName string  `json:"service_name" validate:"required"`
-	MetricType  string  `json:"metric_type" validate:"required"`
-	Threshold   float64 `json:"threshold" validate:"required"`
-	Operator    string  `json:"operator" validate:"required"`
-	Severity    string  `json:"severity" validate:"required"`
-	Enabled     bool    `json:"enabled"`
-	Cooldown    int     `json:"cooldown_seconds"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ServiceName string   `json:"service_name" validate:"required"`
+	MetricType  string   `json:"metric_type" validate:"required_without=Expression"`
+	Threshold   float64  `json:"threshold" validate:"required_without=Expression"`
+	Operator    string   `json:"operator" validate:"required_without=Expression"`
+	Severity    string   `json:"severity" validate:"required"`
+	Enabled     bool     `json:"enabled"`
+	Cooldown    int      `json:"cooldown_seconds"`
+	Expression  string   `json:"expression,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+
+	// Forecast rules
+	Aggregation     string  `json:"aggregation,omitempty"`
+	ForecastHorizon int     `json:"forecast_horizon,omitempty"`
+	MinConfidence   float64 `json:"min_confidence,omitempty"`
+
+	// GroupBy evaluates the rule per combination of these labels
+	GroupBy []string `json:"group_by,omitempty"`
+
+	// Escalations raise the severity at further thresholds
+	Escalations []models.SeverityThreshold `json:"escalations,omitempty"`
+}
+
+// toRule converts the request into a threshold rule.
+func (req *CreateRuleRequest) toRule() *models.ThresholdRule {
+	return &models.ThresholdRule{
+		Name:            req.Name,
+		Description:     req.Description,
+		ServiceName:     models.ServiceName(req.ServiceName),
+		MetricType:      models.CanonicalMetricType(models.MetricType(req.MetricType)),
+		Threshold:       req.Threshold,
+		Operator:        req.Operator,
+		Severity:        models.AlertSeverity(req.Severity),
+		Enabled:         req.Enabled,
+		CooldownSeconds: req.Cooldown,
+		Source:          models.RuleSourceAPI,
+		Expression:      req.Expression,
+		DependsOn:       req.DependsOn,
+		Aggregation:     models.RuleAggregation(req.Aggregation),
+		ForecastHorizon: req.ForecastHorizon,
+		MinConfidence:   req.MinConfidence,
+		GroupBy:         req.GroupBy,
+		Escalations:     req.Escalations,
+	}
+}
+
+// validRuleGraph checks that the references between rules stay resolvable and
+// acyclic after upserting rule or deleting the rule with ID deleted. It writes
+// the error response and returns false otherwise.
+func (h *Handler) validRuleGraph(w http.ResponseWriter, r *http.Request, rule *models.ThresholdRule, deleted string) bool {
+	existing, err := h.store.GetRules(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get rules", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rules")
+		return false
+	}
+
+	rules := make([]*models.ThresholdRule, 0, len(existing)+1)
+	for _, other := range existing {
+		if other.ID == deleted || (rule != nil && other.ID == rule.ID) {
+			continue
+		}
+		rules = append(rules, other)
+	}
+	if rule != nil {
+		rules = append(rules, rule)
+	}
+
+	if _, err := evaluation.NewRuleGraph(rules); err != nil {
+		status := http.StatusBadRequest
+		if deleted != "" {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return false
+	}
+	return true
+}
+
+// editableRule fetches a rule for modification. It writes the error response
+// and returns false if the rule does not exist or is managed by a rule file.
+func (h *Handler) editableRule(w http.ResponseWriter, r *http.Request, ruleID string) (*models.ThresholdRule, bool) {
+	existing, err := h.store.GetRule(r.Context(), ruleID)
+	if err != nil {
+		h.logger.Error("failed to get rule", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rule")
+		return nil, false
+	}
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return nil, false
+	}
+	if existing.ReadOnly() {
+		writeError(w, http.StatusConflict, "rule is managed by rule file "+existing.SourceFile+" and is read-only")
+		return nil, false
+	}
+	return existing, true
 }
 
 // CreateRule creates a new threshold rule.
@@ -249,20 +436,22 @@ func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rule := &models.ThresholdRule{
-		ServiceName:     models.ServiceName(req.ServiceName),
-		MetricType:      models.MetricType(req.MetricType),
-		Threshold:       req.Threshold,
-		Operator:        req.Operator,
-		Severity:        models.AlertSeverity(req.Severity),
-		Enabled:         req.Enabled,
-		CooldownSeconds: req.Cooldown,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+	rule := req.toRule()
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	if err := evaluation.ValidateRule(rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	if !h.validRuleGraph(w, r, rule, "") {
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
 
 	ctx := r.Context()
-	if err := h.store.CreateRule(ctx, rule); err != nil {
+	if err := h.store.CreateRule(ctx, rule, userID); err != nil {
 		h.logger.Error("failed to create rule", zap.Error(err))
 		writeError(w, http.StatusInternalServerError, "failed to create rule")
 		return
@@ -285,20 +474,27 @@ func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rule := &models.ThresholdRule{
-		ID:              ruleID,
-		ServiceName:     models.ServiceName(req.ServiceName),
-		MetricType:      models.MetricType(req.MetricType),
-		Threshold:       req.Threshold,
-		Operator:        req.Operator,
-		Severity:        models.AlertSeverity(req.Severity),
-		Enabled:         req.Enabled,
-		CooldownSeconds: req.Cooldown,
-		UpdatedAt:       time.Now(),
+	existing, ok := h.editableRule(w, r, ruleID)
+	if !ok {
+		return
 	}
 
+	rule := req.toRule()
+	rule.ID = ruleID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+	if err := evaluation.ValidateRule(rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.validRuleGraph(w, r, rule, "") {
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+
 	ctx := r.Context()
-	if err := h.store.UpdateRule(ctx, rule); err != nil {
+	if err := h.store.UpdateRule(ctx, rule, userID); err != nil {
 		h.logger.Error("failed to update rule", zap.Error(err))
 		writeError(w, http.StatusInternalServerError, "failed to update rule")
 		return
@@ -315,8 +511,18 @@ func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.editableRule(w, r, ruleID); !ok {
+		return
+	}
+	// Rules still referenced by composites or dependencies cannot be deleted
+	if !h.validRuleGraph(w, r, nil, ruleID) {
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+
 	ctx := r.Context()
-	if err := h.store.DeleteRule(ctx, ruleID); err != nil {
+	if err := h.store.DeleteRule(ctx, ruleID, userID); err != nil {
 		h.logger.Error("failed to delete rule", zap.Error(err))
 		writeError(w, http.StatusInternalServerError, "failed to delete rule")
 		return
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/microservices-platform/pkg/shared/models"
+)
+
+// RuleDiff is the difference between two versions of a rule.
+type RuleDiff struct {
+	RuleID  string              `json:"rule_id"`
+	From    int                 `json:"from"`
+	To      int                 `json:"to"`
+	Changes []models.RuleChange `json:"changes"`
+}
+
+// RollbackRequest represents a request to roll a rule back to a version.
+type RollbackRequest struct {
+	Version int `json:"version" validate:"required,min=1"`
+}
+
+// GetRuleVersions returns the version history of a rule, oldest first.
+// The history outlives the rule, so deleted rules can still be audited.
+func (h *Handler) GetRuleVersions(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+
+	versions, err := h.store.GetRuleVersions(r.Context(), ruleID)
+	if err != nil {
+		h.logger.Error("failed to get rule versions", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rule versions")
+		return
+	}
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: versions})
+}
+
+// GetRuleVersion returns a single version of a rule.
+func (h *Handler) GetRuleVersion(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+
+	v, err := h.store.GetRuleVersion(r.Context(), ruleID, version)
+	if err != nil {
+		h.logger.Error("failed to get rule version", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rule version")
+		return
+	}
+	if v == nil {
+		writeError(w, http.StatusNotFound, "rule version not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: v})
+}
+
+// DiffRuleVersions diffs two versions of a rule. "to" defaults to the latest
+// version and "from" to the version before it.
+func (h *Handler) DiffRuleVersions(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+	ctx := r.Context()
+
+	versions, err := h.store.GetRuleVersions(ctx, ruleID)
+	if err != nil {
+		h.logger.Error("failed to get rule versions", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rule versions")
+		return
+	}
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+
+	to := len(versions)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid to version")
+			return
+		}
+	}
+	from := to - 1
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from version")
+			return
+		}
+	}
+
+	// Version 0 is the rule before it was created
+	if from < 0 || to < 1 || from > len(versions) || to > len(versions) {
+		writeError(w, http.StatusNotFound, "rule version not found")
+		return
+	}
+
+	var before *models.ThresholdRule
+	if from > 0 {
+		before = versions[from-1].State()
+	}
+	after := versions[to-1].State()
+
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: RuleDiff{
+		RuleID:  ruleID,
+		From:    from,
+		To:      to,
+		Changes: models.DiffRules(before, after),
+	}})
+}
+
+// RollbackRule restores a rule to a previous version. The rollback is itself
+// recorded as a new version.
+func (h *Handler) RollbackRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+
+	var req RollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+
+	existing, err := h.store.GetRule(ctx, ruleID)
+	if err != nil {
+		h.logger.Error("failed to get rule", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rule")
+		return
+	}
+	if existing != nil && existing.ReadOnly() {
+		writeError(w, http.StatusConflict, "rule is managed by rule file "+existing.SourceFile+" and is read-only")
+		return
+	}
+
+	target, err := h.store.GetRuleVersion(ctx, ruleID, req.Version)
+	if err != nil {
+		h.logger.Error("failed to get rule version", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to get rule version")
+		return
+	}
+	if target == nil {
+		writeError(w, http.StatusNotFound, "rule version not found")
+		return
+	}
+	if target.State() == nil {
+		writeError(w, http.StatusBadRequest, "cannot roll back to a deletion")
+		return
+	}
+	if target.State().ReadOnly() {
+		writeError(w, http.StatusConflict, "version was managed by a rule file and cannot be restored")
+		return
+	}
+	if !h.validRuleGraph(w, r, target.State(), "") {
+		return
+	}
+
+	userID, _ := ctx.Value("user_id").(string)
+
+	rule, err := h.store.RollbackRule(ctx, ruleID, req.Version, userID)
+	if err != nil {
+		h.logger.Error("failed to roll back rule", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "failed to roll back rule")
+		return
+	}
+
+	h.logger.Info("rule rolled back",
+		zap.String("rule_id", ruleID),
+		zap.Int("version", req.Version),
+		zap.String("user_id", userID),
+	)
+
+	writeJSON(w, http.StatusOK, Response{Success: true, Data: rule})
+}
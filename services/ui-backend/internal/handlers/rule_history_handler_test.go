@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/ui-backend/internal/store"
+)
+
+func testLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+	logger, err := logging.NewLogger(logging.DefaultConfig("ui-backend"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
+
+// testServer routes the rule endpoints the way main does, with requests
+// authenticated as user "alice".
+func testServer(t *testing.T) (*httptest.Server, *store.RedisStore) {
+	t.Helper()
+	logger := testLogger(t)
+	s, err := store.NewRedisStore(miniredis.RunT(t).Addr(), "", 0, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	handler := NewHandler(s, nil, logger)
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "user_id", "alice")))
+		})
+	})
+	r.Post("/api/rules", handler.CreateRule)
+	r.Put("/api/rules/{id}", handler.UpdateRule)
+	r.Delete("/api/rules/{id}", handler.DeleteRule)
+	r.Get("/api/rules/{id}/versions", handler.GetRuleVersions)
+	r.Get("/api/rules/{id}/versions/{version}", handler.GetRuleVersion)
+	r.Get("/api/rules/{id}/diff", handler.DiffRuleVersions)
+	r.Post("/api/rules/{id}/rollback", handler.RollbackRule)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, s
+}
+
+// call sends a request and decodes the data of the response into data.
+func call(t *testing.T, method, url string, body interface{}, data interface{}) int {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	response := Response{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("%s %s: failed to decode response: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestRuleHistory(t *testing.T) {
+	server, s := testServer(t)
+	ctx := context.Background()
+
+	req := CreateRuleRequest{
+		Name:        "High error rate",
+		ServiceName: string(models.ServiceOrders),
+		MetricType:  string(models.MetricTypeErrorRate),
+		Operator:    ">",
+		Threshold:   0.05,
+		Severity:    string(models.AlertSeverityWarning),
+		Enabled:     true,
+	}
+	var rule models.ThresholdRule
+	if status := call(t, http.MethodPost, server.URL+"/api/rules", req, &rule); status != http.StatusCreated {
+		t.Fatalf("create: status %d", status)
+	}
+	rules := server.URL + "/api/rules/" + rule.ID
+
+	req.Threshold = 0.1
+	req.Severity = string(models.AlertSeverityCritical)
+	if status := call(t, http.MethodPut, rules, req, nil); status != http.StatusOK {
+		t.Fatalf("update: status %d", status)
+	}
+
+	var diff RuleDiff
+	if status := call(t, http.MethodGet, rules+"/diff", nil, &diff); status != http.StatusOK {
+		t.Fatalf("diff: status %d", status)
+	}
+	if diff.From != 1 || diff.To != 2 || len(diff.Changes) != 2 ||
+		diff.Changes[0].Field != "severity" || diff.Changes[1].Field != "threshold" {
+		t.Errorf("diff %+v, want severity and threshold changed from 1 to 2", diff)
+	}
+
+	var restored models.ThresholdRule
+	if status := call(t, http.MethodPost, rules+"/rollback", RollbackRequest{Version: 1}, &restored); status != http.StatusOK {
+		t.Fatalf("rollback: status %d", status)
+	}
+	if restored.Threshold != 0.05 || restored.Severity != models.AlertSeverityWarning {
+		t.Errorf("rolled back to %+v, want the created rule", restored)
+	}
+	stored, err := s.GetRule(ctx, rule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Threshold != 0.05 || stored.Severity != models.AlertSeverityWarning {
+		t.Errorf("stored %+v after the rollback, want the created rule", stored)
+	}
+
+	var versions []*models.RuleVersion
+	if status := call(t, http.MethodGet, rules+"/versions", nil, &versions); status != http.StatusOK {
+		t.Fatalf("versions: status %d", status)
+	}
+	want := []models.RuleAction{models.RuleActionCreate, models.RuleActionUpdate, models.RuleActionRollback}
+	if len(versions) != len(want) {
+		t.Fatalf("%d versions, want %d", len(versions), len(want))
+	}
+	for i, action := range want {
+		if v := versions[i]; v.Version != i+1 || v.Action != action || v.Author != "alice" {
+			t.Errorf("version %d is %d %s by %s, want %d %s by alice", i, v.Version, v.Action, v.Author, i+1, action)
+		}
+	}
+	if versions[2].RollbackTo != 1 {
+		t.Errorf("rollback restored version %d, want 1", versions[2].RollbackTo)
+	}
+
+	var version models.RuleVersion
+	if status := call(t, http.MethodGet, rules+"/versions/2", nil, &version); status != http.StatusOK {
+		t.Fatalf("version: status %d", status)
+	}
+	if version.Action != models.RuleActionUpdate || version.Rule.Threshold != 0.1 {
+		t.Errorf("version 2 is %+v, want the update", version)
+	}
+
+	// The history outlives the rule, and a rollback recreates it
+	if status := call(t, http.MethodDelete, rules, nil, nil); status != http.StatusOK {
+		t.Fatalf("delete: status %d", status)
+	}
+	if status := call(t, http.MethodGet, rules+"/versions", nil, &versions); status != http.StatusOK || len(versions) != 4 {
+		t.Fatalf("versions after delete: status %d, %d versions, want 4", status, len(versions))
+	}
+	if status := call(t, http.MethodPost, rules+"/rollback", RollbackRequest{Version: 2}, nil); status != http.StatusOK {
+		t.Fatalf("rollback after delete: status %d", status)
+	}
+	if stored, _ := s.GetRule(ctx, rule.ID); stored == nil || stored.Threshold != 0.1 {
+		t.Errorf("stored %+v after rolling back the delete, want version 2", stored)
+	}
+}
+
+func TestRuleHistoryErrors(t *testing.T) {
+	server, s := testServer(t)
+	rule := &models.ThresholdRule{
+		ID:          "error-rate",
+		Name:        "High error rate",
+		ServiceName: models.ServiceOrders,
+		MetricType:  models.MetricTypeErrorRate,
+		Operator:    ">",
+		Threshold:   0.05,
+		Severity:    models.AlertSeverityWarning,
+	}
+	if err := s.CreateRule(context.Background(), rule, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRule(context.Background(), rule.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	rules := server.URL + "/api/rules/"
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   interface{}
+		want   int
+	}{
+		{"versions of an unknown rule", http.MethodGet, rules + "unknown/versions", nil, http.StatusNotFound},
+		{"unknown version", http.MethodGet, rules + "error-rate/versions/3", nil, http.StatusNotFound},
+		{"invalid version", http.MethodGet, rules + "error-rate/versions/latest", nil, http.StatusBadRequest},
+		{"diff beyond the history", http.MethodGet, rules + "error-rate/diff?to=3", nil, http.StatusNotFound},
+		{"rollback without a version", http.MethodPost, rules + "error-rate/rollback", RollbackRequest{}, http.StatusBadRequest},
+		{"rollback to an unknown version", http.MethodPost, rules + "error-rate/rollback", RollbackRequest{Version: 3}, http.StatusNotFound},
+		{"rollback to the deletion", http.MethodPost, rules + "error-rate/rollback", RollbackRequest{Version: 2}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := call(t, tt.method, tt.url, tt.body, nil); status != tt.want {
+				t.Errorf("status %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
@@ -14,7 +14,9 @@ import (
 
 	sharedkafka "github.com/microservices-platform/pkg/shared/kafka"
 	"github.com/microservices-platform/pkg/shared/logging"
+	"github.com/microservices-platform/pkg/shared/metrics"
 	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/services/ui-backend/internal/store"
 )
 
 var upgrader = websocket.Upgrader{
@@ -248,35 +250,54 @@ func generateClientID() string {
 
 // MetricsStreamer streams metrics from Kafka to WebSocket clients.
 type MetricsStreamer struct {
-	hub           *WSHub
-	metricsReader *kafka.Reader
-	alertsReader  *kafka.Reader
-	logger        *logging.Logger
-	running       bool
-	mu            sync.Mutex
-	stopCh        chan struct{}
-	wg            sync.WaitGroup
+	hub             *WSHub
+	store           *store.RedisStore
+	metricsConsumer sharedkafka.MessageConsumer
+	alertsConsumer  sharedkafka.MessageConsumer
+	decoder         *sharedkafka.Decoder
+	logger          *logging.Logger
+	running         bool
+	mu              sync.Mutex
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
 }
 
-// NewMetricsStreamer creates a new MetricsStreamer.
+// NewMetricsStreamer creates a new MetricsStreamer. Messages are decoded by
+// decoder according to their content type.
 func NewMetricsStreamer(
-	brokers []string,
+	broker sharedkafka.Broker,
 	metricsTopic, alertsTopic, consumerGroup string,
+	decoder *sharedkafka.Decoder,
 	hub *WSHub,
+	store *store.RedisStore,
 	logger *logging.Logger,
-) *MetricsStreamer {
-	metricsConfig := sharedkafka.DefaultConsumerConfig(brokers, metricsTopic, consumerGroup+"-metrics")
+	m *metrics.Metrics,
+) (*MetricsStreamer, error) {
+	metricsConfig := sharedkafka.DefaultConsumerConfig(nil, metricsTopic, consumerGroup+"-metrics")
 	metricsConfig.StartOffset = kafka.LastOffset
+	metricsConfig.Metrics = m
+	metricsConsumer, err := broker.NewConsumer(metricsConfig, logger)
+	if err != nil {
+		return nil, err
+	}
 
-	alertsConfig := sharedkafka.DefaultConsumerConfig(brokers, alertsTopic, consumerGroup+"-alerts")
+	alertsConfig := sharedkafka.DefaultConsumerConfig(nil, alertsTopic, consumerGroup+"-alerts")
 	alertsConfig.StartOffset = kafka.LastOffset
+	alertsConfig.Metrics = m
+	alertsConsumer, err := broker.NewConsumer(alertsConfig, logger)
+	if err != nil {
+		metricsConsumer.Close()
+		return nil, err
+	}
 
 	return &MetricsStreamer{
-		hub:           hub,
-		metricsReader: kafka.NewReader(metricsConfig.ToReaderConfig()),
-		alertsReader:  kafka.NewReader(alertsConfig.ToReaderConfig()),
-		logger:        logger,
-	}
+		hub:             hub,
+		store:           store,
+		metricsConsumer: metricsConsumer,
+		alertsConsumer:  alertsConsumer,
+		decoder:         decoder,
+		logger:          logger,
+	}, nil
 }
 
 // Start starts the streamer.
@@ -292,9 +313,11 @@ func (s *MetricsStreamer) Start(ctx context.Context) error {
 
 	s.logger.Info("starting metrics streamer")
 
+	// The streamer only mirrors the topics the analyzer and alert engine
+	// consume, so failing messages are dropped rather than dead-lettered twice
 	s.wg.Add(2)
-	go s.streamMetrics(ctx)
-	go s.streamAlerts(ctx)
+	go s.stream(ctx, s.metricsConsumer, s.handleMetric)
+	go s.stream(ctx, s.alertsConsumer, s.handleAlert)
 
 	return nil
 }
@@ -310,85 +333,58 @@ func (s *MetricsStreamer) Stop() error {
 	close(s.stopCh)
 	s.mu.Unlock()
 
+	// Closing the consumers ends their loops
+	s.metricsConsumer.Close()
+	s.alertsConsumer.Close()
 	s.wg.Wait()
 
-	s.metricsReader.Close()
-	s.alertsReader.Close()
-
 	s.logger.Info("metrics streamer stopped")
 	return nil
 }
 
-func (s *MetricsStreamer) streamMetrics(ctx context.Context) {
+func (s *MetricsStreamer) stream(ctx context.Context, consumer sharedkafka.MessageConsumer, handler sharedkafka.MessageHandler) {
 	defer s.wg.Done()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-s.stopCh:
-			return
-		default:
-			msg, err := s.metricsReader.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
-				}
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
-
-			var metric models.ServiceMetric
-			if err := json.Unmarshal(msg.Value, &metric); err != nil {
-				s.metricsReader.CommitMessages(ctx, msg)
-				continue
-			}
-
-			wsMsg := WSMessage{
-				Type:    "metric",
-				Payload: metric,
-			}
-			data, _ := json.Marshal(wsMsg)
-			s.hub.Broadcast(data)
-
-			s.metricsReader.CommitMessages(ctx, msg)
-		}
+	if err := consumer.Consume(ctx, handler, nil); err != nil && ctx.Err() == nil {
+		s.logger.Error("stream consumer stopped", zap.Error(err))
 	}
 }
 
-func (s *MetricsStreamer) streamAlerts(ctx context.Context) {
-	defer s.wg.Done()
+func (s *MetricsStreamer) handleMetric(ctx context.Context, msg kafka.Message) error {
+	var metric models.ServiceMetric
+	if err := s.decoder.Decode(ctx, msg, &metric); err != nil {
+		return err
+	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-s.stopCh:
-			return
-		default:
-			msg, err := s.alertsReader.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
-				}
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
+	wsMsg := WSMessage{
+		Type:    "metric",
+		Payload: metric,
+	}
+	data, _ := json.Marshal(wsMsg)
+	s.hub.Broadcast(data)
 
-			var alert models.Alert
-			if err := json.Unmarshal(msg.Value, &alert); err != nil {
-				s.alertsReader.CommitMessages(ctx, msg)
-				continue
-			}
+	return nil
+}
 
-			wsMsg := WSMessage{
-				Type:    "alert",
-				Payload: alert,
-			}
-			data, _ := json.Marshal(wsMsg)
-			s.hub.Broadcast(data)
+func (s *MetricsStreamer) handleAlert(ctx context.Context, msg kafka.Message) error {
+	var alert models.Alert
+	if err := s.decoder.Decode(ctx, msg, &alert); err != nil {
+		return err
+	}
 
-			s.alertsReader.CommitMessages(ctx, msg)
-		}
+	if err := s.store.StoreAlert(ctx, &alert); err != nil {
+		s.logger.Warn("failed to store alert",
+			zap.String("alert_id", alert.ID),
+			zap.Error(err),
+		)
+	}
+
+	wsMsg := WSMessage{
+		Type:    "alert",
+		Payload: alert,
 	}
+	data, _ := json.Marshal(wsMsg)
+	s.hub.Broadcast(data)
+
+	return nil
 }
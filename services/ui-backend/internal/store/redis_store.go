@@ -10,9 +10,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 
 	"github.com/microservices-platform/pkg/shared/logging"
 	"github.com/microservices-platform/pkg/shared/models"
+	"github.com/microservices-platform/pkg/shared/rulestore"
 )
 
 // RedisStore provides Redis-based storage for the UI backend.
@@ -79,68 +81,38 @@ func (s *RedisStore) GetMetrics(ctx context.Context, service models.ServiceName,
 }
 
 // GetLatestMetric returns the latest aggregated metric for a service.
+// The analyzer stores metrics by canonical type, so the aggregate is assembled
+// from the metric catalogue; percentile metrics are computed over the latency
+// samples in the last latencyWindow.
 func (s *RedisStore) GetLatestMetric(ctx context.Context, service models.ServiceName) (*models.ServiceMetric, error) {
-	// The analyzer stores metrics by type, so we need to aggregate them
-	metricTypes := []models.MetricType{
-		models.MetricTypeCPU,
-		models.MetricTypeMemory,
-		models.MetricTypeLatency,
-		models.MetricTypeError,
-	}
-
 	result := &models.ServiceMetric{
 		ServiceName: service,
-		Timestamp:   time.Now(),
 	}
 
 	foundAny := false
 
-	for _, metricType := range metricTypes {
-		// Try the latest key first (set by analyzer)
-		latestKey := fmt.Sprintf("metrics:latest:%s:%s", service, metricType)
-		data, err := s.client.Get(ctx, latestKey).Result()
-		if err == nil && data != "" {
-			var metric models.ServiceMetric
-			if err := json.Unmarshal([]byte(data), &metric); err == nil {
-				foundAny = true
-				switch metricType {
-				case models.MetricTypeCPU:
-					result.CPUUsage = metric.Value
-				case models.MetricTypeMemory:
-					result.MemoryUsage = metric.Value
-				case models.MetricTypeLatency:
-					result.LatencyP95 = metric.Value
-				case models.MetricTypeError:
-					result.ErrorRate = metric.Value
-				}
-				if metric.Timestamp.After(result.Timestamp) || result.Timestamp.IsZero() {
-					result.Timestamp = metric.Timestamp
-				}
+	for _, def := range models.MetricCatalog() {
+		if def.Derived() {
+			samples, err := s.getMetricsByType(ctx, service, def.Source, latencyWindow)
+			if err != nil || len(samples) == 0 {
+				continue
 			}
+			def.Apply(result, models.Quantile(models.SampleValues(samples), def.Quantile))
 			continue
 		}
 
-		// Fallback to sorted set
-		sortedKey := fmt.Sprintf("metrics:%s:%s", service, metricType)
-		results, err := s.client.ZRevRange(ctx, sortedKey, 0, 0).Result()
-		if err == nil && len(results) > 0 {
-			var metric models.ServiceMetric
-			if err := json.Unmarshal([]byte(results[0]), &metric); err == nil {
-				foundAny = true
-				switch metricType {
-				case models.MetricTypeCPU:
-					result.CPUUsage = metric.Value
-				case models.MetricTypeMemory:
-					result.MemoryUsage = metric.Value
-				case models.MetricTypeLatency:
-					result.LatencyP95 = metric.Value
-				case models.MetricTypeError:
-					result.ErrorRate = metric.Value
-				}
-				if metric.Timestamp.After(result.Timestamp) || result.Timestamp.IsZero() {
-					result.Timestamp = metric.Timestamp
-				}
+		// Legacy aliases are checked after the canonical type
+		for _, metricType := range append([]models.MetricType{def.Type}, def.Aliases...) {
+			metric := s.getLatestByType(ctx, service, metricType)
+			if metric == nil {
+				continue
+			}
+			foundAny = true
+			def.Apply(result, metric.Value)
+			if metric.Timestamp.After(result.Timestamp) {
+				result.Timestamp = metric.Timestamp
 			}
+			break
 		}
 	}
 
@@ -151,6 +123,144 @@ func (s *RedisStore) GetLatestMetric(ctx context.Context, service models.Service
 	return result, nil
 }
 
+// latencyWindow is the window over which dashboard latency percentiles are computed.
+const latencyWindow = 5 * time.Minute
+
+// getLatestByType returns the most recent sample of one metric type, or nil.
+func (s *RedisStore) getLatestByType(ctx context.Context, service models.ServiceName, metricType models.MetricType) *models.ServiceMetric {
+	// Try the latest key first (set by analyzer)
+	latestKey := fmt.Sprintf("metrics:latest:%s:%s", service, metricType)
+	data, err := s.client.Get(ctx, latestKey).Result()
+	if err == nil && data != "" {
+		var metric models.ServiceMetric
+		if err := json.Unmarshal([]byte(data), &metric); err == nil {
+			return &metric
+		}
+	}
+
+	// Fallback to sorted set
+	sortedKey := fmt.Sprintf("metrics:%s:%s", service, metricType)
+	results, err := s.client.ZRevRange(ctx, sortedKey, 0, 0).Result()
+	if err == nil && len(results) > 0 {
+		var metric models.ServiceMetric
+		if err := json.Unmarshal([]byte(results[0]), &metric); err == nil {
+			return &metric
+		}
+	}
+
+	return nil
+}
+
+// getMetricsByType returns the samples of one metric type within a time window.
+func (s *RedisStore) getMetricsByType(ctx context.Context, service models.ServiceName, metricType models.MetricType, window time.Duration) ([]*models.ServiceMetric, error) {
+	now := time.Now()
+	return s.GetMetricsRange(ctx, service, metricType, now.Add(-window), now)
+}
+
+// GetMetricsRange returns the stored samples of one metric series between from
+// and to, oldest first. Samples stored under legacy alias names are included.
+func (s *RedisStore) GetMetricsRange(ctx context.Context, service models.ServiceName, metricType models.MetricType, from, to time.Time) ([]*models.ServiceMetric, error) {
+	metricTypes := []models.MetricType{metricType}
+	if def, ok := models.LookupMetric(metricType); ok {
+		metricTypes = append([]models.MetricType{def.Type}, def.Aliases...)
+	}
+
+	var metrics []*models.ServiceMetric
+	for _, t := range metricTypes {
+		key := fmt.Sprintf("metrics:%s:%s", service, t)
+		results, err := s.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+			Min: fmt.Sprintf("%d", from.UnixNano()),
+			Max: fmt.Sprintf("%d", to.UnixNano()),
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			var metric models.ServiceMetric
+			if err := json.Unmarshal([]byte(r), &metric); err != nil {
+				continue
+			}
+			metrics = append(metrics, &metric)
+		}
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Timestamp.Before(metrics[j].Timestamp)
+	})
+
+	return metrics, nil
+}
+
+// GetSeries returns the labelled series of a service metric indexed by the
+// analyzer, most recently seen first.
+func (s *RedisStore) GetSeries(ctx context.Context, service models.ServiceName, metricType models.MetricType) ([]*models.SeriesInfo, error) {
+	results, err := s.client.ZRevRangeWithScores(ctx, models.SeriesIndexKey(service, metricType), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]*models.SeriesInfo, 0, len(results))
+	for _, z := range results {
+		member, _ := z.Member.(string)
+		var labels models.Labels
+		if err := json.Unmarshal([]byte(member), &labels); err != nil {
+			continue
+		}
+		series = append(series, &models.SeriesInfo{
+			ID:       models.SeriesID(metricType, labels),
+			Metric:   metricType,
+			Labels:   labels,
+			LastSeen: time.Unix(int64(z.Score), 0),
+		})
+	}
+
+	return series, nil
+}
+
+// GetServices returns the services discovered by the analyzer from the metric stream.
+func (s *RedisStore) GetServices(ctx context.Context) ([]*models.ServiceRegistration, error) {
+	results, err := s.client.HGetAll(ctx, "registry:services").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]*models.ServiceRegistration, 0, len(results))
+	for _, v := range results {
+		var registration models.ServiceRegistration
+		if err := json.Unmarshal([]byte(v), &registration); err != nil {
+			continue
+		}
+		services = append(services, &registration)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ServiceName < services[j].ServiceName
+	})
+
+	return services, nil
+}
+
+// GetServiceHealth returns the health status the analyzer recorded for a service.
+func (s *RedisStore) GetServiceHealth(ctx context.Context, service models.ServiceName) (*models.ServiceHealthStatus, error) {
+	key := fmt.Sprintf("health:%s", service)
+
+	data, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var health models.ServiceHealthStatus
+	if err := json.Unmarshal([]byte(data), &health); err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
 // StoreMetric stores a metric.
 func (s *RedisStore) StoreMetric(ctx context.Context, metric *models.ServiceMetric) error {
 	key := fmt.Sprintf("metrics:%s", metric.ServiceName)
@@ -175,19 +285,48 @@ func (s *RedisStore) StoreMetric(ctx context.Context, metric *models.ServiceMetr
 	return nil
 }
 
-// GetAlerts returns alerts with pagination.
+// alertsKey is the sorted set of alert fingerprints, scored by the Unix
+// time of their latest firing.
+const alertsKey = "alerts"
+
+// alertRetention is how long an alert is kept after its latest firing.
+const alertRetention = 7 * 24 * time.Hour
+
+// alertKey is the key of the latest firing of an alert fingerprint.
+func alertKey(fingerprint string) string {
+	return "alert:" + fingerprint
+}
+
+// GetAlerts returns the latest firing of each alert with pagination, most
+// recent first.
 func (s *RedisStore) GetAlerts(ctx context.Context, serviceName, severity string, page, limit int) ([]*models.Alert, int, error) {
-	key := "alerts"
+	fingerprints, err := s.client.ZRevRange(ctx, alertsKey, 0, -1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(fingerprints) == 0 {
+		return []*models.Alert{}, 0, nil
+	}
 
-	results, err := s.client.ZRevRange(ctx, key, 0, -1).Result()
+	keys := make([]string, len(fingerprints))
+	for i, fingerprint := range fingerprints {
+		keys[i] = alertKey(fingerprint)
+	}
+	results, err := s.client.MGet(ctx, keys...).Result()
 	if err != nil {
 		return nil, 0, err
 	}
 
 	alerts := make([]*models.Alert, 0)
 	for _, r := range results {
+		data, ok := r.(string)
+		if !ok {
+			// Expired alerts are dropped from the set on the next store
+			continue
+		}
+
 		var alert models.Alert
-		if err := json.Unmarshal([]byte(r), &alert); err != nil {
+		if err := json.Unmarshal([]byte(data), &alert); err != nil {
 			continue
 		}
 
@@ -216,11 +355,9 @@ func (s *RedisStore) GetAlerts(ctx context.Context, serviceName, severity string
 	return alerts[start:end], total, nil
 }
 
-// GetAlert returns a single alert by ID.
-func (s *RedisStore) GetAlert(ctx context.Context, alertID string) (*models.Alert, error) {
-	key := fmt.Sprintf("alert:%s", alertID)
-
-	data, err := s.client.Get(ctx, key).Result()
+// GetAlert returns the latest firing of an alert by fingerprint.
+func (s *RedisStore) GetAlert(ctx context.Context, fingerprint string) (*models.Alert, error) {
+	data, err := s.client.Get(ctx, alertKey(fingerprint)).Result()
 	if err == redis.Nil {
 		return nil, nil
 	}
@@ -236,29 +373,35 @@ func (s *RedisStore) GetAlert(ctx context.Context, alertID string) (*models.Aler
 	return &alert, nil
 }
 
-// StoreAlert stores an alert.
+// StoreAlert stores an alert under its fingerprint, so a re-fire of the same
+// problem replaces its previous firing.
 func (s *RedisStore) StoreAlert(ctx context.Context, alert *models.Alert) error {
+	fingerprint := alert.EnsureFingerprint()
+
 	data, err := json.Marshal(alert)
 	if err != nil {
 		return err
 	}
 
-	key := fmt.Sprintf("alert:%s", alert.ID)
-	if err := s.client.Set(ctx, key, data, 7*24*time.Hour).Err(); err != nil {
+	if err := s.client.Set(ctx, alertKey(fingerprint), data, alertRetention).Err(); err != nil {
 		return err
 	}
 
-	listKey := "alerts"
 	score := float64(alert.Timestamp.Unix())
-	return s.client.ZAdd(ctx, listKey, redis.Z{
+	if err := s.client.ZAdd(ctx, alertsKey, redis.Z{
 		Score:  score,
-		Member: string(data),
-	}).Err()
+		Member: fingerprint,
+	}).Err(); err != nil {
+		return err
+	}
+
+	cutoff := float64(time.Now().Add(-alertRetention).Unix())
+	return s.client.ZRemRangeByScore(ctx, alertsKey, "-inf", fmt.Sprintf("%f", cutoff)).Err()
 }
 
-// AcknowledgeAlert acknowledges an alert.
-func (s *RedisStore) AcknowledgeAlert(ctx context.Context, alertID, userID string) error {
-	key := fmt.Sprintf("alert:%s", alertID)
+// AcknowledgeAlert acknowledges the latest firing of an alert by fingerprint.
+func (s *RedisStore) AcknowledgeAlert(ctx context.Context, fingerprint, userID string) error {
+	key := alertKey(fingerprint)
 
 	data, err := s.client.Get(ctx, key).Result()
 	if err != nil {
@@ -280,14 +423,60 @@ func (s *RedisStore) AcknowledgeAlert(ctx context.Context, alertID, userID strin
 		return err
 	}
 
-	return s.client.Set(ctx, key, newData, 7*24*time.Hour).Err()
+	return s.client.Set(ctx, key, newData, alertRetention).Err()
+}
+
+// legacyRulesKey is the hash the ui-backend kept rules in before sharing the
+// analyzer's.
+const legacyRulesKey = "rules"
+
+// MigrateLegacyRules moves rules from the legacy hash into the analyzer's,
+// keeping the analyzer's copy of a rule stored in both, and removes the
+// legacy hash. It returns how many rules were moved; once the legacy hash is
+// gone it does nothing.
+func (s *RedisStore) MigrateLegacyRules(ctx context.Context) (int, error) {
+	migrated := 0
+	txf := func(tx *redis.Tx) error {
+		legacy, err := tx.HGetAll(ctx, legacyRulesKey).Result()
+		if err != nil {
+			return err
+		}
+		if len(legacy) == 0 {
+			return nil
+		}
+
+		var moved []*redis.BoolCmd
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for id, data := range legacy {
+				moved = append(moved, pipe.HSetNX(ctx, rulestore.RulesKey, id, data))
+			}
+			pipe.Del(ctx, legacyRulesKey)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, cmd := range moved {
+			if cmd.Val() {
+				migrated++
+			}
+		}
+		return nil
+	}
+
+	if err := s.client.Watch(ctx, txf, legacyRulesKey); err != nil {
+		// Another replica migrated the rules first
+		if err == redis.TxFailedErr {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to migrate legacy rules: %w", err)
+	}
+	return migrated, nil
 }
 
 // GetRules returns all threshold rules.
 func (s *RedisStore) GetRules(ctx context.Context) ([]*models.ThresholdRule, error) {
-	key := "rules"
-
-	results, err := s.client.HGetAll(ctx, key).Result()
+	results, err := s.client.HGetAll(ctx, rulestore.RulesKey).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -310,9 +499,7 @@ func (s *RedisStore) GetRules(ctx context.Context) ([]*models.ThresholdRule, err
 
 // GetRule returns a single rule by ID.
 func (s *RedisStore) GetRule(ctx context.Context, ruleID string) (*models.ThresholdRule, error) {
-	key := "rules"
-
-	data, err := s.client.HGet(ctx, key, ruleID).Result()
+	data, err := s.client.HGet(ctx, rulestore.RulesKey, ruleID).Result()
 	if err == redis.Nil {
 		return nil, nil
 	}
@@ -329,32 +516,109 @@ func (s *RedisStore) GetRule(ctx context.Context, ruleID string) (*models.Thresh
 }
 
 // CreateRule creates a new threshold rule.
-func (s *RedisStore) CreateRule(ctx context.Context, rule *models.ThresholdRule) error {
+func (s *RedisStore) CreateRule(ctx context.Context, rule *models.ThresholdRule, author string) error {
 	if rule.ID == "" {
 		rule.ID = uuid.New().String()
 	}
 
-	data, err := json.Marshal(rule)
-	if err != nil {
+	return rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action: models.RuleActionCreate,
+		Author: author,
+		Rule:   rule,
+	})
+}
+
+// UpdateRule updates a threshold rule.
+func (s *RedisStore) UpdateRule(ctx context.Context, rule *models.ThresholdRule, author string) error {
+	return rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action: models.RuleActionUpdate,
+		Author: author,
+		Rule:   rule,
+	})
+}
+
+// DeleteRule deletes a threshold rule. Its version history is kept.
+func (s *RedisStore) DeleteRule(ctx context.Context, ruleID string, author string) error {
+	rule, err := s.GetRule(ctx, ruleID)
+	if err != nil || rule == nil {
 		return err
 	}
 
-	return s.client.HSet(ctx, "rules", rule.ID, string(data)).Err()
+	return rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action: models.RuleActionDelete,
+		Author: author,
+		Rule:   rule,
+	})
 }
 
-// UpdateRule updates a threshold rule.
-func (s *RedisStore) UpdateRule(ctx context.Context, rule *models.ThresholdRule) error {
-	data, err := json.Marshal(rule)
+// RollbackRule restores a rule to the state it had at a previous version,
+// recreating it if it was deleted since. It returns nil if the version does
+// not exist.
+func (s *RedisStore) RollbackRule(ctx context.Context, ruleID string, version int, author string) (*models.ThresholdRule, error) {
+	target, err := s.GetRuleVersion(ctx, ruleID, version)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	if target.State() == nil {
+		return nil, fmt.Errorf("version %d deleted the rule", version)
+	}
+
+	rule := *target.State()
+	rule.UpdatedAt = time.Now()
+
+	err = rulestore.Write(ctx, s.client, &models.RuleVersion{
+		Action:     models.RuleActionRollback,
+		Author:     author,
+		Rule:       &rule,
+		RollbackTo: version,
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// GetRuleVersions returns the version history of a rule, oldest first.
+func (s *RedisStore) GetRuleVersions(ctx context.Context, ruleID string) ([]*models.RuleVersion, error) {
+	results, err := s.client.LRange(ctx, rulestore.VersionsKey(ruleID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*models.RuleVersion, 0, len(results))
+	for _, r := range results {
+		var version models.RuleVersion
+		if err := json.Unmarshal([]byte(r), &version); err != nil {
+			continue
+		}
+		versions = append(versions, &version)
 	}
 
-	return s.client.HSet(ctx, "rules", rule.ID, string(data)).Err()
+	return versions, nil
 }
 
-// DeleteRule deletes a threshold rule.
-func (s *RedisStore) DeleteRule(ctx context.Context, ruleID string) error {
-	return s.client.HDel(ctx, "rules", ruleID).Err()
+// GetRuleVersion returns a single version of a rule, or nil if it does not exist.
+func (s *RedisStore) GetRuleVersion(ctx context.Context, ruleID string, version int) (*models.RuleVersion, error) {
+	if version < 1 {
+		return nil, nil
+	}
+
+	// Versions are numbered from 1 without gaps
+	data, err := s.client.LIndex(ctx, rulestore.VersionsKey(ruleID), int64(version-1)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var v models.RuleVersion
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
 }
 
 // DashboardStats represents dashboard statistics.
@@ -371,44 +635,61 @@ type DashboardStats struct {
 
 // ServiceStats represents statistics for a service.
 type ServiceStats struct {
-	Name        string  `json:"name"`
-	Status      string  `json:"status"`
-	CPUUsage    float64 `json:"cpu_usage"`
-	MemoryUsage float64 `json:"memory_usage"`
-	LatencyP95  float64 `json:"latency_p95"`
-	ErrorRate   float64 `json:"error_rate"`
-	LastUpdate  string  `json:"last_update"`
+	Name          string  `json:"name"`
+	Status        string  `json:"status"`
+	CPUUsage      float64 `json:"cpu_usage"`
+	MemoryUsage   float64 `json:"memory_usage"`
+	LatencyP95    float64 `json:"latency_p95"`
+	ErrorRate     float64 `json:"error_rate"`
+	LastUpdate    string  `json:"last_update"`
+	LastHeartbeat string  `json:"last_heartbeat,omitempty"`
+	Version       string  `json:"version,omitempty"`
+	Instances     int     `json:"instances"`
 }
 
 // GetDashboardStats returns dashboard statistics.
 func (s *RedisStore) GetDashboardStats(ctx context.Context) (*DashboardStats, error) {
+	registered, err := s.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	stats := &DashboardStats{
-		TotalServices:   4,
-		HealthyServices: 4,
+		TotalServices:   len(registered),
+		HealthyServices: len(registered),
 		ServiceStats:    make(map[string]*ServiceStats),
 	}
 
-	services := []models.ServiceName{
-		models.ServiceNameAuth,
-		models.ServiceNameOrders,
-		models.ServiceNamePayments,
-		models.ServiceNameNotification,
-	}
+	for _, registration := range registered {
+		service := registration.ServiceName
+		health, err := s.GetServiceHealth(ctx, service)
+		if err != nil {
+			s.logger.Warn("failed to get service health",
+				zap.String("service", string(service)),
+				zap.Error(err),
+			)
+		}
+		stale := health != nil && health.Status == models.StatusStale
 
-	for _, service := range services {
 		metric, err := s.GetLatestMetric(ctx, service)
 		if err != nil {
 			continue
 		}
 
 		status := "healthy"
+		if metric == nil && stale {
+			metric = &models.ServiceMetric{ServiceName: service, Timestamp: health.LastHeartbeat}
+		}
 		if metric != nil {
-			if metric.CPUUsage > 80 || metric.MemoryUsage > 80 || metric.ErrorRate > 5 {
+			if stale {
+				status = string(models.StatusStale)
+				stats.HealthyServices--
+			} else if metric.CPUUsage > 80 || metric.MemoryUsage > 80 || metric.ErrorRate > 5 {
 				status = "warning"
 				stats.HealthyServices--
 			}
 
-			stats.ServiceStats[string(service)] = &ServiceStats{
+			serviceStats := &ServiceStats{
 				Name:        string(service),
 				Status:      status,
 				CPUUsage:    metric.CPUUsage,
@@ -416,7 +697,13 @@ func (s *RedisStore) GetDashboardStats(ctx context.Context) (*DashboardStats, er
 				LatencyP95:  metric.LatencyP95,
 				ErrorRate:   metric.ErrorRate,
 				LastUpdate:  metric.Timestamp.Format(time.RFC3339),
+				Version:     registration.Version,
+				Instances:   len(registration.Instances),
+			}
+			if health != nil && !health.LastHeartbeat.IsZero() {
+				serviceStats.LastHeartbeat = health.LastHeartbeat.Format(time.RFC3339)
 			}
+			stats.ServiceStats[string(service)] = serviceStats
 		}
 	}
 